@@ -27,7 +27,23 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var nvmlOnce *sync.Once = new(sync.Once)
+var (
+	nvmlOnce    *sync.Once = new(sync.Once)
+	nvmlInitErr error
+)
+
+// initNVML initializes the NVML library once, and returns the error of the initialization on every call.
+func initNVML() error {
+	nvmlOnce.Do(func() {
+		ret := nvml.Init()
+		if ret != nvml.SUCCESS {
+			nvmlInitErr = errors.New(nvml.ErrorString(ret))
+			logrus.Error("Can not init NVML library.")
+		}
+	})
+
+	return nvmlInitErr
+}
 
 type MIGDeviceInfo struct {
 	ParentUUID        string
@@ -37,15 +53,7 @@ type MIGDeviceInfo struct {
 
 // GetMIGDeviceInfoByID returns information about MIG DEVICE by ID
 func GetMIGDeviceInfoByID(uuid string) (*MIGDeviceInfo, error) {
-	var err error
-
-	nvmlOnce.Do(func() {
-		ret := nvml.Init()
-		if ret != nvml.SUCCESS {
-			err = errors.New(nvml.ErrorString(ret))
-			logrus.Error("Can not init NVML library.")
-		}
-	})
+	err := initNVML()
 	if err != nil {
 		return nil, err
 	}
@@ -112,3 +120,199 @@ func GetMIGDeviceInfoByID(uuid string) (*MIGDeviceInfo, error) {
 		ComputeInstanceID: ci,
 	}, nil
 }
+
+type DeviceUtilization struct {
+	GPU         uint32 // Percent of time a kernel was running
+	Memory      uint32 // Percent of time the device memory was read or written
+	MemoryTotal uint64 // Bytes
+	MemoryFree  uint64 // Bytes
+	MemoryUsed  uint64 // Bytes
+}
+
+// GetDeviceUtilizationByUUID returns the utilization and memory usage of the GPU with the UUID
+func GetDeviceUtilizationByUUID(uuid string) (*DeviceUtilization, error) {
+	err := initNVML()
+	if err != nil {
+		return nil, err
+	}
+
+	device, ret := nvml.DeviceGetHandleByUUID(uuid)
+	if ret != nvml.SUCCESS {
+		return nil, errors.New(nvml.ErrorString(ret))
+	}
+
+	utilization, ret := device.GetUtilizationRates()
+	if ret != nvml.SUCCESS {
+		return nil, errors.New(nvml.ErrorString(ret))
+	}
+
+	memory, ret := device.GetMemoryInfo()
+	if ret != nvml.SUCCESS {
+		return nil, errors.New(nvml.ErrorString(ret))
+	}
+
+	return &DeviceUtilization{
+		GPU:         utilization.Gpu,
+		Memory:      utilization.Memory,
+		MemoryTotal: memory.Total,
+		MemoryFree:  memory.Free,
+		MemoryUsed:  memory.Used,
+	}, nil
+}
+
+// GetComputeCapabilityByUUID returns the CUDA compute capability of the GPU with the UUID, e.g. 8.0
+func GetComputeCapabilityByUUID(uuid string) (string, error) {
+	err := initNVML()
+	if err != nil {
+		return "", err
+	}
+
+	device, ret := nvml.DeviceGetHandleByUUID(uuid)
+	if ret != nvml.SUCCESS {
+		return "", errors.New(nvml.ErrorString(ret))
+	}
+
+	major, minor, ret := device.GetCudaComputeCapability()
+	if ret != nvml.SUCCESS {
+		return "", errors.New(nvml.ErrorString(ret))
+	}
+
+	return fmt.Sprintf("%d.%d", major, minor), nil
+}
+
+// GetEncoderSessionCountByUUID returns the number of active encoder sessions of the GPU with the UUID
+func GetEncoderSessionCountByUUID(uuid string) (int, error) {
+	err := initNVML()
+	if err != nil {
+		return 0, err
+	}
+
+	device, ret := nvml.DeviceGetHandleByUUID(uuid)
+	if ret != nvml.SUCCESS {
+		return 0, errors.New(nvml.ErrorString(ret))
+	}
+
+	sessionCount, _, _, ret := device.GetEncoderStats()
+	if ret != nvml.SUCCESS {
+		return 0, errors.New(nvml.ErrorString(ret))
+	}
+
+	return sessionCount, nil
+}
+
+// GetGPUSlicesByUUID returns the number of slices of the GPU with the UUID, which its largest GPU instance
+// profile takes. It fails when the GPU does not support MIG.
+func GetGPUSlicesByUUID(uuid string) (uint, error) {
+	err := initNVML()
+	if err != nil {
+		return 0, err
+	}
+
+	device, ret := nvml.DeviceGetHandleByUUID(uuid)
+	if ret != nvml.SUCCESS {
+		return 0, errors.New(nvml.ErrorString(ret))
+	}
+
+	var sliceCount uint32
+	for profile := nvml.GPU_INSTANCE_PROFILE_1_SLICE; profile < nvml.GPU_INSTANCE_PROFILE_COUNT; profile++ {
+		info, ret := device.GetGpuInstanceProfileInfo(profile)
+		// The GPU does not support every profile
+		if ret != nvml.SUCCESS {
+			continue
+		}
+
+		sliceCount = max(sliceCount, info.SliceCount)
+	}
+
+	if sliceCount == 0 {
+		return 0, errors.New("no GPU instance profile is supported")
+	}
+
+	return uint(sliceCount), nil
+}
+
+// ProcessUtilization is the utilization of a GPU by a process over its accounting interval
+type ProcessUtilization struct {
+	PID    uint32
+	GPU    uint32 // Percent of time a kernel of the process was running
+	Memory uint32 // Percent of time the device memory was read or written by the process
+}
+
+// GetProcessUtilizationByUUID returns the utilization of the GPU with the UUID by its running processes, from the
+// accounting stats of the GPU. It fails when accounting is disabled.
+func GetProcessUtilizationByUUID(uuid string) ([]ProcessUtilization, error) {
+	err := initNVML()
+	if err != nil {
+		return nil, err
+	}
+
+	device, ret := nvml.DeviceGetHandleByUUID(uuid)
+	if ret != nvml.SUCCESS {
+		return nil, errors.New(nvml.ErrorString(ret))
+	}
+
+	pids, ret := device.GetAccountingPids()
+	if ret != nvml.SUCCESS {
+		return nil, errors.New(nvml.ErrorString(ret))
+	}
+
+	var processes []ProcessUtilization
+	for _, pid := range pids {
+		stats, ret := device.GetAccountingStats(uint32(pid))
+		// The process may have exited and its stats expired since the pids were listed
+		if ret != nvml.SUCCESS || stats.IsRunning == 0 {
+			continue
+		}
+
+		processes = append(processes, ProcessUtilization{
+			PID:    uint32(pid),
+			GPU:    stats.GpuUtilization,
+			Memory: stats.MemoryUtilization,
+		})
+	}
+
+	return processes, nil
+}
+
+// FanSpeed is the speed of a fan of a GPU
+type FanSpeed struct {
+	Fan         int
+	Speed       uint32 // Percent of the maximum speed of the fan
+	TargetSpeed int    // Percent of the maximum speed, which the fan is driven at
+}
+
+// GetFanSpeedsByUUID returns the speed of every fan of the GPU with the UUID, which can be read. It returns no fans
+// for GPUs without a fan, and fails when the GPU does not report its fans.
+func GetFanSpeedsByUUID(uuid string) ([]FanSpeed, error) {
+	err := initNVML()
+	if err != nil {
+		return nil, err
+	}
+
+	device, ret := nvml.DeviceGetHandleByUUID(uuid)
+	if ret != nvml.SUCCESS {
+		return nil, errors.New(nvml.ErrorString(ret))
+	}
+
+	fanCount, ret := device.GetNumFans()
+	if ret != nvml.SUCCESS {
+		return nil, errors.New(nvml.ErrorString(ret))
+	}
+
+	var fans []FanSpeed
+	for fan := 0; fan < fanCount; fan++ {
+		speed, ret := device.GetFanSpeed_v2(fan)
+		if ret != nvml.SUCCESS {
+			continue
+		}
+
+		targetSpeed, ret := device.GetTargetFanSpeed(fan)
+		if ret != nvml.SUCCESS {
+			continue
+		}
+
+		fans = append(fans, FanSpeed{Fan: fan, Speed: speed, TargetSpeed: targetSpeed})
+	}
+
+	return fans, nil
+}
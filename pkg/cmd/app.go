@@ -71,6 +71,54 @@ const (
 	CLIClockEventsCountWindowSize = "clock-events-count-window-size"
 	CLIEnableDCGMLog              = "enable-dcgm-log"
 	CLIDCGMLogLevel               = "dcgm-log-level"
+	CLISampledFields              = "sampled-fields"
+	CLIFailedConversionsAsNaN     = "failed-conversions-as-nan"
+	CLIDCGMMode                   = "dcgm-mode"
+	CLIStaleMetricsMaxAge         = "stale-metrics-max-age"
+	CLIMaxSeries                  = "max-series"
+	CLISelfTest                   = "selftest"
+	CLIFieldsWatchRetries         = "fields-watch-retries"
+	CLIFieldsWatchRetryBackoff    = "fields-watch-retry-backoff"
+	CLIShortestFloatFormat        = "shortest-float-format"
+	CLIIdleCollectInterval        = "idle-collect-interval"
+	CLIEnableDiag                 = "enable-diag"
+	CLIEnableDebugEndpoints       = "enable-debug-endpoints"
+	CLIPolicyViolations           = "policy-violations"
+	CLIConstantMetricsFile        = "constant-metrics-file"
+	CLIStuckFieldThreshold        = "stuck-field-threshold"
+	CLIStuckFields                = "stuck-fields"
+	CLIMaxLabelValueLength        = "max-label-value-length"
+	CLIEntityGroupAddresses       = "entity-group-addresses"
+	CLICollectorsWatchInterval    = "collectors-watch-interval"
+	CLIFloatZeroThreshold         = "float-zero-threshold"
+	CLIBackgroundCollection       = "background-collection"
+	CLISumFields                  = "sum-fields"
+	CLINVMLFallback               = "nvml-fallback"
+	CLIEnableCounterOK            = "enable-counter-ok"
+	CLITensorCapabilities         = "tensor-capabilities"
+	CLIDuplicateFieldPolicy       = "duplicate-field-policy"
+	CLIComputeCapabilityLabel     = "compute-capability-label"
+	CLIJobName                    = "job-name"
+	CLIInstanceID                 = "instance-id"
+	CLIEmitFieldInfo              = "emit-field-info"
+	CLIAllowHostnameHeader        = "allow-hostname-header"
+	CLITemperatureUnit            = "temperature-unit"
+	CLIUtilizationWindow          = "utilization-window"
+	CLIHostengineHealth           = "hostengine-health"
+	CLIMaxClockSkew               = "max-clock-skew"
+	CLIPowerPeakWindow            = "power-peak-window"
+	CLIEmitRuntimeMetrics         = "emit-runtime-metrics"
+	CLICounterFiles               = "counter-files"
+	CLIPreloadCache               = "preload-cache"
+	CLIAttributedFields           = "attributed-fields"
+	CLIEmitRawValues              = "emit-raw-values"
+	CLICodecSessions              = "codec-sessions"
+	CLIFanStatus                  = "fan-status"
+	CLIMetricsChecksum            = "metrics-checksum"
+	CLIProcessUtilizationTopN     = "process-utilization-top-n"
+	CLIGPUIndexRemap              = "gpu-index-remap"
+	CLISkipValues                 = "skip-values"
+	CLISuppressUnchangedWindow    = "suppress-unchanged-window"
 )
 
 func NewApp(buildVersion ...string) *cli.App {
@@ -222,6 +270,299 @@ func NewApp(buildVersion ...string) *cli.App {
 			Usage:   "Specify the DCGM log verbosity level. This parameter is effective only when the '--enable-dcgm-log' option is set to 'true'. Possible values: NONE, FATAL, ERROR, WARN, INFO, DEBUG and VERB",
 			EnvVars: []string{"DCGM_EXPORTER_DCGM_LOG_LEVEL"},
 		},
+		&cli.StringFlag{
+			Name:    CLISampledFields,
+			Value:   "",
+			Usage:   "Comma-separated list of GPU fields, for which every sample taken during the collect interval is exported with its timestamp, instead of only the latest value.",
+			EnvVars: []string{"DCGM_EXPORTER_SAMPLED_FIELDS"},
+		},
+		&cli.BoolFlag{
+			Name:    CLIFailedConversionsAsNaN,
+			Value:   false,
+			Usage:   "Export field values that cannot be converted as NaN with a 'conversion_failed' label, instead of dropping them.",
+			EnvVars: []string{"DCGM_EXPORTER_FAILED_CONVERSIONS_AS_NAN"},
+		},
+		&cli.StringFlag{
+			Name:  CLIDCGMMode,
+			Value: "",
+			Usage: fmt.Sprintf("Specify how to connect to DCGM. Possible values: '%s', '%s', '%s'. Defaults to '%s' when '--%s' is set, and '%s' otherwise.",
+				dcgmexporter.DCGMModeEmbedded, dcgmexporter.DCGMModeStandalone, dcgmexporter.DCGMModeRemote,
+				dcgmexporter.DCGMModeRemote, CLIRemoteHEInfo, dcgmexporter.DCGMModeEmbedded),
+			EnvVars: []string{"DCGM_EXPORTER_DCGM_MODE"},
+		},
+		&cli.IntFlag{
+			Name:    CLIStaleMetricsMaxAge,
+			Value:   0,
+			Usage:   "Set the maximum age in milliseconds (ms) of the last collected metrics served with a 'stale' label while the DCGM connection is lost. 0 disables serving stale metrics.",
+			EnvVars: []string{"DCGM_EXPORTER_STALE_METRICS_MAX_AGE"},
+		},
+		&cli.IntFlag{
+			Name:    CLIMaxSeries,
+			Value:   0,
+			Usage:   "Set the maximum number of series exported by one collection. Counters with the most series are dropped first. 0 disables the limit.",
+			EnvVars: []string{"DCGM_EXPORTER_MAX_SERIES"},
+		},
+		&cli.BoolFlag{
+			Name:    CLISelfTest,
+			Value:   false,
+			Usage:   "Collect the configured counters once from a fake GPU, validate the exposition, and exit. Exits non-zero on any problem.",
+			EnvVars: []string{"DCGM_EXPORTER_SELFTEST"},
+		},
+		&cli.IntFlag{
+			Name:    CLIFieldsWatchRetries,
+			Value:   3,
+			Usage:   "Set the number of retries, when watching the fields in DCGM fails at startup.",
+			EnvVars: []string{"DCGM_EXPORTER_FIELDS_WATCH_RETRIES"},
+		},
+		&cli.IntFlag{
+			Name:    CLIFieldsWatchRetryBackoff,
+			Value:   1000,
+			Usage:   "Set the backoff in milliseconds (ms) before the first retry of watching the fields; doubled for every further retry.",
+			EnvVars: []string{"DCGM_EXPORTER_FIELDS_WATCH_RETRY_BACKOFF"},
+		},
+		&cli.BoolFlag{
+			Name:    CLIShortestFloatFormat,
+			Value:   false,
+			Usage:   "Format floating point values with the shortest representation that keeps their precision, instead of six decimals.",
+			EnvVars: []string{"DCGM_EXPORTER_SHORTEST_FLOAT_FORMAT"},
+		},
+		&cli.IntFlag{
+			Name:    CLIIdleCollectInterval,
+			Value:   0,
+			Usage:   "Set the interval in milliseconds (ms) at which the exporter polls idle GPUs, with a utilization near zero, for their values. Busy GPUs keep the collect interval. DCGM keeps sampling every GPU at the collect interval, so only the polling of the exporter is reduced. 0 polls every GPU at the collect interval.",
+			EnvVars: []string{"DCGM_EXPORTER_IDLE_COLLECT_INTERVAL"},
+		},
+		&cli.BoolFlag{
+			Name:    CLIEnableDiag,
+			Value:   false,
+			Usage:   "Enable the POST /diag?level=<1-4> endpoint, which runs a DCGM diagnostic in the background and exposes its result as metrics.",
+			EnvVars: []string{"DCGM_EXPORTER_ENABLE_DIAG"},
+		},
+		&cli.BoolFlag{
+			Name:    CLIEnableDebugEndpoints,
+			Value:   false,
+			Usage:   "Enable the GET /debug/config endpoint, which returns the effective configuration with its secrets redacted, and the GET /debug/diff endpoint, which returns the changes between the last collections. They expose paths and addresses to anyone, who can reach the metrics port.",
+			EnvVars: []string{"DCGM_EXPORTER_ENABLE_DEBUG_ENDPOINTS"},
+		},
+		&cli.StringFlag{
+			Name:    CLIPolicyViolations,
+			Value:   "dbe,pcie,nvlink,xid",
+			Usage:   "Comma-separated DCGM policies, whose violations DCGM_FI_DEV_POLICY_VIOLATIONS counts: dbe, pcie, nvlink, xid, max_retired_pages, thermal or power. Warning: counting the violations sets these policies on all GPUs of the hostengine and replaces the policies already set, e.g. by an admin of a shared or remote hostengine. max_retired_pages, thermal and power are set with the fixed thresholds of go-dcgm, 10 pages, 100 C and 250 W, so power is violated all the time by GPUs with a higher power limit.",
+			EnvVars: []string{"DCGM_EXPORTER_POLICY_VIOLATIONS"},
+		},
+		&cli.StringFlag{
+			Name:    CLIConstantMetricsFile,
+			Value:   "",
+			Usage:   "Path to a file of constant gauges in the Prometheus text format, which are exported on every scrape, e.g. SLO targets or capacity constants.",
+			EnvVars: []string{"DCGM_EXPORTER_CONSTANT_METRICS_FILE"},
+		},
+		&cli.IntFlag{
+			Name:    CLIStuckFieldThreshold,
+			Value:   0,
+			Usage:   "Export DCGM_EXPORTER_FIELD_STUCK for fields that return the identical value for this number of consecutive collections. 0 disables the detection.",
+			EnvVars: []string{"DCGM_EXPORTER_STUCK_FIELD_THRESHOLD"},
+		},
+		&cli.StringFlag{
+			Name:    CLIStuckFields,
+			Value:   "",
+			Usage:   "Comma-separated list of the DCGM fields checked for stuck values, e.g. DCGM_FI_DEV_GPU_UTIL,DCGM_FI_DEV_TOTAL_ENERGY_CONSUMPTION. DCGM_FI_DEV_POWER_USAGE and DCGM_FI_DEV_TOTAL_ENERGY_CONSUMPTION when empty.",
+			EnvVars: []string{"DCGM_EXPORTER_STUCK_FIELDS"},
+		},
+		&cli.IntFlag{
+			Name:    CLIMaxLabelValueLength,
+			Value:   0,
+			Usage:   "Truncate the values of label fields to this number of characters, ending with an ellipsis. 0 disables the limit.",
+			EnvVars: []string{"DCGM_EXPORTER_MAX_LABEL_VALUE_LENGTH"},
+		},
+		&cli.StringFlag{
+			Name:    CLIEntityGroupAddresses,
+			Value:   "",
+			Usage:   "Comma-separated list of <entity group>=<address>, which serve the metrics of an entity group on a separate address instead of the main one, e.g. switch=:9401,link=:9401. Entity groups: gpu, switch, link, cpu, cpu_core.",
+			EnvVars: []string{"DCGM_EXPORTER_ENTITY_GROUP_ADDRESSES"},
+		},
+		&cli.IntFlag{
+			Name:    CLICollectorsWatchInterval,
+			Value:   0,
+			Usage:   "Interval in milliseconds, at which the collectors file is checked for changes. A changed file reloads the counters like SIGHUP. 0 disables the check.",
+			EnvVars: []string{"DCGM_EXPORTER_COLLECTORS_WATCH_INTERVAL"},
+		},
+		&cli.Float64Flag{
+			Name:    CLIFloatZeroThreshold,
+			Value:   0,
+			Usage:   "Export 0 for double gauges, whose absolute value is below this threshold, e.g. 0.0001 to hide the noise of idle profiling fields. 0 disables the threshold.",
+			EnvVars: []string{"DCGM_EXPORTER_FLOAT_ZERO_THRESHOLD"},
+		},
+		&cli.BoolFlag{
+			Name:    CLIBackgroundCollection,
+			Value:   false,
+			Usage:   "Gather the exporter collectors every collect interval in the background, and serve the latest result on every scrape, instead of gathering them on every scrape.",
+			EnvVars: []string{"DCGM_EXPORTER_BACKGROUND_COLLECTION"},
+		},
+		&cli.StringFlag{
+			Name:    CLISumFields,
+			Value:   "",
+			Usage:   "Comma-separated list of the DCGM fields, which are also exported as the sum of all entities in a series with the aggregation=\"sum\" label, e.g. DCGM_FI_DEV_FB_USED.",
+			EnvVars: []string{"DCGM_EXPORTER_SUM_FIELDS"},
+		},
+		&cli.BoolFlag{
+			Name:    CLINVMLFallback,
+			Value:   false,
+			Usage:   "Report the GPU utilization, memory copy utilization and frame buffer fields from NVML, when DCGM has no value for them, e.g. because it lacks the permissions.",
+			EnvVars: []string{"DCGM_EXPORTER_NVML_FALLBACK"},
+		},
+		&cli.BoolFlag{
+			Name:    CLIEnableCounterOK,
+			Value:   false,
+			Usage:   "Export DCGM_EXPORTER_COUNTER_OK for every collected field, 1 when the field returned a value in the last collection and 0 otherwise.",
+			EnvVars: []string{"DCGM_EXPORTER_ENABLE_COUNTER_OK"},
+		},
+		&cli.StringFlag{
+			Name:    CLITensorCapabilities,
+			Value:   "",
+			Usage:   "Comma-separated list of <GPU model>=<peak tensor TFLOPS>, e.g. 'NVIDIA A100-SXM4-80GB=312'. Exports DCGM_EXPORTER_TENSOR_THROUGHPUT for the GPUs of these models, when DCGM_FI_PROF_PIPE_TENSOR_ACTIVE is collected.",
+			EnvVars: []string{"DCGM_EXPORTER_TENSOR_CAPABILITIES"},
+		},
+		&cli.StringFlag{
+			Name:  CLIDuplicateFieldPolicy,
+			Value: string(dcgmexporter.DuplicateFieldPolicyFirst),
+			Usage: fmt.Sprintf("Specify how a field ID, which is listed under several names in the counters file, is exported. Possible values: '%s' fails to load the counters, '%s' and '%s' export the field under the first or last name, '%s' exports it under every name.",
+				dcgmexporter.DuplicateFieldPolicyError, dcgmexporter.DuplicateFieldPolicyFirst,
+				dcgmexporter.DuplicateFieldPolicyLast, dcgmexporter.DuplicateFieldPolicyBoth),
+			EnvVars: []string{"DCGM_EXPORTER_DUPLICATE_FIELD_POLICY"},
+		},
+		&cli.BoolFlag{
+			Name:    CLIComputeCapabilityLabel,
+			Value:   false,
+			Usage:   "Label the metrics of GPUs with their CUDA compute capability, e.g. compute_capability=\"8.0\".",
+			EnvVars: []string{"DCGM_EXPORTER_COMPUTE_CAPABILITY_LABEL"},
+		},
+		&cli.StringFlag{
+			Name:    CLIJobName,
+			Value:   "",
+			Usage:   "Label the metrics with job=\"<job name>\", e.g. when they are pushed instead of scraped. Prometheus renames the label to exported_job on scrape, unless honor_labels is set.",
+			EnvVars: []string{"DCGM_EXPORTER_JOB_NAME"},
+		},
+		&cli.StringFlag{
+			Name:    CLIInstanceID,
+			Value:   "",
+			Usage:   "Label the metrics with instance=\"<instance ID>\", e.g. when they are pushed instead of scraped. Prometheus renames the label to exported_instance on scrape, unless honor_labels is set.",
+			EnvVars: []string{"DCGM_EXPORTER_INSTANCE_ID"},
+		},
+		&cli.BoolFlag{
+			Name:    CLIEmitFieldInfo,
+			Value:   false,
+			Usage:   "Export DCGM_EXPORTER_FIELD_INFO with the ID, name, type and entity group of every DCGM field, which can be collected on this hardware, to help writing the counters file.",
+			EnvVars: []string{"DCGM_EXPORTER_EMIT_FIELD_INFO"},
+		},
+		&cli.BoolFlag{
+			Name:    CLIAllowHostnameHeader,
+			Value:   false,
+			Usage:   "Replace the Hostname label of the metrics of a scrape with the X-DCGM-Hostname header of the request, e.g. when a proxy in front of the exporter knows the identity of the node. Only enable it when the exporter cannot be reached but through the proxy.",
+			EnvVars: []string{"DCGM_EXPORTER_ALLOW_HOSTNAME_HEADER"},
+		},
+		&cli.StringFlag{
+			Name:  CLITemperatureUnit,
+			Value: string(dcgmexporter.TemperatureUnitCelsius),
+			Usage: fmt.Sprintf("Specify the unit of the temperatures of the GPUs. Possible values: '%s' (Celsius), '%s' (Fahrenheit).",
+				dcgmexporter.TemperatureUnitCelsius, dcgmexporter.TemperatureUnitFahrenheit),
+			EnvVars: []string{"DCGM_EXPORTER_TEMPERATURE_UNIT"},
+		},
+		&cli.IntFlag{
+			Name:    CLIUtilizationWindow,
+			Value:   0,
+			Usage:   "Export DCGM_EXPORTER_WINDOWED_AVERAGE, the average of the utilization fields over the window (in ms), next to their instantaneous values. 0 disables it.",
+			EnvVars: []string{"DCGM_EXPORTER_UTILIZATION_WINDOW"},
+		},
+		&cli.BoolFlag{
+			Name:    CLIHostengineHealth,
+			Value:   false,
+			Usage:   "Export DCGM_EXPORTER_HOSTENGINE_HEALTHY, 0 when the DCGM hostengine fails to report its status, to tell failures of the hostengine from failures of the exporter.",
+			EnvVars: []string{"DCGM_EXPORTER_HOSTENGINE_HEALTH"},
+		},
+		&cli.IntFlag{
+			Name:    CLIMaxClockSkew,
+			Value:   0,
+			Usage:   "Replace the timestamps of the samples, which are more than this (in ms) ahead of the clock of the exporter, by the scrape time and count them in DCGM_EXPORTER_CLOCK_SKEW_EVENTS. 0 disables the check.",
+			EnvVars: []string{"DCGM_EXPORTER_MAX_CLOCK_SKEW"},
+		},
+		&cli.IntFlag{
+			Name:    CLIPowerPeakWindow,
+			Value:   0,
+			Usage:   "Export DCGM_FI_DEV_POWER_USAGE_PEAK, the maximum power draw of every GPU over the window (in ms). 0 disables it.",
+			EnvVars: []string{"DCGM_EXPORTER_POWER_PEAK_WINDOW"},
+		},
+		&cli.BoolFlag{
+			Name:    CLIEmitRuntimeMetrics,
+			Value:   false,
+			Usage:   "Export the goroutine, thread and memory gauges of the Go runtime, e.g. go_goroutines, to diagnose the footprint of the exporter.",
+			EnvVars: []string{"DCGM_EXPORTER_EMIT_RUNTIME_METRICS"},
+		},
+		&cli.StringFlag{
+			Name:    CLICounterFiles,
+			Value:   "",
+			Usage:   "Comma-separated list of <entity group>=<counters file>, which are collected for an entity group instead of the counters of -f, e.g. gpu=/etc/dcgm-exporter/gpu.csv,switch=/etc/dcgm-exporter/switch.csv. Entity groups: gpu, switch, link, cpu, cpu_core.",
+			EnvVars: []string{"DCGM_EXPORTER_COUNTER_FILES"},
+		},
+		&cli.BoolFlag{
+			Name:    CLIPreloadCache,
+			Value:   false,
+			Usage:   "Collect the metrics once at startup, before the first scrape, so that the values cached across collections, e.g. the compute capabilities, are already in the first scrape.",
+			EnvVars: []string{"DCGM_EXPORTER_PRELOAD_CACHE"},
+		},
+		&cli.StringFlag{
+			Name:    CLIAttributedFields,
+			Value:   "",
+			Usage:   "Comma-separated list of fields, whose GPU value is attributed to the MIG instances of the GPU by their slices of the GPU, e.g. DCGM_FI_DEV_POWER_USAGE. MIG instances report the GPU value of other fields.",
+			EnvVars: []string{"DCGM_EXPORTER_ATTRIBUTED_FIELDS"},
+		},
+		&cli.BoolFlag{
+			Name:    CLIEmitRawValues,
+			Value:   false,
+			Usage:   "Export the untransformed value of the fields, whose value is transformed, e.g. by a value expression or the MIG attribution, as <field>_raw next to the transformed one.",
+			EnvVars: []string{"DCGM_EXPORTER_EMIT_RAW_VALUES"},
+		},
+		&cli.BoolFlag{
+			Name:    CLICodecSessions,
+			Value:   false,
+			Usage:   "Export DCGM_EXPORTER_CODEC_SESSIONS, the number of active encoder sessions of every GPU, from NVML.",
+			EnvVars: []string{"DCGM_EXPORTER_CODEC_SESSIONS"},
+		},
+		&cli.BoolFlag{
+			Name:    CLIFanStatus,
+			Value:   false,
+			Usage:   "Export DCGM_EXPORTER_FAN_FAILED, labeled by fan, which is 1 for every fan of a GPU, which is driven but does not spin, from NVML. GPUs without a fan are skipped.",
+			EnvVars: []string{"DCGM_EXPORTER_FAN_STATUS"},
+		},
+		&cli.BoolFlag{
+			Name:    CLIMetricsChecksum,
+			Value:   false,
+			Usage:   "Export DCGM_EXPORTER_METRICS_CHECKSUM, a checksum of the exported series without their values, to verify that two exporters, e.g. during a rolling upgrade, export the same series.",
+			EnvVars: []string{"DCGM_EXPORTER_METRICS_CHECKSUM"},
+		},
+		&cli.IntFlag{
+			Name:    CLIProcessUtilizationTopN,
+			Value:   0,
+			Usage:   "Export the SM and memory utilization of the N processes of every GPU, which use it most, labeled by pid and process name. Requires the accounting mode of the GPUs. 0 disables it.",
+			EnvVars: []string{"DCGM_EXPORTER_PROCESS_UTILIZATION_TOP_N"},
+		},
+		&cli.StringFlag{
+			Name:    CLIGPUIndexRemap,
+			Value:   "",
+			Usage:   "Label the GPUs with their index as visible to the applications, given as a comma separated list of the DCGM indexes or UUIDs of the visible GPUs in order, or 'visible-devices' to read the order from NVIDIA_VISIBLE_DEVICES. The DCGM index is exported as the dcgm_gpu label.",
+			EnvVars: []string{"DCGM_EXPORTER_GPU_INDEX_REMAP"},
+		},
+		&cli.StringFlag{
+			Name:    CLISkipValues,
+			Value:   "",
+			Usage:   "Comma-separated list of <field name>=<value> pairs, whose value means that the field is unavailable and is not exported like a blank value, e.g. DCGM_FI_DEV_FAN_SPEED=-1.",
+			EnvVars: []string{"DCGM_EXPORTER_SKIP_VALUES"},
+		},
+		&cli.IntFlag{
+			Name:    CLISuppressUnchangedWindow,
+			Value:   0,
+			Usage:   "Export the series, whose value did not change since it was last exported, with the timestamp of that export, unless it did not change for this window (in ms), to reduce the volume of remote write. It must stay below the lookback delta of the queries. 0 disables it.",
+			EnvVars: []string{"DCGM_EXPORTER_SUPPRESS_UNCHANGED_WINDOW"},
+		},
 	}
 
 	if runtime.GOOS == "linux" {
@@ -278,6 +619,10 @@ restart:
 
 	enableDebugLogging(config)
 
+	if c.Bool(CLISelfTest) {
+		return selfTest(config)
+	}
+
 	cleanupDCGM := initDCGM(config)
 	defer cleanupDCGM()
 
@@ -286,10 +631,15 @@ restart:
 	dcgm.FieldsInit()
 	defer dcgm.FieldsTerm()
 
+	dcgmexporter.CheckBindingVersion()
+
 	fillConfigMetricGroups(config)
 
 	cs := getCounters(config)
 
+	dcgmexporter.SetAttributedFields(config.AttributedFields)
+	dcgmexporter.SetSkipValues(config.SkipValues)
+
 	fieldEntityGroupTypeSystemInfo := getFieldEntityGroupTypeSystemInfo(cs, config)
 
 	hostname, err := dcgmexporter.GetHostname(config)
@@ -305,7 +655,7 @@ restart:
 	)
 	defer cleanup()
 	if err != nil {
-		logrus.Fatal(err)
+		return err
 	}
 
 	cRegistry := dcgmexporter.NewRegistry()
@@ -314,6 +664,12 @@ restart:
 
 	enableDCGMExpClockEventsCount(cs, fieldEntityGroupTypeSystemInfo, hostname, config, cRegistry)
 
+	enableDCGMFIDevThrottleEvents(cs, fieldEntityGroupTypeSystemInfo, hostname, config, cRegistry)
+
+	enableDCGMFIDevThrottleSeconds(cs, fieldEntityGroupTypeSystemInfo, hostname, config, cRegistry)
+
+	enableDCGMFIDevPolicyViolations(cs, hostname, config, cRegistry)
+
 	defer func() {
 		cRegistry.Cleanup()
 	}()
@@ -328,7 +684,7 @@ restart:
 
 	wg.Add(1)
 
-	server, cleanup, err := dcgmexporter.NewMetricsServer(config, ch, cRegistry)
+	server, cleanup, err := dcgmexporter.NewMetricsServer(config, ch, cRegistry, pipeline.LastError(), pipeline.Snapshots())
 	defer cleanup()
 	if err != nil {
 		return err
@@ -336,8 +692,35 @@ restart:
 
 	go server.Run(stop, &wg)
 
+	for address, metrics := range pipeline.EntityGroupOutputs() {
+		groupConfig := *config
+		groupConfig.Address = address
+		groupConfig.EnableDiag = false
+
+		groupServer, cleanup, err := dcgmexporter.NewMetricsServer(&groupConfig, metrics, dcgmexporter.NewRegistry(), nil, nil)
+		defer cleanup()
+		if err != nil {
+			return err
+		}
+
+		wg.Add(1)
+		go groupServer.Run(stop, &wg)
+	}
+
+	var collectorsChanges <-chan struct{}
+	if config.CollectorsWatchInterval > 0 {
+		collectorsChanges = dcgmexporter.WatchCollectorsFile(config.CollectorsFile,
+			time.Duration(config.CollectorsWatchInterval)*time.Millisecond, stop)
+	}
+
 	sigs := newOSWatcher(syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
-	sig := <-sigs
+	var sig os.Signal
+	select {
+	case sig = <-sigs:
+	case <-collectorsChanges:
+		logrus.Info("Reloading the counters")
+		sig = syscall.SIGHUP
+	}
 	close(stop)
 	cancel()
 	err = dcgmexporter.WaitWithTimeout(&wg, time.Second*2)
@@ -352,6 +735,23 @@ restart:
 	return nil
 }
 
+// selfTest runs the self-test against an embedded hostengine, where fake GPUs can be created.
+func selfTest(config *dcgmexporter.Config) error {
+	config.DCGMMode = dcgmexporter.DCGMModeEmbedded
+
+	cleanupDCGM := initDCGM(config)
+	defer cleanupDCGM()
+
+	dcgm.FieldsInit()
+	defer dcgm.FieldsTerm()
+
+	dcgmexporter.CheckBindingVersion()
+
+	fillConfigMetricGroups(config)
+
+	return dcgmexporter.SelfTest(config, dcgmexporter.NewDCGMCollector)
+}
+
 func enableDCGMExpClockEventsCount(cs *dcgmexporter.CounterSet, fieldEntityGroupTypeSystemInfo *dcgmexporter.FieldEntityGroupTypeSystemInfo, hostname string, config *dcgmexporter.Config, cRegistry *dcgmexporter.Registry) {
 	if dcgmexporter.IsDCGMExpClockEventsCountEnabled(cs.ExporterCounters) {
 		item, exists := fieldEntityGroupTypeSystemInfo.Get(dcgm.FE_GPU)
@@ -370,6 +770,56 @@ func enableDCGMExpClockEventsCount(cs *dcgmexporter.CounterSet, fieldEntityGroup
 	}
 }
 
+func enableDCGMFIDevThrottleEvents(cs *dcgmexporter.CounterSet, fieldEntityGroupTypeSystemInfo *dcgmexporter.FieldEntityGroupTypeSystemInfo, hostname string, config *dcgmexporter.Config, cRegistry *dcgmexporter.Registry) {
+	if dcgmexporter.IsDCGMFIDevThrottleEventsEnabled(cs.ExporterCounters) {
+		item, exists := fieldEntityGroupTypeSystemInfo.Get(dcgm.FE_GPU)
+		if !exists {
+			logrus.Fatalf("%s collector cannot be initialized", dcgmexporter.DCGMThrottleEvents.String())
+		}
+		throttleEventsCollector, err := dcgmexporter.NewThrottleEventsCollector(
+			cs.ExporterCounters, hostname, config, item)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		cRegistry.Register(throttleEventsCollector)
+
+		logrus.Infof("%s collector initialized", dcgmexporter.DCGMThrottleEvents.String())
+	}
+}
+
+func enableDCGMFIDevThrottleSeconds(cs *dcgmexporter.CounterSet, fieldEntityGroupTypeSystemInfo *dcgmexporter.FieldEntityGroupTypeSystemInfo, hostname string, config *dcgmexporter.Config, cRegistry *dcgmexporter.Registry) {
+	if dcgmexporter.IsDCGMFIDevThrottleSecondsEnabled(cs.ExporterCounters) {
+		item, exists := fieldEntityGroupTypeSystemInfo.Get(dcgm.FE_GPU)
+		if !exists {
+			logrus.Fatalf("%s collector cannot be initialized", dcgmexporter.DCGMThrottleSeconds.String())
+		}
+		throttleSecondsCollector, err := dcgmexporter.NewThrottleSecondsCollector(
+			cs.ExporterCounters, hostname, config, item)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		cRegistry.Register(throttleSecondsCollector)
+
+		logrus.Infof("%s collector initialized", dcgmexporter.DCGMThrottleSeconds.String())
+	}
+}
+
+func enableDCGMFIDevPolicyViolations(cs *dcgmexporter.CounterSet, hostname string, config *dcgmexporter.Config, cRegistry *dcgmexporter.Registry) {
+	if dcgmexporter.IsDCGMFIDevPolicyViolationsEnabled(cs.ExporterCounters) {
+		policyViolationsCollector, err := dcgmexporter.NewPolicyViolationsCollector(
+			cs.ExporterCounters, hostname, config)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		cRegistry.Register(policyViolationsCollector)
+
+		logrus.Infof("%s collector initialized", dcgmexporter.DCGMPolicyViolations.String())
+	}
+}
+
 func enableDCGMExpXIDErrorsCountCollector(cs *dcgmexporter.CounterSet, fieldEntityGroupTypeSystemInfo *dcgmexporter.FieldEntityGroupTypeSystemInfo, hostname string, config *dcgmexporter.Config, cRegistry *dcgmexporter.Registry) {
 	if dcgmexporter.IsDCGMExpXIDErrorsCountEnabled(cs.ExporterCounters) {
 		item, exists := fieldEntityGroupTypeSystemInfo.Get(dcgm.FE_GPU)
@@ -402,6 +852,7 @@ func getFieldEntityGroupTypeSystemInfo(cs *dcgmexporter.CounterSet, config *dcgm
 	)
 
 	fieldEntityGroupTypeSystemInfo := dcgmexporter.NewEntityGroupTypeSystemInfo(allCounters, config)
+	fieldEntityGroupTypeSystemInfo.SetEntityGroupCounters(cs.EntityGroupCounters)
 
 	for _, egt := range dcgmexporter.FieldEntityGroupTypeToMonitor {
 		err := fieldEntityGroupTypeSystemInfo.Load(egt)
@@ -452,29 +903,12 @@ func enableDebugLogging(config *dcgmexporter.Config) {
 }
 
 func initDCGM(config *dcgmexporter.Config) func() {
-	if config.UseRemoteHE {
-		logrus.Info("Attemping to connect to remote hostengine at ", config.RemoteHEInfo)
-		cleanup, err := dcgm.Init(dcgm.Standalone, config.RemoteHEInfo, "0")
-		if err != nil {
-			cleanup()
-			logrus.Fatal(err)
-		}
-		return cleanup
-	} else {
-
-		if config.EnableDCGMLog {
-			os.Setenv("__DCGM_DBG_FILE", "-")
-			os.Setenv("__DCGM_DBG_LVL", config.DCGMLogLevel)
-		}
-
-		cleanup, err := dcgm.Init(dcgm.Embedded)
-		if err != nil {
-			cleanup()
-			logrus.Fatal(err)
-		}
-
-		return cleanup
+	cleanup, err := dcgmexporter.InitDCGM(config)
+	if err != nil {
+		logrus.Fatal(err)
 	}
+
+	return cleanup
 }
 
 func parseDeviceOptions(devices string) (dcgmexporter.DeviceOptions, error) {
@@ -540,6 +974,18 @@ func parseDeviceOptions(devices string) (dcgmexporter.DeviceOptions, error) {
 	return dOpt, nil
 }
 
+func parseFieldNames(fields string) []string {
+	var names []string
+	for _, name := range strings.Split(fields, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
 func contextToConfig(c *cli.Context) (*dcgmexporter.Config, error) {
 	gOpt, err := parseDeviceOptions(c.String(CLIGPUDevices))
 	if err != nil {
@@ -561,6 +1007,60 @@ func contextToConfig(c *cli.Context) (*dcgmexporter.Config, error) {
 		return nil, fmt.Errorf("invalid %s parameter value: %s", CLIDCGMLogLevel, dcgmLogLevel)
 	}
 
+	dcgmMode := dcgmexporter.DCGMMode(c.String(CLIDCGMMode))
+	if dcgmMode == "" {
+		dcgmMode = dcgmexporter.DCGMModeEmbedded
+		if c.IsSet(CLIRemoteHEInfo) {
+			dcgmMode = dcgmexporter.DCGMModeRemote
+		}
+	}
+	if !slices.Contains(dcgmexporter.DCGMModeValues, dcgmMode) {
+		return nil, fmt.Errorf("invalid %s parameter value: %s", CLIDCGMMode, dcgmMode)
+	}
+
+	var constantMetrics []dcgmexporter.ConstantMetric
+	if c.String(CLIConstantMetricsFile) != "" {
+		constantMetrics, err = dcgmexporter.ReadConstantMetricsFile(c.String(CLIConstantMetricsFile))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s parameter value: %w", CLIConstantMetricsFile, err)
+		}
+	}
+
+	entityGroupAddresses, err := dcgmexporter.ParseEntityGroupAddresses(parseFieldNames(c.String(CLIEntityGroupAddresses)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s parameter value: %w", CLIEntityGroupAddresses, err)
+	}
+
+	counterFiles, err := dcgmexporter.ParseCounterFiles(parseFieldNames(c.String(CLICounterFiles)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s parameter value: %w", CLICounterFiles, err)
+	}
+
+	attributedFields, err := dcgmexporter.ParseAttributedFields(parseFieldNames(c.String(CLIAttributedFields)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s parameter value: %w", CLIAttributedFields, err)
+	}
+
+	skipValues, err := dcgmexporter.ParseSkipValues(parseFieldNames(c.String(CLISkipValues)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s parameter value: %w", CLISkipValues, err)
+	}
+
+	tensorCapabilities, err := dcgmexporter.ParseTensorCapabilities(parseFieldNames(c.String(CLITensorCapabilities)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s parameter value: %w", CLITensorCapabilities, err)
+	}
+
+	duplicateFieldPolicy := dcgmexporter.DuplicateFieldPolicy(c.String(CLIDuplicateFieldPolicy))
+	if !slices.Contains(dcgmexporter.DuplicateFieldPolicyValues, duplicateFieldPolicy) {
+		return nil, fmt.Errorf("invalid %s parameter value: %s", CLIDuplicateFieldPolicy, duplicateFieldPolicy)
+	}
+
+	temperatureUnit := dcgmexporter.TemperatureUnit(c.String(CLITemperatureUnit))
+	if !slices.Contains(dcgmexporter.TemperatureUnitValues, temperatureUnit) {
+		return nil, fmt.Errorf("invalid %s parameter value: %s", CLITemperatureUnit, temperatureUnit)
+	}
+
 	return &dcgmexporter.Config{
 		CollectorsFile:             c.String(CLIFieldsFile),
 		Address:                    c.String(CLIAddress),
@@ -569,7 +1069,7 @@ func contextToConfig(c *cli.Context) (*dcgmexporter.Config, error) {
 		KubernetesGPUIdType:        dcgmexporter.KubernetesGPUIDType(c.String(CLIKubernetesGPUIDType)),
 		CollectDCP:                 true,
 		UseOldNamespace:            c.Bool(CLIUseOldNamespace),
-		UseRemoteHE:                c.IsSet(CLIRemoteHEInfo),
+		DCGMMode:                   dcgmMode,
 		RemoteHEInfo:               c.String(CLIRemoteHEInfo),
 		GPUDevices:                 gOpt,
 		SwitchDevices:              sOpt,
@@ -585,5 +1085,51 @@ func contextToConfig(c *cli.Context) (*dcgmexporter.Config, error) {
 		ClockEventsCountWindowSize: c.Int(CLIClockEventsCountWindowSize),
 		EnableDCGMLog:              c.Bool(CLIEnableDCGMLog),
 		DCGMLogLevel:               dcgmLogLevel,
+		SampledFields:              parseFieldNames(c.String(CLISampledFields)),
+		FailedConversionsAsNaN:     c.Bool(CLIFailedConversionsAsNaN),
+		StaleMetricsMaxAge:         c.Int(CLIStaleMetricsMaxAge),
+		MaxSeries:                  c.Int(CLIMaxSeries),
+		FieldsWatchRetries:         c.Int(CLIFieldsWatchRetries),
+		FieldsWatchRetryBackoff:    c.Int(CLIFieldsWatchRetryBackoff),
+		ShortestFloatFormat:        c.Bool(CLIShortestFloatFormat),
+		IdleCollectInterval:        c.Int(CLIIdleCollectInterval),
+		EnableDiag:                 c.Bool(CLIEnableDiag),
+		EnableDebugEndpoints:       c.Bool(CLIEnableDebugEndpoints),
+		PolicyViolations:           parseFieldNames(c.String(CLIPolicyViolations)),
+		ConstantMetrics:            constantMetrics,
+		StuckFieldThreshold:        c.Int(CLIStuckFieldThreshold),
+		StuckFields:                parseFieldNames(c.String(CLIStuckFields)),
+		MaxLabelValueLength:        c.Int(CLIMaxLabelValueLength),
+		EntityGroupAddresses:       entityGroupAddresses,
+		CollectorsWatchInterval:    c.Int(CLICollectorsWatchInterval),
+		FloatZeroThreshold:         c.Float64(CLIFloatZeroThreshold),
+		BackgroundCollection:       c.Bool(CLIBackgroundCollection),
+		SumFields:                  parseFieldNames(c.String(CLISumFields)),
+		NVMLFallback:               c.Bool(CLINVMLFallback),
+		EnableCounterOK:            c.Bool(CLIEnableCounterOK),
+		TensorCapabilities:         tensorCapabilities,
+		DuplicateFieldPolicy:       duplicateFieldPolicy,
+		ComputeCapabilityLabel:     c.Bool(CLIComputeCapabilityLabel),
+		JobName:                    c.String(CLIJobName),
+		InstanceID:                 c.String(CLIInstanceID),
+		EmitFieldInfo:              c.Bool(CLIEmitFieldInfo),
+		AllowHostnameHeader:        c.Bool(CLIAllowHostnameHeader),
+		TemperatureUnit:            temperatureUnit,
+		UtilizationWindow:          c.Int(CLIUtilizationWindow),
+		HostengineHealth:           c.Bool(CLIHostengineHealth),
+		MaxClockSkew:               c.Int(CLIMaxClockSkew),
+		PowerPeakWindow:            c.Int(CLIPowerPeakWindow),
+		EmitRuntimeMetrics:         c.Bool(CLIEmitRuntimeMetrics),
+		CounterFiles:               counterFiles,
+		PreloadCache:               c.Bool(CLIPreloadCache),
+		AttributedFields:           attributedFields,
+		EmitRawValues:              c.Bool(CLIEmitRawValues),
+		CodecSessions:              c.Bool(CLICodecSessions),
+		FanStatus:                  c.Bool(CLIFanStatus),
+		MetricsChecksum:            c.Bool(CLIMetricsChecksum),
+		ProcessUtilizationTopN:     c.Int(CLIProcessUtilizationTopN),
+		GPUIndexRemap:              c.String(CLIGPUIndexRemap),
+		SkipValues:                 skipValues,
+		SuppressUnchangedWindow:    c.Int(CLISuppressUnchangedWindow),
 	}, nil
 }
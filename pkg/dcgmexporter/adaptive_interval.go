@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"time"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+// idleUtilizationThreshold is the GPU utilization (in %) at or below which a GPU is considered idle
+const idleUtilizationThreshold = 1.0
+
+// entityPoll is the scheduling state of one entity: the values of its last fetch and whether it was idle then
+type entityPoll struct {
+	values    []dcgm.FieldValue_v1
+	fetchedAt time.Time
+	idle      bool
+}
+
+// idleValues returns the last values fetched for an idle GPU, as long as the idle collect interval
+// has not elapsed since the fetch. Busy GPUs, and every entity that is not a GPU, are always fetched.
+// Only the fetches of the exporter are skipped: DCGM keeps sampling the watched fields of idle GPUs at the
+// collect interval, since the fields of all GPUs are watched in one group.
+func (c *DCGMCollector) idleValues(entity dcgm.GroupEntityPair) ([]dcgm.FieldValue_v1, bool) {
+	if c.IdleCollectInterval <= 0 || entity.EntityGroupId != dcgm.FE_GPU {
+		return nil, false
+	}
+
+	poll, exists := c.polls[entity]
+	if !exists || !poll.idle || timeNow().Sub(poll.fetchedAt) >= c.IdleCollectInterval {
+		return nil, false
+	}
+
+	return poll.values, true
+}
+
+// recordPoll stores the values fetched for a GPU to schedule its next fetch
+func (c *DCGMCollector) recordPoll(entity dcgm.GroupEntityPair, values []dcgm.FieldValue_v1) {
+	if c.IdleCollectInterval <= 0 || entity.EntityGroupId != dcgm.FE_GPU {
+		return
+	}
+
+	if c.polls == nil {
+		c.polls = make(map[dcgm.GroupEntityPair]entityPoll)
+	}
+
+	c.polls[entity] = entityPoll{
+		values:    values,
+		fetchedAt: timeNow(),
+		idle:      isIdle(values),
+	}
+}
+
+// isIdle reports whether the GPU utilization in values is near zero. A GPU without a utilization
+// value is never idle, since there is no way to tell.
+func isIdle(values []dcgm.FieldValue_v1) bool {
+	for _, val := range values {
+		if val.FieldId != uint(dcgm.DCGM_FI_DEV_GPU_UTIL) {
+			continue
+		}
+
+		util, ok := ToFloat64(val)
+		return ok && util <= idleUtilizationThreshold
+	}
+
+	return false
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func utilizationValue(util int64) dcgm.FieldValue_v1 {
+	value := [4096]byte{}
+	binary.LittleEndian.PutUint64(value[:], uint64(util))
+
+	return dcgm.FieldValue_v1{
+		FieldId:   uint(dcgm.DCGM_FI_DEV_GPU_UTIL),
+		FieldType: dcgm.DCGM_FT_INT64,
+		Value:     value,
+	}
+}
+
+// collectWindow collects metrics from an idle GPU 0 and a busy GPU 1 every second for the length
+// of the window, and returns the number of fetches per GPU.
+func collectWindow(t *testing.T, idleCollectInterval, window time.Duration) map[uint]int {
+	t.Helper()
+
+	counter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_UTIL, FieldName: "DCGM_FI_DEV_GPU_UTIL", PromType: "gauge", Help: "GPU utilization (in %)."}
+
+	sysInfo := SystemInfo{
+		GPUCount: 2,
+		InfoType: dcgm.FE_GPU,
+		gOpt:     DeviceOptions{Flex: true},
+	}
+	sysInfo.GPUs[0].DeviceInfo = dcgm.Device{GPU: 0, UUID: "GPU-00000000-0000-0000-0000-000000000000"}
+	sysInfo.GPUs[1].DeviceInfo = dcgm.Device{GPU: 1, UUID: "GPU-00000000-0000-0000-0000-000000000001"}
+
+	collector := &DCGMCollector{
+		Counters:            []Counter{counter},
+		DeviceFields:        []dcgm.Short{counter.FieldID},
+		SysInfo:             sysInfo,
+		IdleCollectInterval: idleCollectInterval,
+	}
+
+	utilization := map[uint]int64{0: 0, 1: 80}
+	fetches := map[uint]int{}
+
+	getLatestValues, now := dcgmEntityGetLatestValues, timeNow
+	t.Cleanup(func() {
+		dcgmEntityGetLatestValues, timeNow = getLatestValues, now
+	})
+
+	dcgmEntityGetLatestValues = func(_ dcgm.Field_Entity_Group, gpu uint, _ []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		fetches[gpu]++
+		return []dcgm.FieldValue_v1{utilizationValue(utilization[gpu])}, nil
+	}
+
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time {
+		return clock
+	}
+
+	for elapsed := time.Duration(0); elapsed < window; elapsed += time.Second {
+		metrics, err := collector.GetMetrics()
+		require.NoError(t, err)
+		require.Len(t, metrics[counter], 2, "idle GPUs keep their series between fetches")
+
+		clock = clock.Add(time.Second)
+	}
+
+	return fetches
+}
+
+func TestGetMetricsPollsIdleGPUsLessFrequently(t *testing.T) {
+	fetches := collectWindow(t, 5*time.Second, time.Minute)
+
+	assert.Equal(t, 60, fetches[1], "busy GPU is fetched every collection")
+	assert.Equal(t, 12, fetches[0], "idle GPU is fetched every idle collect interval")
+	assert.Less(t, fetches[0], fetches[1])
+}
+
+func TestGetMetricsPollsEveryGPUWithoutIdleCollectInterval(t *testing.T) {
+	fetches := collectWindow(t, 0, time.Minute)
+
+	assert.Equal(t, map[uint]int{0: 60, 1: 60}, fetches)
+}
+
+func TestIsIdle(t *testing.T) {
+	assert.True(t, isIdle([]dcgm.FieldValue_v1{utilizationValue(0)}))
+	assert.True(t, isIdle([]dcgm.FieldValue_v1{utilizationValue(1)}))
+	assert.False(t, isIdle([]dcgm.FieldValue_v1{utilizationValue(2)}))
+	assert.False(t, isIdle(nil), "GPUs without a utilization value are never idle")
+}
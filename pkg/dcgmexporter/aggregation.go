@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+// aggregation is how the value of a field is exported for a class of entities.
+type aggregation int
+
+const (
+	// aggregationRaw exports the value as reported by DCGM
+	aggregationRaw aggregation = iota
+	// aggregationAttributed exports the share of the GPU value, which is attributed to a MIG instance
+	// by the slices the instance takes of its GPU
+	aggregationAttributed
+)
+
+type aggregationKey struct {
+	fieldID     dcgm.Short
+	entityGroup dcgm.Field_Entity_Group
+}
+
+// defaultAggregationRules maps a field and an entity class to the aggregation of the field for that class.
+// Fields without a rule are exported raw. The power draw of MIG instances is the power draw of their GPU as
+// reported by DCGM, unless it is configured to be attributed.
+var defaultAggregationRules = map[aggregationKey]aggregation{
+	{dcgm.DCGM_FI_DEV_POWER_USAGE, dcgm.FE_GPU}:   aggregationRaw,
+	{dcgm.DCGM_FI_DEV_POWER_USAGE, dcgm.FE_GPU_I}: aggregationRaw,
+}
+
+// aggregationRules are the default rules and the rules of the configured attributed fields.
+var aggregationRules = maps.Clone(defaultAggregationRules)
+
+// ParseAttributedFields parses the names of the fields, e.g. DCGM_FI_DEV_POWER_USAGE, whose GPU value is attributed
+// to the MIG instances of the GPU by their slices.
+func ParseAttributedFields(names []string) ([]dcgm.Short, error) {
+	var fields []dcgm.Short
+	for _, name := range names {
+		fieldID, exists := dcgm.DCGM_FI[name]
+		if !exists {
+			return nil, fmt.Errorf("could not find DCGM field '%s'", name)
+		}
+
+		fields = append(fields, fieldID)
+	}
+
+	return fields, nil
+}
+
+// SetAttributedFields attributes the GPU value of the fields to the MIG instances of the GPU by their slices,
+// overriding the default rules. It must be called before the metrics are collected.
+func SetAttributedFields(fields []dcgm.Short) {
+	rules := maps.Clone(defaultAggregationRules)
+	for _, fieldID := range fields {
+		rules[aggregationKey{fieldID, dcgm.FE_GPU}] = aggregationRaw
+		rules[aggregationKey{fieldID, dcgm.FE_GPU_I}] = aggregationAttributed
+	}
+
+	aggregationRules = rules
+}
+
+// aggregateValue applies the aggregation rule of the field to v, the formatted value of val.
+func aggregateValue(val dcgm.FieldValue_v1, v string, instanceInfo *GPUInstanceInfo, shortestFloats bool) string {
+	entityGroup := dcgm.FE_GPU
+	if instanceInfo != nil {
+		entityGroup = dcgm.FE_GPU_I
+	}
+
+	if aggregationRules[aggregationKey{dcgm.Short(val.FieldId), entityGroup}] != aggregationAttributed {
+		return v
+	}
+
+	value, ok := ToFloat64(val)
+	if !ok || v == FailedToConvert || instanceInfo.GPUSlices == 0 {
+		return v
+	}
+
+	attributed := value * float64(instanceInfo.Info.NvmlProfileSlices) / float64(instanceInfo.GPUSlices)
+	if shortestFloats {
+		return strconv.FormatFloat(attributed, 'g', -1, 64)
+	}
+
+	return fmt.Sprintf("%f", attributed)
+}
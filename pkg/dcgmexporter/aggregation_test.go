@@ -0,0 +1,206 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToMetricAggregatesPowerPerEntityClass(t *testing.T) {
+	value := [4096]byte{}
+	binary.LittleEndian.PutUint64(value[:], math.Float64bits(280))
+
+	values := []dcgm.FieldValue_v1{{FieldId: 155, FieldType: dcgm.DCGM_FT_DOUBLE, Value: value}}
+	c := []Counter{{FieldID: 155, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge", Help: "Power draw (in W)."}}
+	d := dcgm.Device{GPU: 0, UUID: "fake0"}
+
+	instanceInfo := &GPUInstanceInfo{
+		Info:        dcgm.MigEntityInfo{NvmlInstanceId: 1, NvmlProfileSlices: 2},
+		ProfileName: "2g.20gb",
+		GPUSlices:   7,
+	}
+
+	t.Run("When the power draw is not attributed", func(t *testing.T) {
+		assert.Equal(t, aggregationRaw, aggregationRules[aggregationKey{155, dcgm.FE_GPU}])
+		assert.Equal(t, aggregationRaw, aggregationRules[aggregationKey{155, dcgm.FE_GPU_I}])
+
+		metrics := make(MetricsByCounter)
+		ToMetric(metrics, values, c, d, instanceInfo, MetricOptions{})
+
+		require.Len(t, metrics[c[0]], 1)
+		assert.Equal(t, "280.000000", metrics[c[0]][0].Value, "MIG instances report the power draw of their GPU")
+	})
+
+	SetAttributedFields([]dcgm.Short{dcgm.DCGM_FI_DEV_POWER_USAGE})
+	t.Cleanup(func() {
+		SetAttributedFields(nil)
+	})
+
+	t.Run("When the entity is a GPU", func(t *testing.T) {
+		metrics := make(MetricsByCounter)
+		ToMetric(metrics, values, c, d, nil, MetricOptions{})
+
+		require.Len(t, metrics[c[0]], 1)
+		assert.Equal(t, "280.000000", metrics[c[0]][0].Value, "raw")
+	})
+
+	t.Run("When the entity is a MIG instance", func(t *testing.T) {
+		metrics := make(MetricsByCounter)
+		ToMetric(metrics, values, c, d, instanceInfo, MetricOptions{})
+
+		require.Len(t, metrics[c[0]], 1)
+		assert.Equal(t, "80.000000", metrics[c[0]][0].Value, "attributed by 2 of 7 slices")
+
+		metrics = make(MetricsByCounter)
+		ToMetric(metrics, values, c, d, instanceInfo, MetricOptions{ShortestFloatFormat: true})
+		assert.Equal(t, "80", metrics[c[0]][0].Value)
+	})
+
+	t.Run("When the slices of the GPU are unknown", func(t *testing.T) {
+		metrics := make(MetricsByCounter)
+		ToMetric(metrics, values, c, d, &GPUInstanceInfo{}, MetricOptions{})
+
+		require.Len(t, metrics[c[0]], 1)
+		assert.Equal(t, "280.000000", metrics[c[0]][0].Value)
+	})
+}
+
+func TestAggregateValueWithoutRule(t *testing.T) {
+	value := [4096]byte{}
+	binary.LittleEndian.PutUint64(value[:], 60)
+
+	val := dcgm.FieldValue_v1{FieldId: uint(dcgm.DCGM_FI_DEV_GPU_TEMP), FieldType: dcgm.DCGM_FT_INT64, Value: value}
+	instanceInfo := &GPUInstanceInfo{Info: dcgm.MigEntityInfo{NvmlProfileSlices: 1}, GPUSlices: 7}
+
+	assert.Equal(t, "60", aggregateValue(val, "60", instanceInfo, false), "fields without a rule are raw")
+}
+
+func TestToMetricAttributesConfiguredFields(t *testing.T) {
+	t.Cleanup(func() {
+		SetAttributedFields(nil)
+	})
+
+	fields, err := ParseAttributedFields([]string{"DCGM_FI_DEV_FB_USED"})
+	require.NoError(t, err)
+	assert.Equal(t, []dcgm.Short{dcgm.DCGM_FI_DEV_FB_USED}, fields)
+
+	_, err = ParseAttributedFields([]string{"DCGM_FI_DEV_UNKNOWN"})
+	assert.Error(t, err)
+
+	value := [4096]byte{}
+	binary.LittleEndian.PutUint64(value[:], 70000)
+
+	values := []dcgm.FieldValue_v1{{FieldId: uint(dcgm.DCGM_FI_DEV_FB_USED), FieldType: dcgm.DCGM_FT_INT64, Value: value}}
+	c := []Counter{{FieldID: dcgm.DCGM_FI_DEV_FB_USED, FieldName: "DCGM_FI_DEV_FB_USED", PromType: "gauge", Help: "Framebuffer memory used (in MiB)."}}
+	d := dcgm.Device{GPU: 0, UUID: "fake0"}
+	instanceInfo := &GPUInstanceInfo{
+		Info:        dcgm.MigEntityInfo{NvmlInstanceId: 1, NvmlProfileSlices: 3},
+		ProfileName: "3g.40gb",
+		GPUSlices:   7,
+	}
+
+	metrics := make(MetricsByCounter)
+	ToMetric(metrics, values, c, d, instanceInfo, MetricOptions{})
+	require.Len(t, metrics[c[0]], 1)
+	assert.Equal(t, "70000", metrics[c[0]][0].Value, "the field is raw by default")
+
+	SetAttributedFields(fields)
+
+	metrics = make(MetricsByCounter)
+	ToMetric(metrics, values, c, d, instanceInfo, MetricOptions{})
+	require.Len(t, metrics[c[0]], 1)
+	assert.Equal(t, "30000.000000", metrics[c[0]][0].Value, "attributed by 3 of 7 slices")
+
+	metrics = make(MetricsByCounter)
+	ToMetric(metrics, values, c, d, nil, MetricOptions{})
+	require.Len(t, metrics[c[0]], 1)
+	assert.Equal(t, "70000", metrics[c[0]][0].Value, "the GPU value is raw")
+
+	assert.Equal(t, aggregationRaw, aggregationRules[aggregationKey{dcgm.DCGM_FI_DEV_POWER_USAGE, dcgm.FE_GPU_I}],
+		"the fields, which are not configured, keep their default rule")
+}
+
+func TestAppendMigPowerAttributionError(t *testing.T) {
+	value := [4096]byte{}
+	binary.LittleEndian.PutUint64(value[:], math.Float64bits(280))
+
+	values := []dcgm.FieldValue_v1{{FieldId: 155, FieldType: dcgm.DCGM_FT_DOUBLE, Value: value}}
+	c := []Counter{{FieldID: 155, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge", Help: "Power draw (in W)."}}
+	d := dcgm.Device{GPU: 0, UUID: "fake0"}
+
+	collect := func(instanceSlices ...uint) MetricsByCounter {
+		metrics := make(MetricsByCounter)
+		ToMetric(metrics, values, c, d, nil, MetricOptions{})
+		for i, s := range instanceSlices {
+			instanceInfo := &GPUInstanceInfo{
+				Info:        dcgm.MigEntityInfo{NvmlInstanceId: uint(i + 1), NvmlProfileSlices: s},
+				ProfileName: "mig",
+				GPUSlices:   7,
+			}
+			ToMetric(metrics, values, c, d, instanceInfo, MetricOptions{})
+		}
+		return metrics
+	}
+
+	t.Run("When the power draw is not attributed", func(t *testing.T) {
+		metrics := collect(3, 4)
+		AppendMigPowerAttributionError(metrics, c)
+
+		assert.Empty(t, metrics[migPowerAttributionErrorCounter])
+	})
+
+	SetAttributedFields([]dcgm.Short{dcgm.DCGM_FI_DEV_POWER_USAGE})
+	t.Cleanup(func() {
+		SetAttributedFields(nil)
+	})
+
+	t.Run("When the GPU is perfectly attributed", func(t *testing.T) {
+		metrics := collect(3, 4)
+		AppendMigPowerAttributionError(metrics, c)
+
+		require.Len(t, metrics[migPowerAttributionErrorCounter], 1)
+		m := metrics[migPowerAttributionErrorCounter][0]
+		assert.Equal(t, "0", m.GPU)
+		assert.Empty(t, m.GPUInstanceID)
+
+		attributionError, err := strconv.ParseFloat(m.Value, 64)
+		require.NoError(t, err)
+		assert.InDelta(t, 0, attributionError, 1e-6)
+	})
+
+	t.Run("When slices of the GPU are not used by instances", func(t *testing.T) {
+		metrics := collect(2)
+		AppendMigPowerAttributionError(metrics, c)
+
+		require.Len(t, metrics[migPowerAttributionErrorCounter], 1)
+		assert.Equal(t, "-200.000000", metrics[migPowerAttributionErrorCounter][0].Value)
+	})
+
+	t.Run("When the GPU has no instances", func(t *testing.T) {
+		metrics := collect()
+		AppendMigPowerAttributionError(metrics, c)
+
+		assert.Empty(t, metrics[migPowerAttributionErrorCounter])
+	})
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newTicker returns the ticks at the interval and a func to stop them; it is replaced in tests to drive the ticks.
+var newTicker = func(d time.Duration) (<-chan time.Time, func()) {
+	t := time.NewTicker(d)
+	return t.C, t.Stop
+}
+
+// gatherInBackground gathers the registry once and then on every tick until stop is closed, so that scrapes are
+// served the latest result instead of gathering the registry themselves.
+func (s *MetricsServer) gatherInBackground(stop chan interface{}) {
+	ticks, stopTicker := newTicker(s.gatherInterval)
+	defer stopTicker()
+
+	for {
+		metrics, err := s.registry.Gather()
+		if err != nil {
+			logrus.WithError(err).Error("Failed to gather metrics in the background.")
+		}
+		s.updateGathered(metrics, err)
+
+		select {
+		case <-stop:
+			return
+		case <-ticks:
+		}
+	}
+}
+
+func (s *MetricsServer) updateGathered(metrics MetricsByCounter, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.gathered = metrics
+	s.gatherErr = err
+	s.gatheredAt = timeNow()
+}
+
+// gatheredAge returns the time since the registry was last gathered in the background, which grows when the
+// background gathering is stuck although scrapes keep being served.
+func (s *MetricsServer) gatheredAge() time.Duration {
+	s.Lock()
+	defer s.Unlock()
+
+	return timeNow().Sub(s.gatheredAt)
+}
+
+// gather returns the metrics of the registry: the latest background result if the registry is gathered in the
+// background, otherwise a new result, given up as soon as ctx is done.
+func (s *MetricsServer) gather(ctx context.Context) (MetricsByCounter, error) {
+	if s.gatherInterval == 0 {
+		return s.registry.GatherWithContext(ctx)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	return s.gathered, s.gatherErr
+}
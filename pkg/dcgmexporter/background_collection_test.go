@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingCollector returns the number of times it was collected as the value of its metric.
+type countingCollector struct {
+	collections atomic.Int64
+}
+
+func (c *countingCollector) GetMetrics() (MetricsByCounter, error) {
+	counter := Counter{FieldName: "DCGM_EXP_COLLECTIONS", PromType: "gauge"}
+	value := c.collections.Add(1)
+	return MetricsByCounter{counter: {{Counter: counter, GPU: "0", Value: strconv.FormatInt(value, 10)}}}, nil
+}
+
+func (c *countingCollector) Cleanup() {}
+
+func TestMetricsServer_GatherInBackground(t *testing.T) {
+	ticks := make(chan time.Time)
+	defer func(f func(time.Duration) (<-chan time.Time, func())) { newTicker = f }(newTicker)
+	newTicker = func(time.Duration) (<-chan time.Time, func()) {
+		return ticks, func() {}
+	}
+
+	collector := new(countingCollector)
+	reg := NewRegistry()
+	reg.Register(collector)
+
+	server := &MetricsServer{registry: reg, gatherInterval: time.Second}
+
+	stop := make(chan interface{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.gatherInBackground(stop)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	collections := regexp.MustCompile(`DCGM_EXP_COLLECTIONS\{[^}]*\} (\d+)`)
+	scrape := func() string {
+		recorder := httptest.NewRecorder()
+		server.Metrics(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		match := collections.FindStringSubmatch(recorder.Body.String())
+		require.Len(t, match, 2)
+		return match[1]
+	}
+
+	require.Eventually(t, func() bool { return collector.collections.Load() == 1 }, time.Second, time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "1", scrape())
+	}
+	assert.Equal(t, int64(1), collector.collections.Load(), "scrapes must not gather the registry")
+
+	ticks <- time.Now()
+	ticks <- time.Now()
+
+	require.Eventually(t, func() bool { return collector.collections.Load() == 3 }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return scrape() == "3" }, time.Second, time.Millisecond)
+}
+
+func TestMetricsServer_MetricsAgeGrowsWhenBackgroundGatheringStops(t *testing.T) {
+	var now atomic.Int64
+	now.Store(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	timeNow = func() time.Time { return time.Unix(0, now.Load()) }
+
+	ticks := make(chan time.Time)
+	defer func(f func(time.Duration) (<-chan time.Time, func())) { newTicker = f }(newTicker)
+	newTicker = func(time.Duration) (<-chan time.Time, func()) {
+		return ticks, func() {}
+	}
+
+	collector := new(countingCollector)
+	reg := NewRegistry()
+	reg.Register(collector)
+
+	server := &MetricsServer{registry: reg, gatherInterval: time.Second}
+
+	stop := make(chan interface{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.gatherInBackground(stop)
+	}()
+
+	age := regexp.MustCompile(`(?m)^DCGM_EXPORTER_METRICS_AGE_SECONDS (\S+)$`)
+	scrape := func() string {
+		recorder := httptest.NewRecorder()
+		server.Metrics(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		match := age.FindStringSubmatch(recorder.Body.String())
+		require.Len(t, match, 2)
+		return match[1]
+	}
+
+	require.Eventually(t, func() bool { return scrape() == "0.000" }, time.Second, time.Millisecond)
+
+	now.Add(int64(time.Second))
+	ticks <- time.Now()
+	require.Eventually(t, func() bool { return collector.collections.Load() == 2 }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return scrape() == "0.000" }, time.Second, time.Millisecond)
+
+	close(stop)
+	<-done
+
+	now.Add(int64(30 * time.Second))
+	assert.Equal(t, "30.000", scrape(), "the age grows once the metrics are not gathered anymore")
+	now.Add(int64(30 * time.Second))
+	assert.Equal(t, "60.000", scrape())
+}
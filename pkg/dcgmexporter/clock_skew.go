@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+const dcgmExporterClockSkewEvents = "DCGM_EXPORTER_CLOCK_SKEW_EVENTS"
+
+var clockSkewEventsFormat = `# HELP {{ .Name }} Number of samples, whose timestamp was too far ahead of the clock of the exporter and was replaced by the scrape time.
+# TYPE {{ .Name }} counter
+{{ .Name }} {{ .Value }}
+`
+
+var getClockSkewEventsTemplate = sync.OnceValue(func() *template.Template {
+	return template.Must(template.New("clockSkewEvents").Parse(clockSkewEventsFormat))
+})
+
+// clockSkewEvents counts the samples of all DCGM collectors, whose timestamp was dropped.
+var clockSkewEvents clockSkewCount
+
+type clockSkewCount struct {
+	count atomic.Uint64
+}
+
+func (c *clockSkewCount) encode(out io.Writer) error {
+	return getClockSkewEventsTemplate().Execute(out, struct {
+		Name  string
+		Value uint64
+	}{
+		Name:  dcgmExporterClockSkewEvents,
+		Value: c.count.Load(),
+	})
+}
+
+// newClockSkewCorrector returns a MetricProcessor, which drops the timestamps of the samples that are more than
+// maxSkew ahead of now, e.g. when the clock of the hostengine drifts, so that the scrape time is used instead.
+func newClockSkewCorrector(maxSkew time.Duration) MetricProcessor {
+	return func(metrics MetricsByCounter) MetricsByCounter {
+		limit := timeNow().Add(maxSkew).UnixMilli()
+
+		for _, values := range metrics {
+			for i := range values {
+				if values[i].Timestamp > limit {
+					values[i].Timestamp = 0
+					clockSkewEvents.count.Add(1)
+				}
+			}
+		}
+
+		return metrics
+	}
+}
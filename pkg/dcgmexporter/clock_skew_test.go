@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClockSkewCorrector(t *testing.T) {
+	now := timeNow
+	defer func() {
+		timeNow = now
+	}()
+
+	scrapeTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time {
+		return scrapeTime
+	}
+
+	counter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_UTIL, FieldName: "DCGM_FI_DEV_GPU_UTIL", PromType: "gauge", Help: "GPU utilization (in %)."}
+	past := scrapeTime.Add(-time.Second).UnixMilli()
+	withinSkew := scrapeTime.Add(time.Second).UnixMilli()
+	future := scrapeTime.Add(time.Hour).UnixMilli()
+
+	events := clockSkewEvents.count.Load()
+
+	correct := newClockSkewCorrector(5 * time.Second)
+	metrics := correct(MetricsByCounter{counter: {
+		{Counter: counter, Value: "1", GPU: "0", Timestamp: past},
+		{Counter: counter, Value: "2", GPU: "0", Timestamp: withinSkew},
+		{Counter: counter, Value: "3", GPU: "0", Timestamp: future},
+		{Counter: counter, Value: "4", GPU: "1"},
+	}})
+
+	require.Len(t, metrics[counter], 4)
+	assert.Equal(t, past, metrics[counter][0].Timestamp)
+	assert.Equal(t, withinSkew, metrics[counter][1].Timestamp, "a skew within the bound is kept")
+	assert.Zero(t, metrics[counter][2].Timestamp, "a future-dated sample falls back to the scrape time")
+	assert.Zero(t, metrics[counter][3].Timestamp)
+	assert.Equal(t, events+1, clockSkewEvents.count.Load(), "only the future-dated sample is counted")
+
+	var out bytes.Buffer
+	require.NoError(t, clockSkewEvents.encode(&out))
+	assert.Contains(t, out.String(), "# TYPE DCGM_EXPORTER_CLOCK_SKEW_EVENTS counter\n")
+	assert.Contains(t, out.String(), fmt.Sprintf("DCGM_EXPORTER_CLOCK_SKEW_EVENTS %d\n", events+1))
+}
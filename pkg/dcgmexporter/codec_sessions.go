@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+
+	"github.com/NVIDIA/dcgm-exporter/internal/pkg/nvmlprovider"
+	"github.com/sirupsen/logrus"
+)
+
+const engineAttribute = "engine"
+
+var nvmlGetEncoderSessionCountByUUIDHook = nvmlprovider.GetEncoderSessionCountByUUID
+
+var codecSessionsCounter = Counter{
+	FieldName: "DCGM_EXPORTER_CODEC_SESSIONS",
+	PromType:  "gauge",
+	Help:      "Number of active sessions of the video engines of the GPU, by engine (encoder).",
+}
+
+// appendCodecSessions adds DCGM_EXPORTER_CODEC_SESSIONS for every monitored GPU, from the encoder sessions
+// reported by NVML. DCGM reports the sessions only as a binary field, and neither reports decoder sessions.
+// GPUs without an encoder, or whose query failed, are skipped.
+func (c *DCGMCollector) appendCodecSessions(metrics MetricsByCounter, monitoringInfo []MonitoringInfo) {
+	uuid := "UUID"
+	if c.UseOldNamespace {
+		uuid = "uuid"
+	}
+
+	queried := map[uint]bool{}
+	for _, mi := range monitoringInfo {
+		gpu := mi.DeviceInfo.GPU
+		if queried[gpu] {
+			continue
+		}
+		queried[gpu] = true
+
+		sessions, err := nvmlGetEncoderSessionCountByUUIDHook(mi.DeviceInfo.UUID)
+		if err != nil {
+			logrus.WithError(err).WithField("uuid", mi.DeviceInfo.UUID).Debug("Failed to get the encoder sessions.")
+			continue
+		}
+
+		m := Metric{
+			Counter: codecSessionsCounter,
+			Value:   fmt.Sprint(sessions),
+
+			UUID:         uuid,
+			GPU:          fmt.Sprintf("%d", gpu),
+			GPUUUID:      mi.DeviceInfo.UUID,
+			GPUDevice:    fmt.Sprintf("nvidia%d", gpu),
+			GPUModelName: getGPUModel(mi.DeviceInfo, c.ReplaceBlanksInModelName),
+			Hostname:     c.Hostname,
+
+			Labels:     map[string]string{},
+			Attributes: map[string]string{engineAttribute: "encoder"},
+		}
+
+		metrics[codecSessionsCounter] = append(metrics[codecSessionsCounter], m)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetricsWithCodecSessions(t *testing.T) {
+	tempCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+
+	sysInfo := SystemInfo{GPUCount: 2, InfoType: dcgm.FE_GPU, gOpt: DeviceOptions{Flex: true}}
+	sysInfo.GPUs[0] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-l4"}}
+	sysInfo.GPUs[1] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 1, UUID: "GPU-h100"}}
+
+	collector := &DCGMCollector{
+		Counters:      []Counter{tempCounter},
+		DeviceFields:  []dcgm.Short{tempCounter.FieldID},
+		SysInfo:       sysInfo,
+		CodecSessions: true,
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+	dcgmEntityGetLatestValues = func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], 42)
+		return []dcgm.FieldValue_v1{{FieldId: uint(tempCounter.FieldID), FieldType: dcgm.DCGM_FT_INT64, Value: value}}, nil
+	}
+
+	defer func(getEncoderSessionCount func(string) (int, error)) {
+		nvmlGetEncoderSessionCountByUUIDHook = getEncoderSessionCount
+	}(nvmlGetEncoderSessionCountByUUIDHook)
+	nvmlGetEncoderSessionCountByUUIDHook = func(uuid string) (int, error) {
+		if uuid == "GPU-l4" {
+			return 3, nil
+		}
+		return 0, errors.New("not supported")
+	}
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+
+	require.Len(t, metrics[codecSessionsCounter], 1, "GPUs without an encoder are skipped")
+	m := metrics[codecSessionsCounter][0]
+	assert.Equal(t, "3", m.Value)
+	assert.Equal(t, "GPU-l4", m.GPUUUID)
+	assert.Equal(t, "encoder", m.Attributes[engineAttribute])
+
+	collector.CodecSessions = false
+	metrics, err = collector.GetMetrics()
+	require.NoError(t, err)
+	assert.Empty(t, metrics[codecSessionsCounter])
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"text/template"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+const (
+	dcgmExporterLastError = "DCGM_EXPORTER_LAST_ERROR"
+
+	collectionErrorConnectionLost = "connection_lost"
+	collectionErrorTimeout        = "timeout"
+	collectionErrorPartial        = "partial"
+	collectionErrorOther          = "other"
+)
+
+var collectionErrorReasons = []string{
+	collectionErrorConnectionLost,
+	collectionErrorTimeout,
+	collectionErrorPartial,
+	collectionErrorOther,
+}
+
+// ErrPartialCollection is wrapped into collection errors, when some metrics were collected before the failure.
+var ErrPartialCollection = errors.New("metrics were collected partially")
+
+// collectionErrorReason maps a collection error to the failure class reported in the reason label.
+func collectionErrorReason(err error) string {
+	var derr *dcgm.DcgmError
+	if errors.As(err, &derr) {
+		switch derr.Code {
+		case dcgm.DCGM_ST_CONNECTION_NOT_VALID:
+			return collectionErrorConnectionLost
+		case dcgm.DCGM_ST_TIMEOUT:
+			return collectionErrorTimeout
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return collectionErrorTimeout
+	}
+
+	if errors.Is(err, ErrPartialCollection) {
+		return collectionErrorPartial
+	}
+
+	return collectionErrorOther
+}
+
+// partialCollectionError marks err as partial, when some metrics were already collected.
+func partialCollectionError(collected string, err error) error {
+	if collected == "" {
+		return err
+	}
+
+	return errors.Join(ErrPartialCollection, err)
+}
+
+var lastErrorFormat = `# HELP {{ .Name }} Failure class of the last metrics collection; 1 when the last collection failed for the given reason.
+# TYPE {{ .Name }} gauge
+{{- range $reason, $value := .Reasons }}
+{{ $.Name }}{reason="{{ $reason }}"} {{ $value }}
+{{- end }}
+`
+
+var getLastErrorTemplate = sync.OnceValue(func() *template.Template {
+	return template.Must(template.New("lastError").Parse(lastErrorFormat))
+})
+
+// LastCollectionError retains the failure class of the last collection of every metrics source.
+type LastCollectionError struct {
+	mtx     sync.Mutex
+	reasons map[string]string // Failure class by source; empty when the last collection succeeded
+}
+
+func NewLastCollectionError() *LastCollectionError {
+	return &LastCollectionError{
+		reasons: map[string]string{},
+	}
+}
+
+// Update records the outcome of the last collection of the source.
+func (e *LastCollectionError) Update(source string, err error) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	if err == nil {
+		delete(e.reasons, source)
+		return
+	}
+
+	e.reasons[source] = collectionErrorReason(err)
+}
+
+func (e *LastCollectionError) encode(w io.Writer) error {
+	e.mtx.Lock()
+	reasons := map[string]int{}
+	for _, reason := range collectionErrorReasons {
+		reasons[reason] = 0
+	}
+	for _, reason := range e.reasons {
+		reasons[reason] = 1
+	}
+	e.mtx.Unlock()
+
+	return getLastErrorTemplate().Execute(w, struct {
+		Name    string
+		Reasons map[string]int
+	}{
+		Name:    dcgmExporterLastError,
+		Reasons: reasons,
+	})
+}
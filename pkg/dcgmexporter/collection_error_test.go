@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectionErrorReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "Connection lost",
+			err:      fmt.Errorf("failed to collect gpu metrics; err: %w", &dcgm.DcgmError{Code: dcgm.DCGM_ST_CONNECTION_NOT_VALID}),
+			expected: collectionErrorConnectionLost,
+		},
+		{
+			name:     "DCGM timeout",
+			err:      &dcgm.DcgmError{Code: dcgm.DCGM_ST_TIMEOUT},
+			expected: collectionErrorTimeout,
+		},
+		{
+			name:     "Deadline exceeded",
+			err:      context.DeadlineExceeded,
+			expected: collectionErrorTimeout,
+		},
+		{
+			name:     "Partial collection",
+			err:      partialCollectionError("DCGM_FI_DEV_GPU_TEMP 42", errors.New("boom")),
+			expected: collectionErrorPartial,
+		},
+		{
+			name:     "Connection lost after a partial collection",
+			err:      partialCollectionError("DCGM_FI_DEV_GPU_TEMP 42", &dcgm.DcgmError{Code: dcgm.DCGM_ST_CONNECTION_NOT_VALID}),
+			expected: collectionErrorConnectionLost,
+		},
+		{
+			name:     "Nothing collected before the failure",
+			err:      partialCollectionError("", errors.New("boom")),
+			expected: collectionErrorOther,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, collectionErrorReason(tt.err))
+		})
+	}
+}
+
+func TestLastCollectionError(t *testing.T) {
+	lastError := NewLastCollectionError()
+	lastError.Update(pipelineSource, fmt.Errorf("failed to collect gpu metrics; err: %w",
+		&dcgm.DcgmError{Code: dcgm.DCGM_ST_CONNECTION_NOT_VALID}))
+	lastError.Update(registrySource, nil)
+
+	var out bytes.Buffer
+	require.NoError(t, lastError.encode(&out))
+	assert.Contains(t, out.String(), "# TYPE DCGM_EXPORTER_LAST_ERROR gauge\n")
+	assert.Contains(t, out.String(), `DCGM_EXPORTER_LAST_ERROR{reason="connection_lost"} 1`+"\n")
+	assert.Contains(t, out.String(), `DCGM_EXPORTER_LAST_ERROR{reason="timeout"} 0`+"\n")
+	assert.Contains(t, out.String(), `DCGM_EXPORTER_LAST_ERROR{reason="partial"} 0`+"\n")
+
+	lastError.Update(pipelineSource, nil)
+
+	out.Reset()
+	require.NoError(t, lastError.encode(&out))
+	assert.Contains(t, out.String(), `DCGM_EXPORTER_LAST_ERROR{reason="connection_lost"} 0`+"\n")
+}
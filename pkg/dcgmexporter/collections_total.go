@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"text/template"
+)
+
+const dcgmExporterCollectionsTotal = "DCGM_EXPORTER_COLLECTIONS_TOTAL"
+
+var collectionsTotalFormat = `# HELP {{ .Name }} Number of completed collections of DCGM fields, by result (success or error).
+# TYPE {{ .Name }} counter
+{{ .Name }}{result="success"} {{ .Succeeded }}
+{{ .Name }}{result="error"} {{ .Failed }}
+`
+
+var getCollectionsTotalTemplate = sync.OnceValue(func() *template.Template {
+	return template.Must(template.New("collectionsTotal").Parse(collectionsTotalFormat))
+})
+
+// collectionsTotal counts the collections of all DCGM collectors, to verify that the exporter is polling DCGM.
+var collectionsTotal collectionCount
+
+type collectionCount struct {
+	succeeded atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// record counts a completed collection, which failed with err or succeeded when err is nil.
+func (c *collectionCount) record(err error) {
+	if err != nil {
+		c.failed.Add(1)
+		return
+	}
+
+	c.succeeded.Add(1)
+}
+
+func (c *collectionCount) encode(out io.Writer) error {
+	return getCollectionsTotalTemplate().Execute(out, struct {
+		Name      string
+		Succeeded uint64
+		Failed    uint64
+	}{
+		Name:      dcgmExporterCollectionsTotal,
+		Succeeded: c.succeeded.Load(),
+		Failed:    c.failed.Load(),
+	})
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectionsTotal(t *testing.T) {
+	tempCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+
+	sysInfo := SystemInfo{GPUCount: 1, InfoType: dcgm.FE_GPU, gOpt: DeviceOptions{Flex: true}}
+	sysInfo.GPUs[0] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-0"}}
+
+	collector := &DCGMCollector{
+		Counters:     []Counter{tempCounter},
+		DeviceFields: []dcgm.Short{tempCounter.FieldID},
+		SysInfo:      sysInfo,
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+
+	var fetchErr error
+	dcgmEntityGetLatestValues = func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], 42)
+		return []dcgm.FieldValue_v1{{FieldId: uint(tempCounter.FieldID), FieldType: dcgm.DCGM_FT_INT64, Value: value}}, fetchErr
+	}
+
+	succeeded, failed := collectionsTotal.succeeded.Load(), collectionsTotal.failed.Load()
+
+	for i := 0; i < 3; i++ {
+		_, err := collector.GetMetrics()
+		require.NoError(t, err)
+	}
+	assert.Equal(t, succeeded+3, collectionsTotal.succeeded.Load(), "every successful collection increments the counter")
+	assert.Equal(t, failed, collectionsTotal.failed.Load())
+
+	fetchErr = errors.New("DCGM failed")
+	_, err := collector.GetMetrics()
+	require.Error(t, err)
+	assert.Equal(t, succeeded+3, collectionsTotal.succeeded.Load())
+	assert.Equal(t, failed+1, collectionsTotal.failed.Load(), "a failed collection increments the error variant")
+
+	var out bytes.Buffer
+	require.NoError(t, collectionsTotal.encode(&out))
+	assert.Contains(t, out.String(), "# TYPE DCGM_EXPORTER_COLLECTIONS_TOTAL counter\n")
+	assert.Contains(t, out.String(), fmt.Sprintf("DCGM_EXPORTER_COLLECTIONS_TOTAL{result=\"success\"} %d\n", succeeded+3))
+	assert.Contains(t, out.String(), fmt.Sprintf("DCGM_EXPORTER_COLLECTIONS_TOTAL{result=\"error\"} %d\n", failed+1))
+}
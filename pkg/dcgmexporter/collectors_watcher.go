@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fileState is the part of the file info, which changes when the file is written or replaced.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+
+	return fileState{modTime: info.ModTime(), size: info.Size(), exists: true}
+}
+
+// WatchCollectorsFile polls the file at path every interval, until stop is closed. It notifies once the file has
+// changed and then stayed unchanged for one more interval, so that a burst of writes, e.g. when a mounted ConfigMap
+// is updated, triggers a single reload.
+func WatchCollectorsFile(path string, interval time.Duration, stop chan interface{}) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		last := statFile(path)
+		pending := false
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				current := statFile(path)
+				if current != last {
+					last = current
+					pending = true
+					continue
+				}
+
+				if !pending || !current.exists {
+					continue
+				}
+				pending = false
+
+				logrus.Infof("Collectors file '%s' changed", path)
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changes
+}
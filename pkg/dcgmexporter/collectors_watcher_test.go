@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchCollectorsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counters.csv")
+	require.NoError(t, os.WriteFile(path, []byte("DCGM_FI_DEV_GPU_TEMP, gauge, GPU temperature (in C).\n"), 0o644))
+
+	config := &Config{CollectorsFile: path, ConfigMapData: undefinedConfigMapData}
+	cs, err := GetCounterSet(config)
+	require.NoError(t, err)
+	require.Len(t, cs.DCGMCounters, 1)
+
+	stop := make(chan interface{})
+	defer close(stop)
+	changes := WatchCollectorsFile(path, 10*time.Millisecond, stop)
+
+	select {
+	case <-changes:
+		t.Fatal("unchanged file triggered a reload")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// A burst of writes triggers a single reload
+	for _, counters := range []string{
+		"DCGM_FI_DEV_GPU_TEMP, gauge, GPU temperature (in C).\n",
+		"DCGM_FI_DEV_GPU_TEMP, gauge, GPU temperature (in C).\nDCGM_FI_DEV_POWER_USAGE, gauge, Power draw (in W).\n",
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(counters), 0o644))
+		require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+	}
+
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("changed file did not trigger a reload")
+	}
+
+	cs, err = GetCounterSet(config)
+	require.NoError(t, err)
+	require.Len(t, cs.DCGMCounters, 2)
+	assert.Equal(t, "DCGM_FI_DEV_POWER_USAGE", cs.DCGMCounters[1].FieldName)
+
+	select {
+	case <-changes:
+		t.Fatal("a single change triggered another reload")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
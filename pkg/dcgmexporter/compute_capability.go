@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"github.com/NVIDIA/dcgm-exporter/internal/pkg/nvmlprovider"
+	"github.com/sirupsen/logrus"
+)
+
+const computeCapabilityLabel = "compute_capability"
+
+var nvmlGetComputeCapabilityByUUIDHook = nvmlprovider.GetComputeCapabilityByUUID
+
+// labelComputeCapability labels the metrics of every GPU, including its MIG instances, with the CUDA compute
+// capability of the GPU. The capability is static, so it is queried once per GPU; GPUs, whose query failed, are
+// queried again on the next collection.
+func (c *DCGMCollector) labelComputeCapability(metrics MetricsByCounter) {
+	if c.computeCapabilities == nil {
+		c.computeCapabilities = map[string]string{}
+	}
+
+	failed := map[string]bool{}
+	for _, values := range metrics {
+		for i, m := range values {
+			if m.GPUUUID == "" || failed[m.GPUUUID] {
+				continue
+			}
+
+			capability, cached := c.computeCapabilities[m.GPUUUID]
+			if !cached {
+				var err error
+				capability, err = nvmlGetComputeCapabilityByUUIDHook(m.GPUUUID)
+				if err != nil {
+					logrus.WithError(err).WithField("uuid", m.GPUUUID).Debug("Failed to get the compute capability.")
+					failed[m.GPUUUID] = true
+					continue
+				}
+				c.computeCapabilities[m.GPUUUID] = capability
+			}
+
+			if m.Labels == nil {
+				values[i].Labels = map[string]string{}
+			}
+			values[i].Labels[computeCapabilityLabel] = capability
+		}
+	}
+}
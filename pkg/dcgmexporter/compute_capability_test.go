@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetricsWithComputeCapabilityLabel(t *testing.T) {
+	tempCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+
+	sysInfo := SystemInfo{GPUCount: 2, InfoType: dcgm.FE_GPU, gOpt: DeviceOptions{Flex: true}}
+	sysInfo.GPUs[0] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-a100",
+		Identifiers: dcgm.DeviceIdentifiers{Model: "NVIDIA A100-SXM4-80GB"}}}
+	sysInfo.GPUs[1] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 1, UUID: "GPU-unknown"}}
+
+	collector := &DCGMCollector{
+		Counters:               []Counter{tempCounter},
+		DeviceFields:           []dcgm.Short{tempCounter.FieldID},
+		SysInfo:                sysInfo,
+		ComputeCapabilityLabel: true,
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+	dcgmEntityGetLatestValues = func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], 42)
+		return []dcgm.FieldValue_v1{{FieldId: uint(tempCounter.FieldID), FieldType: dcgm.DCGM_FT_INT64, Value: value}}, nil
+	}
+
+	defer func(getComputeCapability func(string) (string, error)) {
+		nvmlGetComputeCapabilityByUUIDHook = getComputeCapability
+	}(nvmlGetComputeCapabilityByUUIDHook)
+
+	queries := map[string]int{}
+	nvmlGetComputeCapabilityByUUIDHook = func(uuid string) (string, error) {
+		queries[uuid]++
+		if uuid == "GPU-a100" {
+			return "8.0", nil
+		}
+		return "", errors.New("not supported")
+	}
+
+	for i := 0; i < 3; i++ {
+		metrics, err := collector.GetMetrics()
+		require.NoError(t, err)
+
+		require.Len(t, metrics[tempCounter], 2)
+		for _, m := range metrics[tempCounter] {
+			if m.GPUUUID == "GPU-a100" {
+				assert.Equal(t, "8.0", m.Labels[computeCapabilityLabel])
+			} else {
+				assert.NotContains(t, m.Labels, computeCapabilityLabel)
+			}
+		}
+	}
+
+	assert.Equal(t, 1, queries["GPU-a100"], "the capability is cached")
+	assert.Equal(t, 3, queries["GPU-unknown"], "failed queries are retried on the next collection")
+}
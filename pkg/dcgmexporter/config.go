@@ -24,6 +24,51 @@ const (
 	DeviceName KubernetesGPUIDType = "device-name"
 )
 
+// DCGMMode selects how the exporter connects to DCGM.
+type DCGMMode string
+
+const (
+	DCGMModeEmbedded   DCGMMode = "embedded"   // Run the hostengine inside the exporter process
+	DCGMModeStandalone DCGMMode = "standalone" // Start a standalone nv-hostengine process and connect to it
+	DCGMModeRemote     DCGMMode = "remote"     // Connect to an already running nv-hostengine at RemoteHEInfo
+)
+
+var DCGMModeValues = []DCGMMode{
+	DCGMModeEmbedded,
+	DCGMModeStandalone,
+	DCGMModeRemote,
+}
+
+// DuplicateFieldPolicy selects how a field ID, which is listed under several names in the counters file, is exported.
+type DuplicateFieldPolicy string
+
+const (
+	DuplicateFieldPolicyError DuplicateFieldPolicy = "error" // Fail to load the counters
+	DuplicateFieldPolicyFirst DuplicateFieldPolicy = "first" // Export the field under the first name
+	DuplicateFieldPolicyLast  DuplicateFieldPolicy = "last"  // Export the field under the last name
+	DuplicateFieldPolicyBoth  DuplicateFieldPolicy = "both"  // Export the field under every name
+)
+
+var DuplicateFieldPolicyValues = []DuplicateFieldPolicy{
+	DuplicateFieldPolicyError,
+	DuplicateFieldPolicyFirst,
+	DuplicateFieldPolicyLast,
+	DuplicateFieldPolicyBoth,
+}
+
+// TemperatureUnit selects the unit of the temperatures of the GPUs.
+type TemperatureUnit string
+
+const (
+	TemperatureUnitCelsius    TemperatureUnit = "C"
+	TemperatureUnitFahrenheit TemperatureUnit = "F"
+)
+
+var TemperatureUnitValues = []TemperatureUnit{
+	TemperatureUnitCelsius,
+	TemperatureUnitFahrenheit,
+}
+
 type DeviceOptions struct {
 	Flex       bool  // If true, then monitor all GPUs if MIG mode is disabled or all GPU instances if MIG is enabled.
 	MajorRange []int // The indices of each GPU/NvSwitch to monitor, or -1 to monitor all
@@ -38,7 +83,7 @@ type Config struct {
 	KubernetesGPUIdType        KubernetesGPUIDType
 	CollectDCP                 bool
 	UseOldNamespace            bool
-	UseRemoteHE                bool
+	DCGMMode                   DCGMMode
 	RemoteHEInfo               string
 	GPUDevices                 DeviceOptions
 	SwitchDevices              DeviceOptions
@@ -55,4 +100,50 @@ type Config struct {
 	ClockEventsCountWindowSize int
 	EnableDCGMLog              bool
 	DCGMLogLevel               string
+	SampledFields              []string
+	FailedConversionsAsNaN     bool
+	StaleMetricsMaxAge         int
+	MaxSeries                  int
+	FieldsWatchRetries         int
+	FieldsWatchRetryBackoff    int
+	ShortestFloatFormat        bool
+	IdleCollectInterval        int
+	EnableDiag                 bool
+	EnableDebugEndpoints       bool
+	PolicyViolations           []string
+	ConstantMetrics            []ConstantMetric
+	StuckFieldThreshold        int
+	StuckFields                []string
+	MaxLabelValueLength        int
+	EntityGroupAddresses       map[dcgm.Field_Entity_Group]string
+	CollectorsWatchInterval    int
+	FloatZeroThreshold         float64
+	BackgroundCollection       bool
+	SumFields                  []string
+	NVMLFallback               bool
+	EnableCounterOK            bool
+	TensorCapabilities         map[string]float64
+	DuplicateFieldPolicy       DuplicateFieldPolicy
+	ComputeCapabilityLabel     bool
+	JobName                    string
+	InstanceID                 string
+	EmitFieldInfo              bool
+	AllowHostnameHeader        bool
+	TemperatureUnit            TemperatureUnit
+	UtilizationWindow          int
+	HostengineHealth           bool
+	MaxClockSkew               int
+	PowerPeakWindow            int
+	EmitRuntimeMetrics         bool
+	CounterFiles               map[dcgm.Field_Entity_Group]string
+	PreloadCache               bool
+	AttributedFields           []dcgm.Short
+	EmitRawValues              bool
+	CodecSessions              bool
+	FanStatus                  bool
+	MetricsChecksum            bool
+	ProcessUtilizationTopN     int
+	GPUIndexRemap              string
+	SkipValues                 map[dcgm.Short][]string
+	SuppressUnchangedWindow    int
 }
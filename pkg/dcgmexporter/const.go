@@ -32,6 +32,23 @@ const (
 	windowSizeInMSLabel = "window_size_in_ms"
 )
 
+const (
+	conversionFailedAttribute = "conversion_failed"
+	unitAttribute             = "unit"
+	staleAttribute            = "stale"
+	linkStateAttribute        = "state"
+)
+
+const (
+	scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+)
+
+// Sources of collected metrics, used to track the last collection error
+const (
+	pipelineSource = "pipeline"
+	registrySource = "registry"
+)
+
 // DCGMDbgLvl is a DCGM library debug level.
 const (
 	DCGMDbgLvlNone  = "NONE"
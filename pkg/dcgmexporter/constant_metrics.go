@@ -0,0 +1,179 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const constantMetricHelp = "Constant metric set in the configuration."
+
+// ConstantMetric is a metric with a constant value, which is exported on every scrape,
+// e.g. to stamp SLO targets or capacity constants next to the collected metrics.
+type ConstantMetric struct {
+	Name   string
+	Help   string
+	Labels map[string]string
+	Value  float64
+}
+
+var constantMetricsFormat = `
+{{- range $counter, $metrics := . -}}
+# HELP {{ $counter.FieldName }} {{ $counter.Help }}
+# TYPE {{ $counter.FieldName }} {{ $counter.PromType }}
+{{- range $metric := $metrics }}
+{{ $counter.FieldName }}
+{{- if $metric.Labels }}{
+{{- $sep := "" -}}
+{{- range $k, $v := $metric.Labels -}}
+	{{ $sep }}{{ $k }}="{{ $v }}"
+	{{- $sep = "," -}}
+{{- end -}}
+}{{- end }} {{ $metric.Value -}}
+{{- end }}
+{{ end }}`
+
+var getConstantMetricsTemplate = sync.OnceValue(func() *template.Template {
+	return template.Must(template.New("constantMetrics").Parse(constantMetricsFormat))
+})
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// ReadConstantMetricsFile reads the constant metrics from a file in the Prometheus text format, e.g.
+//
+//	# HELP slo_gpu_availability_target Target availability of the GPUs.
+//	slo_gpu_availability_target{tier="gold"} 0.999
+//
+// Only gauge and untyped metrics can be constant.
+func ReadConstantMetricsFile(filename string) ([]ConstantMetric, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return ParseConstantMetrics(file)
+}
+
+// ParseConstantMetrics parses constant metrics in the Prometheus text format.
+func ParseConstantMetrics(r io.Reader) ([]ConstantMetric, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse constant metrics; err: %w", err)
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var constants []ConstantMetric
+	for _, name := range names {
+		family := families[name]
+
+		help := family.GetHelp()
+		if help == "" {
+			help = constantMetricHelp
+		}
+
+		for _, metric := range family.GetMetric() {
+			var value float64
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				value = metric.GetGauge().GetValue()
+			case dto.MetricType_UNTYPED:
+				value = metric.GetUntyped().GetValue()
+			default:
+				return nil, fmt.Errorf("constant metric '%s' must be a gauge, not a %s",
+					name, strings.ToLower(family.GetType().String()))
+			}
+
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+
+			constants = append(constants, ConstantMetric{
+				Name:   name,
+				Help:   help,
+				Labels: labels,
+				Value:  value,
+			})
+		}
+	}
+
+	return constants, nil
+}
+
+// constantMetricsByCounter returns the constant metrics as gauges, with the identity labels,
+// unless a constant metric sets them itself.
+func constantMetricsByCounter(constants []ConstantMetric, identity map[string]string) MetricsByCounter {
+	metrics := make(MetricsByCounter)
+	for _, constant := range constants {
+		counter := Counter{
+			FieldName: constant.Name,
+			PromType:  "gauge",
+			Help:      constant.Help,
+		}
+
+		labels := map[string]string{}
+		for k, v := range identity {
+			labels[k] = labelValueEscaper.Replace(v)
+		}
+		for k, v := range constant.Labels {
+			labels[k] = labelValueEscaper.Replace(v)
+		}
+
+		metrics[counter] = append(metrics[counter], Metric{
+			Counter:    counter,
+			Value:      strconv.FormatFloat(constant.Value, 'g', -1, 64),
+			Labels:     labels,
+			Attributes: map[string]string{},
+		})
+	}
+
+	return metrics
+}
+
+// formatConstantMetrics returns the constant metrics of the configuration.
+func formatConstantMetrics(config *Config) (string, error) {
+	if len(config.ConstantMetrics) == 0 {
+		return "", nil
+	}
+
+	var res bytes.Buffer
+	err := getConstantMetricsTemplate().Execute(&res, constantMetricsByCounter(config.ConstantMetrics, identityLabels(config)))
+	if err != nil {
+		return "", err
+	}
+
+	return res.String(), nil
+}
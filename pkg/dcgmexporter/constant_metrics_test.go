@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConstantMetrics(t *testing.T) {
+	constants, err := ParseConstantMetrics(strings.NewReader(`# HELP slo_gpu_availability_target Target availability of the GPUs.
+# TYPE slo_gpu_availability_target gauge
+slo_gpu_availability_target{tier="gold",cluster="a"} 0.999
+slo_gpu_availability_target{tier="silver",cluster="a"} 0.99
+cluster_gpu_capacity 64
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []ConstantMetric{
+		{Name: "cluster_gpu_capacity", Help: constantMetricHelp, Labels: map[string]string{}, Value: 64},
+		{Name: "slo_gpu_availability_target", Help: "Target availability of the GPUs.", Labels: map[string]string{"tier": "gold", "cluster": "a"}, Value: 0.999},
+		{Name: "slo_gpu_availability_target", Help: "Target availability of the GPUs.", Labels: map[string]string{"tier": "silver", "cluster": "a"}, Value: 0.99},
+	}, constants)
+}
+
+func TestParseConstantMetricsErrors(t *testing.T) {
+	_, err := ParseConstantMetrics(strings.NewReader("# TYPE requests_total counter\nrequests_total 1\n"))
+	assert.ErrorContains(t, err, "must be a gauge")
+
+	_, err = ParseConstantMetrics(strings.NewReader("not a metric\n"))
+	assert.Error(t, err)
+
+	_, err = ReadConstantMetricsFile(filepath.Join(t.TempDir(), "missing.prom"))
+	assert.Error(t, err)
+}
+
+func TestFormatConstantMetrics(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "constants.prom")
+	require.NoError(t, os.WriteFile(filename, []byte(`# HELP slo_gpu_availability_target Target availability of the GPUs.
+slo_gpu_availability_target{tier="gold",note="say \"hi\""} 0.999
+cluster_gpu_capacity 64
+`), 0o600))
+
+	constants, err := ReadConstantMetricsFile(filename)
+	require.NoError(t, err)
+
+	out, err := formatStaticGauges(&Config{ConstantMetrics: constants}, sampleCounters)
+	require.NoError(t, err)
+	assert.Contains(t, out, `# HELP slo_gpu_availability_target Target availability of the GPUs.
+# TYPE slo_gpu_availability_target gauge
+slo_gpu_availability_target{note="say \"hi\"",tier="gold"} 0.999
+`)
+	assert.Contains(t, out, `# HELP cluster_gpu_capacity Constant metric set in the configuration.
+# TYPE cluster_gpu_capacity gauge
+cluster_gpu_capacity 64
+`)
+	assert.NoError(t, validateExposition(out))
+
+	out, err = formatConstantMetrics(&Config{})
+	require.NoError(t, err)
+	assert.Empty(t, out)
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"text/template"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/sirupsen/logrus"
+)
+
+const dcgmExporterConversionErrors = "DCGM_EXPORTER_CONVERSION_ERRORS"
+
+var conversionErrorsFormat = `# HELP {{ .Name }} Number of values of fields, which could not be converted to a number.
+# TYPE {{ .Name }} counter
+{{ .Name }} {{ .Value }}
+`
+
+var getConversionErrorsTemplate = sync.OnceValue(func() *template.Template {
+	return template.Must(template.New("conversionErrors").Parse(conversionErrorsFormat))
+})
+
+// conversionErrors counts the values of all DCGM collectors, which could not be converted to a number.
+var conversionErrors conversionErrorCount
+
+type conversionErrorCount struct {
+	count atomic.Uint64
+}
+
+func (c *conversionErrorCount) encode(out io.Writer) error {
+	return getConversionErrorsTemplate().Execute(out, struct {
+		Name  string
+		Value uint64
+	}{
+		Name:  dcgmExporterConversionErrors,
+		Value: c.count.Load(),
+	})
+}
+
+// loggedConversionErrors are the fields and entities, whose conversion errors were already logged
+var loggedConversionErrors sync.Map
+
+// numericValue returns the value of a metric, or FailedToConvert when it is not a number, e.g. a string reported
+// for a numeric field. The values of string fields are returned as they are. Conversion errors are counted and
+// logged once per field and entity.
+func numericValue(val dcgm.FieldValue_v1, counter Counter, v string, entity string) string {
+	if v != FailedToConvert {
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return v
+		}
+
+		if meta, known := fieldMeta(dcgm.Short(val.FieldId)); !known || uint(meta.FieldType) == dcgm.DCGM_FT_STRING {
+			return v
+		}
+	}
+
+	conversionErrors.count.Add(1)
+
+	key := fmt.Sprintf("%d-%s", val.FieldId, entity)
+	if _, logged := loggedConversionErrors.LoadOrStore(key, true); !logged {
+		logrus.WithFields(logrus.Fields{
+			"field_id":   val.FieldId,
+			"field":      counter.FieldName,
+			"field_type": string(rune(val.FieldType)),
+			"entity":     entity,
+			"value":      v,
+		}).Warn("Failed to convert the value of the field to a number.")
+	}
+
+	return FailedToConvert
+}
+
+// entityName describes the GPU or GPU instance a value belongs to, for logging.
+func entityName(d dcgm.Device, instanceInfo *GPUInstanceInfo) string {
+	if instanceInfo != nil {
+		return fmt.Sprintf("GPU %d instance %d", d.GPU, instanceInfo.Info.NvmlInstanceId)
+	}
+
+	return fmt.Sprintf("GPU %d", d.GPU)
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToMetricCountsConversionErrors(t *testing.T) {
+	fieldGetById := dcgmFieldGetById
+	defer func() {
+		dcgmFieldGetById = fieldGetById
+	}()
+
+	dcgmFieldGetById = func(fieldID dcgm.Short) dcgm.FieldMeta {
+		return dcgm.FieldMeta{FieldId: fieldID, FieldType: byte(dcgm.DCGM_FT_DOUBLE)}
+	}
+
+	value := [4096]byte{}
+	copy(value[:], "N/A")
+
+	values := []dcgm.FieldValue_v1{
+		{FieldId: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldType: dcgm.DCGM_FT_STRING, Value: value},
+	}
+
+	c := []Counter{
+		{FieldID: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge", Help: "Power draw (in W)."},
+	}
+
+	before := conversionErrors.count.Load()
+
+	metrics := make(MetricsByCounter)
+	ToMetric(metrics, values, c, dcgm.Device{UUID: "fake0"}, nil, MetricOptions{})
+	assert.Empty(t, metrics, "the value is not exported")
+	assert.Equal(t, before+1, conversionErrors.count.Load())
+
+	metrics = make(MetricsByCounter)
+	ToMetric(metrics, values, c, dcgm.Device{UUID: "fake0"}, nil, MetricOptions{FailedConversionsAsNaN: true})
+	require.Len(t, metrics[c[0]], 1)
+	assert.Equal(t, "NaN", metrics[c[0]][0].Value, "the value is exported as NaN")
+	assert.Equal(t, before+2, conversionErrors.count.Load())
+
+	var out bytes.Buffer
+	require.NoError(t, conversionErrors.encode(&out))
+	assert.Contains(t, out.String(), "# TYPE DCGM_EXPORTER_CONVERSION_ERRORS counter")
+}
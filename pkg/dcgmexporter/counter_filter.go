@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+const countersQueryParam = "counters"
+
+// counterFilter restricts a scrape to the counters requested in the counters query parameter,
+// e.g. /metrics?counters=DCGM_FI_DEV_POWER_USAGE,DCGM_FI_DEV_GPU_TEMP. A nil filter keeps all counters.
+type counterFilter map[string]bool
+
+// newCounterFilter returns the filter requested by r, or an error when it requests an unknown counter.
+func newCounterFilter(r *http.Request) (counterFilter, error) {
+	values, exists := r.URL.Query()[countersQueryParam]
+	if !exists {
+		return nil, nil
+	}
+
+	filter := counterFilter{}
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+
+			if !isCounterName(name) {
+				return nil, fmt.Errorf("unknown counter '%s'", name)
+			}
+
+			filter[name] = true
+		}
+	}
+
+	if len(filter) == 0 {
+		return nil, fmt.Errorf("no counters in the '%s' query parameter", countersQueryParam)
+	}
+
+	return filter, nil
+}
+
+func isCounterName(name string) bool {
+	if _, ok := dcgm.DCGM_FI[name]; ok {
+		return true
+	}
+
+	if _, ok := dcgm.OLD_DCGM_FI[name]; ok {
+		return true
+	}
+
+	if slices.ContainsFunc(derivedCounters, func(c Counter) bool { return c.FieldName == name }) {
+		return true
+	}
+
+	if slices.Contains(exporterGaugeNames, name) {
+		return true
+	}
+
+	if isRegisteredDerivedMetric(name) {
+		return true
+	}
+
+	_, err := IdentifyMetricType(name)
+
+	return err == nil
+}
+
+// filterMetrics returns the metrics of the requested counters.
+func (f counterFilter) filterMetrics(metrics MetricsByCounter) MetricsByCounter {
+	if f == nil {
+		return metrics
+	}
+
+	filtered := MetricsByCounter{}
+	for counter, m := range metrics {
+		if f[counter.FieldName] {
+			filtered[counter] = m
+		}
+	}
+
+	return filtered
+}
+
+// filterExposition returns the lines of the exposition, which belong to the requested counters.
+func (f counterFilter) filterExposition(exposition string) string {
+	if f == nil {
+		return exposition
+	}
+
+	var filtered strings.Builder
+	for _, line := range strings.SplitAfter(exposition, "\n") {
+		if f[expositionMetricName(line)] {
+			filtered.WriteString(line)
+		}
+	}
+
+	return filtered.String()
+}
+
+// expositionMetricName returns the metric name of a sample, HELP or TYPE line of the text format.
+func expositionMetricName(line string) string {
+	if strings.HasPrefix(line, "# HELP ") || strings.HasPrefix(line, "# TYPE ") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return ""
+		}
+
+		return fields[2]
+	}
+
+	name, _, _ := strings.Cut(line, "{")
+	name, _, _ = strings.Cut(name, " ")
+
+	return strings.TrimSpace(name)
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"slices"
+)
+
+var counterOKCounter = Counter{
+	FieldName: "DCGM_EXPORTER_COUNTER_OK",
+	PromType:  "gauge",
+	Help:      "1 when the field returned a value in the last collection, 0 when it returned none, e.g. only blank values.",
+}
+
+// appendCounterOK adds DCGM_EXPORTER_COUNTER_OK, labeled by field, for every field the collector watches,
+// so that a field, which stopped returning values, is visible without alerting on the absence of its series.
+func (c *DCGMCollector) appendCounterOK(metrics MetricsByCounter) {
+	uuid := "UUID"
+	if c.UseOldNamespace {
+		uuid = "uuid"
+	}
+
+	for _, counter := range c.Counters {
+		if counter.PromType == "label" || !slices.Contains(c.DeviceFields, counter.FieldID) {
+			continue
+		}
+
+		value := "0"
+		if len(metrics[counter]) > 0 {
+			value = "1"
+		}
+
+		metrics[counterOKCounter] = append(metrics[counterOKCounter], Metric{
+			Counter:    counterOKCounter,
+			Value:      value,
+			UUID:       uuid,
+			Hostname:   c.Hostname,
+			Labels:     map[string]string{},
+			Attributes: map[string]string{fieldAttribute: counter.FieldName},
+		})
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetricsWithCounterOK(t *testing.T) {
+	utilCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_UTIL, FieldName: "DCGM_FI_DEV_GPU_UTIL", PromType: "gauge", Help: "GPU utilization (in %)."}
+	tempCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+	driverCounter := Counter{FieldID: dcgm.DCGM_FI_DRIVER_VERSION, FieldName: "DCGM_FI_DRIVER_VERSION", PromType: "label", Help: "Driver Version"}
+
+	int64Value := func(v int64) [4096]byte {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], uint64(v))
+		return value
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+	dcgmEntityGetLatestValues = func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		return []dcgm.FieldValue_v1{
+			{FieldId: dcgm.DCGM_FI_DEV_GPU_UTIL, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(dcgm.DCGM_FT_INT32_BLANK)},
+			{FieldId: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(42)},
+		}, nil
+	}
+
+	sysInfo := SystemInfo{GPUCount: 2, InfoType: dcgm.FE_GPU, gOpt: DeviceOptions{Flex: true}}
+	sysInfo.GPUs[0] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-0"}}
+	sysInfo.GPUs[1] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 1, UUID: "GPU-1"}}
+
+	collector := &DCGMCollector{
+		Counters:      []Counter{utilCounter, tempCounter, driverCounter},
+		DeviceFields:  []dcgm.Short{utilCounter.FieldID, tempCounter.FieldID, driverCounter.FieldID},
+		SysInfo:       sysInfo,
+		MetricOptions: MetricOptions{Hostname: "node"},
+		CounterOK:     true,
+	}
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+
+	ok := map[string]string{}
+	for _, m := range metrics[counterOKCounter] {
+		assert.Equal(t, "node", m.Hostname)
+		ok[m.Attributes[fieldAttribute]] = m.Value
+	}
+	assert.Equal(t, map[string]string{"DCGM_FI_DEV_GPU_UTIL": "0", "DCGM_FI_DEV_GPU_TEMP": "1"}, ok)
+
+	collector.CounterOK = false
+	metrics, err = collector.GetMetrics()
+	require.NoError(t, err)
+	assert.Empty(t, metrics[counterOKCounter])
+}
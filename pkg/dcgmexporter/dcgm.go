@@ -19,11 +19,53 @@ package dcgmexporter
 import (
 	"fmt"
 	"math/rand"
+	"os"
+	"slices"
+	"time"
 
 	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	dcgmInitEmbedded = func() (func(), error) {
+		return dcgm.Init(dcgm.Embedded)
+	}
+	dcgmInitStartHostengine = func() (func(), error) {
+		return dcgm.Init(dcgm.StartHostengine)
+	}
+	dcgmInitStandalone = func(address string, isUnixSocket string) (func(), error) {
+		return dcgm.Init(dcgm.Standalone, address, isUnixSocket)
+	}
+	dcgmFieldGetById           = dcgm.FieldGetById
+	dcgmFieldGroupCreate       = dcgm.FieldGroupCreate
+	dcgmFieldGroupDestroy      = dcgm.FieldGroupDestroy
+	dcgmWatchFieldsWithGroupEx = dcgm.WatchFieldsWithGroupEx
+
+	setupDcgmFieldsWatch = SetupDcgmFieldsWatch
+)
+
+// InitDCGM initializes the DCGM handle according to config.DCGMMode. An empty mode means embedded.
+func InitDCGM(config *Config) (func(), error) {
+	switch config.DCGMMode {
+	case DCGMModeEmbedded, "":
+		if config.EnableDCGMLog {
+			os.Setenv("__DCGM_DBG_FILE", "-")
+			os.Setenv("__DCGM_DBG_LVL", config.DCGMLogLevel)
+		}
+
+		return dcgmInitEmbedded()
+	case DCGMModeStandalone:
+		logrus.Info("Starting standalone hostengine")
+		return dcgmInitStartHostengine()
+	case DCGMModeRemote:
+		logrus.Info("Attemping to connect to remote hostengine at ", config.RemoteHEInfo)
+		return dcgmInitStandalone(config.RemoteHEInfo, "0")
+	}
+
+	return func() {}, fmt.Errorf("unsupported DCGM mode '%s'", config.DCGMMode)
+}
+
 func NewGroup() (dcgm.GroupHandle, func(), error) {
 	group, err := dcgm.NewDefaultGroup(fmt.Sprintf("gpu-collector-group-%d", rand.Uint64()))
 	if err != nil {
@@ -31,24 +73,57 @@ func NewGroup() (dcgm.GroupHandle, func(), error) {
 	}
 
 	return group, func() {
-		err := dcgm.DestroyGroup(group)
+		err := dcgmDestroyGroup(group)
 		if err != nil {
 			logrus.WithError(err).Warn("Cannot destroy field group.")
 		}
 	}, nil
 }
 
+// fieldBelongsToEntityType reports whether a field of the entity level is collected for the entity type.
+func fieldBelongsToEntityType(entityLevel, entityType dcgm.Field_Entity_Group) bool {
+	switch {
+	case entityLevel == entityType:
+		return true
+	case entityType == dcgm.FE_GPU:
+		return entityLevel == dcgm.FE_GPU_CI || entityLevel == dcgm.FE_GPU_I || entityLevel == dcgm.FE_VGPU
+	case entityType == dcgm.FE_CPU:
+		return entityLevel == dcgm.FE_CPU_CORE
+	}
+
+	return false
+}
+
 func NewDeviceFields(counters []Counter, entityType dcgm.Field_Entity_Group) []dcgm.Short {
 	var deviceFields []dcgm.Short
+	add := func(fieldID dcgm.Short) {
+		meta := dcgmFieldGetById(fieldID)
+
+		if meta.EntityLevel == dcgm.FE_NONE || fieldBelongsToEntityType(meta.EntityLevel, entityType) {
+			deviceFields = append(deviceFields, fieldID)
+		}
+	}
+
+	for _, f := range counters {
+		add(f.FieldID)
+	}
+
+	// The expressions of the counters may use fields, which are not exported themselves
 	for _, f := range counters {
-		meta := dcgm.FieldGetById(f.FieldID)
-
-		if meta.EntityLevel == entityType || meta.EntityLevel == dcgm.FE_NONE {
-			deviceFields = append(deviceFields, f.FieldID)
-		} else if entityType == dcgm.FE_GPU && (meta.EntityLevel == dcgm.FE_GPU_CI || meta.EntityLevel == dcgm.FE_GPU_I || meta.EntityLevel == dcgm.FE_VGPU) {
-			deviceFields = append(deviceFields, f.FieldID)
-		} else if entityType == dcgm.FE_CPU && (meta.EntityLevel == dcgm.FE_CPU || meta.EntityLevel == dcgm.FE_CPU_CORE) {
-			deviceFields = append(deviceFields, f.FieldID)
+		if f.Expr == "" {
+			continue
+		}
+
+		expr, err := compileValueExpr(f.Expr)
+		if err != nil {
+			logrus.WithError(err).Warnf("Not watching the fields of the expression of %s", f.FieldName)
+			continue
+		}
+
+		for _, fieldID := range expr.fields {
+			if !slices.Contains(deviceFields, fieldID) {
+				add(fieldID)
+			}
 		}
 	}
 
@@ -57,13 +132,13 @@ func NewDeviceFields(counters []Counter, entityType dcgm.Field_Entity_Group) []d
 
 func NewFieldGroup(deviceFields []dcgm.Short) (dcgm.FieldHandle, func(), error) {
 	name := fmt.Sprintf("gpu-collector-fieldgroup-%d", rand.Uint64())
-	fieldGroup, err := dcgm.FieldGroupCreate(name, deviceFields)
+	fieldGroup, err := dcgmFieldGroupCreate(name, deviceFields)
 	if err != nil {
 		return dcgm.FieldHandle{}, func() {}, err
 	}
 
 	return fieldGroup, func() {
-		err := dcgm.FieldGroupDestroy(fieldGroup)
+		err := dcgmFieldGroupDestroy(fieldGroup)
 		if err != nil {
 			logrus.WithError(err).Warn("Cannot destroy field group.")
 		}
@@ -73,7 +148,7 @@ func NewFieldGroup(deviceFields []dcgm.Short) (dcgm.FieldHandle, func(), error)
 func WatchFieldGroup(
 	group dcgm.GroupHandle, field dcgm.FieldHandle, updateFreq int64, maxKeepAge float64, maxKeepSamples int32,
 ) error {
-	err := dcgm.WatchFieldsWithGroupEx(field, group, updateFreq, maxKeepAge, maxKeepSamples)
+	err := dcgmWatchFieldsWithGroupEx(field, group, updateFreq, maxKeepAge, maxKeepSamples)
 	if err != nil {
 		return err
 	}
@@ -81,12 +156,16 @@ func WatchFieldGroup(
 	return nil
 }
 
-func SetupDcgmFieldsWatch(deviceFields []dcgm.Short, sysInfo SystemInfo, collectIntervalUsec int64) ([]func(), error) {
+// SetupDcgmFieldsWatch watches the fields on the entities of sysInfo. Fields, which cannot be watched, e.g. because
+// they are not supported by some GPUs, are skipped with a warning and returned, so that the supported fields
+// are still watched.
+func SetupDcgmFieldsWatch(deviceFields []dcgm.Short, sysInfo SystemInfo, collectIntervalUsec int64,
+) ([]func(), []dcgm.Short, error) {
 	var err error
 	var cleanups []func()
 	var cleanup func()
 	var groups []dcgm.GroupHandle
-	var fieldGroup dcgm.FieldHandle
+	var skipped, groupSkipped []dcgm.Short
 
 	if sysInfo.InfoType == dcgm.FE_LINK {
 		/* one group per-nvswitch is created for nvlinks */
@@ -107,25 +186,95 @@ func SetupDcgmFieldsWatch(deviceFields []dcgm.Short, sysInfo SystemInfo, collect
 	}
 
 	for _, gr := range groups {
-		fieldGroup, cleanup, err = NewFieldGroup(deviceFields)
+		cleanup, groupSkipped, err = watchSupportedFields(gr, deviceFields, collectIntervalUsec)
 		if err != nil {
 			goto fail
 		}
 
 		cleanups = append(cleanups, cleanup)
+		cleanups = append(cleanups, watchedFields.add(len(deviceFields)-len(groupSkipped)))
 
-		err = WatchFieldGroup(gr, fieldGroup, collectIntervalUsec, 0.0, 1)
-		if err != nil {
-			goto fail
+		for _, field := range groupSkipped {
+			if !slices.Contains(skipped, field) {
+				skipped = append(skipped, field)
+			}
 		}
 	}
 
-	return cleanups, nil
+	return cleanups, skipped, nil
 
 fail:
 	for _, f := range cleanups {
 		f()
 	}
 
-	return nil, err
+	return nil, nil, err
+}
+
+// watchSupportedFields watches the fields on the group. When the fields cannot be watched together, every field
+// is tried alone, and the fields, which cannot be watched alone either, are skipped and returned. The error is
+// returned, when none of the fields or each of them can be watched alone.
+func watchSupportedFields(group dcgm.GroupHandle, deviceFields []dcgm.Short, collectIntervalUsec int64,
+) (func(), []dcgm.Short, error) {
+	cleanup, err := watchFields(group, deviceFields, collectIntervalUsec)
+	if err == nil || len(deviceFields) < 2 {
+		return cleanup, nil, err
+	}
+
+	var supported, skipped []dcgm.Short
+	for _, field := range deviceFields {
+		fieldCleanup, fieldErr := watchFields(group, []dcgm.Short{field}, collectIntervalUsec)
+		if fieldErr != nil {
+			logrus.WithError(fieldErr).Warnf("Skipping field %d: it cannot be watched", field)
+			skipped = append(skipped, field)
+			continue
+		}
+
+		fieldCleanup()
+		supported = append(supported, field)
+	}
+
+	if len(supported) == 0 || len(skipped) == 0 {
+		return func() {}, nil, err
+	}
+
+	cleanup, err = watchFields(group, supported, collectIntervalUsec)
+	if err != nil {
+		return func() {}, nil, err
+	}
+
+	return cleanup, skipped, nil
+}
+
+// watchFields watches the fields on the group, and returns the cleanup of their field group.
+func watchFields(group dcgm.GroupHandle, deviceFields []dcgm.Short, collectIntervalUsec int64) (func(), error) {
+	fieldGroup, cleanup, err := NewFieldGroup(deviceFields)
+	if err != nil {
+		return func() {}, err
+	}
+
+	err = WatchFieldGroup(group, fieldGroup, collectIntervalUsec, 0.0, 1)
+	if err != nil {
+		cleanup()
+		return func() {}, err
+	}
+
+	return cleanup, nil
+}
+
+// SetupDcgmFieldsWatchWithRetry calls SetupDcgmFieldsWatch, retrying up to retries times after a failure
+// and doubling the backoff between the attempts.
+func SetupDcgmFieldsWatchWithRetry(deviceFields []dcgm.Short, sysInfo SystemInfo, collectIntervalUsec int64,
+	retries int, backoff time.Duration,
+) ([]func(), []dcgm.Short, error) {
+	cleanups, skipped, err := setupDcgmFieldsWatch(deviceFields, sysInfo, collectIntervalUsec)
+	for attempt := 1; err != nil && attempt <= retries; attempt++ {
+		logrus.WithError(err).Warnf("Failed to watch metrics; retrying in %s (%d/%d)", backoff, attempt, retries)
+		time.Sleep(backoff)
+		backoff *= 2
+
+		cleanups, skipped, err = setupDcgmFieldsWatch(deviceFields, sysInfo, collectIntervalUsec)
+	}
+
+	return cleanups, skipped, err
 }
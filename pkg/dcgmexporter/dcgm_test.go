@@ -0,0 +1,252 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitDCGM(t *testing.T) {
+	var called []string
+	var remoteArgs []string
+
+	initEmbedded, initStartHostengine, initStandalone := dcgmInitEmbedded, dcgmInitStartHostengine, dcgmInitStandalone
+	defer func() {
+		dcgmInitEmbedded, dcgmInitStartHostengine, dcgmInitStandalone = initEmbedded, initStartHostengine, initStandalone
+	}()
+
+	dcgmInitEmbedded = func() (func(), error) {
+		called = append(called, "embedded")
+		return func() {}, nil
+	}
+	dcgmInitStartHostengine = func() (func(), error) {
+		called = append(called, "standalone")
+		return func() {}, nil
+	}
+	dcgmInitStandalone = func(address string, isUnixSocket string) (func(), error) {
+		called = append(called, "remote")
+		remoteArgs = []string{address, isUnixSocket}
+		return func() {}, nil
+	}
+
+	tests := []struct {
+		name     string
+		mode     DCGMMode
+		expected string
+	}{
+		{name: "Embedded mode", mode: DCGMModeEmbedded, expected: "embedded"},
+		{name: "Empty mode", mode: "", expected: "embedded"},
+		{name: "Standalone mode", mode: DCGMModeStandalone, expected: "standalone"},
+		{name: "Remote mode", mode: DCGMModeRemote, expected: "remote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = nil
+			cleanup, err := InitDCGM(&Config{DCGMMode: tt.mode, RemoteHEInfo: "localhost:5555"})
+			require.NoError(t, err)
+			require.NotNil(t, cleanup)
+			assert.Equal(t, []string{tt.expected}, called)
+		})
+	}
+
+	assert.Equal(t, []string{"localhost:5555", "0"}, remoteArgs)
+
+	called = nil
+	_, err := InitDCGM(&Config{DCGMMode: "unknown"})
+	require.Error(t, err)
+	assert.Empty(t, called)
+}
+
+func TestSetupDcgmFieldsWatchWithRetry(t *testing.T) {
+	setupFieldsWatch := setupDcgmFieldsWatch
+	defer func() {
+		setupDcgmFieldsWatch = setupFieldsWatch
+	}()
+
+	tests := []struct {
+		name         string
+		failures     int
+		retries      int
+		wantAttempts int
+		wantError    bool
+	}{
+		{name: "First attempt succeeds", failures: 0, retries: 3, wantAttempts: 1},
+		{name: "Second attempt succeeds", failures: 1, retries: 3, wantAttempts: 2},
+		{name: "All attempts fail", failures: 5, retries: 3, wantAttempts: 4, wantError: true},
+		{name: "No retries", failures: 1, retries: 0, wantAttempts: 1, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			setupDcgmFieldsWatch = func([]dcgm.Short, SystemInfo, int64) ([]func(), []dcgm.Short, error) {
+				attempts++
+				if attempts <= tt.failures {
+					return nil, nil, &dcgm.DcgmError{Code: dcgm.DCGM_ST_CONNECTION_NOT_VALID}
+				}
+				return []func(){func() {}}, nil, nil
+			}
+
+			cleanups, _, err := SetupDcgmFieldsWatchWithRetry(nil, SystemInfo{}, 1000, tt.retries, time.Millisecond)
+			assert.Equal(t, tt.wantAttempts, attempts)
+			if tt.wantError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Len(t, cleanups, 1)
+		})
+	}
+}
+
+func TestNewDCGMCollectorWhenWatchFails(t *testing.T) {
+	setupFieldsWatch := setupDcgmFieldsWatch
+	defer func() {
+		setupDcgmFieldsWatch = setupFieldsWatch
+	}()
+
+	item := FieldEntityGroupTypeSystemInfoItem{
+		SystemInfo:   SystemInfo{InfoType: dcgm.FE_GPU},
+		DeviceFields: []dcgm.Short{dcgm.DCGM_FI_DEV_GPU_TEMP},
+	}
+
+	t.Run("When the first attempt fails", func(t *testing.T) {
+		attempts := 0
+		setupDcgmFieldsWatch = func([]dcgm.Short, SystemInfo, int64) ([]func(), []dcgm.Short, error) {
+			attempts++
+			if attempts == 1 {
+				return nil, nil, &dcgm.DcgmError{Code: dcgm.DCGM_ST_TIMEOUT}
+			}
+			return nil, nil, nil
+		}
+
+		collector, cleanup, err := NewDCGMCollector(sampleCounters, "", &Config{FieldsWatchRetries: 1}, item)
+		require.NoError(t, err)
+		defer cleanup()
+		assert.NotNil(t, collector)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("When a field cannot be watched", func(t *testing.T) {
+		setupDcgmFieldsWatch = func([]dcgm.Short, SystemInfo, int64) ([]func(), []dcgm.Short, error) {
+			return nil, []dcgm.Short{dcgm.DCGM_FI_DEV_GPU_TEMP}, nil
+		}
+
+		item := FieldEntityGroupTypeSystemInfoItem{
+			SystemInfo:   SystemInfo{InfoType: dcgm.FE_GPU},
+			DeviceFields: []dcgm.Short{dcgm.DCGM_FI_DEV_GPU_TEMP, dcgm.DCGM_FI_DEV_POWER_USAGE},
+		}
+
+		collector, cleanup, err := NewDCGMCollector(sampleCounters, "", &Config{}, item)
+		require.NoError(t, err)
+		defer cleanup()
+		assert.Equal(t, []dcgm.Short{dcgm.DCGM_FI_DEV_POWER_USAGE}, collector.DeviceFields)
+		assert.Len(t, item.DeviceFields, 2, "the fields of the entity group are not modified")
+	})
+
+	t.Run("When all attempts fail", func(t *testing.T) {
+		setupDcgmFieldsWatch = func([]dcgm.Short, SystemInfo, int64) ([]func(), []dcgm.Short, error) {
+			return nil, nil, &dcgm.DcgmError{Code: dcgm.DCGM_ST_TIMEOUT}
+		}
+
+		collector, cleanup, err := NewDCGMCollector(sampleCounters, "", &Config{FieldsWatchRetries: 1}, item)
+		require.ErrorContains(t, err, "failed to watch metrics")
+		assert.Nil(t, collector)
+		assert.NotNil(t, cleanup)
+	})
+}
+
+func TestSetupDcgmFieldsWatchSkipsUnsupportedFields(t *testing.T) {
+	createGroup, addEntityToGroup, destroyGroup := dcgmCreateGroup, dcgmAddEntityToGroup, dcgmDestroyGroup
+	fieldGroupCreate, fieldGroupDestroy, watchFieldsWithGroupEx := dcgmFieldGroupCreate, dcgmFieldGroupDestroy, dcgmWatchFieldsWithGroupEx
+	defer func() {
+		dcgmCreateGroup, dcgmAddEntityToGroup, dcgmDestroyGroup = createGroup, addEntityToGroup, destroyGroup
+		dcgmFieldGroupCreate, dcgmFieldGroupDestroy, dcgmWatchFieldsWithGroupEx = fieldGroupCreate, fieldGroupDestroy, watchFieldsWithGroupEx
+	}()
+
+	dcgmCreateGroup = func(string) (dcgm.GroupHandle, error) {
+		return dcgm.GroupHandle{}, nil
+	}
+	dcgmAddEntityToGroup = func(dcgm.GroupHandle, dcgm.Field_Entity_Group, uint) error {
+		return nil
+	}
+	dcgmDestroyGroup = func(dcgm.GroupHandle) error {
+		return nil
+	}
+
+	// The watch applies to the fields of the field group, which was created last
+	var fieldGroups int
+	var lastFields []dcgm.Short
+	var watched [][]dcgm.Short
+	dcgmFieldGroupCreate = func(_ string, fields []dcgm.Short) (dcgm.FieldHandle, error) {
+		fieldGroups++
+		lastFields = fields
+		return dcgm.FieldHandle{}, nil
+	}
+	dcgmFieldGroupDestroy = func(dcgm.FieldHandle) error {
+		fieldGroups--
+		return nil
+	}
+
+	sysInfo := SystemInfo{GPUCount: 1, InfoType: dcgm.FE_GPU, gOpt: DeviceOptions{Flex: true}}
+	fields := []dcgm.Short{dcgm.DCGM_FI_DEV_GPU_TEMP, dcgm.DCGM_FI_PROF_SM_OCCUPANCY, dcgm.DCGM_FI_DEV_POWER_USAGE}
+
+	t.Run("When one field is unsupported", func(t *testing.T) {
+		watched, fieldGroups = nil, 0
+		dcgmWatchFieldsWithGroupEx = func(dcgm.FieldHandle, dcgm.GroupHandle, int64, float64, int32) error {
+			if slices.Contains(lastFields, dcgm.DCGM_FI_PROF_SM_OCCUPANCY) {
+				return errors.New("Error watching fields: This request is serviced by a module of DCGM that is not currently loaded")
+			}
+			watched = append(watched, lastFields)
+			return nil
+		}
+
+		cleanups, skipped, err := SetupDcgmFieldsWatch(fields, sysInfo, 1000)
+		require.NoError(t, err)
+		assert.Equal(t, []dcgm.Short{dcgm.DCGM_FI_PROF_SM_OCCUPANCY}, skipped)
+		assert.Equal(t, []dcgm.Short{dcgm.DCGM_FI_DEV_GPU_TEMP, dcgm.DCGM_FI_DEV_POWER_USAGE}, watched[len(watched)-1],
+			"the supported fields are watched together")
+		assert.Equal(t, 1, fieldGroups, "only the field group of the supported fields is kept")
+
+		for _, cleanup := range cleanups {
+			cleanup()
+		}
+		assert.Equal(t, 0, fieldGroups)
+	})
+
+	t.Run("When no field can be watched", func(t *testing.T) {
+		fieldGroups = 0
+		dcgmWatchFieldsWithGroupEx = func(dcgm.FieldHandle, dcgm.GroupHandle, int64, float64, int32) error {
+			return errors.New("Error watching fields: Host engine connection invalid/disconnected")
+		}
+
+		cleanups, skipped, err := SetupDcgmFieldsWatch(fields, sysInfo, 1000)
+		require.Error(t, err)
+		assert.Nil(t, cleanups)
+		assert.Nil(t, skipped)
+		assert.Equal(t, 0, fieldGroups)
+	})
+}
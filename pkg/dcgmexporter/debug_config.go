@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+const redactedConfigValue = "REDACTED"
+
+// secretConfigField matches the names of the config fields, whose values are credentials, and are redacted
+// by /debug/config.
+var secretConfigField = regexp.MustCompile(`(?i)(password|secret|token|credential|auth)`)
+
+// encodeConfig returns the config as JSON, with the values of the fields holding credentials redacted.
+func encodeConfig(c *Config) ([]byte, error) {
+	encoded, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	err = json.Unmarshal(encoded, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	redactConfigFields(fields)
+
+	return json.MarshalIndent(fields, "", "  ")
+}
+
+// redactConfigFields replaces the values of the fields holding credentials, including nested ones.
+func redactConfigFields(fields map[string]any) {
+	for name, value := range fields {
+		if secretConfigField.MatchString(name) {
+			if value != nil && value != "" {
+				fields[name] = redactedConfigValue
+			}
+			continue
+		}
+
+		if nested, ok := value.(map[string]any); ok {
+			redactConfigFields(nested)
+		}
+	}
+}
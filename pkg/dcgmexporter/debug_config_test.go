@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsServer_Config(t *testing.T) {
+	config := &Config{
+		Address:         ":9400",
+		CollectInterval: 30000,
+		GPUDevices:      DeviceOptions{MajorRange: []int{0, 1}, MinorRange: []int{-1}},
+		WebConfigFile:   "/etc/dcgm-exporter/web-config.yml",
+		EntityGroupAddresses: map[dcgm.Field_Entity_Group]string{
+			dcgm.FE_SWITCH: ":9401",
+		},
+	}
+
+	server := &MetricsServer{config: config}
+
+	recorder := httptest.NewRecorder()
+	server.Config(recorder, httptest.NewRequest(http.MethodGet, "/debug/config", nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var loaded Config
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &loaded))
+	assert.Equal(t, *config, loaded)
+}
+
+func TestMetricsServer_ConfigEndpoint(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		server, cleanup, err := NewMetricsServer(&Config{EnableDebugEndpoints: enabled}, make(chan string), NewRegistry(), nil, nil)
+		require.NoError(t, err)
+		defer cleanup()
+
+		recorder := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/config", nil))
+		if enabled {
+			assert.Equal(t, http.StatusOK, recorder.Code)
+		} else {
+			assert.Equal(t, http.StatusNotFound, recorder.Code, "the configuration is not exposed by default")
+		}
+	}
+}
+
+func TestRedactConfigFields(t *testing.T) {
+	fields := map[string]any{
+		"Address":           ":9400",
+		"BasicAuthPassword": "hunter2",
+		"EmptyToken":        "",
+		"Web": map[string]any{
+			"AuthHeader": "Bearer abc",
+			"ConfigFile": "/etc/web-config.yml",
+		},
+	}
+
+	redactConfigFields(fields)
+
+	assert.Equal(t, map[string]any{
+		"Address":           ":9400",
+		"BasicAuthPassword": redactedConfigValue,
+		"EmptyToken":        "",
+		"Web": map[string]any{
+			"AuthHeader": redactedConfigValue,
+			"ConfigFile": "/etc/web-config.yml",
+		},
+	}, fields)
+}
@@ -0,0 +1,627 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+// Derived metrics are computed by the exporter from the values of other collected fields.
+
+var fbUsedPercentCounter = Counter{
+	FieldID:   dcgm.DCGM_FI_DEV_FB_USED_PERCENT,
+	FieldName: "DCGM_FI_DEV_FB_USED_PERCENT",
+	PromType:  "gauge",
+	Help:      "Percentage of frame buffer memory used (in %).",
+}
+
+var bar1UsedPercentCounter = Counter{
+	FieldName: "DCGM_EXPORTER_BAR1_USED_PERCENT",
+	PromType:  "gauge",
+	Help:      "Percentage of BAR1 memory used (in %).",
+}
+
+var perfPerWattCounter = Counter{
+	FieldName: "DCGM_EXPORTER_PERF_PER_WATT",
+	PromType:  "gauge",
+	Help:      "Ratio of cycles the tensor pipes are active per watt of power draw (in 1/W).",
+}
+
+var powerCapHeadroomCounter = Counter{
+	FieldName: "DCGM_EXPORTER_POWER_CAP_HEADROOM",
+	PromType:  "gauge",
+	Help:      "Utilization the GPU could add before its power draw reaches the enforced power limit (in %).",
+}
+
+var retiredPagesCounter = Counter{
+	FieldName: "DCGM_EXPORTER_RETIRED_PAGES",
+	PromType:  "gauge",
+	Help:      "Number of retired pages, by cause (single_bit or double_bit errors).",
+}
+
+var rowRemapFailedCounter = Counter{
+	FieldName: "DCGM_EXPORTER_ROW_REMAP_FAILED",
+	PromType:  "gauge",
+	Help:      "1 when the remapping of rows has failed.",
+}
+
+var clockCounter = Counter{
+	FieldName: "DCGM_EXPORTER_CLOCK",
+	PromType:  "gauge",
+	Help:      "Clock frequency (in MHz), by clock domain (sm, memory or video).",
+}
+
+var powerScopeCounter = Counter{
+	FieldName: "DCGM_EXPORTER_POWER_USAGE",
+	PromType:  "gauge",
+	Help:      "Power draw (in W), by scope (board or module).",
+}
+
+var temperatureSensorCounter = Counter{
+	FieldName: "DCGM_EXPORTER_TEMPERATURE",
+	PromType:  "gauge",
+	Help:      "Temperature in the unit of the temperature fields, by sensor (gpu or memory).",
+}
+
+var pcieDegradedCounter = Counter{
+	FieldName: "DCGM_EXPORTER_PCIE_DEGRADED",
+	PromType:  "gauge",
+	Help:      "1 when the PCIe link runs below its max generation or width.",
+}
+
+var nvlinkBandwidthCounter = Counter{
+	FieldName: "DCGM_EXPORTER_NVLINK_BANDWIDTH",
+	PromType:  "counter",
+	Help:      "Number of bytes of NVLink rx and tx data of the GPU, summed over its links.",
+}
+
+var migPowerAttributionErrorCounter = Counter{
+	FieldName: "DCGM_EXPORTER_MIG_POWER_ATTRIBUTION_ERROR",
+	PromType:  "gauge",
+	Help:      "Power draw attributed to the MIG instances of a GPU minus the power draw of the GPU (in W).",
+}
+
+const (
+	causeAttribute       = "cause"
+	clockDomainAttribute = "clock_domain"
+	scopeAttribute       = "scope"
+	sensorAttribute      = "sensor"
+)
+
+// clockDomains maps the clock fields to the clock_domain label of DCGM_EXPORTER_CLOCK.
+var clockDomains = []struct {
+	fieldID uint
+	domain  string
+}{
+	{dcgm.DCGM_FI_DEV_SM_CLOCK, "sm"},
+	{dcgm.DCGM_FI_DEV_MEM_CLOCK, "memory"},
+	{dcgm.DCGM_FI_DEV_VIDEO_CLOCK, "video"},
+}
+
+// retiredPagesCauses maps the retired pages fields to the cause label of DCGM_EXPORTER_RETIRED_PAGES.
+var retiredPagesCauses = []struct {
+	fieldID uint
+	cause   string
+}{
+	{dcgm.DCGM_FI_DEV_RETIRED_SBE, "single_bit"},
+	{dcgm.DCGM_FI_DEV_RETIRED_DBE, "double_bit"},
+}
+
+// powerScopes maps the power fields to the scope label of DCGM_EXPORTER_POWER_USAGE. The power draw of the
+// module, which is shared by several GPUs on some boards, is not a field of the DCGM version the exporter is
+// built against; it belongs here once it is.
+var powerScopes = []struct {
+	fieldID uint
+	scope   string
+}{
+	{dcgm.DCGM_FI_DEV_POWER_USAGE, "board"},
+}
+
+// temperatureSensors maps the temperature fields to the sensor label of DCGM_EXPORTER_TEMPERATURE, so that the
+// temperature of the HBM memory is not mistaken for the temperature of the GPU core.
+var temperatureSensors = []struct {
+	fieldID uint
+	sensor  string
+}{
+	{dcgm.DCGM_FI_DEV_GPU_TEMP, "gpu"},
+	{dcgm.DCGM_FI_DEV_MEMORY_TEMP, "memory"},
+}
+
+// pcieLinkFields pairs the current PCIe link fields with their max; a link is degraded when the current value
+// of either pair is below its max.
+var pcieLinkFields = []struct {
+	currentFieldID uint
+	maxFieldID     uint
+}{
+	{dcgm.DCGM_FI_DEV_PCIE_LINK_GEN, dcgm.DCGM_FI_DEV_PCIE_MAX_LINK_GEN},
+	{dcgm.DCGM_FI_DEV_PCIE_LINK_WIDTH, dcgm.DCGM_FI_DEV_PCIE_MAX_LINK_WIDTH},
+}
+
+// nvlinkBandwidthFields are the bandwidth fields of the NvLinks of a GPU, one per link.
+var nvlinkBandwidthFields = []uint{
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L0,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L1,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L2,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L3,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L4,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L5,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L6,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L7,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L8,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L9,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L10,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L11,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L12,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L13,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L14,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L15,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L16,
+	dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L17,
+}
+
+// derivedCounters are the counters computed by the exporter, which are not DCGM fields.
+var derivedCounters = []Counter{perfPerWattCounter, retiredPagesCounter, rowRemapFailedCounter, fieldStuckCounter,
+	clockCounter, powerScopeCounter, linkStateCounter, pcieDegradedCounter,
+	migPowerAttributionErrorCounter, counterOKCounter, tensorThroughputCounter, windowedAverageCounter,
+	powerPeakCounter, migInstanceCountCounter, bar1UsedPercentCounter,
+	nvlinkBandwidthCounter, temperatureSensorCounter, codecSessionsCounter, fanFailedCounter, processSMUtilCounter,
+	processMemUtilCounter, powerCapHeadroomCounter, migScalingFactorCounter,
+	migIdlePowerCounter, migActivePowerCounter}
+
+// derivedMetricKey identifies the entity a metric belongs to, so metrics of different fields can be matched.
+func derivedMetricKey(m Metric) string {
+	return fmt.Sprintf("%s-%s", m.GPU, m.GPUInstanceID)
+}
+
+// AppendFBUsedPercent adds DCGM_FI_DEV_FB_USED_PERCENT computed from DCGM_FI_DEV_FB_USED and DCGM_FI_DEV_FB_TOTAL,
+// when both fields are collected and the percentage itself is not.
+func AppendFBUsedPercent(metrics MetricsByCounter, counters []Counter) {
+	appendUsedPercent(metrics, counters, dcgm.DCGM_FI_DEV_FB_USED, dcgm.DCGM_FI_DEV_FB_TOTAL, fbUsedPercentCounter)
+}
+
+// AppendBAR1UsedPercent adds DCGM_EXPORTER_BAR1_USED_PERCENT computed from DCGM_FI_DEV_BAR1_USED and
+// DCGM_FI_DEV_BAR1_TOTAL, when both fields are collected.
+func AppendBAR1UsedPercent(metrics MetricsByCounter, counters []Counter) {
+	appendUsedPercent(metrics, counters, dcgm.DCGM_FI_DEV_BAR1_USED, dcgm.DCGM_FI_DEV_BAR1_TOTAL, bar1UsedPercentCounter)
+}
+
+// appendUsedPercent adds the percent counter computed from the used and total fields of a memory, when both fields
+// are collected and the percentage itself is not.
+func appendUsedPercent(metrics MetricsByCounter, counters []Counter, usedField, totalField uint,
+	percentCounter Counter,
+) {
+	if len(counters) == 0 || slices.ContainsFunc(counters, func(c Counter) bool {
+		return c.FieldName == percentCounter.FieldName
+	}) {
+		return
+	}
+
+	usedCounter, usedErr := FindCounterField(counters, usedField)
+	totalCounter, totalErr := FindCounterField(counters, totalField)
+	if usedErr != nil || totalErr != nil {
+		return
+	}
+
+	totals := map[string]float64{}
+	for _, m := range metrics[totalCounter] {
+		total, err := strconv.ParseFloat(m.Value, 64)
+		if err != nil || total <= 0 {
+			continue
+		}
+		totals[derivedMetricKey(m)] = total
+	}
+
+	for _, m := range metrics[usedCounter] {
+		total, exists := totals[derivedMetricKey(m)]
+		if !exists {
+			continue
+		}
+
+		used, err := strconv.ParseFloat(m.Value, 64)
+		if err != nil {
+			continue
+		}
+
+		derived := m
+		derived.Counter = percentCounter
+		derived.Value = fmt.Sprintf("%f", used/total*100)
+		derived.Attributes = maps.Clone(m.Attributes)
+
+		metrics[percentCounter] = append(metrics[percentCounter], derived)
+	}
+}
+
+// AppendPerfPerWatt adds DCGM_EXPORTER_PERF_PER_WATT computed from DCGM_FI_PROF_PIPE_TENSOR_ACTIVE and
+// DCGM_FI_DEV_POWER_USAGE, when both fields are collected. Every entity is divided by its own power draw, so MIG
+// instances are only added when the power draw is attributed to them; otherwise their power draw is the one of
+// their GPU. Entities without a power draw are skipped.
+func AppendPerfPerWatt(metrics MetricsByCounter, counters []Counter) {
+	tensorCounter, tensorErr := FindCounterField(counters, dcgm.DCGM_FI_PROF_PIPE_TENSOR_ACTIVE)
+	powerCounter, powerErr := FindCounterField(counters, dcgm.DCGM_FI_DEV_POWER_USAGE)
+	if tensorErr != nil || powerErr != nil {
+		return
+	}
+
+	attributed := aggregationRules[aggregationKey{dcgm.DCGM_FI_DEV_POWER_USAGE, dcgm.FE_GPU_I}] == aggregationAttributed
+
+	powerByEntity := map[string]float64{}
+	for _, m := range metrics[powerCounter] {
+		if m.GPUInstanceID != "" && !attributed {
+			continue
+		}
+
+		power, err := strconv.ParseFloat(m.Value, 64)
+		if err != nil || power <= 0 {
+			continue
+		}
+		powerByEntity[derivedMetricKey(m)] = power
+	}
+
+	for _, m := range metrics[tensorCounter] {
+		power, exists := powerByEntity[derivedMetricKey(m)]
+		if !exists {
+			continue
+		}
+
+		tensorActive, err := strconv.ParseFloat(m.Value, 64)
+		if err != nil {
+			continue
+		}
+
+		derived := m
+		derived.Counter = perfPerWattCounter
+		derived.Value = fmt.Sprintf("%f", tensorActive/power)
+		derived.Attributes = maps.Clone(m.Attributes)
+
+		metrics[perfPerWattCounter] = append(metrics[perfPerWattCounter], derived)
+	}
+}
+
+// AppendPowerCapHeadroom adds DCGM_EXPORTER_POWER_CAP_HEADROOM computed from DCGM_FI_DEV_GPU_UTIL,
+// DCGM_FI_DEV_POWER_USAGE and DCGM_FI_DEV_ENFORCED_POWER_LIMIT, when all fields are collected. The utilization is
+// assumed to grow with the power draw, so the GPU would reach its limit at util * limit / power, capped at 100%.
+// GPUs missing any of the fields or without a power draw are skipped.
+func AppendPowerCapHeadroom(metrics MetricsByCounter, counters []Counter) {
+	utilCounter, utilErr := FindCounterField(counters, dcgm.DCGM_FI_DEV_GPU_UTIL)
+	powerCounter, powerErr := FindCounterField(counters, dcgm.DCGM_FI_DEV_POWER_USAGE)
+	limitCounter, limitErr := FindCounterField(counters, dcgm.DCGM_FI_DEV_ENFORCED_POWER_LIMIT)
+	if utilErr != nil || powerErr != nil || limitErr != nil {
+		return
+	}
+
+	valuesByGPU := func(counter Counter) map[string]float64 {
+		values := map[string]float64{}
+		for _, m := range metrics[counter] {
+			if m.GPUInstanceID != "" {
+				continue
+			}
+
+			value, err := strconv.ParseFloat(m.Value, 64)
+			if err != nil || value <= 0 {
+				continue
+			}
+			values[m.GPU] = value
+		}
+		return values
+	}
+	powerByGPU := valuesByGPU(powerCounter)
+	limitByGPU := valuesByGPU(limitCounter)
+
+	for _, m := range metrics[utilCounter] {
+		power, powerExists := powerByGPU[m.GPU]
+		limit, limitExists := limitByGPU[m.GPU]
+		if m.GPUInstanceID != "" || !powerExists || !limitExists {
+			continue
+		}
+
+		util, err := strconv.ParseFloat(m.Value, 64)
+		if err != nil {
+			continue
+		}
+
+		derived := m
+		derived.Counter = powerCapHeadroomCounter
+		derived.Value = fmt.Sprintf("%f", max(min(util*limit/power, 100)-util, 0))
+		derived.Attributes = maps.Clone(m.Attributes)
+
+		metrics[powerCapHeadroomCounter] = append(metrics[powerCapHeadroomCounter], derived)
+	}
+}
+
+// AppendRetiredPages adds DCGM_EXPORTER_RETIRED_PAGES, labeled by cause, from DCGM_FI_DEV_RETIRED_SBE and
+// DCGM_FI_DEV_RETIRED_DBE, and DCGM_EXPORTER_ROW_REMAP_FAILED from DCGM_FI_DEV_ROW_REMAP_FAILURE,
+// for the fields that are collected.
+func AppendRetiredPages(metrics MetricsByCounter, counters []Counter) {
+	for _, retired := range retiredPagesCauses {
+		counter, err := FindCounterField(counters, retired.fieldID)
+		if err != nil {
+			continue
+		}
+
+		for _, m := range metrics[counter] {
+			pages, err := strconv.ParseFloat(m.Value, 64)
+			if err != nil {
+				continue
+			}
+
+			derived := m
+			derived.Counter = retiredPagesCounter
+			derived.Value = fmt.Sprintf("%.0f", pages)
+			derived.Attributes = maps.Clone(m.Attributes)
+			if derived.Attributes == nil {
+				derived.Attributes = map[string]string{}
+			}
+			derived.Attributes[causeAttribute] = retired.cause
+
+			metrics[retiredPagesCounter] = append(metrics[retiredPagesCounter], derived)
+		}
+	}
+
+	failureCounter, err := FindCounterField(counters, dcgm.DCGM_FI_DEV_ROW_REMAP_FAILURE)
+	if err != nil {
+		return
+	}
+
+	for _, m := range metrics[failureCounter] {
+		failure, err := strconv.ParseFloat(m.Value, 64)
+		if err != nil {
+			continue
+		}
+
+		derived := m
+		derived.Counter = rowRemapFailedCounter
+		derived.Value = "0"
+		if failure != 0 {
+			derived.Value = "1"
+		}
+		derived.Attributes = maps.Clone(m.Attributes)
+
+		metrics[rowRemapFailedCounter] = append(metrics[rowRemapFailedCounter], derived)
+	}
+}
+
+// AppendClocks adds DCGM_EXPORTER_CLOCK, labeled by clock domain, from DCGM_FI_DEV_SM_CLOCK, DCGM_FI_DEV_MEM_CLOCK
+// and DCGM_FI_DEV_VIDEO_CLOCK, for the fields that are collected.
+func AppendClocks(metrics MetricsByCounter, counters []Counter) {
+	for _, clock := range clockDomains {
+		counter, err := FindCounterField(counters, clock.fieldID)
+		if err != nil {
+			continue
+		}
+
+		for _, m := range metrics[counter] {
+			if _, err := strconv.ParseFloat(m.Value, 64); err != nil {
+				continue
+			}
+
+			derived := m
+			derived.Counter = clockCounter
+			derived.Attributes = maps.Clone(m.Attributes)
+			if derived.Attributes == nil {
+				derived.Attributes = map[string]string{}
+			}
+			derived.Attributes[clockDomainAttribute] = clock.domain
+
+			metrics[clockCounter] = append(metrics[clockCounter], derived)
+		}
+	}
+}
+
+// AppendPowerScopes adds DCGM_EXPORTER_POWER_USAGE, labeled by scope, from the power fields that are collected,
+// so that the power draw of a board and of the module it shares with other GPUs are not added up.
+func AppendPowerScopes(metrics MetricsByCounter, counters []Counter) {
+	for _, power := range powerScopes {
+		counter, err := FindCounterField(counters, power.fieldID)
+		if err != nil {
+			continue
+		}
+
+		for _, m := range metrics[counter] {
+			if _, err := strconv.ParseFloat(m.Value, 64); err != nil {
+				continue
+			}
+
+			derived := m
+			derived.Counter = powerScopeCounter
+			derived.Attributes = maps.Clone(m.Attributes)
+			if derived.Attributes == nil {
+				derived.Attributes = map[string]string{}
+			}
+			derived.Attributes[scopeAttribute] = power.scope
+
+			metrics[powerScopeCounter] = append(metrics[powerScopeCounter], derived)
+		}
+	}
+}
+
+// AppendTemperatureSensors adds DCGM_EXPORTER_TEMPERATURE, labeled by sensor, from the temperature fields that
+// are collected.
+func AppendTemperatureSensors(metrics MetricsByCounter, counters []Counter) {
+	for _, temperature := range temperatureSensors {
+		counter, err := FindCounterField(counters, temperature.fieldID)
+		if err != nil {
+			continue
+		}
+
+		for _, m := range metrics[counter] {
+			if _, err := strconv.ParseFloat(m.Value, 64); err != nil {
+				continue
+			}
+
+			derived := m
+			derived.Counter = temperatureSensorCounter
+			derived.Attributes = maps.Clone(m.Attributes)
+			if derived.Attributes == nil {
+				derived.Attributes = map[string]string{}
+			}
+			derived.Attributes[sensorAttribute] = temperature.sensor
+
+			metrics[temperatureSensorCounter] = append(metrics[temperatureSensorCounter], derived)
+		}
+	}
+}
+
+// AppendPCIeDegraded adds DCGM_EXPORTER_PCIE_DEGRADED from the current and max PCIe link generation and width,
+// for the pairs of fields that are collected, e.g. 1 for a GPU running at gen1 x4 on a gen4 x16 link.
+func AppendPCIeDegraded(metrics MetricsByCounter, counters []Counter) {
+	degraded := map[string]bool{}
+	var order []Metric
+
+	for _, link := range pcieLinkFields {
+		currentCounter, currentErr := FindCounterField(counters, link.currentFieldID)
+		maxCounter, maxErr := FindCounterField(counters, link.maxFieldID)
+		if currentErr != nil || maxErr != nil {
+			continue
+		}
+
+		maxByEntity := map[string]float64{}
+		for _, m := range metrics[maxCounter] {
+			maxValue, err := strconv.ParseFloat(m.Value, 64)
+			if err != nil || maxValue <= 0 {
+				continue
+			}
+			maxByEntity[derivedMetricKey(m)] = maxValue
+		}
+
+		for _, m := range metrics[currentCounter] {
+			maxValue, exists := maxByEntity[derivedMetricKey(m)]
+			if !exists {
+				continue
+			}
+
+			current, err := strconv.ParseFloat(m.Value, 64)
+			if err != nil {
+				continue
+			}
+
+			key := derivedMetricKey(m)
+			if _, exists := degraded[key]; !exists {
+				order = append(order, m)
+			}
+			degraded[key] = degraded[key] || current < maxValue
+		}
+	}
+
+	for _, m := range order {
+		derived := m
+		derived.Counter = pcieDegradedCounter
+		derived.Value = "0"
+		if degraded[derivedMetricKey(m)] {
+			derived.Value = "1"
+		}
+		derived.Attributes = maps.Clone(m.Attributes)
+
+		metrics[pcieDegradedCounter] = append(metrics[pcieDegradedCounter], derived)
+	}
+}
+
+// AppendNvLinkBandwidth adds DCGM_EXPORTER_NVLINK_BANDWIDTH, the sum of the bandwidth fields of the NvLinks of
+// a GPU, for the GPUs with at least one of these fields collected. The links are not MIG-aware, so only the
+// metrics of whole GPUs are summed.
+func AppendNvLinkBandwidth(metrics MetricsByCounter, counters []Counter) {
+	bandwidth := map[string]float64{}
+	var order []Metric
+
+	for _, fieldID := range nvlinkBandwidthFields {
+		counter, err := FindCounterField(counters, fieldID)
+		if err != nil {
+			continue
+		}
+
+		for _, m := range metrics[counter] {
+			if m.GPUInstanceID != "" {
+				continue
+			}
+
+			value, err := strconv.ParseFloat(m.Value, 64)
+			if err != nil {
+				continue
+			}
+
+			key := derivedMetricKey(m)
+			if _, exists := bandwidth[key]; !exists {
+				order = append(order, m)
+			}
+			bandwidth[key] += value
+		}
+	}
+
+	for _, m := range order {
+		derived := m
+		derived.Counter = nvlinkBandwidthCounter
+		derived.Value = fmt.Sprintf("%f", bandwidth[derivedMetricKey(m)])
+		derived.Attributes = maps.Clone(m.Attributes)
+
+		metrics[nvlinkBandwidthCounter] = append(metrics[nvlinkBandwidthCounter], derived)
+	}
+}
+
+// AppendMigPowerAttributionError adds DCGM_EXPORTER_MIG_POWER_ATTRIBUTION_ERROR, the sum of the power draw attributed
+// to the MIG instances of a GPU minus the power draw of the GPU, as a self-check of the attribution of
+// DCGM_FI_DEV_POWER_USAGE to MIG instances. It is only added when the power draw is attributed to MIG instances, for
+// GPUs whose own power draw is collected as well.
+func AppendMigPowerAttributionError(metrics MetricsByCounter, counters []Counter) {
+	if aggregationRules[aggregationKey{dcgm.DCGM_FI_DEV_POWER_USAGE, dcgm.FE_GPU_I}] != aggregationAttributed {
+		return
+	}
+
+	powerCounter, err := FindCounterField(counters, dcgm.DCGM_FI_DEV_POWER_USAGE)
+	if err != nil {
+		return
+	}
+
+	attributed := map[string]float64{}
+	for _, m := range metrics[powerCounter] {
+		if m.GPUInstanceID == "" {
+			continue
+		}
+
+		power, err := strconv.ParseFloat(m.Value, 64)
+		if err != nil {
+			continue
+		}
+		attributed[m.GPU] += power
+	}
+
+	for _, m := range metrics[powerCounter] {
+		if m.GPUInstanceID != "" {
+			continue
+		}
+
+		sum, exists := attributed[m.GPU]
+		if !exists {
+			continue
+		}
+
+		power, err := strconv.ParseFloat(m.Value, 64)
+		if err != nil {
+			continue
+		}
+
+		derived := m
+		derived.Counter = migPowerAttributionErrorCounter
+		derived.Value = fmt.Sprintf("%f", sum-power)
+		derived.Attributes = maps.Clone(m.Attributes)
+
+		metrics[migPowerAttributionErrorCounter] = append(metrics[migPowerAttributionErrorCounter], derived)
+	}
+}
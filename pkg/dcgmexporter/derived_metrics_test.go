@@ -0,0 +1,491 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendFBUsedPercent(t *testing.T) {
+	usedCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_FB_USED, FieldName: "DCGM_FI_DEV_FB_USED", PromType: "gauge", Help: "Frame buffer memory used (in MB)."}
+	totalCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_FB_TOTAL, FieldName: "DCGM_FI_DEV_FB_TOTAL", PromType: "gauge", Help: "Frame buffer memory total (in MB)."}
+
+	newMetrics := func() MetricsByCounter {
+		return MetricsByCounter{
+			usedCounter: {
+				{Counter: usedCounter, Value: "8192", GPU: "0", Attributes: map[string]string{}},
+				{Counter: usedCounter, Value: "1024", GPU: "1", Attributes: map[string]string{}},
+			},
+			totalCounter: {
+				{Counter: totalCounter, Value: "16384", GPU: "0", Attributes: map[string]string{}},
+			},
+		}
+	}
+
+	t.Run("When used and total are collected", func(t *testing.T) {
+		metrics := newMetrics()
+		AppendFBUsedPercent(metrics, []Counter{usedCounter, totalCounter})
+
+		require.Len(t, metrics[fbUsedPercentCounter], 1, "GPU 1 has no total and must be skipped")
+		percent := metrics[fbUsedPercentCounter][0]
+		assert.Equal(t, "0", percent.GPU)
+		assert.Equal(t, 50.0, mustParseFloat(t, percent.Value))
+		assert.Len(t, metrics[usedCounter], 2, "raw fields must be kept")
+		assert.Len(t, metrics[totalCounter], 1, "raw fields must be kept")
+	})
+
+	t.Run("When total is not collected", func(t *testing.T) {
+		metrics := newMetrics()
+		AppendFBUsedPercent(metrics, []Counter{usedCounter})
+		assert.NotContains(t, metrics, fbUsedPercentCounter)
+	})
+
+	t.Run("When the percentage is collected from DCGM", func(t *testing.T) {
+		metrics := newMetrics()
+		AppendFBUsedPercent(metrics, []Counter{usedCounter, totalCounter, fbUsedPercentCounter})
+		assert.NotContains(t, metrics, fbUsedPercentCounter)
+	})
+}
+
+func TestAppendBAR1UsedPercent(t *testing.T) {
+	usedCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_BAR1_USED, FieldName: "DCGM_FI_DEV_BAR1_USED", PromType: "gauge", Help: "BAR1 memory used (in MB)."}
+	totalCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_BAR1_TOTAL, FieldName: "DCGM_FI_DEV_BAR1_TOTAL", PromType: "gauge", Help: "BAR1 memory total (in MB)."}
+
+	metrics := MetricsByCounter{
+		usedCounter: {
+			{Counter: usedCounter, Value: "64", GPU: "0", Attributes: map[string]string{}},
+			{Counter: usedCounter, Value: "256", GPU: "1", Attributes: map[string]string{}},
+		},
+		totalCounter: {
+			{Counter: totalCounter, Value: "256", GPU: "0", Attributes: map[string]string{}},
+			{Counter: totalCounter, Value: "256", GPU: "1", Attributes: map[string]string{}},
+		},
+	}
+	AppendBAR1UsedPercent(metrics, []Counter{usedCounter, totalCounter})
+
+	require.Len(t, metrics[bar1UsedPercentCounter], 2)
+	percents := map[string]float64{}
+	for _, m := range metrics[bar1UsedPercentCounter] {
+		percents[m.GPU] = mustParseFloat(t, m.Value)
+	}
+	assert.Equal(t, map[string]float64{"0": 25, "1": 100}, percents)
+	assert.NotContains(t, metrics, fbUsedPercentCounter)
+}
+
+func TestAppendNvLinkBandwidth(t *testing.T) {
+	link0 := Counter{FieldID: dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L0, FieldName: "DCGM_FI_DEV_NVLINK_BANDWIDTH_L0", PromType: "counter", Help: "NvLink 0 bandwidth."}
+	link1 := Counter{FieldID: dcgm.DCGM_FI_DEV_NVLINK_BANDWIDTH_L1, FieldName: "DCGM_FI_DEV_NVLINK_BANDWIDTH_L1", PromType: "counter", Help: "NvLink 1 bandwidth."}
+
+	metrics := MetricsByCounter{
+		link0: {
+			{Counter: link0, Value: "1000", GPU: "0", GPUUUID: "GPU-0", Attributes: map[string]string{}},
+			{Counter: link0, Value: "10", GPU: "1", GPUUUID: "GPU-1", Attributes: map[string]string{}},
+			{Counter: link0, Value: "5", GPU: "0", GPUInstanceID: "1", Attributes: map[string]string{}},
+		},
+		link1: {
+			{Counter: link1, Value: "2500", GPU: "0", GPUUUID: "GPU-0", Attributes: map[string]string{}},
+		},
+	}
+	AppendNvLinkBandwidth(metrics, []Counter{link0, link1})
+
+	require.Len(t, metrics[nvlinkBandwidthCounter], 2, "GPU instances are not summed")
+	bandwidth := map[string]float64{}
+	for _, m := range metrics[nvlinkBandwidthCounter] {
+		assert.Empty(t, m.GPUInstanceID)
+		bandwidth[m.GPUUUID] = mustParseFloat(t, m.Value)
+	}
+	assert.Equal(t, map[string]float64{"GPU-0": 3500, "GPU-1": 10}, bandwidth)
+	assert.Len(t, metrics[link0], 3, "raw fields must be kept")
+}
+
+func TestAppendPerfPerWatt(t *testing.T) {
+	tensorCounter := Counter{FieldID: dcgm.DCGM_FI_PROF_PIPE_TENSOR_ACTIVE, FieldName: "DCGM_FI_PROF_PIPE_TENSOR_ACTIVE", PromType: "gauge", Help: "Ratio of cycles the tensor (HMMA) pipe is active."}
+	powerCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge", Help: "Power draw (in W)."}
+
+	newMetrics := func() MetricsByCounter {
+		return MetricsByCounter{
+			tensorCounter: {
+				{Counter: tensorCounter, Value: "0.500000", GPU: "0", Attributes: map[string]string{}},
+				{Counter: tensorCounter, Value: "0.250000", GPU: "1", GPUInstanceID: "3", MigProfile: "1g.10gb", Attributes: map[string]string{}},
+				{Counter: tensorCounter, Value: "0.750000", GPU: "2", Attributes: map[string]string{}},
+				{Counter: tensorCounter, Value: "0.750000", GPU: "3", Attributes: map[string]string{}},
+			},
+			powerCounter: {
+				{Counter: powerCounter, Value: "250.000000", GPU: "0", Attributes: map[string]string{}},
+				{Counter: powerCounter, Value: "100.000000", GPU: "1", GPUInstanceID: "3", MigProfile: "1g.10gb", Attributes: map[string]string{}},
+				{Counter: powerCounter, Value: "0.000000", GPU: "2", Attributes: map[string]string{}},
+			},
+		}
+	}
+
+	t.Run("When tensor activity and power are collected", func(t *testing.T) {
+		metrics := newMetrics()
+		AppendPerfPerWatt(metrics, []Counter{tensorCounter, powerCounter})
+
+		require.Len(t, metrics[perfPerWattCounter], 1,
+			"GPU 1 is a MIG instance without attributed power, GPU 2 draws no power and GPU 3 has no power draw")
+
+		gpu := metrics[perfPerWattCounter][0]
+		assert.Equal(t, "0", gpu.GPU)
+		assert.Equal(t, 0.002, mustParseFloat(t, gpu.Value))
+
+		assert.Len(t, metrics[tensorCounter], 4, "raw fields must be kept")
+		assert.Len(t, metrics[powerCounter], 3, "raw fields must be kept")
+	})
+
+	t.Run("When power is not collected", func(t *testing.T) {
+		metrics := newMetrics()
+		AppendPerfPerWatt(metrics, []Counter{tensorCounter})
+		assert.NotContains(t, metrics, perfPerWattCounter)
+	})
+}
+
+func TestAppendPerfPerWattWithMigInFlexMode(t *testing.T) {
+	SetAttributedFields([]dcgm.Short{dcgm.DCGM_FI_DEV_POWER_USAGE})
+	t.Cleanup(func() {
+		SetAttributedFields(nil)
+	})
+
+	tensorCounter := Counter{FieldID: dcgm.DCGM_FI_PROF_PIPE_TENSOR_ACTIVE, FieldName: "DCGM_FI_PROF_PIPE_TENSOR_ACTIVE", PromType: "gauge"}
+	powerCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge"}
+	counters := []Counter{tensorCounter, powerCounter}
+
+	// GPU 0 is a whole GPU and GPU 1 is split into a 3g and a 4g instance, so that only its instances are monitored
+	sysInfo := SystemInfo{
+		GPUCount: 2,
+		InfoType: dcgm.FE_GPU,
+		gOpt:     DeviceOptions{Flex: true},
+	}
+	sysInfo.GPUs[0] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-0"}}
+	sysInfo.GPUs[1] = GPUInfo{
+		DeviceInfo: dcgm.Device{GPU: 1, UUID: "GPU-1"},
+		MigEnabled: true,
+		GPUInstances: []GPUInstanceInfo{
+			{EntityId: 10, ProfileName: "3g.40gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 1, NvmlProfileSlices: 3}, GPUSlices: 7},
+			{EntityId: 11, ProfileName: "4g.40gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 2, NvmlProfileSlices: 4}, GPUSlices: 7},
+		},
+	}
+
+	// DCGM reports the power draw of the GPU for its instances, and the tensor activity of every instance
+	tensorActive := map[uint]float64{0: 0.5, 10: 0.6, 11: 0.2}
+	power := map[uint]float64{0: 250, 10: 350, 11: 350}
+
+	double := func(fieldID dcgm.Short, v float64) dcgm.FieldValue_v1 {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], math.Float64bits(v))
+		return dcgm.FieldValue_v1{FieldId: uint(fieldID), FieldType: dcgm.DCGM_FT_DOUBLE, Value: value}
+	}
+
+	metrics := MetricsByCounter{}
+	monitoringInfo := GetMonitoredEntities(sysInfo)
+	require.Len(t, monitoringInfo, 3, "the MIG GPU is only monitored through its instances")
+	for _, mi := range monitoringInfo {
+		values := []dcgm.FieldValue_v1{
+			double(dcgm.DCGM_FI_PROF_PIPE_TENSOR_ACTIVE, tensorActive[mi.Entity.EntityId]),
+			double(dcgm.DCGM_FI_DEV_POWER_USAGE, power[mi.Entity.EntityId]),
+		}
+		ToMetric(metrics, values, counters, mi.DeviceInfo, mi.InstanceInfo, MetricOptions{})
+	}
+
+	AppendPerfPerWatt(metrics, counters)
+
+	perfPerWatt := map[string]float64{}
+	for _, m := range metrics[perfPerWattCounter] {
+		perfPerWatt[derivedMetricKey(m)] = mustParseFloat(t, m.Value)
+	}
+	require.Len(t, perfPerWatt, 3, "every monitored entity has a power draw")
+	assert.InDelta(t, 0.5/250, perfPerWatt["0-"], 1e-9)
+	assert.InDelta(t, 0.6/150, perfPerWatt["1-1"], 1e-9, "3 of 7 slices of 350 W")
+	assert.InDelta(t, 0.2/200, perfPerWatt["1-2"], 1e-9, "4 of 7 slices of 350 W")
+}
+
+func TestAppendPowerCapHeadroom(t *testing.T) {
+	utilCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_UTIL, FieldName: "DCGM_FI_DEV_GPU_UTIL", PromType: "gauge", Help: "GPU utilization (in %)."}
+	powerCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge", Help: "Power draw (in W)."}
+	limitCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_ENFORCED_POWER_LIMIT, FieldName: "DCGM_FI_DEV_ENFORCED_POWER_LIMIT", PromType: "gauge", Help: "Enforced power limit (in W)."}
+
+	newMetrics := func() MetricsByCounter {
+		return MetricsByCounter{
+			utilCounter: {
+				{Counter: utilCounter, Value: "50", GPU: "0", Attributes: map[string]string{}},
+				{Counter: utilCounter, Value: "90", GPU: "1", Attributes: map[string]string{}},
+				{Counter: utilCounter, Value: "50", GPU: "2", Attributes: map[string]string{}},
+			},
+			powerCounter: {
+				{Counter: powerCounter, Value: "210.000000", GPU: "0", Attributes: map[string]string{}},
+				{Counter: powerCounter, Value: "250.000000", GPU: "1", Attributes: map[string]string{}},
+				{Counter: powerCounter, Value: "100.000000", GPU: "2", Attributes: map[string]string{}},
+			},
+			limitCounter: {
+				{Counter: limitCounter, Value: "300.000000", GPU: "0", Attributes: map[string]string{}},
+				{Counter: limitCounter, Value: "300.000000", GPU: "1", Attributes: map[string]string{}},
+			},
+		}
+	}
+
+	t.Run("When utilization, power and power limit are collected", func(t *testing.T) {
+		metrics := newMetrics()
+		AppendPowerCapHeadroom(metrics, []Counter{utilCounter, powerCounter, limitCounter})
+
+		require.Len(t, metrics[powerCapHeadroomCounter], 2, "GPU 2 has no power limit")
+
+		gpu := metrics[powerCapHeadroomCounter][0]
+		assert.Equal(t, "0", gpu.GPU)
+		assert.InDelta(t, 50.0/0.7-50, mustParseFloat(t, gpu.Value), 1e-6, "50% utilization at 70% of the power limit")
+
+		capped := metrics[powerCapHeadroomCounter][1]
+		assert.Equal(t, "1", capped.GPU)
+		assert.Equal(t, 10.0, mustParseFloat(t, capped.Value), "the utilization is capped at 100%")
+
+		assert.Len(t, metrics[utilCounter], 3, "raw fields must be kept")
+	})
+
+	t.Run("When the power limit is not collected", func(t *testing.T) {
+		metrics := newMetrics()
+		AppendPowerCapHeadroom(metrics, []Counter{utilCounter, powerCounter})
+		assert.NotContains(t, metrics, powerCapHeadroomCounter)
+	})
+}
+
+func TestAppendRetiredPages(t *testing.T) {
+	sbeCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_RETIRED_SBE, FieldName: "DCGM_FI_DEV_RETIRED_SBE", PromType: "counter", Help: "Total number of retired pages due to single-bit errors."}
+	dbeCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_RETIRED_DBE, FieldName: "DCGM_FI_DEV_RETIRED_DBE", PromType: "counter", Help: "Total number of retired pages due to double-bit errors."}
+	failureCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_ROW_REMAP_FAILURE, FieldName: "DCGM_FI_DEV_ROW_REMAP_FAILURE", PromType: "gauge", Help: "Whether remapping of rows has failed"}
+
+	newMetrics := func() MetricsByCounter {
+		return MetricsByCounter{
+			sbeCounter: {
+				{Counter: sbeCounter, Value: "3", GPU: "0", Attributes: map[string]string{}},
+			},
+			dbeCounter: {
+				{Counter: dbeCounter, Value: "1", GPU: "0", Attributes: map[string]string{}},
+			},
+			failureCounter: {
+				{Counter: failureCounter, Value: "0", GPU: "0", Attributes: map[string]string{}},
+				{Counter: failureCounter, Value: "1", GPU: "1", Attributes: map[string]string{}},
+			},
+		}
+	}
+
+	t.Run("When the retired pages and remap failure fields are collected", func(t *testing.T) {
+		metrics := newMetrics()
+		AppendRetiredPages(metrics, []Counter{sbeCounter, dbeCounter, failureCounter})
+
+		require.Len(t, metrics[retiredPagesCounter], 2)
+		pages := map[string]string{}
+		for _, m := range metrics[retiredPagesCounter] {
+			assert.Equal(t, "0", m.GPU)
+			pages[m.Attributes[causeAttribute]] = m.Value
+		}
+		assert.Equal(t, map[string]string{"single_bit": "3", "double_bit": "1"}, pages)
+
+		require.Len(t, metrics[rowRemapFailedCounter], 2)
+		assert.Equal(t, "0", metrics[rowRemapFailedCounter][0].Value)
+		assert.Equal(t, "1", metrics[rowRemapFailedCounter][1].Value)
+		assert.Empty(t, metrics[sbeCounter][0].Attributes, "raw fields must not be labeled")
+	})
+
+	t.Run("When only single-bit retired pages are collected", func(t *testing.T) {
+		metrics := newMetrics()
+		AppendRetiredPages(metrics, []Counter{sbeCounter})
+
+		require.Len(t, metrics[retiredPagesCounter], 1)
+		assert.Equal(t, "single_bit", metrics[retiredPagesCounter][0].Attributes[causeAttribute])
+		assert.NotContains(t, metrics, rowRemapFailedCounter)
+	})
+}
+
+func TestAppendClocks(t *testing.T) {
+	smCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_SM_CLOCK, FieldName: "DCGM_FI_DEV_SM_CLOCK", PromType: "gauge", Help: "SM clock frequency (in MHz)."}
+	memCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_MEM_CLOCK, FieldName: "DCGM_FI_DEV_MEM_CLOCK", PromType: "gauge", Help: "Memory clock frequency (in MHz)."}
+	videoCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_VIDEO_CLOCK, FieldName: "DCGM_FI_DEV_VIDEO_CLOCK", PromType: "gauge", Help: "Video encoder/decoder clock (in MHz)."}
+
+	newMetrics := func() MetricsByCounter {
+		return MetricsByCounter{
+			smCounter: {
+				{Counter: smCounter, Value: "1410", GPU: "0", Attributes: map[string]string{}},
+				{Counter: smCounter, Value: "1380", GPU: "1", Attributes: map[string]string{}},
+			},
+			memCounter: {
+				{Counter: memCounter, Value: "1593", GPU: "0", Attributes: map[string]string{}},
+			},
+			videoCounter: {
+				{Counter: videoCounter, Value: "1275", GPU: "0", Attributes: map[string]string{}},
+			},
+		}
+	}
+
+	t.Run("When all clock fields are collected", func(t *testing.T) {
+		metrics := newMetrics()
+		AppendClocks(metrics, []Counter{smCounter, memCounter, videoCounter})
+
+		require.Len(t, metrics[clockCounter], 4)
+		clocks := map[string]string{}
+		for _, m := range metrics[clockCounter] {
+			clocks[m.GPU+"/"+m.Attributes[clockDomainAttribute]] = m.Value
+		}
+		assert.Equal(t, map[string]string{"0/sm": "1410", "1/sm": "1380", "0/memory": "1593", "0/video": "1275"}, clocks)
+		assert.Empty(t, metrics[smCounter][0].Attributes, "raw fields must not be labeled")
+	})
+
+	t.Run("When only the memory clock is collected", func(t *testing.T) {
+		metrics := newMetrics()
+		AppendClocks(metrics, []Counter{memCounter})
+
+		require.Len(t, metrics[clockCounter], 1)
+		assert.Equal(t, "memory", metrics[clockCounter][0].Attributes[clockDomainAttribute])
+	})
+}
+
+func TestAppendPowerScopes(t *testing.T) {
+	boardCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge", Help: "Power draw (in W)."}
+	moduleCounter := Counter{FieldID: dcgm.Short(9999), FieldName: "DCGM_FI_DEV_MODULE_POWER_USAGE", PromType: "gauge", Help: "Module power draw (in W)."}
+
+	scopes := powerScopes
+	t.Cleanup(func() {
+		powerScopes = scopes
+	})
+	powerScopes = append(slices.Clone(scopes), struct {
+		fieldID uint
+		scope   string
+	}{uint(moduleCounter.FieldID), "module"})
+
+	metrics := MetricsByCounter{
+		boardCounter: {
+			{Counter: boardCounter, Value: "300", GPU: "0", Attributes: map[string]string{}},
+			{Counter: boardCounter, Value: "310", GPU: "1", Attributes: map[string]string{}},
+		},
+		moduleCounter: {
+			{Counter: moduleCounter, Value: "900", GPU: "0", Attributes: map[string]string{}},
+			{Counter: moduleCounter, Value: "900", GPU: "1", Attributes: map[string]string{}},
+		},
+	}
+
+	t.Run("When the board power is collected", func(t *testing.T) {
+		metrics := maps.Clone(metrics)
+		AppendPowerScopes(metrics, []Counter{boardCounter})
+
+		require.Len(t, metrics[powerScopeCounter], 2)
+		for _, m := range metrics[powerScopeCounter] {
+			assert.Equal(t, "board", m.Attributes[scopeAttribute])
+		}
+	})
+
+	t.Run("When the board and module power are collected", func(t *testing.T) {
+		metrics := maps.Clone(metrics)
+		AppendPowerScopes(metrics, []Counter{boardCounter, moduleCounter})
+
+		require.Len(t, metrics[powerScopeCounter], 4)
+		power := map[string]string{}
+		for _, m := range metrics[powerScopeCounter] {
+			power[m.GPU+"/"+m.Attributes[scopeAttribute]] = m.Value
+		}
+		assert.Equal(t, map[string]string{"0/board": "300", "1/board": "310", "0/module": "900", "1/module": "900"}, power)
+		assert.Empty(t, metrics[boardCounter][0].Attributes, "raw fields must not be labeled")
+	})
+}
+
+func TestAppendTemperatureSensors(t *testing.T) {
+	gpuCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+	memoryCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_MEMORY_TEMP, FieldName: "DCGM_FI_DEV_MEMORY_TEMP", PromType: "gauge", Help: "Memory temperature (in C)."}
+
+	metrics := MetricsByCounter{
+		gpuCounter: {
+			{Counter: gpuCounter, Value: "45", GPU: "0", Attributes: map[string]string{}},
+			{Counter: gpuCounter, Value: "47", GPU: "1", Attributes: map[string]string{}},
+		},
+		memoryCounter: {
+			{Counter: memoryCounter, Value: "60", GPU: "0", Attributes: map[string]string{}},
+			{Counter: memoryCounter, Value: FailedToConvert, GPU: "1", Attributes: map[string]string{}},
+		},
+	}
+
+	AppendTemperatureSensors(metrics, []Counter{gpuCounter, memoryCounter})
+
+	require.Len(t, metrics[temperatureSensorCounter], 3)
+	temperatures := map[string]string{}
+	for _, m := range metrics[temperatureSensorCounter] {
+		temperatures[m.GPU+"/"+m.Attributes[sensorAttribute]] = m.Value
+	}
+	assert.Equal(t, map[string]string{"0/gpu": "45", "1/gpu": "47", "0/memory": "60"}, temperatures)
+	assert.Empty(t, metrics[gpuCounter][0].Attributes, "raw fields must not be labeled")
+}
+
+func TestAppendPCIeDegraded(t *testing.T) {
+	genCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_PCIE_LINK_GEN, FieldName: "DCGM_FI_DEV_PCIE_LINK_GEN", PromType: "gauge", Help: "PCIe current link generation."}
+	widthCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_PCIE_LINK_WIDTH, FieldName: "DCGM_FI_DEV_PCIE_LINK_WIDTH", PromType: "gauge", Help: "PCIe current link width."}
+	maxGenCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_PCIE_MAX_LINK_GEN, FieldName: "DCGM_FI_DEV_PCIE_MAX_LINK_GEN", PromType: "gauge", Help: "PCIe max link generation."}
+	maxWidthCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_PCIE_MAX_LINK_WIDTH, FieldName: "DCGM_FI_DEV_PCIE_MAX_LINK_WIDTH", PromType: "gauge", Help: "PCIe max link width."}
+	counters := []Counter{genCounter, widthCounter, maxGenCounter, maxWidthCounter}
+
+	link := func(counter Counter, values ...string) []Metric {
+		var metrics []Metric
+		for gpu, value := range values {
+			metrics = append(metrics, Metric{Counter: counter, Value: value, GPU: strconv.Itoa(gpu)})
+		}
+		return metrics
+	}
+
+	degraded := func(metrics MetricsByCounter) map[string]string {
+		values := map[string]string{}
+		for _, m := range metrics[pcieDegradedCounter] {
+			values[m.GPU] = m.Value
+		}
+		return values
+	}
+
+	t.Run("When the current link is below the max", func(t *testing.T) {
+		metrics := MetricsByCounter{
+			genCounter:      link(genCounter, "4", "1", "4"),
+			widthCounter:    link(widthCounter, "16", "4", "8"),
+			maxGenCounter:   link(maxGenCounter, "4", "4", "4"),
+			maxWidthCounter: link(maxWidthCounter, "16", "16", "16"),
+		}
+		AppendPCIeDegraded(metrics, counters)
+
+		assert.Equal(t, map[string]string{"0": "0", "1": "1", "2": "1"}, degraded(metrics))
+	})
+
+	t.Run("When only the generation is collected", func(t *testing.T) {
+		metrics := MetricsByCounter{
+			genCounter:    link(genCounter, "3", "4"),
+			maxGenCounter: link(maxGenCounter, "4", "4"),
+		}
+		AppendPCIeDegraded(metrics, []Counter{genCounter, maxGenCounter})
+
+		assert.Equal(t, map[string]string{"0": "1", "1": "0"}, degraded(metrics))
+	})
+
+	t.Run("When the max is not collected", func(t *testing.T) {
+		metrics := MetricsByCounter{
+			genCounter:   link(genCounter, "1"),
+			widthCounter: link(widthCounter, "4"),
+		}
+		AppendPCIeDegraded(metrics, []Counter{genCounter, widthCounter})
+
+		assert.Empty(t, metrics[pcieDegradedCounter])
+	})
+}
@@ -0,0 +1,215 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	dcgmExporterDiagRunning      = "DCGM_EXPORTER_DIAG_RUNNING"
+	dcgmExporterDiagLastRun      = "DCGM_EXPORTER_DIAG_LAST_RUN_TIMESTAMP_SECONDS"
+	dcgmExporterDiagLastRunError = "DCGM_EXPORTER_DIAG_LAST_RUN_ERROR"
+	dcgmExporterDiagTestPassed   = "DCGM_EXPORTER_DIAG_TEST_PASSED"
+
+	diagLevelQueryParam = "level"
+	diagStatusPass      = "pass"
+)
+
+var dcgmRunDiag = dcgm.RunDiag
+
+// errDiagRunning is returned when a diagnostic is triggered, while the previous one is still running.
+var errDiagRunning = errors.New("a DCGM diagnostic is already running")
+
+var diagFormat = `# HELP {{ .Running.Name }} 1 while a DCGM diagnostic triggered with POST /diag is running.
+# TYPE {{ .Running.Name }} gauge
+{{ .Running.Name }} {{ .Running.Value }}
+{{- if .Finished }}
+# HELP {{ .LastRun.Name }} Time the last DCGM diagnostic finished (in seconds since the epoch).
+# TYPE {{ .LastRun.Name }} gauge
+{{ .LastRun.Name }}{level="{{ $.Level }}"} {{ .LastRun.Value }}
+# HELP {{ .LastRunError.Name }} 1 when the last DCGM diagnostic could not be run.
+# TYPE {{ .LastRunError.Name }} gauge
+{{ .LastRunError.Name }}{level="{{ $.Level }}"} {{ .LastRunError.Value }}
+{{- if .Tests }}
+# HELP {{ .TestPassedName }} Result of every test of the last DCGM diagnostic; 1 when the test passed.
+# TYPE {{ .TestPassedName }} gauge
+{{- range $test := .Tests }}
+{{ $.TestPassedName }}{level="{{ $.Level }}",{{ if $test.GPU }}gpu="{{ $test.GPU }}",{{ end }}test="{{ $test.Name }}",status="{{ $test.Status }}"} {{ $test.Passed }}
+{{- end }}
+{{- end }}
+{{- end }}
+`
+
+var getDiagTemplate = sync.OnceValue(func() *template.Template {
+	return template.Must(template.New("diag").Parse(diagFormat))
+})
+
+type diagGauge struct {
+	Name  string
+	Value any
+}
+
+type diagTest struct {
+	GPU    string // Empty for the software tests, which are not run per GPU
+	Name   string
+	Status string
+	Passed int
+}
+
+// diagRunner runs DCGM diagnostics in the background, so that scrapes are never blocked by a diagnostic,
+// and retains the result of the last one.
+type diagRunner struct {
+	mtx sync.Mutex
+	wg  sync.WaitGroup
+
+	triggered  bool
+	running    bool
+	level      dcgm.DiagType
+	finishedAt time.Time
+	err        error
+	results    dcgm.DiagResults
+}
+
+// start runs a diagnostic of the given level on all GPUs, unless a diagnostic is already running.
+func (d *diagRunner) start(level dcgm.DiagType) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if d.running {
+		return errDiagRunning
+	}
+
+	d.triggered = true
+	d.running = true
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		logrus.Infof("Running DCGM diagnostic of level %d", level)
+		results, err := dcgmRunDiag(level, dcgm.GroupAllGPUs())
+		if err != nil {
+			logrus.WithError(err).Error("Failed to run the DCGM diagnostic.")
+		}
+
+		d.mtx.Lock()
+		defer d.mtx.Unlock()
+
+		d.running = false
+		d.level = level
+		d.finishedAt = timeNow()
+		d.err = err
+		d.results = results
+	}()
+
+	return nil
+}
+
+// wait blocks until the running diagnostic, if any, is finished.
+func (d *diagRunner) wait() {
+	d.wg.Wait()
+}
+
+// encode writes the diagnostic metrics; nothing, until the first diagnostic is triggered.
+func (d *diagRunner) encode(w io.Writer) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if !d.triggered {
+		return nil
+	}
+
+	lastRunError := 0
+	if d.err != nil {
+		lastRunError = 1
+	}
+
+	return getDiagTemplate().Execute(w, struct {
+		Running        diagGauge
+		Finished       bool
+		Level          dcgm.DiagType
+		LastRun        diagGauge
+		LastRunError   diagGauge
+		TestPassedName string
+		Tests          []diagTest
+	}{
+		Running:        diagGauge{Name: dcgmExporterDiagRunning, Value: boolToInt(d.running)},
+		Finished:       !d.finishedAt.IsZero(),
+		Level:          d.level,
+		LastRun:        diagGauge{Name: dcgmExporterDiagLastRun, Value: d.finishedAt.Unix()},
+		LastRunError:   diagGauge{Name: dcgmExporterDiagLastRunError, Value: lastRunError},
+		TestPassedName: dcgmExporterDiagTestPassed,
+		Tests:          toDiagTests(d.results),
+	})
+}
+
+// toDiagTests flattens the software and per GPU results of a diagnostic.
+func toDiagTests(results dcgm.DiagResults) []diagTest {
+	var tests []diagTest
+
+	for _, result := range results.Software {
+		tests = append(tests, newDiagTest("", result))
+	}
+
+	for _, gpu := range results.PerGpu {
+		for _, result := range gpu.DiagResults {
+			tests = append(tests, newDiagTest(fmt.Sprint(gpu.GPU), result))
+		}
+	}
+
+	return tests
+}
+
+func newDiagTest(gpu string, result dcgm.DiagResult) diagTest {
+	return diagTest{
+		GPU:    gpu,
+		Name:   result.TestName,
+		Status: result.Status,
+		Passed: boolToInt(result.Status == diagStatusPass),
+	}
+}
+
+// parseDiagLevel parses the level query parameter; the quick diagnostic is run by default.
+func parseDiagLevel(value string) (dcgm.DiagType, error) {
+	if value == "" {
+		return dcgm.DiagQuick, nil
+	}
+
+	level, err := strconv.Atoi(value)
+	if err != nil || level < int(dcgm.DiagQuick) || level > dcgm.DiagExtended {
+		return 0, fmt.Errorf("invalid diagnostic level '%s'; expected %d to %d", value, dcgm.DiagQuick, dcgm.DiagExtended)
+	}
+
+	return dcgm.DiagType(level), nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
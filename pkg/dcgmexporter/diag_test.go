@@ -0,0 +1,166 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockRunDiag(t *testing.T, runDiag func(dcgm.DiagType, dcgm.GroupHandle) (dcgm.DiagResults, error)) {
+	t.Helper()
+
+	saved := dcgmRunDiag
+	t.Cleanup(func() {
+		dcgmRunDiag = saved
+	})
+
+	dcgmRunDiag = runDiag
+}
+
+func TestDiagRunner(t *testing.T) {
+	now := timeNow
+	t.Cleanup(func() {
+		timeNow = now
+	})
+	timeNow = func() time.Time {
+		return time.Unix(1700000000, 0)
+	}
+
+	t.Run("When no diagnostic was triggered", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, (&diagRunner{}).encode(&buf))
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("When the diagnostic finishes", func(t *testing.T) {
+		mockRunDiag(t, func(level dcgm.DiagType, _ dcgm.GroupHandle) (dcgm.DiagResults, error) {
+			assert.Equal(t, dcgm.DiagType(dcgm.DiagMedium), level)
+			return dcgm.DiagResults{
+				Software: []dcgm.DiagResult{
+					{Status: "pass", TestName: "character device permissions"},
+				},
+				PerGpu: []dcgm.GpuResult{
+					{GPU: 0, DiagResults: []dcgm.DiagResult{
+						{Status: "pass", TestName: "Memory"},
+						{Status: "fail", TestName: "PCIe", ErrorMessage: "PCIe replay rate exceeded"},
+					}},
+					{GPU: 1, DiagResults: []dcgm.DiagResult{
+						{Status: "skipped", TestName: "PCIe"},
+					}},
+				},
+			}, nil
+		})
+
+		runner := &diagRunner{}
+		require.NoError(t, runner.start(dcgm.DiagMedium))
+		runner.wait()
+
+		var buf bytes.Buffer
+		require.NoError(t, runner.encode(&buf))
+		assert.Equal(t, `# HELP DCGM_EXPORTER_DIAG_RUNNING 1 while a DCGM diagnostic triggered with POST /diag is running.
+# TYPE DCGM_EXPORTER_DIAG_RUNNING gauge
+DCGM_EXPORTER_DIAG_RUNNING 0
+# HELP DCGM_EXPORTER_DIAG_LAST_RUN_TIMESTAMP_SECONDS Time the last DCGM diagnostic finished (in seconds since the epoch).
+# TYPE DCGM_EXPORTER_DIAG_LAST_RUN_TIMESTAMP_SECONDS gauge
+DCGM_EXPORTER_DIAG_LAST_RUN_TIMESTAMP_SECONDS{level="2"} 1700000000
+# HELP DCGM_EXPORTER_DIAG_LAST_RUN_ERROR 1 when the last DCGM diagnostic could not be run.
+# TYPE DCGM_EXPORTER_DIAG_LAST_RUN_ERROR gauge
+DCGM_EXPORTER_DIAG_LAST_RUN_ERROR{level="2"} 0
+# HELP DCGM_EXPORTER_DIAG_TEST_PASSED Result of every test of the last DCGM diagnostic; 1 when the test passed.
+# TYPE DCGM_EXPORTER_DIAG_TEST_PASSED gauge
+DCGM_EXPORTER_DIAG_TEST_PASSED{level="2",test="character device permissions",status="pass"} 1
+DCGM_EXPORTER_DIAG_TEST_PASSED{level="2",gpu="0",test="Memory",status="pass"} 1
+DCGM_EXPORTER_DIAG_TEST_PASSED{level="2",gpu="0",test="PCIe",status="fail"} 0
+DCGM_EXPORTER_DIAG_TEST_PASSED{level="2",gpu="1",test="PCIe",status="skipped"} 0
+`, buf.String())
+	})
+
+	t.Run("When the diagnostic cannot be run", func(t *testing.T) {
+		mockRunDiag(t, func(dcgm.DiagType, dcgm.GroupHandle) (dcgm.DiagResults, error) {
+			return dcgm.DiagResults{}, errors.New("boom")
+		})
+
+		runner := &diagRunner{}
+		require.NoError(t, runner.start(dcgm.DiagQuick))
+		runner.wait()
+
+		var buf bytes.Buffer
+		require.NoError(t, runner.encode(&buf))
+		assert.Contains(t, buf.String(), `DCGM_EXPORTER_DIAG_LAST_RUN_ERROR{level="1"} 1`)
+		assert.NotContains(t, buf.String(), dcgmExporterDiagTestPassed)
+	})
+}
+
+func TestMetricsServer_Diag(t *testing.T) {
+	release := make(chan struct{})
+	mockRunDiag(t, func(dcgm.DiagType, dcgm.GroupHandle) (dcgm.DiagResults, error) {
+		<-release
+		return dcgm.DiagResults{PerGpu: []dcgm.GpuResult{
+			{GPU: 0, DiagResults: []dcgm.DiagResult{{Status: "pass", TestName: "Memory"}}},
+		}}, nil
+	})
+
+	server, cleanup, err := NewMetricsServer(&Config{EnableDiag: true}, make(chan string), NewRegistry(), nil, nil)
+	require.NoError(t, err)
+	defer cleanup()
+
+	diag := func(method, target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusMethodNotAllowed, diag(http.MethodGet, "/diag").Code)
+	assert.Equal(t, http.StatusBadRequest, diag(http.MethodPost, "/diag?level=5").Code)
+	assert.Equal(t, http.StatusBadRequest, diag(http.MethodPost, "/diag?level=one").Code)
+
+	require.Equal(t, http.StatusAccepted, diag(http.MethodPost, "/diag?level=1").Code)
+	assert.Equal(t, http.StatusConflict, diag(http.MethodPost, "/diag?level=1").Code, "a diagnostic is running")
+
+	metrics := diag(http.MethodGet, "/metrics")
+	require.Equal(t, http.StatusOK, metrics.Code, "scrapes are not blocked by the diagnostic")
+	assert.Contains(t, metrics.Body.String(), "DCGM_EXPORTER_DIAG_RUNNING 1\n")
+
+	close(release)
+	server.diag.wait()
+
+	metrics = diag(http.MethodGet, "/metrics")
+	assert.Contains(t, metrics.Body.String(), "DCGM_EXPORTER_DIAG_RUNNING 0\n")
+	assert.Contains(t, metrics.Body.String(), `DCGM_EXPORTER_DIAG_TEST_PASSED{level="1",gpu="0",test="Memory",status="pass"} 1`)
+
+	require.Equal(t, http.StatusAccepted, diag(http.MethodPost, "/diag").Code, "the quick diagnostic is run by default")
+	server.diag.wait()
+}
+
+func TestMetricsServer_DiagDisabled(t *testing.T) {
+	server, cleanup, err := NewMetricsServer(&Config{}, make(chan string), NewRegistry(), nil, nil)
+	require.NoError(t, err)
+	defer cleanup()
+
+	recorder := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/diag", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+// entityGroupNames are the names of the entity groups, which can be configured separately.
+var entityGroupNames = map[string]dcgm.Field_Entity_Group{
+	"gpu":      dcgm.FE_GPU,
+	"switch":   dcgm.FE_SWITCH,
+	"link":     dcgm.FE_LINK,
+	"cpu":      dcgm.FE_CPU,
+	"cpu_core": dcgm.FE_CPU_CORE,
+}
+
+// ParseEntityGroupAddresses parses entries of the form <entity group>=<address>, e.g. switch=:9401.
+func ParseEntityGroupAddresses(entries []string) (map[dcgm.Field_Entity_Group]string, error) {
+	return parseEntityGroupValues(entries, "address")
+}
+
+// parseEntityGroupValues parses entries of the form <entity group>=<value>, where kind names the value in errors.
+func parseEntityGroupValues(entries []string, kind string) (map[dcgm.Field_Entity_Group]string, error) {
+	values := map[dcgm.Field_Entity_Group]string{}
+	for _, entry := range entries {
+		name, value, found := strings.Cut(entry, "=")
+		if !found || value == "" {
+			return nil, fmt.Errorf("invalid entity group %s '%s'; expected <entity group>=<%s>", kind, entry, kind)
+		}
+
+		group, exists := entityGroupNames[strings.ToLower(strings.TrimSpace(name))]
+		if !exists {
+			return nil, fmt.Errorf("unknown entity group '%s'", name)
+		}
+
+		values[group] = strings.TrimSpace(value)
+	}
+
+	return values, nil
+}
+
+// entityGroupAddress returns the address, which serves the metrics of the entity group, or an empty string when
+// they are served on the main address.
+func entityGroupAddress(config *Config, group dcgm.Field_Entity_Group) string {
+	address := config.EntityGroupAddresses[group]
+	if address == config.Address {
+		return ""
+	}
+
+	return address
+}
+
+// newEntityGroupOutputs returns a channel for every separate address of an entity group.
+func newEntityGroupOutputs(config *Config) map[string]chan string {
+	outputs := map[string]chan string{}
+	for group := range config.EntityGroupAddresses {
+		if address := entityGroupAddress(config, group); address != "" {
+			outputs[address] = make(chan string, 10)
+		}
+	}
+
+	return outputs
+}
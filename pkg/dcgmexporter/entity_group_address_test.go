@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEntityGroupAddresses(t *testing.T) {
+	addresses, err := ParseEntityGroupAddresses([]string{"switch=:9401", " LINK = :9401", "cpu_core=:9402"})
+	require.NoError(t, err)
+	assert.Equal(t, map[dcgm.Field_Entity_Group]string{
+		dcgm.FE_SWITCH:   ":9401",
+		dcgm.FE_LINK:     ":9401",
+		dcgm.FE_CPU_CORE: ":9402",
+	}, addresses)
+
+	for _, entry := range []string{"switch", "switch=", "nvlink=:9401"} {
+		_, err := ParseEntityGroupAddresses([]string{entry})
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestPipelineServesEntityGroupsOnSeparateAddresses(t *testing.T) {
+	fieldValue := [4096]byte{}
+	binary.LittleEndian.PutUint64(fieldValue[:], math.Float64bits(42))
+
+	util := Counter{FieldID: dcgm.DCGM_FI_DEV_CPU_UTIL_TOTAL, FieldName: "DCGM_FI_DEV_CPU_UTIL_TOTAL", PromType: "gauge", Help: "Total CPU utilization"}
+	power := Counter{FieldID: dcgm.DCGM_FI_DEV_CPU_POWER_UTIL_CURRENT, FieldName: "DCGM_FI_DEV_CPU_POWER_UTIL_CURRENT", PromType: "gauge", Help: "CPU socket power draw (in W)."}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+
+	dcgmEntityGetLatestValues = func(_ dcgm.Field_Entity_Group, _ uint, fields []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		var values []dcgm.FieldValue_v1
+		for _, field := range fields {
+			values = append(values, dcgm.FieldValue_v1{FieldId: uint(field), FieldType: dcgm.DCGM_FT_DOUBLE, Value: fieldValue})
+		}
+		return values, nil
+	}
+
+	sysInfo := func(group dcgm.Field_Entity_Group) SystemInfo {
+		return SystemInfo{
+			InfoType: group,
+			CPUs:     []CPUInfo{{EntityId: 0, Cores: []uint{0}}},
+			cOpt:     DeviceOptions{Flex: true},
+		}
+	}
+
+	fieldEntityGroupTypeSystemInfo := &FieldEntityGroupTypeSystemInfo{
+		items: map[dcgm.Field_Entity_Group]FieldEntityGroupTypeSystemInfoItem{
+			dcgm.FE_CPU:      {SystemInfo: sysInfo(dcgm.FE_CPU)},
+			dcgm.FE_CPU_CORE: {SystemInfo: sysInfo(dcgm.FE_CPU_CORE)},
+		},
+	}
+
+	config := &Config{
+		Address:              ":9400",
+		EntityGroupAddresses: map[dcgm.Field_Entity_Group]string{dcgm.FE_CPU_CORE: ":9401"},
+	}
+
+	p, cleanup, err := NewMetricsPipeline(config, nil, "", func(
+		_ []Counter, _ string, _ *Config, item FieldEntityGroupTypeSystemInfoItem,
+	) (*DCGMCollector, func(), error) {
+		counter := util
+		if item.SystemInfo.InfoType == dcgm.FE_CPU_CORE {
+			counter = power
+		}
+
+		return &DCGMCollector{
+			Counters:     []Counter{counter},
+			DeviceFields: []dcgm.Short{counter.FieldID},
+			SysInfo:      item.SystemInfo,
+		}, func() {}, nil
+	}, fieldEntityGroupTypeSystemInfo)
+	require.NoError(t, err)
+	defer cleanup()
+
+	require.Len(t, p.EntityGroupOutputs(), 1)
+	require.Contains(t, p.EntityGroupOutputs(), ":9401")
+
+	outputs, err := p.collect()
+	require.NoError(t, err)
+
+	scrape := func(metrics string) string {
+		server := &MetricsServer{registry: NewRegistry(), metrics: metrics}
+		recorder := httptest.NewRecorder()
+		server.Metrics(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		return recorder.Body.String()
+	}
+
+	main := scrape(outputs[""])
+	assert.Contains(t, main, "DCGM_FI_DEV_CPU_UTIL_TOTAL{")
+	assert.NotContains(t, main, "DCGM_FI_DEV_CPU_POWER_UTIL_CURRENT")
+
+	core := scrape(outputs[":9401"])
+	assert.Contains(t, core, "DCGM_FI_DEV_CPU_POWER_UTIL_CURRENT{")
+	assert.NotContains(t, core, "DCGM_FI_DEV_CPU_UTIL_TOTAL")
+}
+
+func TestEntityGroupAddressOfMainAddress(t *testing.T) {
+	config := &Config{
+		Address:              ":9400",
+		EntityGroupAddresses: map[dcgm.Field_Entity_Group]string{dcgm.FE_SWITCH: ":9400"},
+	}
+
+	assert.Empty(t, entityGroupAddress(config, dcgm.FE_SWITCH))
+	assert.Empty(t, newEntityGroupOutputs(config))
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCounterFiles(t *testing.T) {
+	files, err := ParseCounterFiles([]string{"gpu=/etc/gpu.csv", " Switch = /etc/switch.yaml"})
+	require.NoError(t, err)
+	assert.Equal(t, map[dcgm.Field_Entity_Group]string{
+		dcgm.FE_GPU:    "/etc/gpu.csv",
+		dcgm.FE_SWITCH: "/etc/switch.yaml",
+	}, files)
+
+	_, err = ParseCounterFiles([]string{"gpu"})
+	assert.ErrorContains(t, err, "expected <entity group>=<counters file>")
+
+	_, err = ParseCounterFiles([]string{"vgpu=/etc/vgpu.csv"})
+	assert.ErrorContains(t, err, "unknown entity group 'vgpu'")
+}
+
+func TestNewMetricsPipelineWithEntityGroupCounters(t *testing.T) {
+	mockSelfTestDCGM(t)
+
+	dir := t.TempDir()
+	collectorsFile := filepath.Join(dir, "counters.csv")
+	require.NoError(t, os.WriteFile(collectorsFile, []byte(`DCGM_FI_DEV_GPU_TEMP, gauge, GPU temperature (in C).
+DCGM_FI_DEV_POWER_USAGE, gauge, Power draw (in W).
+DCGM_FI_DEV_CPU_UTIL_TOTAL, gauge, Total CPU utilization.
+`), 0o600))
+	gpuFile := filepath.Join(dir, "gpu.csv")
+	require.NoError(t, os.WriteFile(gpuFile, []byte(`DCGM_FI_DEV_POWER_USAGE, gauge, Power draw (in W).
+`), 0o600))
+
+	config := &Config{
+		ConfigMapData:  undefinedConfigMapData,
+		CollectorsFile: collectorsFile,
+		CounterFiles:   map[dcgm.Field_Entity_Group]string{dcgm.FE_GPU: gpuFile},
+		UseFakeGPUs:    true,
+	}
+
+	cs, err := GetCounterSet(config)
+	require.NoError(t, err)
+	require.Len(t, cs.DCGMCounters, 3)
+	require.Contains(t, cs.EntityGroupCounters, dcgm.FE_GPU)
+
+	fieldEntityGroupTypeSystemInfo := NewEntityGroupTypeSystemInfo(cs.DCGMCounters, config)
+	fieldEntityGroupTypeSystemInfo.SetEntityGroupCounters(cs.EntityGroupCounters)
+	require.NoError(t, fieldEntityGroupTypeSystemInfo.Load(dcgm.FE_GPU))
+
+	item, exists := fieldEntityGroupTypeSystemInfo.Get(dcgm.FE_GPU)
+	require.True(t, exists)
+	assert.Equal(t, []dcgm.Short{dcgm.DCGM_FI_DEV_POWER_USAGE}, item.DeviceFields,
+		"the GPU collector only watches the fields of the GPU counters file")
+
+	pipeline, cleanup, err := NewMetricsPipeline(config, cs.DCGMCounters, "", newSelfTestCollector,
+		fieldEntityGroupTypeSystemInfo)
+	require.NoError(t, err)
+	defer cleanup()
+
+	require.NotNil(t, pipeline.gpuCollector)
+	assert.Equal(t, cs.EntityGroupCounters[dcgm.FE_GPU], pipeline.gpuCollector.Counters)
+	assert.Equal(t, []dcgm.Short{dcgm.DCGM_FI_DEV_POWER_USAGE}, pipeline.gpuCollector.DeviceFields)
+}
@@ -179,6 +179,8 @@ func (c *expCollector) getMetrics() (MetricsByCounter, error) {
 		}
 	}
 
+	labelIdentity(metrics, identityLabels(c.config))
+
 	return metrics, nil
 }
 
@@ -189,11 +191,12 @@ func (c *expCollector) getLabelsFromCounters(mi MonitoringInfo, labels map[strin
 	}
 	// Extract Labels
 	for _, val := range latestValues {
-		v := ToString(val)
+		typed := ToTypedValue(val)
 		// Filter out counters with no value and ignored fields for this entity
-		if v == SkipDCGMValue {
+		if typed.Skip {
 			continue
 		}
+		v := typed.Value
 
 		counter, err := FindCounterField(c.labelsCounters, val.FieldId)
 		if err != nil {
@@ -250,7 +253,7 @@ func newExpCollector(
 
 	var err error
 
-	collector.cleanups, err = SetupDcgmFieldsWatch(collector.counterDeviceFields,
+	collector.cleanups, _, err = SetupDcgmFieldsWatch(collector.counterDeviceFields,
 		collector.sysInfo,
 		int64(config.CollectInterval)*1000)
 	if err != nil {
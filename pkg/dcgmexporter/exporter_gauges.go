@@ -0,0 +1,148 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	dcgmExporterFakeGPUs      = "DCGM_EXPORTER_FAKE_GPUS"
+	dcgmExporterUptimeSeconds = "DCGM_EXPORTER_UPTIME_SECONDS"
+	dcgmExporterMetricsAge    = "DCGM_EXPORTER_METRICS_AGE_SECONDS"
+)
+
+// exporterGaugeNames are the gauges, which describe the exporter itself. They can be requested with the counters
+// query parameter like the fields.
+var exporterGaugeNames = []string{
+	dcgmExporterLastError,
+	dcgmExporterFakeGPUs,
+	dcgmExporterUptimeSeconds,
+	dcgmExporterMetricsAge,
+	dcgmExporterWatchedFields,
+	dcgmExporterCollectionsTotal,
+	dcgmExporterClockSkewEvents,
+	dcgmExporterConversionErrors,
+	dcgmExporterOrphanLinks,
+	dcgmExporterSeriesDropped,
+	dcgmExporterMetricsChecksum,
+	dcgmExporterHostengineHealthy,
+	dcgmExporterVersionMismatch,
+	dcgmExporterFieldInfo,
+	dcgmExporterProfilingMultiplexedEstimate,
+	dcgmExporterFieldMultiplexedEstimate,
+	dcgmExporterDiagRunning,
+	dcgmExporterDiagLastRun,
+	dcgmExporterDiagLastRunError,
+	dcgmExporterDiagTestPassed,
+}
+
+var (
+	timeNow      = time.Now
+	processStart = time.Now()
+)
+
+var exporterGaugeFormat = `# HELP {{ .Name }} {{ .Help }}
+# TYPE {{ .Name }} gauge
+{{ .Name }} {{ .Value }}
+`
+
+var getExporterGaugeTemplate = sync.OnceValue(func() *template.Template {
+	return template.Must(template.New("exporterGauge").Parse(exporterGaugeFormat))
+})
+
+// formatExporterGauge returns an unlabeled gauge, which describes the exporter itself.
+func formatExporterGauge(name, help string, value any) (string, error) {
+	var res bytes.Buffer
+	err := getExporterGaugeTemplate().Execute(&res, struct {
+		Name  string
+		Help  string
+		Value any
+	}{
+		Name:  name,
+		Help:  help,
+		Value: value,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return res.String(), nil
+}
+
+// formatStaticGauges returns the exporter gauges, which do not change during the lifetime of the pipeline.
+func formatStaticGauges(config *Config, counters []Counter) (string, error) {
+	profilingMultiplexed, err := formatProfilingMultiplexed(config, counters)
+	if err != nil {
+		return "", err
+	}
+
+	fieldMultiplexed, err := formatFieldMultiplexed(config, counters)
+	if err != nil {
+		return "", err
+	}
+
+	fakeGPUs, err := formatFakeGPUs(config)
+	if err != nil {
+		return "", err
+	}
+
+	constantMetrics, err := formatConstantMetrics(config)
+	if err != nil {
+		return "", err
+	}
+
+	fieldInfo, err := formatFieldInfo(config)
+	if err != nil {
+		return "", err
+	}
+
+	versionMismatch, err := formatVersionMismatch()
+	if err != nil {
+		return "", err
+	}
+
+	return profilingMultiplexed + fieldMultiplexed + fakeGPUs + constantMetrics + fieldInfo + versionMismatch, nil
+}
+
+// formatFakeGPUs returns the DCGM_EXPORTER_FAKE_GPUS gauge, so that metrics of fake GPUs can be told apart.
+func formatFakeGPUs(config *Config) (string, error) {
+	value := 0
+	if config.UseFakeGPUs {
+		value = 1
+	}
+
+	return formatExporterGauge(dcgmExporterFakeGPUs, "1 when the exporter collects metrics of fake GPUs.", value)
+}
+
+// formatUptime returns the DCGM_EXPORTER_UPTIME_SECONDS gauge.
+func formatUptime() (string, error) {
+	uptime := timeNow().Sub(processStart).Seconds()
+
+	return formatExporterGauge(dcgmExporterUptimeSeconds, "Time since the exporter process started (in seconds).",
+		strconv.FormatFloat(uptime, 'f', 3, 64))
+}
+
+// formatMetricsAge returns the DCGM_EXPORTER_METRICS_AGE_SECONDS gauge.
+func formatMetricsAge(age time.Duration) (string, error) {
+	return formatExporterGauge(dcgmExporterMetricsAge, "Time since the served metrics were collected (in seconds).",
+		strconv.FormatFloat(age.Seconds(), 'f', 3, 64))
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatFakeGPUs(t *testing.T) {
+	tests := []struct {
+		name        string
+		useFakeGPUs bool
+		want        string
+	}{
+		{name: "Fake GPUs", useFakeGPUs: true, want: "DCGM_EXPORTER_FAKE_GPUS 1\n"},
+		{name: "Real GPUs", useFakeGPUs: false, want: "DCGM_EXPORTER_FAKE_GPUS 0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := formatFakeGPUs(&Config{UseFakeGPUs: tt.useFakeGPUs})
+			require.NoError(t, err)
+			assert.Contains(t, out, "# TYPE DCGM_EXPORTER_FAKE_GPUS gauge\n")
+			assert.Contains(t, out, tt.want)
+		})
+	}
+}
+
+func TestFormatStaticGauges(t *testing.T) {
+	out, err := formatStaticGauges(&Config{UseFakeGPUs: true}, sampleCounters)
+	require.NoError(t, err)
+	assert.Contains(t, out, "DCGM_EXPORTER_FAKE_GPUS 1\n")
+	assert.NotContains(t, out, dcgmExporterProfilingMultiplexedEstimate, "profiling metrics are not collected")
+	assert.NoError(t, validateExposition(out))
+}
@@ -19,8 +19,11 @@ package dcgmexporter
 import "fmt"
 
 const (
-	dcgmExpClockEventsCount = "DCGM_EXP_CLOCK_EVENTS_COUNT"
-	dcgmExpXIDErrorsCount   = "DCGM_EXP_XID_ERRORS_COUNT"
+	dcgmExpClockEventsCount   = "DCGM_EXP_CLOCK_EVENTS_COUNT"
+	dcgmExpXIDErrorsCount     = "DCGM_EXP_XID_ERRORS_COUNT"
+	dcgmFIDevThrottleEvents   = "DCGM_FI_DEV_THROTTLE_EVENTS"
+	dcgmFIDevThrottleSeconds  = "DCGM_FI_DEV_THROTTLE_SECONDS_TOTAL"
+	dcgmFIDevPolicyViolations = "DCGM_FI_DEV_POLICY_VIOLATIONS"
 )
 
 type ExporterCounter uint16
@@ -29,6 +32,9 @@ const (
 	DCGMFIUnknown        ExporterCounter = 0
 	DCGMXIDErrorsCount   ExporterCounter = iota + 9000
 	DCGMClockEventsCount ExporterCounter = iota
+	DCGMThrottleEvents   ExporterCounter = iota
+	DCGMThrottleSeconds  ExporterCounter = iota
+	DCGMPolicyViolations ExporterCounter = iota
 )
 
 // String method to convert the enum value to a string
@@ -38,6 +44,12 @@ func (enm ExporterCounter) String() string {
 		return dcgmExpXIDErrorsCount
 	case DCGMClockEventsCount:
 		return dcgmExpClockEventsCount
+	case DCGMThrottleEvents:
+		return dcgmFIDevThrottleEvents
+	case DCGMThrottleSeconds:
+		return dcgmFIDevThrottleSeconds
+	case DCGMPolicyViolations:
+		return dcgmFIDevPolicyViolations
 	default:
 		return "DCGM_FI_UNKNOWN"
 	}
@@ -47,6 +59,9 @@ func (enm ExporterCounter) String() string {
 var DCGMFields = map[string]ExporterCounter{
 	DCGMXIDErrorsCount.String():   DCGMXIDErrorsCount,
 	DCGMClockEventsCount.String(): DCGMClockEventsCount,
+	DCGMThrottleEvents.String():   DCGMThrottleEvents,
+	DCGMThrottleSeconds.String():  DCGMThrottleSeconds,
+	DCGMPolicyViolations.String(): DCGMPolicyViolations,
 	DCGMFIUnknown.String():        DCGMFIUnknown,
 }
 
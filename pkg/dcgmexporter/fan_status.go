@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+
+	"github.com/NVIDIA/dcgm-exporter/internal/pkg/nvmlprovider"
+	"github.com/sirupsen/logrus"
+)
+
+const fanAttribute = "fan"
+
+var nvmlGetFanSpeedsByUUIDHook = nvmlprovider.GetFanSpeedsByUUID
+
+var fanFailedCounter = Counter{
+	FieldName: "DCGM_EXPORTER_FAN_FAILED",
+	PromType:  "gauge",
+	Help:      "Whether the fan of the GPU is driven, but does not spin (1) or not (0), by fan.",
+}
+
+// fanFailed returns whether the fan is driven at a speed, but does not spin.
+func fanFailed(fan nvmlprovider.FanSpeed) bool {
+	return fan.TargetSpeed > 0 && fan.Speed == 0
+}
+
+// appendFanStatus adds DCGM_EXPORTER_FAN_FAILED for every fan of every monitored GPU, from the fan speeds reported
+// by NVML. DCGM has neither a fan status field nor per fan fields. GPUs without a fan, or whose query failed,
+// are skipped.
+func (c *DCGMCollector) appendFanStatus(metrics MetricsByCounter, monitoringInfo []MonitoringInfo) {
+	uuid := "UUID"
+	if c.UseOldNamespace {
+		uuid = "uuid"
+	}
+
+	queried := map[uint]bool{}
+	for _, mi := range monitoringInfo {
+		gpu := mi.DeviceInfo.GPU
+		if queried[gpu] {
+			continue
+		}
+		queried[gpu] = true
+
+		fans, err := nvmlGetFanSpeedsByUUIDHook(mi.DeviceInfo.UUID)
+		if err != nil {
+			logrus.WithError(err).WithField("uuid", mi.DeviceInfo.UUID).Debug("Failed to get the fan speeds.")
+			continue
+		}
+
+		for _, fan := range fans {
+			failed := 0
+			if fanFailed(fan) {
+				failed = 1
+			}
+
+			m := Metric{
+				Counter: fanFailedCounter,
+				Value:   fmt.Sprint(failed),
+
+				UUID:         uuid,
+				GPU:          fmt.Sprintf("%d", gpu),
+				GPUUUID:      mi.DeviceInfo.UUID,
+				GPUDevice:    fmt.Sprintf("nvidia%d", gpu),
+				GPUModelName: getGPUModel(mi.DeviceInfo, c.ReplaceBlanksInModelName),
+				Hostname:     c.Hostname,
+
+				Labels:     map[string]string{},
+				Attributes: map[string]string{fanAttribute: fmt.Sprint(fan.Fan)},
+			}
+
+			metrics[fanFailedCounter] = append(metrics[fanFailedCounter], m)
+		}
+	}
+}
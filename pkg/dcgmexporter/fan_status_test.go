@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/NVIDIA/dcgm-exporter/internal/pkg/nvmlprovider"
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetricsWithFanStatus(t *testing.T) {
+	tempCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+
+	sysInfo := SystemInfo{GPUCount: 3, InfoType: dcgm.FE_GPU, gOpt: DeviceOptions{Flex: true}}
+	sysInfo.GPUs[0] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-a40"}}
+	sysInfo.GPUs[1] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 1, UUID: "GPU-h100"}}
+	sysInfo.GPUs[2] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 2, UUID: "GPU-lost"}}
+
+	collector := &DCGMCollector{
+		Counters:     []Counter{tempCounter},
+		DeviceFields: []dcgm.Short{tempCounter.FieldID},
+		SysInfo:      sysInfo,
+		FanStatus:    true,
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+	dcgmEntityGetLatestValues = func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], 42)
+		return []dcgm.FieldValue_v1{{FieldId: uint(tempCounter.FieldID), FieldType: dcgm.DCGM_FT_INT64, Value: value}}, nil
+	}
+
+	defer func(getFanSpeeds func(string) ([]nvmlprovider.FanSpeed, error)) {
+		nvmlGetFanSpeedsByUUIDHook = getFanSpeeds
+	}(nvmlGetFanSpeedsByUUIDHook)
+	nvmlGetFanSpeedsByUUIDHook = func(uuid string) ([]nvmlprovider.FanSpeed, error) {
+		switch uuid {
+		case "GPU-a40":
+			return []nvmlprovider.FanSpeed{
+				{Fan: 0, Speed: 45, TargetSpeed: 45},
+				{Fan: 1, Speed: 0, TargetSpeed: 45},
+			}, nil
+		case "GPU-h100":
+			return nil, nil
+		}
+		return nil, errors.New("GPU is lost")
+	}
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+
+	require.Len(t, metrics[fanFailedCounter], 2, "GPUs without a fan, or whose fans are unknown, are skipped")
+	values := map[string]string{}
+	for _, m := range metrics[fanFailedCounter] {
+		assert.Equal(t, "GPU-a40", m.GPUUUID)
+		values[m.Attributes[fanAttribute]] = m.Value
+	}
+	assert.Equal(t, map[string]string{"0": "0", "1": "1"}, values, "the fan, which is driven but does not spin, failed")
+
+	collector.FanStatus = false
+	metrics, err = collector.GetMetrics()
+	require.NoError(t, err)
+	assert.Empty(t, metrics[fanFailedCounter])
+}
+
+func TestFanFailed(t *testing.T) {
+	assert.False(t, fanFailed(nvmlprovider.FanSpeed{Speed: 30, TargetSpeed: 30}))
+	assert.False(t, fanFailed(nvmlprovider.FanSpeed{Speed: 0, TargetSpeed: 0}), "a fan, which is stopped, did not fail")
+	assert.True(t, fanFailed(nvmlprovider.FanSpeed{Speed: 0, TargetSpeed: 30}))
+}
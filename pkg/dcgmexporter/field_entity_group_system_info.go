@@ -17,7 +17,7 @@
 package dcgmexporter
 
 import (
-	"fmt"
+	"errors"
 
 	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
 )
@@ -34,16 +34,27 @@ var FieldEntityGroupTypeToMonitor = []dcgm.Field_Entity_Group{
 type FieldEntityGroupTypeSystemInfoItem struct {
 	SystemInfo   SystemInfo
 	DeviceFields []dcgm.Short
+	Counters     []Counter // Counters of the entity group, when it has a counters file of its own
 }
 
 func (f FieldEntityGroupTypeSystemInfoItem) isEmpty() bool {
 	return len(f.DeviceFields) == 0
 }
 
+// countersOr returns the counters of the entity group, or the common counters when it has no counters of its own.
+func (f FieldEntityGroupTypeSystemInfoItem) countersOr(counters []Counter) []Counter {
+	if f.Counters != nil {
+		return f.Counters
+	}
+
+	return counters
+}
+
 // FieldEntityGroupTypeSystemInfo represents a mapping between FieldEntityGroupType and SystemInfo
 type FieldEntityGroupTypeSystemInfo struct {
 	items         map[dcgm.Field_Entity_Group]FieldEntityGroupTypeSystemInfoItem
 	counters      []Counter
+	groupCounters map[dcgm.Field_Entity_Group][]Counter
 	gpuDevices    DeviceOptions
 	switchDevices DeviceOptions
 	cpuDevices    DeviceOptions
@@ -62,12 +73,23 @@ func NewEntityGroupTypeSystemInfo(c []Counter, config *Config) *FieldEntityGroup
 	}
 }
 
+// SetEntityGroupCounters sets the counters of the entity groups with a counters file of their own, which are
+// loaded instead of the common counters.
+func (e *FieldEntityGroupTypeSystemInfo) SetEntityGroupCounters(counters map[dcgm.Field_Entity_Group][]Counter) {
+	e.groupCounters = counters
+}
+
 // Load loads SystemInfo for a provided Field_Entity_Group
 func (e *FieldEntityGroupTypeSystemInfo) Load(entityType dcgm.Field_Entity_Group) error {
-	var deviceFields = NewDeviceFields(e.counters, entityType)
+	counters, ownCounters := e.groupCounters[entityType]
+	if !ownCounters {
+		counters = e.counters
+	}
 
-	if !ShouldMonitorDeviceType(deviceFields, entityType) {
-		return fmt.Errorf("no fields to watch for device type: %d", entityType)
+	var deviceFields = NewDeviceFields(counters, entityType)
+
+	if decision := ShouldMonitorDeviceType(deviceFields, entityType); !decision.Monitor {
+		return errors.New(decision.Reason)
 	}
 
 	sysInfo, err := GetSystemInfo(&Config{
@@ -80,10 +102,14 @@ func (e *FieldEntityGroupTypeSystemInfo) Load(entityType dcgm.Field_Entity_Group
 		return err
 	}
 
-	e.items[entityType] = FieldEntityGroupTypeSystemInfoItem{
+	item := FieldEntityGroupTypeSystemInfoItem{
 		SystemInfo:   *sysInfo,
 		DeviceFields: deviceFields,
 	}
+	if ownCounters {
+		item.Counters = counters
+	}
+	e.items[entityType] = item
 
 	return err
 }
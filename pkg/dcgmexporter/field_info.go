@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+const (
+	dcgmExporterFieldInfo = "DCGM_EXPORTER_FIELD_INFO"
+
+	fieldIDLabel     = "field_id"
+	fieldNameLabel   = "field_name"
+	fieldTypeLabel   = "field_type"
+	entityGroupLabel = "entity_group"
+)
+
+var fieldTypeNames = map[uint]string{
+	dcgm.DCGM_FT_BINARY:    "binary",
+	dcgm.DCGM_FT_DOUBLE:    "double",
+	dcgm.DCGM_FT_INT64:     "int64",
+	dcgm.DCGM_FT_STRING:    "string",
+	dcgm.DCGM_FT_TIMESTAMP: "timestamp",
+}
+
+var fieldInfoCounter = Counter{
+	FieldName: dcgmExporterFieldInfo,
+	PromType:  "gauge",
+	Help:      "Metadata of a DCGM field, which can be collected on this hardware (always 1).",
+}
+
+// fieldMeta returns the metadata of a field, or false when DCGM does not know the field.
+func fieldMeta(fieldID dcgm.Short) (meta dcgm.FieldMeta, known bool) {
+	// DCGM returns no metadata for the fields, which were added in later versions
+	defer func() {
+		if recover() != nil {
+			known = false
+		}
+	}()
+
+	meta = dcgmFieldGetById(fieldID)
+
+	return meta, meta.FieldId == fieldID && meta.FieldId != 0
+}
+
+// fieldInfoMetrics returns DCGM_EXPORTER_FIELD_INFO for every field, which DCGM knows and which can be collected
+// with the configuration, e.g. the profiling fields only if the GPUs support them.
+func fieldInfoMetrics(config *Config) MetricsByCounter {
+	var names []string
+	for name := range dcgm.DCGM_FI {
+		// The map has the names of the field types as well
+		if strings.HasPrefix(name, "DCGM_FI_") {
+			names = append(names, name)
+		}
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if c := cmp.Compare(dcgm.DCGM_FI[a], dcgm.DCGM_FI[b]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	metrics := make(MetricsByCounter)
+	for _, name := range names {
+		fieldID := dcgm.DCGM_FI[name]
+		if !fieldIsSupported(uint(fieldID), config) {
+			continue
+		}
+
+		meta, known := fieldMeta(fieldID)
+		if !known {
+			continue
+		}
+
+		fieldType, exists := fieldTypeNames[uint(meta.FieldType)]
+		if !exists {
+			fieldType = "unknown"
+		}
+
+		metrics[fieldInfoCounter] = append(metrics[fieldInfoCounter], Metric{
+			Counter: fieldInfoCounter,
+			Value:   "1",
+			Labels: map[string]string{
+				fieldIDLabel:     fmt.Sprint(fieldID),
+				fieldNameLabel:   name,
+				fieldTypeLabel:   fieldType,
+				entityGroupLabel: entityGroupName(meta.EntityLevel),
+			},
+			Attributes: map[string]string{},
+		})
+	}
+
+	return metrics
+}
+
+// entityGroupName returns the name of the entity group of a field; the fields of the driver have none.
+func entityGroupName(group dcgm.Field_Entity_Group) string {
+	if group == dcgm.FE_NONE {
+		return "none"
+	}
+
+	return group.String()
+}
+
+// formatFieldInfo returns the DCGM_EXPORTER_FIELD_INFO metrics, when they are enabled.
+func formatFieldInfo(config *Config) (string, error) {
+	if !config.EmitFieldInfo {
+		return "", nil
+	}
+
+	var res bytes.Buffer
+	err := getConstantMetricsTemplate().Execute(&res, fieldInfoMetrics(config))
+	if err != nil {
+		return "", err
+	}
+
+	return res.String(), nil
+}
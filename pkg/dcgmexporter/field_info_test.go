@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldInfoMetrics(t *testing.T) {
+	fieldGetById := dcgmFieldGetById
+	defer func() {
+		dcgmFieldGetById = fieldGetById
+	}()
+
+	// DCGM knows a few of the fields only
+	known := map[dcgm.Short]dcgm.FieldMeta{
+		dcgm.DCGM_FI_DEV_GPU_TEMP:          {FieldId: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldType: byte(dcgm.DCGM_FT_INT64), EntityLevel: dcgm.FE_GPU},
+		dcgm.DCGM_FI_DEV_POWER_USAGE:       {FieldId: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldType: byte(dcgm.DCGM_FT_DOUBLE), EntityLevel: dcgm.FE_GPU},
+		dcgm.DCGM_FI_DRIVER_VERSION:        {FieldId: dcgm.DCGM_FI_DRIVER_VERSION, FieldType: byte(dcgm.DCGM_FT_STRING), EntityLevel: dcgm.FE_NONE},
+		dcgm.DCGM_FI_PROF_GR_ENGINE_ACTIVE: {FieldId: dcgm.DCGM_FI_PROF_GR_ENGINE_ACTIVE, FieldType: byte(dcgm.DCGM_FT_DOUBLE), EntityLevel: dcgm.FE_GPU},
+		dcgm.DCGM_FI_PROF_SM_ACTIVE:        {FieldId: dcgm.DCGM_FI_PROF_SM_ACTIVE, FieldType: byte(dcgm.DCGM_FT_DOUBLE), EntityLevel: dcgm.FE_GPU},
+		dcgm.DCGM_FI_DEV_CPU_UTIL_TOTAL:    {FieldId: dcgm.DCGM_FI_DEV_CPU_UTIL_TOTAL, FieldType: byte(dcgm.DCGM_FT_DOUBLE), EntityLevel: dcgm.FE_CPU},
+	}
+	dcgmFieldGetById = func(fieldID dcgm.Short) dcgm.FieldMeta {
+		meta, exists := known[fieldID]
+		if !exists {
+			var unknown *dcgm.FieldMeta
+			return *unknown
+		}
+		return meta
+	}
+
+	names := func(metrics MetricsByCounter) []string {
+		var names []string
+		for _, m := range metrics[fieldInfoCounter] {
+			names = append(names, m.Labels[fieldNameLabel])
+		}
+		return names
+	}
+
+	t.Run("Without profiling", func(t *testing.T) {
+		metrics := fieldInfoMetrics(&Config{})
+		assert.Equal(t, []string{
+			"DCGM_FI_DRIVER_VERSION",
+			"DCGM_FI_DEV_GPU_TEMP",
+			"DCGM_FI_DEV_POWER_USAGE",
+			"DCGM_FI_DEV_CPU_UTIL_TOTAL",
+		}, names(metrics))
+
+		assert.Equal(t, Metric{
+			Counter: fieldInfoCounter,
+			Value:   "1",
+			Labels: map[string]string{
+				fieldIDLabel:     "155",
+				fieldNameLabel:   "DCGM_FI_DEV_POWER_USAGE",
+				fieldTypeLabel:   "double",
+				entityGroupLabel: "GPU",
+			},
+			Attributes: map[string]string{},
+		}, metrics[fieldInfoCounter][2])
+	})
+
+	t.Run("With the profiling fields, which the GPUs support", func(t *testing.T) {
+		metrics := fieldInfoMetrics(&Config{
+			CollectDCP:   true,
+			MetricGroups: []dcgm.MetricGroup{{FieldIds: []uint{uint(dcgm.DCGM_FI_PROF_SM_ACTIVE)}}},
+		})
+		assert.Contains(t, names(metrics), "DCGM_FI_PROF_SM_ACTIVE")
+		assert.NotContains(t, names(metrics), "DCGM_FI_PROF_GR_ENGINE_ACTIVE")
+	})
+
+	t.Run("Formatted", func(t *testing.T) {
+		out, err := formatStaticGauges(&Config{EmitFieldInfo: true}, nil)
+		require.NoError(t, err)
+		assert.Contains(t, out, "# TYPE DCGM_EXPORTER_FIELD_INFO gauge\n")
+		assert.Contains(t, out,
+			`DCGM_EXPORTER_FIELD_INFO{entity_group="none",field_id="1",field_name="DCGM_FI_DRIVER_VERSION",field_type="string"} 1`+"\n")
+		assert.Contains(t, out,
+			`DCGM_EXPORTER_FIELD_INFO{entity_group="GPU",field_id="150",field_name="DCGM_FI_DEV_GPU_TEMP",field_type="int64"} 1`+"\n")
+		assert.NoError(t, validateExposition(out))
+
+		out, err = formatFieldInfo(&Config{})
+		require.NoError(t, err)
+		assert.Empty(t, out)
+	})
+}
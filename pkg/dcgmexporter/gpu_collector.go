@@ -19,13 +19,31 @@ package dcgmexporter
 import (
 	"errors"
 	"fmt"
+	"math"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	dcgmEntityGetLatestValues = dcgm.EntityGetLatestValues
+	dcgmLinkGetLatestValues   = dcgm.LinkGetLatestValues
+	dcgmGetNvLinkLinkStatus   = dcgm.GetNvLinkLinkStatus
+	dcgmGetValuesSince        = dcgm.GetValuesSince
+)
+
+const labelValueEllipsis = "..."
+
+// truncatedLabelFields are the label fields, whose truncation was already logged
+var truncatedLabelFields sync.Map
+
 type DCGMCollectorConstructor func([]Counter, string, *Config, FieldEntityGroupTypeSystemInfoItem) (*DCGMCollector, func(), error)
 
 func NewDCGMCollector(c []Counter,
@@ -38,10 +56,10 @@ func NewDCGMCollector(c []Counter,
 	}
 
 	collector := &DCGMCollector{
-		Counters:     c,
-		DeviceFields: fieldEntityGroupTypeSystemInfo.DeviceFields,
-		SysInfo:      fieldEntityGroupTypeSystemInfo.SystemInfo,
-		Hostname:     hostname,
+		Counters:      c,
+		DeviceFields:  fieldEntityGroupTypeSystemInfo.DeviceFields,
+		SysInfo:       fieldEntityGroupTypeSystemInfo.SystemInfo,
+		MetricOptions: MetricOptions{Hostname: hostname},
 	}
 
 	if config == nil {
@@ -49,21 +67,152 @@ func NewDCGMCollector(c []Counter,
 		return collector, func() { collector.Cleanup() }, nil
 	}
 
-	collector.UseOldNamespace = config.UseOldNamespace
-	collector.ReplaceBlanksInModelName = config.ReplaceBlanksInModelName
+	collector.MetricOptions = MetricOptions{
+		UseOldNamespace:          config.UseOldNamespace,
+		Hostname:                 hostname,
+		ReplaceBlanksInModelName: config.ReplaceBlanksInModelName,
+		FailedConversionsAsNaN:   config.FailedConversionsAsNaN,
+		ShortestFloatFormat:      config.ShortestFloatFormat,
+		MaxLabelValueLength:      config.MaxLabelValueLength,
+		FloatZeroThreshold:       config.FloatZeroThreshold,
+	}
+	collector.StaleMetricsMaxAge = time.Duration(config.StaleMetricsMaxAge) * time.Millisecond
+	collector.IdleCollectInterval = time.Duration(config.IdleCollectInterval) * time.Millisecond
+	collector.NVMLFallback = config.NVMLFallback
+	collector.CounterOK = config.EnableCounterOK
+	collector.TensorCapabilities = config.TensorCapabilities
+	collector.ComputeCapabilityLabel = config.ComputeCapabilityLabel
+	collector.RawValues = config.EmitRawValues
+	collector.CodecSessions = config.CodecSessions
+	collector.FanStatus = config.FanStatus
+	collector.ProcessUtilizationTopN = config.ProcessUtilizationTopN
+
+	if config.MaxClockSkew > 0 {
+		collector.Processors = append(collector.Processors,
+			newClockSkewCorrector(time.Duration(config.MaxClockSkew)*time.Millisecond))
+	}
+
+	if config.StuckFieldThreshold > 0 {
+		collector.Processors = append(collector.Processors,
+			newStuckFieldDetector(config.StuckFieldThreshold, config.StuckFields))
+	}
+
+	if len(config.SumFields) > 0 {
+		collector.Processors = append(collector.Processors, newFieldSummer(config.SumFields))
+	}
+
+	if config.UtilizationWindow > 0 {
+		collector.Processors = append(collector.Processors,
+			newWindowedAverager(time.Duration(config.UtilizationWindow)*time.Millisecond))
+	}
+
+	if config.PowerPeakWindow > 0 {
+		collector.Processors = append(collector.Processors,
+			newPowerPeakTracker(time.Duration(config.PowerPeakWindow)*time.Millisecond))
+	}
+
+	if labels := identityLabels(config); len(labels) > 0 {
+		collector.Processors = append(collector.Processors, newIdentityLabeler(labels))
+	}
+
+	if config.SuppressUnchangedWindow > 0 {
+		collector.Processors = append(collector.Processors,
+			newUnchangedSuppressor(time.Duration(config.SuppressUnchangedWindow)*time.Millisecond))
+	}
+
+	// The GPUs are remapped last, so that the other processors see the DCGM indexes
+	if order := gpuIndexRemapOrder(config.GPUIndexRemap); len(order) > 0 {
+		collector.Processors = append(collector.Processors, newGPUIndexRemapper(order))
+	}
 
-	cleanups, err := SetupDcgmFieldsWatch(collector.DeviceFields,
+	cleanups, skipped, err := SetupDcgmFieldsWatchWithRetry(collector.DeviceFields,
 		fieldEntityGroupTypeSystemInfo.SystemInfo,
-		int64(config.CollectInterval)*1000)
+		int64(config.CollectInterval)*1000,
+		config.FieldsWatchRetries,
+		time.Duration(config.FieldsWatchRetryBackoff)*time.Millisecond)
 	if err != nil {
-		logrus.Fatal("Failed to watch metrics: ", err)
+		return nil, func() {}, fmt.Errorf("failed to watch metrics; err: %w", err)
 	}
 
 	collector.Cleanups = cleanups
 
+	// The fields, which cannot be watched, are not collected
+	if len(skipped) > 0 {
+		collector.DeviceFields = slices.DeleteFunc(slices.Clone(collector.DeviceFields), func(field dcgm.Short) bool {
+			return slices.Contains(skipped, field)
+		})
+	}
+
+	err = collector.setupSampledFieldsWatch(config)
+	if err != nil {
+		collector.Cleanup()
+		return nil, func() {}, fmt.Errorf("failed to watch sampled metrics; err: %w", err)
+	}
+
+	if config.PreloadCache {
+		collector.preloadCache()
+	}
+
 	return collector, func() { collector.Cleanup() }, nil
 }
 
+// preloadCache performs a priming collection, whose metrics are discarded, so that the values cached across
+// collections, e.g. the compute capabilities and the stale metrics, are already there for the first scrape.
+func (c *DCGMCollector) preloadCache() {
+	if _, err := c.getMetrics(); err != nil {
+		logrus.WithError(err).Warn("Failed to preload the cache; it is populated by the first scrape instead.")
+	}
+}
+
+// setupSampledFieldsWatch watches the counters listed in config.SampledFields with enough history
+// to return every sample taken during a collect interval, instead of only the latest one.
+func (c *DCGMCollector) setupSampledFieldsWatch(config *Config) error {
+	if c.SysInfo.InfoType != dcgm.FE_GPU || len(config.SampledFields) == 0 {
+		return nil
+	}
+
+	for _, counter := range c.Counters {
+		if slices.Contains(config.SampledFields, counter.FieldName) && slices.Contains(c.DeviceFields, counter.FieldID) {
+			c.SampledFields = append(c.SampledFields, counter.FieldID)
+		}
+	}
+
+	if len(c.SampledFields) == 0 {
+		return nil
+	}
+
+	// The samples are watched on the entities of the collector only, e.g. not on the GPUs excluded by --devices
+	group, cleanup, err := CreateGroupFromSystemInfo(c.SysInfo)
+	c.Cleanups = append(c.Cleanups, cleanup)
+	if err != nil {
+		return err
+	}
+	c.sampledGroup = group
+
+	fieldGroup, cleanup, err := NewFieldGroup(c.SampledFields)
+	if err != nil {
+		return err
+	}
+
+	c.Cleanups = append(c.Cleanups, cleanup)
+	c.sampledFieldGroup = fieldGroup
+
+	// Keep twice the collect interval of history, so that no samples are lost when a collection is late.
+	maxKeepAge := 2 * time.Duration(config.CollectInterval) * time.Millisecond
+
+	err = WatchFieldGroup(group, fieldGroup, int64(config.CollectInterval)*1000, maxKeepAge.Seconds(), 0)
+	if err != nil {
+		return err
+	}
+
+	c.Cleanups = append(c.Cleanups, watchedFields.add(len(c.SampledFields)))
+
+	// The first collection returns the samples taken since the collector was created, not the whole history of DCGM
+	c.samplesSince = timeNow()
+
+	return nil
+}
+
 func GetSystemInfo(config *Config, entityType dcgm.Field_Entity_Group) (*SystemInfo, error) {
 	sysInfo, err := InitializeSystemInfo(config.GPUDevices,
 		config.SwitchDevices,
@@ -98,58 +247,236 @@ func (c *DCGMCollector) Cleanup() {
 }
 
 func (c *DCGMCollector) GetMetrics() (MetricsByCounter, error) {
+	metrics, err := c.getMetrics()
+	collectionsTotal.record(err)
+
+	return metrics, err
+}
+
+func (c *DCGMCollector) getMetrics() (MetricsByCounter, error) {
 	monitoringInfo := GetMonitoredEntities(c.SysInfo)
+	SortMonitoringInfo(monitoringInfo)
 
 	metrics := make(MetricsByCounter)
 
+	samples, err := c.getSamples()
+	if err != nil {
+		return nil, err
+	}
+
 	for _, mi := range monitoringInfo {
-		var vals []dcgm.FieldValue_v1
+		if isOrphanLink(mi, c.SysInfo) {
+			continue
+		}
+
+		// Idle GPUs reuse their last values until the idle collect interval elapses
+		vals, cached := c.idleValues(mi.Entity)
 		var err error
-		if mi.Entity.EntityGroupId == dcgm.FE_LINK {
-			vals, err = dcgm.LinkGetLatestValues(mi.Entity.EntityId, mi.ParentId, c.DeviceFields)
-		} else {
-			vals, err = dcgm.EntityGetLatestValues(mi.Entity.EntityGroupId, mi.Entity.EntityId, c.DeviceFields)
+		if !cached && mi.Entity.EntityGroupId == dcgm.FE_LINK {
+			vals, err = dcgmLinkGetLatestValues(mi.Entity.EntityId, mi.ParentId, c.DeviceFields)
+		} else if !cached {
+			vals, err = dcgmEntityGetLatestValues(mi.Entity.EntityGroupId, mi.Entity.EntityId, c.DeviceFields)
 		}
 
 		if err != nil {
 			if derr, ok := err.(*dcgm.DcgmError); ok {
 				if derr.Code == dcgm.DCGM_ST_CONNECTION_NOT_VALID {
+					if stale, ok := c.staleMetrics(); ok {
+						logrus.Warn("Serving stale metrics while DCGM is reconnecting: ", err)
+						return stale, nil
+					}
 					logrus.Fatal("Could not retrieve metrics: ", err)
 				}
 			}
 			return nil, err
 		}
 
+		if !cached && c.NVMLFallback && mi.Entity.EntityGroupId == dcgm.FE_GPU {
+			vals = nvmlFallbackValues(mi.DeviceInfo.UUID, vals)
+		}
+
+		if !cached {
+			c.recordPoll(mi.Entity, vals)
+		}
+
+		appendRegisteredDerivedMetrics(metrics, vals, mi, c.Hostname, c.ReplaceBlanksInModelName)
+
 		// InstanceInfo will be nil for GPUs
 		if c.SysInfo.InfoType == dcgm.FE_SWITCH || c.SysInfo.InfoType == dcgm.FE_LINK {
-			ToSwitchMetric(metrics, vals, c.Counters, mi, c.UseOldNamespace, c.Hostname)
+			ToSwitchMetric(metrics, vals, c.Counters, mi, c.MetricOptions)
 		} else if c.SysInfo.InfoType == dcgm.FE_CPU || c.SysInfo.InfoType == dcgm.FE_CPU_CORE {
-			ToCPUMetric(metrics, vals, c.Counters, mi, c.UseOldNamespace, c.Hostname)
+			ToCPUMetric(metrics, vals, c.Counters, mi, c.MetricOptions)
 		} else {
+			vals = ToSampledMetric(metrics,
+				vals,
+				samples,
+				c.Counters,
+				mi,
+				c.MetricOptions)
+
 			ToMetric(metrics,
 				vals,
 				c.Counters,
 				mi.DeviceInfo,
 				mi.InstanceInfo,
-				c.UseOldNamespace,
-				c.Hostname,
-				c.ReplaceBlanksInModelName)
+				c.MetricOptions)
+		}
+	}
+
+	appendDuplicateFields(metrics, c.Counters)
+
+	if c.RawValues {
+		appendRawValues(metrics)
+	}
+
+	if c.SysInfo.InfoType == dcgm.FE_GPU {
+		AppendFBUsedPercent(metrics, c.Counters)
+		AppendBAR1UsedPercent(metrics, c.Counters)
+		AppendPerfPerWatt(metrics, c.Counters)
+		AppendPowerCapHeadroom(metrics, c.Counters)
+		AppendRetiredPages(metrics, c.Counters)
+		AppendClocks(metrics, c.Counters)
+		AppendPowerScopes(metrics, c.Counters)
+		AppendTemperatureSensors(metrics, c.Counters)
+		AppendPCIeDegraded(metrics, c.Counters)
+		AppendNvLinkBandwidth(metrics, c.Counters)
+		AppendMigPowerAttributionError(metrics, c.Counters)
+		AppendTensorThroughput(metrics, c.Counters, c.TensorCapabilities)
+		c.appendMigMode(metrics, monitoringInfo)
+		c.appendMigInstanceCount(metrics, monitoringInfo)
+		c.appendMigScalingFactors(metrics, monitoringInfo)
+		AppendMigPowerSplit(metrics, c.Counters)
+
+		if c.CodecSessions {
+			c.appendCodecSessions(metrics, monitoringInfo)
+		}
+
+		if c.FanStatus {
+			c.appendFanStatus(metrics, monitoringInfo)
+		}
+
+		if c.ProcessUtilizationTopN > 0 {
+			c.appendProcessUtilization(metrics, monitoringInfo)
 		}
 	}
 
+	if c.SysInfo.InfoType == dcgm.FE_LINK {
+		// The links, which are not up, are not collected, but their state is exported
+		links, err := dcgmGetNvLinkLinkStatus()
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to get the NvLink link status.")
+		} else {
+			ToLinkStateMetric(metrics, links, c.SysInfo, c.Hostname)
+		}
+	}
+
+	if c.ComputeCapabilityLabel && c.SysInfo.InfoType == dcgm.FE_GPU {
+		c.labelComputeCapability(metrics)
+	}
+
+	metrics = ProcessMetrics(metrics, c.Processors...)
+
+	if c.CounterOK {
+		c.appendCounterOK(metrics)
+	}
+
+	if c.StaleMetricsMaxAge > 0 {
+		c.lastMetrics = metrics
+		c.lastMetricsAt = time.Now()
+	}
+
 	return metrics, nil
 }
 
-func ShouldMonitorDeviceType(fields []dcgm.Short, entityType dcgm.Field_Entity_Group) bool {
+// staleMetrics returns a copy of the last collected metrics labeled with stale="true",
+// when they are not older than StaleMetricsMaxAge.
+func (c *DCGMCollector) staleMetrics() (MetricsByCounter, bool) {
+	if c.StaleMetricsMaxAge <= 0 || c.lastMetrics == nil || time.Since(c.lastMetricsAt) > c.StaleMetricsMaxAge {
+		return nil, false
+	}
+
+	stale := make(MetricsByCounter, len(c.lastMetrics))
+	for counter, metrics := range c.lastMetrics {
+		staleMetrics := make([]Metric, 0, len(metrics))
+		for _, m := range metrics {
+			attrs := make(map[string]string, len(m.Attributes)+1)
+			for k, v := range m.Attributes {
+				attrs[k] = v
+			}
+			attrs[staleAttribute] = "true"
+			m.Attributes = attrs
+			staleMetrics = append(staleMetrics, m)
+		}
+		stale[counter] = staleMetrics
+	}
+
+	return stale, true
+}
+
+// getSamples returns all values of the sampled fields recorded since the previous call.
+func (c *DCGMCollector) getSamples() ([]dcgm.FieldValue_v2, error) {
+	if len(c.SampledFields) == 0 {
+		return nil, nil
+	}
+
+	values, nextSince, err := dcgmGetValuesSince(c.sampledGroup, c.sampledFieldGroup, c.samplesSince)
+	if err != nil {
+		return nil, err
+	}
+
+	c.samplesSince = nextSince
+
+	return values, nil
+}
+
+// DeviceTypeMonitoring is the result of ShouldMonitorDeviceType.
+type DeviceTypeMonitoring struct {
+	Monitor bool
+	Reason  string // Why the device type is not monitored
+}
+
+// ShouldMonitorDeviceType decides whether devices of the entity type are monitored, which requires a field that
+// belongs to the entity type. Fields without an entity, like the driver version, are collected along with the
+// fields of the device but do not justify monitoring it on their own.
+func ShouldMonitorDeviceType(fields []dcgm.Short, entityType dcgm.Field_Entity_Group) DeviceTypeMonitoring {
 	if len(fields) == 0 {
-		return false
+		return DeviceTypeMonitoring{Reason: "no fields to watch"}
 	}
 
-	if len(fields) == 1 && fields[0] == dcgm.DCGM_FI_DRIVER_VERSION {
-		return false
+	withoutEntity := 0
+	for _, field := range fields {
+		entityLevel := dcgmFieldGetById(field).EntityLevel
+		if entityLevel == dcgm.FE_NONE {
+			withoutEntity++
+			continue
+		}
+
+		if fieldBelongsToEntityType(entityLevel, entityType) {
+			return DeviceTypeMonitoring{Monitor: true}
+		}
 	}
 
-	return true
+	if withoutEntity == len(fields) {
+		return DeviceTypeMonitoring{Reason: "only fields without an entity, like the driver version, to watch"}
+	}
+
+	return DeviceTypeMonitoring{Reason: fmt.Sprintf("no fields of the %s entity group to watch", entityGroupName(entityType))}
+}
+
+// appendDuplicateFields exports the metrics of a field, which is listed under several names, under every name.
+// The metrics are converted for the first counter of the field, see FindCounterField.
+func appendDuplicateFields(metrics MetricsByCounter, c []Counter) {
+	for i, counter := range c {
+		first, err := FindCounterField(c, uint(counter.FieldID))
+		if err != nil || first == c[i] {
+			continue
+		}
+
+		for _, m := range metrics[first] {
+			m.Counter = counter
+			metrics[counter] = append(metrics[counter], m)
+		}
+	}
 }
 
 func FindCounterField(c []Counter, fieldId uint) (Counter, error) {
@@ -163,11 +490,12 @@ func FindCounterField(c []Counter, fieldId uint) (Counter, error) {
 }
 
 func ToSwitchMetric(metrics MetricsByCounter,
-	values []dcgm.FieldValue_v1, c []Counter, mi MonitoringInfo, useOld bool, hostname string) {
+	values []dcgm.FieldValue_v1, c []Counter, mi MonitoringInfo, opts MetricOptions) {
 	labels := map[string]string{}
 
 	for _, val := range values {
-		v := ToString(val)
+		typed := formatFieldValue(val, opts.ShortestFloatFormat)
+		v := typed.Value
 		// Filter out counters with no value and ignored fields for this entity
 
 		counter, err := FindCounterField(c, val.FieldId)
@@ -175,16 +503,20 @@ func ToSwitchMetric(metrics MetricsByCounter,
 			continue
 		}
 
+		if v == FailedToConvert && (!opts.FailedConversionsAsNaN || counter.PromType == "label") {
+			continue
+		}
+
 		if counter.PromType == "label" {
-			labels[counter.FieldName] = v
+			labels[counter.FieldName] = truncateLabelValue(counter.FieldName, v, opts.MaxLabelValueLength)
 			continue
 		}
 		uuid := "UUID"
-		if useOld {
+		if opts.UseOldNamespace {
 			uuid = "uuid"
 		}
 		var m Metric
-		if v == SkipDCGMValue {
+		if typed.Skip {
 			continue
 		} else {
 			m = Metric{
@@ -195,22 +527,80 @@ func ToSwitchMetric(metrics MetricsByCounter,
 				GPUUUID:      "",
 				GPUDevice:    fmt.Sprintf("nvswitch%d", mi.ParentId),
 				GPUModelName: "",
-				Hostname:     hostname,
+				Hostname:     opts.Hostname,
 				Labels:       labels,
 				Attributes:   nil,
 			}
 		}
 
+		if v == FailedToConvert {
+			markConversionFailed(&m)
+		}
+
 		metrics[m.Counter] = append(metrics[m.Counter], m)
 	}
 }
 
+var linkStateCounter = Counter{
+	FieldName: "DCGM_EXPORTER_NVLINK_UP",
+	PromType:  "gauge",
+	Help:      "1 when the NvLink is up; the state label is up, down or disabled.",
+}
+
+// linkStates maps the states of the NvLinks to the state label of DCGM_EXPORTER_NVLINK_UP.
+var linkStates = map[dcgm.Link_State]string{
+	dcgm.LS_DISABLED: "disabled",
+	dcgm.LS_DOWN:     "down",
+	dcgm.LS_UP:       "up",
+}
+
+// ToLinkStateMetric appends the state of every watched NvLink of the watched switches.
+func ToLinkStateMetric(metrics MetricsByCounter, links []dcgm.NvLinkStatus, sysInfo SystemInfo, hostname string) {
+	for _, link := range links {
+		state, supported := linkStates[link.State]
+		if !supported || link.ParentType != dcgm.FE_SWITCH {
+			continue
+		}
+
+		known := slices.ContainsFunc(sysInfo.Switches, func(sw SwitchInfo) bool {
+			return sw.EntityId == link.ParentId
+		})
+		if !known || !IsSwitchWatched(link.ParentId, sysInfo) || !IsLinkWatched(link.Index, link.ParentId, sysInfo) {
+			continue
+		}
+
+		value := "0"
+		if link.State == dcgm.LS_UP {
+			value = "1"
+		}
+
+		metrics[linkStateCounter] = append(metrics[linkStateCounter], Metric{
+			Counter:    linkStateCounter,
+			Value:      value,
+			GPU:        fmt.Sprintf("%d", link.Index),
+			GPUDevice:  fmt.Sprintf("nvswitch%d", link.ParentId),
+			Hostname:   hostname,
+			Attributes: map[string]string{linkStateAttribute: state},
+		})
+	}
+}
+
+// cpuFieldUnits contains the units of the Grace CPU power and thermal fields, exported in the unit label.
+var cpuFieldUnits = map[dcgm.Short]string{
+	dcgm.DCGM_FI_DEV_CPU_POWER_UTIL_CURRENT: "watts",
+	dcgm.DCGM_FI_DEV_CPU_POWER_LIMIT:        "watts",
+	dcgm.DCGM_FI_DEV_CPU_TEMP_CURRENT:       "celsius",
+	dcgm.DCGM_FI_DEV_CPU_TEMP_WARNING:       "celsius",
+	dcgm.DCGM_FI_DEV_CPU_TEMP_CRITICAL:      "celsius",
+}
+
 func ToCPUMetric(metrics MetricsByCounter,
-	values []dcgm.FieldValue_v1, c []Counter, mi MonitoringInfo, useOld bool, hostname string) {
+	values []dcgm.FieldValue_v1, c []Counter, mi MonitoringInfo, opts MetricOptions) {
 	var labels = map[string]string{}
 
 	for _, val := range values {
-		v := ToString(val)
+		typed := formatFieldValue(val, opts.ShortestFloatFormat)
+		v := typed.Value
 		// Filter out counters with no value and ignored fields for this entity
 
 		counter, err := FindCounterField(c, val.FieldId)
@@ -218,16 +608,20 @@ func ToCPUMetric(metrics MetricsByCounter,
 			continue
 		}
 
+		if v == FailedToConvert && (!opts.FailedConversionsAsNaN || counter.PromType == "label") {
+			continue
+		}
+
 		if counter.PromType == "label" {
-			labels[counter.FieldName] = v
+			labels[counter.FieldName] = truncateLabelValue(counter.FieldName, v, opts.MaxLabelValueLength)
 			continue
 		}
 		uuid := "UUID"
-		if useOld {
+		if opts.UseOldNamespace {
 			uuid = "uuid"
 		}
 		var m Metric
-		if v == SkipDCGMValue {
+		if typed.Skip {
 			continue
 		} else {
 			m = Metric{
@@ -238,12 +632,20 @@ func ToCPUMetric(metrics MetricsByCounter,
 				GPUUUID:      "",
 				GPUDevice:    fmt.Sprintf("%d", mi.ParentId),
 				GPUModelName: "",
-				Hostname:     hostname,
+				Hostname:     opts.Hostname,
 				Labels:       labels,
 				Attributes:   nil,
 			}
 		}
 
+		if unit, exists := cpuFieldUnits[counter.FieldID]; exists {
+			m.Attributes = map[string]string{unitAttribute: unit}
+		}
+
+		if v == FailedToConvert {
+			markConversionFailed(&m)
+		}
+
 		metrics[m.Counter] = append(metrics[m.Counter], m)
 	}
 }
@@ -254,16 +656,15 @@ func ToMetric(
 	c []Counter,
 	d dcgm.Device,
 	instanceInfo *GPUInstanceInfo,
-	useOld bool,
-	hostname string,
-	replaceBlanksInModelName bool,
+	opts MetricOptions,
 ) {
 	var labels = map[string]string{}
 
 	for _, val := range values {
-		v := ToString(val)
+		typed := formatFieldValue(val, opts.ShortestFloatFormat)
+		v := typed.Value
 		// Filter out counters with no value and ignored fields for this entity
-		if v == SkipDCGMValue {
+		if typed.Skip {
 			continue
 		}
 
@@ -273,15 +674,34 @@ func ToMetric(
 		}
 
 		if counter.PromType == "label" {
-			labels[counter.FieldName] = v
+			if v != FailedToConvert {
+				labels[counter.FieldName] = truncateLabelValue(counter.FieldName, v, opts.MaxLabelValueLength)
+			}
 			continue
 		}
+
+		v = numericValue(val, counter, v, entityName(d, instanceInfo))
+		if v == FailedToConvert && !opts.FailedConversionsAsNaN {
+			continue
+		}
+
+		raw := v
+		v = aggregateValue(val, v, instanceInfo, opts.ShortestFloatFormat)
+
+		if counter.Expr != "" && v != FailedToConvert {
+			v = evalValueExpr(counter, v, values, opts.ShortestFloatFormat)
+			if v == FailedToConvert && !opts.FailedConversionsAsNaN {
+				continue
+			}
+		}
+
+		v = zeroBelowThreshold(val, counter, v, opts.FloatZeroThreshold)
 		uuid := "UUID"
-		if useOld {
+		if opts.UseOldNamespace {
 			uuid = "uuid"
 		}
 
-		gpuModel := getGPUModel(d, replaceBlanksInModelName)
+		gpuModel := getGPUModel(d, opts.ReplaceBlanksInModelName)
 
 		m := Metric{
 			Counter: counter,
@@ -292,7 +712,7 @@ func ToMetric(
 			GPUUUID:      d.UUID,
 			GPUDevice:    fmt.Sprintf("nvidia%d", d.GPU),
 			GPUModelName: gpuModel,
-			Hostname:     hostname,
+			Hostname:     opts.Hostname,
 
 			Labels:     labels,
 			Attributes: map[string]string{},
@@ -305,10 +725,115 @@ func ToMetric(
 			m.GPUInstanceID = ""
 		}
 
+		if raw != v && raw != FailedToConvert {
+			m.RawValue = raw
+		}
+
+		if v == FailedToConvert {
+			markConversionFailed(&m)
+		}
+
 		metrics[m.Counter] = append(metrics[m.Counter], m)
 	}
 }
 
+// ToSampledMetric appends a metric with its sample timestamp for every sample recorded for the entity.
+// It returns the latest values that are not covered by the samples and must still be converted with ToMetric.
+func ToSampledMetric(
+	metrics MetricsByCounter,
+	values []dcgm.FieldValue_v1,
+	samples []dcgm.FieldValue_v2,
+	c []Counter,
+	mi MonitoringInfo,
+	opts MetricOptions,
+) []dcgm.FieldValue_v1 {
+	sampledFields := map[uint]bool{}
+
+	// Label values are taken from the latest values, so that samples carry the same labels
+	var labelValues []dcgm.FieldValue_v1
+	for _, val := range values {
+		counter, err := FindCounterField(c, val.FieldId)
+		if err == nil && counter.PromType == "label" {
+			labelValues = append(labelValues, val)
+		}
+	}
+
+	for _, sample := range samples {
+		if sample.EntityGroupId != mi.Entity.EntityGroupId || sample.EntityId != mi.Entity.EntityId {
+			continue
+		}
+
+		sampledFields[sample.FieldId] = true
+
+		sampleMetrics := make(MetricsByCounter)
+		ToMetric(sampleMetrics,
+			append(slices.Clone(labelValues), toFieldValueV1(sample)),
+			c,
+			mi.DeviceInfo,
+			mi.InstanceInfo,
+			opts)
+
+		for counter, sampleValues := range sampleMetrics {
+			for i := range sampleValues {
+				sampleValues[i].Timestamp = time.UnixMicro(sample.Ts).UnixMilli()
+			}
+			metrics[counter] = append(metrics[counter], sampleValues...)
+		}
+	}
+
+	if len(sampledFields) == 0 {
+		return values
+	}
+
+	var latest []dcgm.FieldValue_v1
+	for _, val := range values {
+		if !sampledFields[val.FieldId] {
+			latest = append(latest, val)
+		}
+	}
+
+	return latest
+}
+
+func toFieldValueV1(value dcgm.FieldValue_v2) dcgm.FieldValue_v1 {
+	return dcgm.FieldValue_v1{
+		Version:   value.Version,
+		FieldId:   value.FieldId,
+		FieldType: value.FieldType,
+		Status:    value.Status,
+		Ts:        value.Ts,
+		Value:     value.Value,
+	}
+}
+
+// markConversionFailed exports a value that ToString could not convert as NaN, flagged for debugging.
+func markConversionFailed(m *Metric) {
+	m.Value = "NaN"
+	if m.Attributes == nil {
+		m.Attributes = map[string]string{}
+	}
+	m.Attributes[conversionFailedAttribute] = "true"
+}
+
+// truncateLabelValue truncates v to maxLength characters, the last of them an ellipsis, and logs the first
+// truncation of every field. A maxLength of 0 keeps the value.
+func truncateLabelValue(field, v string, maxLength int) string {
+	if maxLength <= 0 || utf8.RuneCountInString(v) <= maxLength {
+		return v
+	}
+
+	if _, logged := truncatedLabelFields.LoadOrStore(field, true); !logged {
+		logrus.Warnf("Truncating the values of label %s to %d characters", field, maxLength)
+	}
+
+	runes := []rune(v)
+	if maxLength <= len(labelValueEllipsis) {
+		return string(runes[:maxLength])
+	}
+
+	return string(runes[:maxLength-len(labelValueEllipsis)]) + labelValueEllipsis
+}
+
 func getGPUModel(d dcgm.Device, replaceBlanksInModelName bool) string {
 	gpuModel := d.Identifiers.Model
 
@@ -320,56 +845,138 @@ func getGPUModel(d dcgm.Device, replaceBlanksInModelName bool) string {
 	return gpuModel
 }
 
+// SkipReason is why a field value is not exported.
+type SkipReason string
+
+const (
+	// SkipBlank is set for fields, which are supported, but have no value yet
+	SkipBlank           SkipReason = "blank"
+	SkipNotFound        SkipReason = "not_found"
+	SkipNotSupported    SkipReason = "not_supported"
+	SkipNotPermissioned SkipReason = "not_permissioned"
+	// SkipConfigured is set for the configured skip values of a field
+	SkipConfigured SkipReason = "configured"
+)
+
+// TypedValue is a field value converted to a string. Skip is set for blank, not found, not supported
+// and not permissioned values, which are not exported, so that no value is mistaken for a skipped one;
+// SkipReason tells them apart.
+type TypedValue struct {
+	Value      string
+	Skip       bool
+	SkipReason SkipReason
+}
+
+// ToString converts the value to a string, or SkipDCGMValue if the value is not exported
 func ToString(value dcgm.FieldValue_v1) string {
+	typed := ToTypedValue(value)
+	if typed.Skip {
+		return SkipDCGMValue
+	}
+
+	return typed.Value
+}
+
+// ToTypedValue converts the value to a string, flagging the values that are not exported
+func ToTypedValue(value dcgm.FieldValue_v1) TypedValue {
 	switch value.FieldType {
 	case dcgm.DCGM_FT_INT64:
 		switch v := value.Int64(); v {
 		case dcgm.DCGM_FT_INT32_BLANK:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipBlank}
 		case dcgm.DCGM_FT_INT32_NOT_FOUND:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipNotFound}
 		case dcgm.DCGM_FT_INT32_NOT_SUPPORTED:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipNotSupported}
 		case dcgm.DCGM_FT_INT32_NOT_PERMISSIONED:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipNotPermissioned}
 		case dcgm.DCGM_FT_INT64_BLANK:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipBlank}
 		case dcgm.DCGM_FT_INT64_NOT_FOUND:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipNotFound}
 		case dcgm.DCGM_FT_INT64_NOT_SUPPORTED:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipNotSupported}
 		case dcgm.DCGM_FT_INT64_NOT_PERMISSIONED:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipNotPermissioned}
 		default:
-			return fmt.Sprintf("%d", value.Int64())
+			return TypedValue{Value: fmt.Sprintf("%d", value.Int64())}
 		}
 	case dcgm.DCGM_FT_DOUBLE:
 		switch v := value.Float64(); v {
 		case dcgm.DCGM_FT_FP64_BLANK:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipBlank}
 		case dcgm.DCGM_FT_FP64_NOT_FOUND:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipNotFound}
 		case dcgm.DCGM_FT_FP64_NOT_SUPPORTED:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipNotSupported}
 		case dcgm.DCGM_FT_FP64_NOT_PERMISSIONED:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipNotPermissioned}
 		default:
-			return fmt.Sprintf("%f", value.Float64())
+			return TypedValue{Value: fmt.Sprintf("%f", value.Float64())}
 		}
 	case dcgm.DCGM_FT_STRING:
 		switch v := value.String(); v {
 		case dcgm.DCGM_FT_STR_BLANK:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipBlank}
 		case dcgm.DCGM_FT_STR_NOT_FOUND:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipNotFound}
 		case dcgm.DCGM_FT_STR_NOT_SUPPORTED:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipNotSupported}
 		case dcgm.DCGM_FT_STR_NOT_PERMISSIONED:
-			return SkipDCGMValue
+			return TypedValue{Skip: true, SkipReason: SkipNotPermissioned}
 		default:
-			return v
+			return TypedValue{Value: v}
 		}
 	}
 
-	return FailedToConvert
+	return TypedValue{Value: FailedToConvert}
+}
+
+// zeroBelowThreshold returns 0 for a double gauge, whose absolute value is below the threshold, so that noise
+// like 0.000001 of an idle profiling field is not exported. Thresholds up to 0 disable it.
+func zeroBelowThreshold(val dcgm.FieldValue_v1, counter Counter, v string, threshold float64) string {
+	if threshold <= 0 || val.FieldType != dcgm.DCGM_FT_DOUBLE || counter.PromType != "gauge" {
+		return v
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(f) || math.Abs(f) >= threshold {
+		return v
+	}
+
+	return "0"
+}
+
+// formatFieldValue returns ToTypedValue of the value, but formats doubles with the shortest representation,
+// which parses back to the same value, when shortestFloats is set.
+func formatFieldValue(value dcgm.FieldValue_v1, shortestFloats bool) TypedValue {
+	typed := ToTypedValue(value)
+	if !typed.Skip && isSkipValue(value, typed.Value) {
+		return TypedValue{Skip: true, SkipReason: SkipConfigured}
+	}
+
+	if !shortestFloats || value.FieldType != dcgm.DCGM_FT_DOUBLE || typed.Skip || typed.Value == FailedToConvert {
+		return typed
+	}
+
+	typed.Value = strconv.FormatFloat(value.Float64(), 'g', -1, 64)
+	return typed
+}
+
+// ToFloat64 returns the numeric value of a field, regardless of whether DCGM reports it as an integer or a double.
+// The same field may be reported with a different type by different drivers, so the raw value must never be read
+// without checking the field type first. It returns false if the value is blank or not numeric.
+func ToFloat64(value dcgm.FieldValue_v1) (float64, bool) {
+	if ToTypedValue(value).Skip {
+		return 0, false
+	}
+
+	switch value.FieldType {
+	case dcgm.DCGM_FT_INT64:
+		return float64(value.Int64()), true
+	case dcgm.DCGM_FT_DOUBLE:
+		return value.Float64(), true
+	}
+
+	return 0, false
 }
@@ -17,9 +17,13 @@
 package dcgmexporter
 
 import (
+	"encoding/binary"
 	"fmt"
+	"math"
 	"reflect"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
 	"github.com/stretchr/testify/assert"
@@ -27,17 +31,17 @@ import (
 )
 
 var sampleCounters = []Counter{
-	{dcgm.DCGM_FI_DEV_GPU_TEMP, "DCGM_FI_DEV_GPU_TEMP", "gauge", "Temperature Help info"},
-	{dcgm.DCGM_FI_DEV_TOTAL_ENERGY_CONSUMPTION, "DCGM_FI_DEV_TOTAL_ENERGY_CONSUMPTION", "gauge", "Energy help info"},
-	{dcgm.DCGM_FI_DEV_POWER_USAGE, "DCGM_FI_DEV_POWER_USAGE", "gauge", "Power help info"},
-	{dcgm.DCGM_FI_DRIVER_VERSION, "DCGM_FI_DRIVER_VERSION", "label", "Driver version"},
+	{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "Temperature Help info"},
+	{FieldID: dcgm.DCGM_FI_DEV_TOTAL_ENERGY_CONSUMPTION, FieldName: "DCGM_FI_DEV_TOTAL_ENERGY_CONSUMPTION", PromType: "gauge", Help: "Energy help info"},
+	{FieldID: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge", Help: "Power help info"},
+	{FieldID: dcgm.DCGM_FI_DRIVER_VERSION, FieldName: "DCGM_FI_DRIVER_VERSION", PromType: "label", Help: "Driver version"},
 	/* test that switch and link metrics are filtered out automatically when devices are not detected */
-	{dcgm.DCGM_FI_DEV_NVSWITCH_TEMPERATURE_CURRENT, "DCGM_FI_DEV_NVSWITCH_TEMPERATURE_CURRENT", "gauge", "switch temperature"},
-	{dcgm.DCGM_FI_DEV_NVSWITCH_LINK_FLIT_ERRORS, "DCGM_FI_DEV_NVSWITCH_LINK_FLIT_ERRORS", "gauge", "per-link flit errors"},
+	{FieldID: dcgm.DCGM_FI_DEV_NVSWITCH_TEMPERATURE_CURRENT, FieldName: "DCGM_FI_DEV_NVSWITCH_TEMPERATURE_CURRENT", PromType: "gauge", Help: "switch temperature"},
+	{FieldID: dcgm.DCGM_FI_DEV_NVSWITCH_LINK_FLIT_ERRORS, FieldName: "DCGM_FI_DEV_NVSWITCH_LINK_FLIT_ERRORS", PromType: "gauge", Help: "per-link flit errors"},
 	/* test that vgpu metrics are not filtered out */
-	{dcgm.DCGM_FI_DEV_VGPU_LICENSE_STATUS, "DCGM_FI_DEV_VGPU_LICENSE_STATUS", "gauge", "vgpu license status"},
+	{FieldID: dcgm.DCGM_FI_DEV_VGPU_LICENSE_STATUS, FieldName: "DCGM_FI_DEV_VGPU_LICENSE_STATUS", PromType: "gauge", Help: "vgpu license status"},
 	/* test that cpu and cpu core metrics are filtered out automatically when devices are not detected */
-	{dcgm.DCGM_FI_DEV_CPU_UTIL_TOTAL, "DCGM_FI_DEV_CPU_UTIL_TOTAL", "gauge", "Total CPU utilization"},
+	{FieldID: dcgm.DCGM_FI_DEV_CPU_UTIL_TOTAL, FieldName: "DCGM_FI_DEV_CPU_UTIL_TOTAL", PromType: "gauge", Help: "Total CPU utilization"},
 }
 
 var expectedMetrics = map[string]bool{
@@ -305,7 +309,7 @@ func TestToMetric(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(fmt.Sprintf("When replaceBlanksInModelName is %t", tc.replaceBlanksInModelName), func(t *testing.T) {
 			metrics := make(map[Counter][]Metric)
-			ToMetric(metrics, values, c, d, instanceInfo, false, "", tc.replaceBlanksInModelName)
+			ToMetric(metrics, values, c, d, instanceInfo, MetricOptions{ReplaceBlanksInModelName: tc.replaceBlanksInModelName})
 			assert.Len(t, metrics, 1)
 			// We get metric value with 0 index
 			metricValues := metrics[reflect.ValueOf(metrics).MapKeys()[0].Interface().(Counter)]
@@ -382,3 +386,1145 @@ func TestGPUCollector_GetMetrics(t *testing.T) {
 
 	require.Equal(t, numGPUs, uint(len(values)))
 }
+
+func TestToSampledMetric(t *testing.T) {
+	int64Value := func(v byte) [4096]byte {
+		value := [4096]byte{}
+		value[0] = v
+		return value
+	}
+
+	c := []Counter{
+		{
+			FieldID:   150,
+			FieldName: "DCGM_FI_DEV_GPU_TEMP",
+			PromType:  "gauge",
+			Help:      "Temperature Help info",
+		},
+		{
+			FieldID:   155,
+			FieldName: "DCGM_FI_DEV_POWER_USAGE",
+			PromType:  "gauge",
+			Help:      "Power help info",
+		},
+	}
+
+	mi := MonitoringInfo{
+		Entity:     dcgm.GroupEntityPair{EntityGroupId: dcgm.FE_GPU, EntityId: 0},
+		DeviceInfo: dcgm.Device{GPU: 0, UUID: "fake0"},
+	}
+
+	values := []dcgm.FieldValue_v1{
+		{FieldId: 150, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(42)},
+		{FieldId: 155, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(100)},
+	}
+
+	samples := []dcgm.FieldValue_v2{
+		{EntityGroupId: dcgm.FE_GPU, EntityId: 0, FieldId: 150, FieldType: dcgm.DCGM_FT_INT64, Ts: 1000000, Value: int64Value(40)},
+		{EntityGroupId: dcgm.FE_GPU, EntityId: 0, FieldId: 150, FieldType: dcgm.DCGM_FT_INT64, Ts: 2000000, Value: int64Value(41)},
+		{EntityGroupId: dcgm.FE_GPU, EntityId: 0, FieldId: 150, FieldType: dcgm.DCGM_FT_INT64, Ts: 3000000, Value: int64Value(42)},
+		// Samples of other entities must be ignored
+		{EntityGroupId: dcgm.FE_GPU, EntityId: 1, FieldId: 150, FieldType: dcgm.DCGM_FT_INT64, Ts: 3000000, Value: int64Value(50)},
+	}
+
+	metrics := make(MetricsByCounter)
+	latest := ToSampledMetric(metrics, values, samples, c, mi, MetricOptions{})
+	require.Len(t, latest, 1, "the sampled field must be removed from the latest values")
+	assert.Equal(t, uint(155), latest[0].FieldId)
+
+	sampled := metrics[c[0]]
+	require.Len(t, sampled, 3)
+
+	timestamps := map[int64]bool{}
+	for i, m := range sampled {
+		assert.Equal(t, fmt.Sprint(40+i), m.Value)
+		assert.Equal(t, "0", m.GPU)
+		timestamps[m.Timestamp] = true
+	}
+	assert.Equal(t, map[int64]bool{1000: true, 2000: true, 3000: true}, timestamps)
+
+	ToMetric(metrics, latest, c, mi.DeviceInfo, mi.InstanceInfo, MetricOptions{})
+	require.Len(t, metrics[c[1]], 1)
+	assert.Zero(t, metrics[c[1]][0].Timestamp)
+
+	p, cleanup, err := NewMetricsPipelineWithGPUCollector(&Config{}, &DCGMCollector{})
+	require.NoError(t, err)
+	defer cleanup()
+
+	out, err := FormatMetrics(p.migMetricsFormat, metrics)
+	require.NoError(t, err)
+	assert.Contains(t, out, `DCGM_FI_DEV_GPU_TEMP{gpu="0",UUID="fake0",device="nvidia0",modelName=""} 40 1000`)
+	assert.Contains(t, out, `DCGM_FI_DEV_GPU_TEMP{gpu="0",UUID="fake0",device="nvidia0",modelName=""} 42 3000`)
+	assert.Contains(t, out, `DCGM_FI_DEV_POWER_USAGE{gpu="0",UUID="fake0",device="nvidia0",modelName=""} 100`+"\n")
+}
+
+func TestSetupSampledFieldsWatch(t *testing.T) {
+	createGroup, addEntityToGroup, destroyGroup := dcgmCreateGroup, dcgmAddEntityToGroup, dcgmDestroyGroup
+	fieldGroupCreate, fieldGroupDestroy, watchFieldsWithGroupEx := dcgmFieldGroupCreate, dcgmFieldGroupDestroy, dcgmWatchFieldsWithGroupEx
+	getValuesSince, now := dcgmGetValuesSince, timeNow
+	defer func() {
+		dcgmCreateGroup, dcgmAddEntityToGroup, dcgmDestroyGroup = createGroup, addEntityToGroup, destroyGroup
+		dcgmFieldGroupCreate, dcgmFieldGroupDestroy, dcgmWatchFieldsWithGroupEx = fieldGroupCreate, fieldGroupDestroy, watchFieldsWithGroupEx
+		dcgmGetValuesSince, timeNow = getValuesSince, now
+	}()
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time {
+		return created
+	}
+
+	var entities []dcgm.GroupEntityPair
+	dcgmCreateGroup = func(string) (dcgm.GroupHandle, error) {
+		return dcgm.GroupHandle{}, nil
+	}
+	dcgmAddEntityToGroup = func(_ dcgm.GroupHandle, group dcgm.Field_Entity_Group, id uint) error {
+		entities = append(entities, dcgm.GroupEntityPair{EntityGroupId: group, EntityId: id})
+		return nil
+	}
+	dcgmDestroyGroup = func(dcgm.GroupHandle) error {
+		return nil
+	}
+	dcgmFieldGroupCreate = func(string, []dcgm.Short) (dcgm.FieldHandle, error) {
+		return dcgm.FieldHandle{}, nil
+	}
+	dcgmFieldGroupDestroy = func(dcgm.FieldHandle) error {
+		return nil
+	}
+
+	var watched []dcgm.GroupHandle
+	dcgmWatchFieldsWithGroupEx = func(_ dcgm.FieldHandle, group dcgm.GroupHandle, _ int64, _ float64, _ int32) error {
+		watched = append(watched, group)
+		return nil
+	}
+
+	var queried []dcgm.GroupHandle
+	var since []time.Time
+	dcgmGetValuesSince = func(group dcgm.GroupHandle, _ dcgm.FieldHandle, sinceTime time.Time) ([]dcgm.FieldValue_v2, time.Time, error) {
+		queried = append(queried, group)
+		since = append(since, sinceTime)
+		return nil, sinceTime.Add(time.Second), nil
+	}
+
+	sysInfo := SystemInfo{
+		GPUCount: 1,
+		InfoType: dcgm.FE_GPU,
+		gOpt:     DeviceOptions{Flex: true},
+	}
+	sysInfo.GPUs[0].DeviceInfo.GPU = 1
+
+	c := &DCGMCollector{
+		Counters:     sampleCounters[:1],
+		DeviceFields: []dcgm.Short{dcgm.DCGM_FI_DEV_GPU_TEMP},
+		SysInfo:      sysInfo,
+	}
+	defer c.Cleanup()
+
+	require.NoError(t, c.setupSampledFieldsWatch(&Config{SampledFields: []string{"DCGM_FI_DEV_GPU_TEMP"}, CollectInterval: 1000}))
+	assert.Equal(t, []dcgm.GroupEntityPair{{EntityGroupId: dcgm.FE_GPU, EntityId: 1}}, entities,
+		"only the entities of the collector are watched")
+	require.Len(t, watched, 1)
+	assert.NotEqual(t, dcgm.GroupAllGPUs(), watched[0])
+
+	_, err := c.getSamples()
+	require.NoError(t, err)
+	_, err = c.getSamples()
+	require.NoError(t, err)
+
+	assert.Equal(t, []dcgm.GroupHandle{watched[0], watched[0]}, queried)
+	assert.Equal(t, []time.Time{created, created.Add(time.Second)}, since,
+		"the first samples are the ones taken since the collector was created")
+}
+
+func TestToMetricWhenConversionFails(t *testing.T) {
+	values := []dcgm.FieldValue_v1{
+		{
+			FieldId:   150,
+			FieldType: dcgm.DCGM_FT_BINARY,
+		},
+	}
+
+	require.Equal(t, FailedToConvert, ToString(values[0]))
+
+	c := []Counter{
+		{
+			FieldID:   150,
+			FieldName: "DCGM_FI_DEV_GPU_TEMP",
+			PromType:  "gauge",
+			Help:      "Temperature Help info",
+		},
+	}
+
+	d := dcgm.Device{
+		UUID: "fake0",
+	}
+
+	mi := MonitoringInfo{
+		Entity:   dcgm.GroupEntityPair{EntityGroupId: dcgm.FE_SWITCH, EntityId: 0},
+		ParentId: PARENT_ID_IGNORED,
+	}
+
+	t.Run("When failedAsNaN is false", func(t *testing.T) {
+		metrics := make(MetricsByCounter)
+		ToMetric(metrics, values, c, d, nil, MetricOptions{})
+		assert.Empty(t, metrics)
+
+		ToSwitchMetric(metrics, values, c, mi, MetricOptions{})
+		assert.Empty(t, metrics)
+
+		ToCPUMetric(metrics, values, c, mi, MetricOptions{})
+		assert.Empty(t, metrics)
+	})
+
+	t.Run("When failedAsNaN is true", func(t *testing.T) {
+		metrics := make(MetricsByCounter)
+		ToMetric(metrics, values, c, d, nil, MetricOptions{FailedConversionsAsNaN: true})
+		ToSwitchMetric(metrics, values, c, mi, MetricOptions{FailedConversionsAsNaN: true})
+		ToCPUMetric(metrics, values, c, mi, MetricOptions{FailedConversionsAsNaN: true})
+		require.Len(t, metrics[c[0]], 3)
+		for _, m := range metrics[c[0]] {
+			assert.Equal(t, "NaN", m.Value)
+			assert.Equal(t, "true", m.Attributes[conversionFailedAttribute])
+		}
+	})
+}
+
+func TestToMetricECCMode(t *testing.T) {
+	int64Value := func(v int64) [4096]byte {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], uint64(v))
+		return value
+	}
+
+	values := []dcgm.FieldValue_v1{
+		{FieldId: dcgm.DCGM_FI_DEV_ECC_CURRENT, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(1)},
+		{FieldId: dcgm.DCGM_FI_DEV_ECC_PENDING, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(0)},
+	}
+
+	c := []Counter{
+		{FieldID: dcgm.DCGM_FI_DEV_ECC_CURRENT, FieldName: "DCGM_FI_DEV_ECC_CURRENT", PromType: "gauge", Help: "ECC mode (1 if enabled)."},
+		{FieldID: dcgm.DCGM_FI_DEV_ECC_PENDING, FieldName: "DCGM_FI_DEV_ECC_PENDING", PromType: "gauge", Help: "ECC mode after the next reboot (1 if enabled)."},
+	}
+
+	metrics := make(MetricsByCounter)
+	ToMetric(metrics, values, c, dcgm.Device{UUID: "fake0"}, nil, MetricOptions{})
+
+	require.Len(t, metrics[c[0]], 1)
+	assert.Equal(t, "1", metrics[c[0]][0].Value, "ECC is enabled")
+	require.Len(t, metrics[c[1]], 1)
+	assert.Equal(t, "0", metrics[c[1]][0].Value, "ECC is disabled after the next reboot")
+}
+
+func TestToMetricPersistenceMode(t *testing.T) {
+	int64Value := func(v int64) [4096]byte {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], uint64(v))
+		return value
+	}
+
+	c := []Counter{
+		{FieldID: dcgm.DCGM_FI_DEV_PERSISTENCE_MODE, FieldName: "DCGM_FI_DEV_PERSISTENCE_MODE", PromType: "gauge", Help: "Persistence mode (1 if enabled)."},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		mode     int64
+		expected string
+	}{
+		{name: "on", mode: 1, expected: "1"},
+		{name: "off", mode: 0, expected: "0"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			values := []dcgm.FieldValue_v1{
+				{FieldId: dcgm.DCGM_FI_DEV_PERSISTENCE_MODE, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(tc.mode)},
+			}
+
+			metrics := make(MetricsByCounter)
+			ToMetric(metrics, values, c, dcgm.Device{UUID: "fake0"}, nil, MetricOptions{})
+
+			require.Len(t, metrics[c[0]], 1)
+			assert.Equal(t, tc.expected, metrics[c[0]][0].Value)
+		})
+	}
+}
+
+func TestToMetricRowRemapPending(t *testing.T) {
+	int64Value := func(v int64) [4096]byte {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], uint64(v))
+		return value
+	}
+
+	c := []Counter{
+		{FieldID: dcgm.DCGM_FI_DEV_ROW_REMAP_PENDING, FieldName: "DCGM_FI_DEV_ROW_REMAP_PENDING", PromType: "gauge", Help: "Whether remapping of rows is pending a GPU reset or reboot (1 if pending)"},
+	}
+
+	values := []dcgm.FieldValue_v1{
+		{FieldId: dcgm.DCGM_FI_DEV_ROW_REMAP_PENDING, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(1)},
+	}
+
+	metrics := make(MetricsByCounter)
+	ToMetric(metrics, values, c, dcgm.Device{GPU: 2, UUID: "fake2"}, nil, MetricOptions{})
+
+	require.Len(t, metrics[c[0]], 1)
+	assert.Equal(t, "1", metrics[c[0]][0].Value)
+	assert.Equal(t, "2", metrics[c[0]][0].GPU)
+	assert.Equal(t, "fake2", metrics[c[0]][0].GPUUUID)
+}
+
+func TestToMetricWhenStringValueEqualsSkipToken(t *testing.T) {
+	fieldGetById := dcgmFieldGetById
+	defer func() {
+		dcgmFieldGetById = fieldGetById
+	}()
+
+	dcgmFieldGetById = func(fieldID dcgm.Short) dcgm.FieldMeta {
+		return dcgm.FieldMeta{FieldId: fieldID, FieldType: byte(dcgm.DCGM_FT_STRING)}
+	}
+
+	stringValue := func(v string) [4096]byte {
+		value := [4096]byte{}
+		copy(value[:], v)
+		return value
+	}
+
+	values := []dcgm.FieldValue_v1{
+		{FieldId: dcgm.DCGM_FI_DEV_SERIAL, FieldType: dcgm.DCGM_FT_STRING, Value: stringValue(SkipDCGMValue)},
+		{FieldId: dcgm.DCGM_FI_DEV_VBIOS_VERSION, FieldType: dcgm.DCGM_FT_STRING, Value: stringValue(SkipDCGMValue)},
+		{FieldId: dcgm.DCGM_FI_DEV_INFOROM_IMAGE_VER, FieldType: dcgm.DCGM_FT_STRING, Value: stringValue(dcgm.DCGM_FT_STR_BLANK)},
+	}
+
+	c := []Counter{
+		{FieldID: dcgm.DCGM_FI_DEV_SERIAL, FieldName: "DCGM_FI_DEV_SERIAL", PromType: "label", Help: "Serial number."},
+		{FieldID: dcgm.DCGM_FI_DEV_VBIOS_VERSION, FieldName: "DCGM_FI_DEV_VBIOS_VERSION", PromType: "gauge", Help: "VBIOS version."},
+		{FieldID: dcgm.DCGM_FI_DEV_INFOROM_IMAGE_VER, FieldName: "DCGM_FI_DEV_INFOROM_IMAGE_VER", PromType: "gauge", Help: "Inforom image version."},
+	}
+
+	assert.Equal(t, TypedValue{Value: SkipDCGMValue}, ToTypedValue(values[0]))
+	assert.Equal(t, TypedValue{Skip: true, SkipReason: SkipBlank}, ToTypedValue(values[2]))
+
+	metrics := make(MetricsByCounter)
+	ToMetric(metrics, values, c, dcgm.Device{UUID: "fake0"}, nil, MetricOptions{})
+
+	require.Len(t, metrics, 1, "only the blank value is skipped")
+	require.Len(t, metrics[c[1]], 1)
+	assert.Equal(t, SkipDCGMValue, metrics[c[1]][0].Value)
+	assert.Equal(t, map[string]string{"DCGM_FI_DEV_SERIAL": SkipDCGMValue}, metrics[c[1]][0].Labels)
+}
+
+func TestToMetricTruncatesLongLabelValues(t *testing.T) {
+	name := [4096]byte{}
+	copy(name[:], "NVIDIA H100 80GB HBM3 Engineering Sample With A Very Long Model Name")
+
+	values := []dcgm.FieldValue_v1{
+		{FieldId: dcgm.DCGM_FI_DEV_NAME, FieldType: dcgm.DCGM_FT_STRING, Value: name},
+		{FieldId: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldType: dcgm.DCGM_FT_INT64, Value: [4096]byte{42}},
+	}
+
+	c := []Counter{
+		{FieldID: dcgm.DCGM_FI_DEV_NAME, FieldName: "DCGM_FI_DEV_NAME", PromType: "label", Help: "Device name."},
+		{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."},
+	}
+
+	metrics := make(MetricsByCounter)
+	ToMetric(metrics, values, c, dcgm.Device{UUID: "fake0"}, nil, MetricOptions{MaxLabelValueLength: 24})
+
+	require.Len(t, metrics[c[1]], 1)
+	assert.Equal(t, map[string]string{"DCGM_FI_DEV_NAME": "NVIDIA H100 80GB HBM3..."}, metrics[c[1]][0].Labels)
+	assert.Len(t, metrics[c[1]][0].Labels["DCGM_FI_DEV_NAME"], 24)
+
+	metrics = make(MetricsByCounter)
+	ToMetric(metrics, values, c, dcgm.Device{UUID: "fake0"}, nil, MetricOptions{})
+	assert.Equal(t, "NVIDIA H100 80GB HBM3 Engineering Sample With A Very Long Model Name",
+		metrics[c[1]][0].Labels["DCGM_FI_DEV_NAME"], "no limit")
+}
+
+func TestToMetricZeroesValuesBelowThreshold(t *testing.T) {
+	double := func(field dcgm.Short, f float64) dcgm.FieldValue_v1 {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], math.Float64bits(f))
+		return dcgm.FieldValue_v1{FieldId: uint(field), FieldType: dcgm.DCGM_FT_DOUBLE, Value: value}
+	}
+
+	c := []Counter{
+		{FieldID: dcgm.DCGM_FI_PROF_SM_ACTIVE, FieldName: "DCGM_FI_PROF_SM_ACTIVE", PromType: "gauge", Help: "Ratio of cycles an SM has at least 1 warp assigned."},
+		{FieldID: dcgm.DCGM_FI_PROF_PIPE_TENSOR_ACTIVE, FieldName: "DCGM_FI_PROF_PIPE_TENSOR_ACTIVE", PromType: "gauge", Help: "Ratio of cycles the tensor pipe is active."},
+		{FieldID: dcgm.DCGM_FI_PROF_DRAM_ACTIVE, FieldName: "DCGM_FI_PROF_DRAM_ACTIVE", PromType: "counter", Help: "Ratio of cycles the memory interface is active."},
+	}
+	values := []dcgm.FieldValue_v1{
+		double(dcgm.DCGM_FI_PROF_SM_ACTIVE, 0.000001),
+		double(dcgm.DCGM_FI_PROF_PIPE_TENSOR_ACTIVE, 0.25),
+		double(dcgm.DCGM_FI_PROF_DRAM_ACTIVE, 0.000001),
+	}
+
+	metrics := make(MetricsByCounter)
+	ToMetric(metrics, values, c, dcgm.Device{UUID: "fake0"}, nil, MetricOptions{ShortestFloatFormat: true, FloatZeroThreshold: 0.0001})
+
+	assert.Equal(t, "0", metrics[c[0]][0].Value, "below the threshold")
+	assert.Equal(t, "0.25", metrics[c[1]][0].Value, "above the threshold")
+	assert.Equal(t, "1e-06", metrics[c[2]][0].Value, "only gauges are zeroed")
+
+	metrics = make(MetricsByCounter)
+	ToMetric(metrics, values, c, dcgm.Device{UUID: "fake0"}, nil, MetricOptions{ShortestFloatFormat: true})
+	assert.Equal(t, "1e-06", metrics[c[0]][0].Value, "no threshold")
+}
+
+func TestTruncateLabelValue(t *testing.T) {
+	assert.Equal(t, "short", truncateLabelValue("DCGM_FI_DEV_SERIAL", "short", 5))
+	assert.Equal(t, "lo...", truncateLabelValue("DCGM_FI_DEV_SERIAL", "longer", 5))
+	assert.Equal(t, "lo", truncateLabelValue("DCGM_FI_DEV_SERIAL", "longer", 2))
+	assert.Equal(t, "ÄÖ...", truncateLabelValue("DCGM_FI_DEV_SERIAL", "ÄÖÜäöü", 5), "characters are not split")
+	assert.Equal(t, "longer", truncateLabelValue("DCGM_FI_DEV_SERIAL", "longer", 0))
+}
+
+func TestFormatFieldValue(t *testing.T) {
+	doubleValue := func(v float64) dcgm.FieldValue_v1 {
+		value := dcgm.FieldValue_v1{FieldType: dcgm.DCGM_FT_DOUBLE}
+		binary.LittleEndian.PutUint64(value.Value[:], math.Float64bits(v))
+		return value
+	}
+
+	int64Value := dcgm.FieldValue_v1{FieldType: dcgm.DCGM_FT_INT64}
+	binary.LittleEndian.PutUint64(int64Value.Value[:], 123456789012)
+
+	tests := []struct {
+		name           string
+		value          dcgm.FieldValue_v1
+		expectedFixed  string
+		expectedShort  string
+		expectedSkip   bool
+		expectedReason SkipReason
+	}{
+		{
+			name:          "Large double",
+			value:         doubleValue(123456789012345.67),
+			expectedFixed: "123456789012345.671875",
+			expectedShort: "1.2345678901234567e+14",
+		},
+		{
+			name:          "Small double",
+			value:         doubleValue(0.0000001234),
+			expectedFixed: "0.000000",
+			expectedShort: "1.234e-07",
+		},
+		{
+			name:          "Double without decimals",
+			value:         doubleValue(42),
+			expectedFixed: "42.000000",
+			expectedShort: "42",
+		},
+		{
+			name:           "Blank double",
+			value:          doubleValue(dcgm.DCGM_FT_FP64_BLANK),
+			expectedSkip:   true,
+			expectedReason: SkipBlank,
+		},
+		{
+			name:          "Integer",
+			value:         int64Value,
+			expectedFixed: "123456789012",
+			expectedShort: "123456789012",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, TypedValue{Value: tt.expectedFixed, Skip: tt.expectedSkip, SkipReason: tt.expectedReason},
+				formatFieldValue(tt.value, false))
+			assert.Equal(t, TypedValue{Value: tt.expectedShort, Skip: tt.expectedSkip, SkipReason: tt.expectedReason},
+				formatFieldValue(tt.value, true))
+		})
+	}
+}
+
+func TestToFloat64(t *testing.T) {
+	int64Value := func(v int64) [4096]byte {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], uint64(v))
+		return value
+	}
+
+	float64Value := func(v float64) [4096]byte {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], math.Float64bits(v))
+		return value
+	}
+
+	tests := []struct {
+		name     string
+		value    dcgm.FieldValue_v1
+		expected float64
+		ok       bool
+	}{
+		{
+			name:     "Power reported as INT64",
+			value:    dcgm.FieldValue_v1{FieldId: 155, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(250)},
+			expected: 250,
+			ok:       true,
+		},
+		{
+			name:     "Power reported as DOUBLE",
+			value:    dcgm.FieldValue_v1{FieldId: 155, FieldType: dcgm.DCGM_FT_DOUBLE, Value: float64Value(250.5)},
+			expected: 250.5,
+			ok:       true,
+		},
+		{
+			name:  "Blank INT64 power",
+			value: dcgm.FieldValue_v1{FieldId: 155, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(dcgm.DCGM_FT_INT64_BLANK)},
+			ok:    false,
+		},
+		{
+			name:  "Blank DOUBLE power",
+			value: dcgm.FieldValue_v1{FieldId: 155, FieldType: dcgm.DCGM_FT_DOUBLE, Value: float64Value(dcgm.DCGM_FT_FP64_BLANK)},
+			ok:    false,
+		},
+		{
+			name:  "Non numeric value",
+			value: dcgm.FieldValue_v1{FieldId: 155, FieldType: dcgm.DCGM_FT_BINARY},
+			ok:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ToFloat64(tt.value)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.expected, got)
+				assert.Equal(t, tt.expected, mustParseFloat(t, ToString(tt.value)))
+			}
+		})
+	}
+}
+
+func TestToTypedValueSkipReasons(t *testing.T) {
+	int64Value := func(v int64) [4096]byte {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], uint64(v))
+		return value
+	}
+
+	float64Value := func(v float64) [4096]byte {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], math.Float64bits(v))
+		return value
+	}
+
+	stringValue := func(v string) [4096]byte {
+		value := [4096]byte{}
+		copy(value[:], v)
+		return value
+	}
+
+	tests := []struct {
+		name     string
+		value    dcgm.FieldValue_v1
+		expected SkipReason
+	}{
+		{
+			name:     "Blank INT64",
+			value:    dcgm.FieldValue_v1{FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(dcgm.DCGM_FT_INT64_BLANK)},
+			expected: SkipBlank,
+		},
+		{
+			name:     "Not supported INT64",
+			value:    dcgm.FieldValue_v1{FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(dcgm.DCGM_FT_INT64_NOT_SUPPORTED)},
+			expected: SkipNotSupported,
+		},
+		{
+			name:     "Blank INT32 reported as INT64",
+			value:    dcgm.FieldValue_v1{FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(dcgm.DCGM_FT_INT32_BLANK)},
+			expected: SkipBlank,
+		},
+		{
+			name:     "Not supported INT32 reported as INT64",
+			value:    dcgm.FieldValue_v1{FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(dcgm.DCGM_FT_INT32_NOT_SUPPORTED)},
+			expected: SkipNotSupported,
+		},
+		{
+			name:     "Blank DOUBLE",
+			value:    dcgm.FieldValue_v1{FieldType: dcgm.DCGM_FT_DOUBLE, Value: float64Value(dcgm.DCGM_FT_FP64_BLANK)},
+			expected: SkipBlank,
+		},
+		{
+			name:     "Not supported DOUBLE",
+			value:    dcgm.FieldValue_v1{FieldType: dcgm.DCGM_FT_DOUBLE, Value: float64Value(dcgm.DCGM_FT_FP64_NOT_SUPPORTED)},
+			expected: SkipNotSupported,
+		},
+		{
+			name:     "Blank STRING",
+			value:    dcgm.FieldValue_v1{FieldType: dcgm.DCGM_FT_STRING, Value: stringValue(dcgm.DCGM_FT_STR_BLANK)},
+			expected: SkipBlank,
+		},
+		{
+			name:     "Not supported STRING",
+			value:    dcgm.FieldValue_v1{FieldType: dcgm.DCGM_FT_STRING, Value: stringValue(dcgm.DCGM_FT_STR_NOT_SUPPORTED)},
+			expected: SkipNotSupported,
+		},
+		{
+			name:     "Not permissioned INT64",
+			value:    dcgm.FieldValue_v1{FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(dcgm.DCGM_FT_INT64_NOT_PERMISSIONED)},
+			expected: SkipNotPermissioned,
+		},
+		{
+			name:     "Value",
+			value:    dcgm.FieldValue_v1{FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(42)},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typed := ToTypedValue(tt.value)
+			assert.Equal(t, tt.expected != "", typed.Skip)
+			assert.Equal(t, tt.expected, typed.SkipReason)
+		})
+	}
+}
+
+func mustParseFloat(t *testing.T, s string) float64 {
+	t.Helper()
+	f, err := strconv.ParseFloat(s, 64)
+	require.NoError(t, err)
+	return f
+}
+
+func TestToCPUMetric(t *testing.T) {
+	fieldValue := [4096]byte{}
+	binary.LittleEndian.PutUint64(fieldValue[:], math.Float64bits(87.5))
+
+	values := []dcgm.FieldValue_v1{
+		{
+			FieldId:   dcgm.DCGM_FI_DEV_CPU_POWER_UTIL_CURRENT,
+			FieldType: dcgm.DCGM_FT_DOUBLE,
+			Value:     fieldValue,
+		},
+		{
+			FieldId:   dcgm.DCGM_FI_DEV_CPU_UTIL_TOTAL,
+			FieldType: dcgm.DCGM_FT_DOUBLE,
+			Value:     fieldValue,
+		},
+	}
+
+	c := []Counter{
+		{
+			FieldID:   dcgm.DCGM_FI_DEV_CPU_POWER_UTIL_CURRENT,
+			FieldName: "DCGM_FI_DEV_CPU_POWER_UTIL_CURRENT",
+			PromType:  "gauge",
+			Help:      "CPU socket power draw (in W).",
+		},
+		{
+			FieldID:   dcgm.DCGM_FI_DEV_CPU_UTIL_TOTAL,
+			FieldName: "DCGM_FI_DEV_CPU_UTIL_TOTAL",
+			PromType:  "gauge",
+			Help:      "Total CPU utilization",
+		},
+	}
+
+	mi := MonitoringInfo{
+		Entity:   dcgm.GroupEntityPair{EntityGroupId: dcgm.FE_CPU, EntityId: 1},
+		ParentId: PARENT_ID_IGNORED,
+	}
+
+	metrics := make(MetricsByCounter)
+	ToCPUMetric(metrics, values, c, mi, MetricOptions{Hostname: "host"})
+
+	require.Len(t, metrics[c[0]], 1)
+	power := metrics[c[0]][0]
+	assert.Equal(t, "1", power.GPU)
+	assert.Equal(t, "87.500000", power.Value)
+	assert.Equal(t, map[string]string{unitAttribute: "watts"}, power.Attributes)
+
+	require.Len(t, metrics[c[1]], 1)
+	assert.Empty(t, metrics[c[1]][0].Attributes)
+
+	p, cleanup, err := NewMetricsPipelineWithGPUCollector(&Config{}, &DCGMCollector{})
+	require.NoError(t, err)
+	defer cleanup()
+
+	out, err := FormatMetrics(p.cpuMetricsFormat, MetricsByCounter{c[0]: metrics[c[0]]})
+	require.NoError(t, err)
+	assert.Contains(t, out, `DCGM_FI_DEV_CPU_POWER_UTIL_CURRENT{cpu="1",Hostname="host",unit="watts"} 87.500000`)
+}
+
+func TestGetMetricsServesStaleMetricsDuringReconnect(t *testing.T) {
+	fieldValue := [4096]byte{}
+	binary.LittleEndian.PutUint64(fieldValue[:], math.Float64bits(42))
+
+	counter := Counter{FieldID: dcgm.DCGM_FI_DEV_CPU_UTIL_TOTAL, FieldName: "DCGM_FI_DEV_CPU_UTIL_TOTAL", PromType: "gauge", Help: "Total CPU utilization"}
+
+	collector := &DCGMCollector{
+		Counters:     []Counter{counter},
+		DeviceFields: []dcgm.Short{counter.FieldID},
+		SysInfo: SystemInfo{
+			InfoType: dcgm.FE_CPU,
+			CPUs:     []CPUInfo{{EntityId: 0}},
+			cOpt:     DeviceOptions{Flex: true},
+		},
+		MetricOptions:      MetricOptions{Hostname: "host"},
+		StaleMetricsMaxAge: time.Minute,
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+
+	dcgmEntityGetLatestValues = func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		return []dcgm.FieldValue_v1{{FieldId: uint(counter.FieldID), FieldType: dcgm.DCGM_FT_DOUBLE, Value: fieldValue}}, nil
+	}
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+	require.Len(t, metrics[counter], 1)
+	assert.Empty(t, metrics[counter][0].Attributes)
+
+	dcgmEntityGetLatestValues = func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		return nil, &dcgm.DcgmError{Code: dcgm.DCGM_ST_CONNECTION_NOT_VALID}
+	}
+
+	stale, err := collector.GetMetrics()
+	require.NoError(t, err)
+	require.Len(t, stale[counter], 1)
+	assert.Equal(t, "42.000000", stale[counter][0].Value)
+	assert.Equal(t, map[string]string{staleAttribute: "true"}, stale[counter][0].Attributes)
+	assert.Empty(t, metrics[counter][0].Attributes, "the retained snapshot must not be modified")
+}
+
+func TestStaleMetrics(t *testing.T) {
+	counter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "Temperature Help info"}
+	lastMetrics := MetricsByCounter{counter: {{Counter: counter, Value: "42", Attributes: map[string]string{"unit": "celsius"}}}}
+
+	tests := []struct {
+		name      string
+		maxAge    time.Duration
+		collected time.Time
+		metrics   MetricsByCounter
+		wantOK    bool
+	}{
+		{
+			name:      "within the age cap",
+			maxAge:    time.Minute,
+			collected: time.Now().Add(-30 * time.Second),
+			metrics:   lastMetrics,
+			wantOK:    true,
+		},
+		{
+			name:      "older than the age cap",
+			maxAge:    time.Minute,
+			collected: time.Now().Add(-2 * time.Minute),
+			metrics:   lastMetrics,
+		},
+		{
+			name:      "disabled",
+			collected: time.Now(),
+			metrics:   lastMetrics,
+		},
+		{
+			name:      "nothing collected yet",
+			maxAge:    time.Minute,
+			collected: time.Now(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &DCGMCollector{
+				StaleMetricsMaxAge: tt.maxAge,
+				lastMetrics:        tt.metrics,
+				lastMetricsAt:      tt.collected,
+			}
+
+			stale, ok := collector.staleMetrics()
+			require.Equal(t, tt.wantOK, ok)
+			if !tt.wantOK {
+				return
+			}
+
+			require.Len(t, stale[counter], 1)
+			assert.Equal(t, map[string]string{"unit": "celsius", staleAttribute: "true"}, stale[counter][0].Attributes)
+		})
+	}
+}
+
+func TestGetMetricsCollectsEntitiesInStableOrder(t *testing.T) {
+	util := Counter{FieldID: dcgm.DCGM_FI_DEV_CPU_UTIL_TOTAL, FieldName: "DCGM_FI_DEV_CPU_UTIL_TOTAL", PromType: "gauge", Help: "Total CPU utilization"}
+
+	collector := &DCGMCollector{
+		Counters:     []Counter{util},
+		DeviceFields: []dcgm.Short{util.FieldID},
+		SysInfo: SystemInfo{
+			InfoType: dcgm.FE_CPU_CORE,
+			CPUs:     []CPUInfo{{EntityId: 1, Cores: []uint{3, 2}}, {EntityId: 0, Cores: []uint{1, 0}}},
+			cOpt:     DeviceOptions{Flex: true},
+		},
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+
+	var collected []uint
+	dcgmEntityGetLatestValues = func(_ dcgm.Field_Entity_Group, core uint, _ []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		collected = append(collected, core)
+		return nil, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		collected = nil
+		_, err := collector.GetMetrics()
+		require.NoError(t, err)
+		assert.Equal(t, []uint{0, 1, 2, 3}, collected)
+	}
+}
+
+func TestToLinkStateMetric(t *testing.T) {
+	links := []dcgm.NvLinkStatus{
+		{ParentId: 0, ParentType: dcgm.FE_SWITCH, State: dcgm.LS_UP, Index: 0},
+		{ParentId: 0, ParentType: dcgm.FE_SWITCH, State: dcgm.LS_DOWN, Index: 1},
+		{ParentId: 0, ParentType: dcgm.FE_SWITCH, State: dcgm.LS_DISABLED, Index: 2},
+		{ParentId: 0, ParentType: dcgm.FE_SWITCH, State: dcgm.LS_NOT_SUPPORTED, Index: 3},
+		{ParentId: 0, ParentType: dcgm.FE_GPU, State: dcgm.LS_DOWN, Index: 0},
+		{ParentId: 7, ParentType: dcgm.FE_SWITCH, State: dcgm.LS_DOWN, Index: 0},
+	}
+
+	sysInfo := SystemInfo{
+		InfoType: dcgm.FE_LINK,
+		Switches: []SwitchInfo{{EntityId: 0, NvLinks: links[:4]}},
+		sOpt:     DeviceOptions{Flex: true},
+	}
+
+	metrics := make(MetricsByCounter)
+	ToLinkStateMetric(metrics, links, sysInfo, "testhost")
+
+	require.Len(t, metrics[linkStateCounter], 3, "unsupported links, GPU links and unknown switches are skipped")
+
+	states := map[string]string{}
+	for _, m := range metrics[linkStateCounter] {
+		assert.Equal(t, "nvswitch0", m.GPUDevice)
+		assert.Equal(t, "testhost", m.Hostname)
+		states[m.GPU+"/"+m.Attributes[linkStateAttribute]] = m.Value
+	}
+	assert.Equal(t, map[string]string{"0/up": "1", "1/down": "0", "2/disabled": "0"}, states)
+}
+
+func TestGetMetricsExportsLinkStates(t *testing.T) {
+	flitErrors := Counter{FieldID: dcgm.DCGM_FI_DEV_NVSWITCH_LINK_FLIT_ERRORS, FieldName: "DCGM_FI_DEV_NVSWITCH_LINK_FLIT_ERRORS", PromType: "gauge", Help: "per-link flit errors"}
+
+	links := []dcgm.NvLinkStatus{
+		{ParentId: 0, ParentType: dcgm.FE_SWITCH, State: dcgm.LS_UP, Index: 0},
+		{ParentId: 0, ParentType: dcgm.FE_SWITCH, State: dcgm.LS_UP, Index: 1},
+	}
+
+	collector := &DCGMCollector{
+		Counters:     []Counter{flitErrors},
+		DeviceFields: []dcgm.Short{flitErrors.FieldID},
+		SysInfo: SystemInfo{
+			InfoType: dcgm.FE_LINK,
+			Switches: []SwitchInfo{{EntityId: 0, NvLinks: links}},
+			sOpt:     DeviceOptions{Flex: true},
+		},
+	}
+
+	getLatestValues, getLinkStatus := dcgmLinkGetLatestValues, dcgmGetNvLinkLinkStatus
+	t.Cleanup(func() {
+		dcgmLinkGetLatestValues, dcgmGetNvLinkLinkStatus = getLatestValues, getLinkStatus
+	})
+	dcgmLinkGetLatestValues = func(uint, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		return []dcgm.FieldValue_v1{{FieldId: uint(flitErrors.FieldID), FieldType: dcgm.DCGM_FT_INT64, Value: [4096]byte{0}}}, nil
+	}
+	dcgmGetNvLinkLinkStatus = func() ([]dcgm.NvLinkStatus, error) {
+		// Link 1 went down after the system info was read
+		return []dcgm.NvLinkStatus{links[0], {ParentId: 0, ParentType: dcgm.FE_SWITCH, State: dcgm.LS_DOWN, Index: 1}}, nil
+	}
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+	require.Len(t, metrics[linkStateCounter], 2)
+	assert.Equal(t, "1", metrics[linkStateCounter][0].Value)
+	assert.Equal(t, "0", metrics[linkStateCounter][1].Value)
+	assert.Equal(t, "down", metrics[linkStateCounter][1].Attributes[linkStateAttribute])
+}
+
+func TestGetMetricsSMOccupancyPerGPUInstance(t *testing.T) {
+	occupancy := Counter{FieldID: dcgm.DCGM_FI_PROF_SM_OCCUPANCY, FieldName: "DCGM_FI_PROF_SM_OCCUPANCY", PromType: "gauge", Help: "The ratio of number of warps resident on an SM (in %)."}
+
+	sysInfo := SystemInfo{
+		GPUCount: 2,
+		InfoType: dcgm.FE_GPU,
+		gOpt:     DeviceOptions{Flex: true},
+	}
+	sysInfo.GPUs[0] = GPUInfo{
+		DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-0"},
+		MigEnabled: true,
+		GPUInstances: []GPUInstanceInfo{
+			{EntityId: 10, ProfileName: "3g.40gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 1}},
+			{EntityId: 11, ProfileName: "3g.40gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 2}},
+		},
+	}
+	sysInfo.GPUs[1] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 1, UUID: "GPU-1"}}
+
+	collector := &DCGMCollector{
+		Counters:     []Counter{occupancy},
+		DeviceFields: []dcgm.Short{occupancy.FieldID},
+		SysInfo:      sysInfo,
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+
+	occupancies := map[dcgm.GroupEntityPair]float64{
+		{EntityGroupId: dcgm.FE_GPU_I, EntityId: 10}: 0.25,
+		{EntityGroupId: dcgm.FE_GPU_I, EntityId: 11}: 0.5,
+		{EntityGroupId: dcgm.FE_GPU, EntityId: 1}:    0.75,
+	}
+	dcgmEntityGetLatestValues = func(group dcgm.Field_Entity_Group, id uint, _ []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], math.Float64bits(occupancies[dcgm.GroupEntityPair{EntityGroupId: group, EntityId: id}]))
+		return []dcgm.FieldValue_v1{{FieldId: uint(occupancy.FieldID), FieldType: dcgm.DCGM_FT_DOUBLE, Value: value}}, nil
+	}
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+	require.Len(t, metrics[occupancy], 3)
+
+	values := map[string]string{}
+	for _, m := range metrics[occupancy] {
+		values[m.GPU+"/"+m.GPUInstanceID] = m.Value
+	}
+	assert.Equal(t, map[string]string{"0/1": "0.250000", "0/2": "0.500000", "1/": "0.750000"}, values)
+}
+
+func TestGetMetricsExportsDuplicateFieldsUnderEveryName(t *testing.T) {
+	clockCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_SM_CLOCK, FieldName: "DCGM_FI_DEV_SM_CLOCK", PromType: "gauge", Help: "SM clock frequency (in MHz)."}
+	oldClockCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_SM_CLOCK, FieldName: "dcgm_sm_clock", PromType: "gauge", Help: "SM clock frequency (in MHz)."}
+
+	sysInfo := SystemInfo{GPUCount: 1, InfoType: dcgm.FE_GPU, gOpt: DeviceOptions{Flex: true}}
+	sysInfo.GPUs[0] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-0"}}
+
+	collector := &DCGMCollector{
+		Counters:     []Counter{clockCounter, oldClockCounter},
+		DeviceFields: []dcgm.Short{clockCounter.FieldID},
+		SysInfo:      sysInfo,
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+	dcgmEntityGetLatestValues = func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], 1410)
+		return []dcgm.FieldValue_v1{{FieldId: uint(clockCounter.FieldID), FieldType: dcgm.DCGM_FT_INT64, Value: value}}, nil
+	}
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+
+	require.Len(t, metrics[clockCounter], 1)
+	require.Len(t, metrics[oldClockCounter], 1)
+	assert.Equal(t, "1410", metrics[oldClockCounter][0].Value)
+	assert.Equal(t, oldClockCounter, metrics[oldClockCounter][0].Counter)
+	assert.Equal(t, "0", metrics[oldClockCounter][0].GPU)
+}
+
+func TestGetMetricsMigModePerGPU(t *testing.T) {
+	migMode := Counter{FieldID: dcgm.DCGM_FI_DEV_MIG_MODE, FieldName: "DCGM_FI_DEV_MIG_MODE", PromType: "gauge", Help: "MIG mode of the GPU (1 if enabled)."}
+
+	sysInfo := SystemInfo{
+		GPUCount: 2,
+		InfoType: dcgm.FE_GPU,
+		gOpt:     DeviceOptions{Flex: true},
+	}
+	sysInfo.GPUs[0] = GPUInfo{
+		DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-0"},
+		MigEnabled: true,
+		GPUInstances: []GPUInstanceInfo{
+			{EntityId: 10, ProfileName: "3g.40gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 1}},
+			{EntityId: 11, ProfileName: "3g.40gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 2}},
+		},
+	}
+	sysInfo.GPUs[1] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 1, UUID: "GPU-1"}}
+
+	collector := &DCGMCollector{
+		Counters:     []Counter{migMode},
+		DeviceFields: []dcgm.Short{migMode.FieldID},
+		SysInfo:      sysInfo,
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+	defer func(entitiesGetLatestValues func([]dcgm.GroupEntityPair, []dcgm.Short, uint) ([]dcgm.FieldValue_v2, error)) {
+		dcgmEntitiesGetLatestValues = entitiesGetLatestValues
+	}(dcgmEntitiesGetLatestValues)
+
+	// The GPU instances do not have a MIG mode of their own
+	dcgmEntityGetLatestValues = func(group dcgm.Field_Entity_Group, _ uint, _ []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		value := [4096]byte{}
+		if group == dcgm.FE_GPU_I {
+			binary.LittleEndian.PutUint64(value[:], uint64(dcgm.DCGM_FT_INT64_BLANK))
+		}
+		return []dcgm.FieldValue_v1{{FieldId: uint(migMode.FieldID), FieldType: dcgm.DCGM_FT_INT64, Value: value}}, nil
+	}
+
+	var fetched []dcgm.GroupEntityPair
+	dcgmEntitiesGetLatestValues = func(entities []dcgm.GroupEntityPair, _ []dcgm.Short, _ uint) ([]dcgm.FieldValue_v2, error) {
+		fetched = entities
+		var values []dcgm.FieldValue_v2
+		for _, entity := range entities {
+			value := [4096]byte{}
+			binary.LittleEndian.PutUint64(value[:], 1)
+			values = append(values, dcgm.FieldValue_v2{
+				EntityGroupId: entity.EntityGroupId,
+				EntityId:      entity.EntityId,
+				FieldId:       uint(migMode.FieldID),
+				FieldType:     dcgm.DCGM_FT_INT64,
+				Value:         value,
+			})
+		}
+		return values, nil
+	}
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+
+	assert.Equal(t, []dcgm.GroupEntityPair{{EntityGroupId: dcgm.FE_GPU, EntityId: 0}}, fetched,
+		"the mode is fetched for the MIG-enabled GPU only")
+	require.Len(t, metrics[migMode], 2)
+
+	values := map[string]string{}
+	for _, m := range metrics[migMode] {
+		assert.Empty(t, m.GPUInstanceID)
+		assert.Empty(t, m.MigProfile)
+		values[m.GPUUUID] = m.Value
+	}
+	assert.Equal(t, map[string]string{"GPU-0": "1", "GPU-1": "0"}, values)
+}
+
+func TestShouldMonitorDeviceType(t *testing.T) {
+	fieldGetById := dcgmFieldGetById
+	defer func() {
+		dcgmFieldGetById = fieldGetById
+	}()
+
+	entityLevels := map[dcgm.Short]dcgm.Field_Entity_Group{
+		dcgm.DCGM_FI_DRIVER_VERSION:                dcgm.FE_NONE,
+		dcgm.DCGM_FI_DEV_GPU_TEMP:                  dcgm.FE_GPU,
+		dcgm.DCGM_FI_PROF_GR_ENGINE_ACTIVE:         dcgm.FE_GPU_I,
+		dcgm.DCGM_FI_DEV_NVSWITCH_LINK_FLIT_ERRORS: dcgm.FE_LINK,
+	}
+	dcgmFieldGetById = func(fieldID dcgm.Short) dcgm.FieldMeta {
+		return dcgm.FieldMeta{FieldId: fieldID, EntityLevel: entityLevels[fieldID]}
+	}
+
+	tests := []struct {
+		name       string
+		fields     []dcgm.Short
+		entityType dcgm.Field_Entity_Group
+		monitor    bool
+		reason     string
+	}{
+		{
+			name:       "No fields",
+			entityType: dcgm.FE_GPU,
+			reason:     "no fields to watch",
+		},
+		{
+			name:       "Driver version only",
+			fields:     []dcgm.Short{dcgm.DCGM_FI_DRIVER_VERSION},
+			entityType: dcgm.FE_GPU,
+			reason:     "only fields without an entity, like the driver version, to watch",
+		},
+		{
+			name:       "GPU fields for GPUs",
+			fields:     []dcgm.Short{dcgm.DCGM_FI_DRIVER_VERSION, dcgm.DCGM_FI_DEV_GPU_TEMP},
+			entityType: dcgm.FE_GPU,
+			monitor:    true,
+		},
+		{
+			name:       "GPU instance fields for GPUs",
+			fields:     []dcgm.Short{dcgm.DCGM_FI_PROF_GR_ENGINE_ACTIVE},
+			entityType: dcgm.FE_GPU,
+			monitor:    true,
+		},
+		{
+			name:       "GPU fields for switches",
+			fields:     []dcgm.Short{dcgm.DCGM_FI_DRIVER_VERSION, dcgm.DCGM_FI_DEV_GPU_TEMP},
+			entityType: dcgm.FE_SWITCH,
+			reason:     "no fields of the NvSwitch entity group to watch",
+		},
+		{
+			name:       "Link fields for links",
+			fields:     []dcgm.Short{dcgm.DCGM_FI_DEV_NVSWITCH_LINK_FLIT_ERRORS},
+			entityType: dcgm.FE_LINK,
+			monitor:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := ShouldMonitorDeviceType(tt.fields, tt.entityType)
+			assert.Equal(t, tt.monitor, decision.Monitor)
+			assert.Equal(t, tt.reason, decision.Reason)
+		})
+	}
+}
+
+func TestNewDCGMCollectorPreloadsCache(t *testing.T) {
+	tempCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+
+	sysInfo := SystemInfo{GPUCount: 1, InfoType: dcgm.FE_GPU, gOpt: DeviceOptions{Flex: true}}
+	sysInfo.GPUs[0] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-a100"}}
+
+	item := FieldEntityGroupTypeSystemInfoItem{
+		SystemInfo:   sysInfo,
+		DeviceFields: []dcgm.Short{tempCounter.FieldID},
+	}
+
+	defer func(setupFieldsWatch func([]dcgm.Short, SystemInfo, int64) ([]func(), []dcgm.Short, error)) {
+		setupDcgmFieldsWatch = setupFieldsWatch
+	}(setupDcgmFieldsWatch)
+	setupDcgmFieldsWatch = func([]dcgm.Short, SystemInfo, int64) ([]func(), []dcgm.Short, error) {
+		return nil, nil, nil
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+	dcgmEntityGetLatestValues = func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], 42)
+		return []dcgm.FieldValue_v1{{FieldId: uint(tempCounter.FieldID), FieldType: dcgm.DCGM_FT_INT64, Value: value}}, nil
+	}
+
+	defer func(getComputeCapability func(string) (string, error)) {
+		nvmlGetComputeCapabilityByUUIDHook = getComputeCapability
+	}(nvmlGetComputeCapabilityByUUIDHook)
+	queries := 0
+	nvmlGetComputeCapabilityByUUIDHook = func(string) (string, error) {
+		queries++
+		return "8.0", nil
+	}
+
+	for _, preload := range []bool{false, true} {
+		queries = 0
+		config := &Config{ComputeCapabilityLabel: true, PreloadCache: preload}
+
+		collector, cleanup, err := NewDCGMCollector([]Counter{tempCounter}, "", config, item)
+		require.NoError(t, err)
+
+		if preload {
+			assert.Equal(t, map[string]string{"GPU-a100": "8.0"}, collector.computeCapabilities,
+				"the cache is populated before the first GetMetrics")
+		} else {
+			assert.Empty(t, collector.computeCapabilities)
+		}
+
+		metrics, err := collector.GetMetrics()
+		require.NoError(t, err)
+		require.Len(t, metrics[tempCounter], 1)
+		assert.Equal(t, "8.0", metrics[tempCounter][0].Labels[computeCapabilityLabel])
+		assert.Equal(t, 1, queries, "the capability is queried once")
+
+		cleanup()
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// visibleDevicesRemap remaps the GPU indexes to the order of the GPUs in NVIDIA_VISIBLE_DEVICES
+	visibleDevicesRemap = "visible-devices"
+	visibleDevicesEnv   = "NVIDIA_VISIBLE_DEVICES"
+
+	dcgmGPUAttribute = "dcgm_gpu"
+)
+
+// gpuIndexRemapOrder returns the GPUs, DCGM indexes or UUIDs, in the order they are visible to the applications.
+// The order is either given by remap as a comma separated list, or read from NVIDIA_VISIBLE_DEVICES when remap is
+// visible-devices. No GPUs are returned when all GPUs are visible in the DCGM order.
+func gpuIndexRemapOrder(remap string) []string {
+	if remap == visibleDevicesRemap {
+		remap = os.Getenv(visibleDevicesEnv)
+	}
+
+	var order []string
+	for _, gpu := range strings.Split(remap, ",") {
+		gpu = strings.TrimSpace(gpu)
+		switch gpu {
+		case "", "all", "none", "void":
+			return nil
+		}
+		order = append(order, gpu)
+	}
+
+	return order
+}
+
+// remapGPUIndexes replaces the gpu label of the metrics of the GPUs in order with their index in order, and adds
+// the DCGM index of the GPUs as the dcgm_gpu label. The metrics of other GPUs are not modified.
+func remapGPUIndexes(metrics MetricsByCounter, order []string) {
+	for counter := range metrics {
+		for i, m := range metrics[counter] {
+			visible := -1
+			for index, gpu := range order {
+				if gpu == m.GPU || (m.GPUUUID != "" && gpu == m.GPUUUID) {
+					visible = index
+					break
+				}
+			}
+			if visible < 0 {
+				continue
+			}
+
+			if m.Attributes == nil {
+				metrics[counter][i].Attributes = map[string]string{}
+			}
+			metrics[counter][i].Attributes[dcgmGPUAttribute] = m.GPU
+			metrics[counter][i].GPU = strconv.Itoa(visible)
+		}
+	}
+}
+
+// newGPUIndexRemapper returns a MetricProcessor, which remaps the GPU indexes to the order of the GPUs in order.
+func newGPUIndexRemapper(order []string) MetricProcessor {
+	return func(metrics MetricsByCounter) MetricsByCounter {
+		remapGPUIndexes(metrics, order)
+		return metrics
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGPUIndexRemapOrder(t *testing.T) {
+	t.Setenv(visibleDevicesEnv, "3,GPU-1")
+
+	assert.Equal(t, []string{"3", "1"}, gpuIndexRemapOrder("3, 1"))
+	assert.Equal(t, []string{"3", "GPU-1"}, gpuIndexRemapOrder(visibleDevicesRemap))
+	assert.Empty(t, gpuIndexRemapOrder(""))
+
+	t.Setenv(visibleDevicesEnv, "all")
+	assert.Empty(t, gpuIndexRemapOrder(visibleDevicesRemap), "all GPUs are visible in the DCGM order")
+}
+
+func TestRemapGPUIndexes(t *testing.T) {
+	counter := Counter{FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge"}
+	metrics := MetricsByCounter{
+		counter: {
+			{Counter: counter, Value: "40", GPU: "3", GPUUUID: "GPU-3"},
+			{Counter: counter, Value: "41", GPU: "1", GPUUUID: "GPU-1", Attributes: map[string]string{"a": "b"}},
+			{Counter: counter, Value: "42", GPU: "0", GPUUUID: "GPU-0"},
+		},
+	}
+
+	metrics = newGPUIndexRemapper([]string{"3", "GPU-1"})(metrics)
+
+	remapped := metrics[counter]
+	assert.Equal(t, "0", remapped[0].GPU, "DCGM index 3 is the first visible GPU")
+	assert.Equal(t, map[string]string{dcgmGPUAttribute: "3"}, remapped[0].Attributes)
+	assert.Equal(t, "1", remapped[1].GPU)
+	assert.Equal(t, map[string]string{"a": "b", dcgmGPUAttribute: "1"}, remapped[1].Attributes)
+	assert.Equal(t, "0", remapped[2].GPU, "GPUs, which are not visible, are not remapped")
+	assert.Empty(t, remapped[2].Attributes)
+}
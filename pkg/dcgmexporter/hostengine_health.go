@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/sirupsen/logrus"
+)
+
+const dcgmExporterHostengineHealthy = "DCGM_EXPORTER_HOSTENGINE_HEALTHY"
+
+var dcgmIntrospect = dcgm.Introspect
+
+// hostengineHealthy queries the status of the hostengine; the hostengine is degraded when it fails to report it.
+func hostengineHealthy() bool {
+	_, err := dcgmIntrospect()
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to query the status of the hostengine.")
+		return false
+	}
+
+	return true
+}
+
+// formatHostengineHealth returns the DCGM_EXPORTER_HOSTENGINE_HEALTHY gauge, so that failures of the hostengine
+// can be told apart from failures of the exporter.
+func formatHostengineHealth() (string, error) {
+	value := 0
+	if hostengineHealthy() {
+		value = 1
+	}
+
+	return formatExporterGauge(dcgmExporterHostengineHealthy,
+		"1 when the DCGM hostengine reports its status, 0 when it is degraded.", value)
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatHostengineHealth(t *testing.T) {
+	introspect := dcgmIntrospect
+	defer func() {
+		dcgmIntrospect = introspect
+	}()
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "Healthy hostengine", want: "DCGM_EXPORTER_HOSTENGINE_HEALTHY 1\n"},
+		{
+			name: "Degraded hostengine",
+			err:  &dcgm.DcgmError{Code: dcgm.DCGM_ST_CONNECTION_NOT_VALID},
+			want: "DCGM_EXPORTER_HOSTENGINE_HEALTHY 0\n",
+		},
+		{
+			name: "Hostengine timing out",
+			err:  &dcgm.DcgmError{Code: dcgm.DCGM_ST_TIMEOUT},
+			want: "DCGM_EXPORTER_HOSTENGINE_HEALTHY 0\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dcgmIntrospect = func() (dcgm.DcgmStatus, error) {
+				return dcgm.DcgmStatus{Memory: 1024, CPU: 1}, tt.err
+			}
+
+			out, err := formatHostengineHealth()
+			require.NoError(t, err)
+			assert.Contains(t, out, "# TYPE DCGM_EXPORTER_HOSTENGINE_HEALTHY gauge\n")
+			assert.Contains(t, out, tt.want)
+			assert.NoError(t, validateExposition(out))
+		})
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const hostnameHeader = "X-DCGM-Hostname"
+
+var hostnameLabelPattern = regexp.MustCompile(`([{,]Hostname=")(?:[^"\\]|\\.)*"`)
+
+// hostnameOverride is the hostname, which a scrape requests in the X-DCGM-Hostname header, e.g. when a proxy
+// in front of the exporter knows the identity of the node better than the exporter. It replaces the Hostname
+// label of the metrics of the scrape, which have one. An empty override keeps the labels.
+type hostnameOverride string
+
+// newHostnameOverride returns the hostname requested by r, when the configuration allows the header.
+func newHostnameOverride(r *http.Request, config *Config) hostnameOverride {
+	if config == nil || !config.AllowHostnameHeader {
+		return ""
+	}
+
+	return hostnameOverride(labelValueEscaper.Replace(strings.TrimSpace(r.Header.Get(hostnameHeader))))
+}
+
+// overrideMetrics returns the metrics with the hostname replaced. The metrics are copied, since they may be shared
+// with other scrapes.
+func (o hostnameOverride) overrideMetrics(metrics MetricsByCounter) MetricsByCounter {
+	if o == "" {
+		return metrics
+	}
+
+	overridden := make(MetricsByCounter, len(metrics))
+	for counter, values := range metrics {
+		overriddenValues := make([]Metric, len(values))
+		for i, m := range values {
+			if m.Hostname != "" {
+				m.Hostname = string(o)
+			}
+			overriddenValues[i] = m
+		}
+		overridden[counter] = overriddenValues
+	}
+
+	return overridden
+}
+
+// overrideExposition returns the exposition with the values of the Hostname labels replaced.
+func (o hostnameOverride) overrideExposition(exposition string) string {
+	if o == "" {
+		return exposition
+	}
+
+	return hostnameLabelPattern.ReplaceAllStringFunc(exposition, func(label string) string {
+		return label[:strings.Index(label, `"`)+1] + string(o) + `"`
+	})
+}
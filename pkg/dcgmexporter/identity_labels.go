@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+const (
+	jobLabel      = "job"
+	instanceLabel = "instance"
+)
+
+// identityLabels returns the job and instance labels of the configuration, which identify the exporter when its
+// metrics are not scraped, e.g. when they are pushed to a Pushgateway or sent with remote write.
+func identityLabels(config *Config) map[string]string {
+	labels := map[string]string{}
+	if config == nil {
+		return labels
+	}
+
+	if config.JobName != "" {
+		labels[jobLabel] = config.JobName
+	}
+
+	if config.InstanceID != "" {
+		labels[instanceLabel] = config.InstanceID
+	}
+
+	return labels
+}
+
+// labelIdentity adds the identity labels to every metric.
+func labelIdentity(metrics MetricsByCounter, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+
+	for counter := range metrics {
+		for i := range metrics[counter] {
+			if metrics[counter][i].Attributes == nil {
+				metrics[counter][i].Attributes = map[string]string{}
+			}
+
+			for k, v := range labels {
+				metrics[counter][i].Attributes[k] = v
+			}
+		}
+	}
+}
+
+// newIdentityLabeler returns a MetricProcessor, which adds the identity labels to every metric.
+func newIdentityLabeler(labels map[string]string) MetricProcessor {
+	return func(metrics MetricsByCounter) MetricsByCounter {
+		labelIdentity(metrics, labels)
+		return metrics
+	}
+}
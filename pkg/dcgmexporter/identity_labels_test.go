@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityLabels(t *testing.T) {
+	assert.Empty(t, identityLabels(nil))
+	assert.Empty(t, identityLabels(&Config{}))
+	assert.Equal(t, map[string]string{jobLabel: "dcgm-exporter"}, identityLabels(&Config{JobName: "dcgm-exporter"}))
+	assert.Equal(t, map[string]string{jobLabel: "dcgm-exporter", instanceLabel: "node-1"},
+		identityLabels(&Config{JobName: "dcgm-exporter", InstanceID: "node-1"}))
+}
+
+func TestIdentityLabeler(t *testing.T) {
+	counter := Counter{FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "Temperature"}
+	metrics := MetricsByCounter{
+		counter: {
+			{Counter: counter, Value: "40", UUID: "UUID", GPU: "0", GPUUUID: "fake0", GPUDevice: "nvidia0",
+				Attributes: map[string]string{}},
+			{Counter: counter, Value: "41", UUID: "UUID", GPU: "1", GPUUUID: "fake1", GPUDevice: "nvidia1"},
+		},
+	}
+
+	config := &Config{JobName: "dcgm-exporter", InstanceID: "node-1"}
+	metrics = ProcessMetrics(metrics, newIdentityLabeler(identityLabels(config)))
+
+	out, err := FormatMetrics(template.Must(template.New("migMetrics").Parse(migMetricsFormat)), metrics)
+	require.NoError(t, err)
+	assert.Contains(t, out,
+		`DCGM_FI_DEV_GPU_TEMP{gpu="0",UUID="fake0",device="nvidia0",modelName="",instance="node-1",job="dcgm-exporter"} 40`)
+	assert.Contains(t, out,
+		`DCGM_FI_DEV_GPU_TEMP{gpu="1",UUID="fake1",device="nvidia1",modelName="",instance="node-1",job="dcgm-exporter"} 41`)
+	assert.NoError(t, validateExposition(out))
+}
+
+func TestConstantMetricsWithIdentityLabels(t *testing.T) {
+	config := &Config{
+		JobName:    "dcgm-exporter",
+		InstanceID: "node-1",
+		ConstantMetrics: []ConstantMetric{
+			{Name: "cluster_gpu_capacity", Help: constantMetricHelp, Labels: map[string]string{}, Value: 64},
+			{Name: "slo_gpu_availability_target", Help: constantMetricHelp, Labels: map[string]string{"job": "slo"}, Value: 0.999},
+		},
+	}
+
+	out, err := formatConstantMetrics(config)
+	require.NoError(t, err)
+	assert.Contains(t, out, `cluster_gpu_capacity{instance="node-1",job="dcgm-exporter"} 64`)
+	assert.Contains(t, out, `slo_gpu_availability_target{instance="node-1",job="slo"} 0.999`,
+		"the labels of a constant metric take precedence")
+}
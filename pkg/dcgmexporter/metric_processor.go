@@ -0,0 +1,30 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+// MetricProcessor transforms the metrics of one collection, e.g. to clamp or relabel them.
+// It may modify metrics in place and return them, or return new metrics.
+type MetricProcessor func(metrics MetricsByCounter) MetricsByCounter
+
+// ProcessMetrics runs the processors in order, passing the metrics returned by each processor to the next one.
+func ProcessMetrics(metrics MetricsByCounter, processors ...MetricProcessor) MetricsByCounter {
+	for _, process := range processors {
+		metrics = process(metrics)
+	}
+
+	return metrics
+}
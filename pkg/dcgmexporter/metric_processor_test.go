@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clampProcessor limits the values of the counter to max.
+func clampProcessor(fieldName string, max float64) MetricProcessor {
+	return func(metrics MetricsByCounter) MetricsByCounter {
+		for counter, values := range metrics {
+			if counter.FieldName != fieldName {
+				continue
+			}
+
+			for i := range values {
+				v, err := strconv.ParseFloat(values[i].Value, 64)
+				if err == nil && v > max {
+					values[i].Value = strconv.FormatFloat(max, 'f', 6, 64)
+				}
+			}
+		}
+
+		return metrics
+	}
+}
+
+// relabelProcessor renames the attribute from to to.
+func relabelProcessor(from, to string) MetricProcessor {
+	return func(metrics MetricsByCounter) MetricsByCounter {
+		for _, values := range metrics {
+			for i := range values {
+				if v, exists := values[i].Attributes[from]; exists {
+					delete(values[i].Attributes, from)
+					values[i].Attributes[to] = v
+				}
+			}
+		}
+
+		return metrics
+	}
+}
+
+func TestProcessMetrics(t *testing.T) {
+	util := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_UTIL, FieldName: "DCGM_FI_DEV_GPU_UTIL", PromType: "gauge", Help: "GPU utilization (in %)."}
+	temp := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+
+	metrics := MetricsByCounter{
+		util: {{Counter: util, Value: "104.000000", Attributes: map[string]string{"unit": "percent"}}},
+		temp: {{Counter: temp, Value: "104", Attributes: map[string]string{}}},
+	}
+
+	out := ProcessMetrics(metrics, clampProcessor("DCGM_FI_DEV_GPU_UTIL", 100), relabelProcessor("unit", "units"))
+
+	require.Len(t, out[util], 1)
+	assert.Equal(t, "100.000000", out[util][0].Value)
+	assert.Equal(t, map[string]string{"units": "percent"}, out[util][0].Attributes)
+
+	require.Len(t, out[temp], 1)
+	assert.Equal(t, "104", out[temp][0].Value, "other counters are not clamped")
+}
+
+func TestProcessMetricsRunsInOrder(t *testing.T) {
+	var calls []string
+	processor := func(name string) MetricProcessor {
+		return func(metrics MetricsByCounter) MetricsByCounter {
+			calls = append(calls, name)
+			return metrics
+		}
+	}
+
+	ProcessMetrics(MetricsByCounter{}, processor("first"), processor("second"))
+	assert.Equal(t, []string{"first", "second"}, calls)
+
+	metrics := MetricsByCounter{}
+	assert.Equal(t, metrics, ProcessMetrics(metrics))
+}
+
+func TestGetMetricsRunsProcessors(t *testing.T) {
+	fieldValue := [4096]byte{}
+	binary.LittleEndian.PutUint64(fieldValue[:], math.Float64bits(104))
+
+	counter := Counter{FieldID: dcgm.DCGM_FI_DEV_CPU_UTIL_TOTAL, FieldName: "DCGM_FI_DEV_CPU_UTIL_TOTAL", PromType: "gauge", Help: "Total CPU utilization"}
+
+	collector := &DCGMCollector{
+		Counters:     []Counter{counter},
+		DeviceFields: []dcgm.Short{counter.FieldID},
+		SysInfo: SystemInfo{
+			InfoType: dcgm.FE_CPU,
+			CPUs:     []CPUInfo{{EntityId: 0}},
+			cOpt:     DeviceOptions{Flex: true},
+		},
+		Processors: []MetricProcessor{clampProcessor("DCGM_FI_DEV_CPU_UTIL_TOTAL", 100)},
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+
+	dcgmEntityGetLatestValues = func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		return []dcgm.FieldValue_v1{{FieldId: uint(counter.FieldID), FieldType: dcgm.DCGM_FT_DOUBLE, Value: fieldValue}}, nil
+	}
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+	require.Len(t, metrics[counter], 1)
+	assert.Equal(t, "100.000000", metrics[counter][0].Value)
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"hash/fnv"
+	"slices"
+	"strings"
+)
+
+const dcgmExporterMetricsChecksum = "DCGM_EXPORTER_METRICS_CHECKSUM"
+
+// exposedSeries returns the sorted series of the exposition, i.e. the metric names with their labels,
+// without the values and timestamps of the samples.
+func exposedSeries(exposition string) []string {
+	var series []string
+	for _, line := range strings.Split(exposition, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.Contains(line, "{") {
+			series = append(series, line[:strings.LastIndex(line, "}")+1])
+		} else {
+			series = append(series, strings.Fields(line)[0])
+		}
+	}
+
+	slices.Sort(series)
+
+	return series
+}
+
+// metricsChecksum returns the FNV-1a checksum of the series of the exposition, which changes when a series is
+// added or removed, but not when a value changes. It is 32 bits wide, so that it is exact as a sample value.
+func metricsChecksum(exposition string) uint32 {
+	h := fnv.New32a()
+	for _, series := range exposedSeries(exposition) {
+		h.Write([]byte(series))
+		h.Write([]byte{'\n'})
+	}
+
+	return h.Sum32()
+}
+
+// formatMetricsChecksum returns the DCGM_EXPORTER_METRICS_CHECKSUM gauge of the exposition.
+func formatMetricsChecksum(exposition string) (string, error) {
+	return formatExporterGauge(dcgmExporterMetricsChecksum,
+		"Checksum of the exported series without their values, which differs between exporters exporting different series.",
+		metricsChecksum(exposition))
+}
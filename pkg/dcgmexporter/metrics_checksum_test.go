@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const checksumExposition = `# HELP DCGM_FI_DEV_GPU_TEMP GPU temperature (in C).
+# TYPE DCGM_FI_DEV_GPU_TEMP gauge
+DCGM_FI_DEV_GPU_TEMP{gpu="1",UUID="GPU-1"} 40
+DCGM_FI_DEV_GPU_TEMP{gpu="0",UUID="GPU-0"} 41 1700000000000
+`
+
+func TestMetricsChecksum(t *testing.T) {
+	assert.Equal(t, []string{
+		`DCGM_FI_DEV_GPU_TEMP{gpu="0",UUID="GPU-0"}`,
+		`DCGM_FI_DEV_GPU_TEMP{gpu="1",UUID="GPU-1"}`,
+	}, exposedSeries(checksumExposition))
+
+	checksum := metricsChecksum(checksumExposition)
+
+	assert.Equal(t, checksum, metricsChecksum(`DCGM_FI_DEV_GPU_TEMP{gpu="0",UUID="GPU-0"} 60
+DCGM_FI_DEV_GPU_TEMP{gpu="1",UUID="GPU-1"} 61
+`), "the values and the order of the series do not change the checksum")
+
+	assert.NotEqual(t, checksum, metricsChecksum(checksumExposition+`# HELP DCGM_FI_DEV_POWER_USAGE Power draw (in W).
+# TYPE DCGM_FI_DEV_POWER_USAGE gauge
+DCGM_FI_DEV_POWER_USAGE{gpu="0",UUID="GPU-0"} 300
+`), "adding a counter changes the checksum")
+
+	assert.NotEqual(t, checksum, metricsChecksum(`DCGM_FI_DEV_GPU_TEMP{gpu="0",UUID="GPU-0"} 41
+DCGM_FI_DEV_GPU_TEMP{gpu="2",UUID="GPU-2"} 40
+`), "changing a label changes the checksum")
+}
+
+func TestMetricsServer_MetricsWithChecksum(t *testing.T) {
+	collector := new(mockCollector)
+	collector.On("GetMetrics").Return(MetricsByCounter{}, nil)
+
+	reg := NewRegistry()
+	reg.Register(collector)
+
+	scrape := func(config *Config) string {
+		server := &MetricsServer{registry: reg, config: config, metrics: checksumExposition}
+		recorder := httptest.NewRecorder()
+		server.Metrics(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		return recorder.Body.String()
+	}
+
+	out := scrape(&Config{MetricsChecksum: true})
+	assert.Contains(t, out, "# TYPE DCGM_EXPORTER_METRICS_CHECKSUM gauge\n")
+	assert.Regexp(t, `(?m)^DCGM_EXPORTER_METRICS_CHECKSUM [0-9]+$`, out)
+
+	assert.NotContains(t, scrape(&Config{}), dcgmExporterMetricsChecksum, "the checksum is opt-in")
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const diffThresholdQueryParam = "threshold"
+
+// MetricsSnapshots retains the metrics of the last two collections, so that they can be compared.
+type MetricsSnapshots struct {
+	mtx      sync.Mutex
+	previous MetricsByCounter
+	current  MetricsByCounter
+}
+
+func NewMetricsSnapshots() *MetricsSnapshots {
+	return &MetricsSnapshots{}
+}
+
+// Record makes metrics the current snapshot; the current snapshot becomes the previous one.
+func (s *MetricsSnapshots) Record(metrics MetricsByCounter) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.previous, s.current = s.current, metrics
+}
+
+// mergeSnapshot adds the metrics of a collector to the snapshot of a collection.
+func mergeSnapshot(snapshot, metrics MetricsByCounter) {
+	for counter, ms := range metrics {
+		snapshot[counter] = append(snapshot[counter], ms...)
+	}
+}
+
+// seriesDiff is the change of a series between two snapshots; an empty value means the series was missing.
+type seriesDiff struct {
+	series   string
+	previous string
+	current  string
+}
+
+// diff returns the series, which were added, removed, or whose value changed by more than threshold between
+// the last two snapshots. Values, which are not numbers, change when they differ at all.
+func (s *MetricsSnapshots) diff(threshold float64) []seriesDiff {
+	s.mtx.Lock()
+	previous, current := seriesValues(s.previous), seriesValues(s.current)
+	s.mtx.Unlock()
+
+	var diffs []seriesDiff
+	for series, value := range current {
+		previousValue, exists := previous[series]
+		if !exists || valueChanged(previousValue, value, threshold) {
+			diffs = append(diffs, seriesDiff{series: series, previous: previousValue, current: value})
+		}
+	}
+	for series, value := range previous {
+		if _, exists := current[series]; !exists {
+			diffs = append(diffs, seriesDiff{series: series, previous: value})
+		}
+	}
+
+	slices.SortFunc(diffs, func(a, b seriesDiff) int {
+		return strings.Compare(a.series, b.series)
+	})
+
+	return diffs
+}
+
+// encodeDiff writes a line per changed series: "+" for added, "-" for removed and "~" for changed series.
+func (s *MetricsSnapshots) encodeDiff(w io.Writer, threshold float64) error {
+	for _, d := range s.diff(threshold) {
+		var err error
+		switch {
+		case d.previous == "":
+			_, err = fmt.Fprintf(w, "+ %s %s\n", d.series, d.current)
+		case d.current == "":
+			_, err = fmt.Fprintf(w, "- %s %s\n", d.series, d.previous)
+		default:
+			_, err = fmt.Fprintf(w, "~ %s %s -> %s\n", d.series, d.previous, d.current)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func valueChanged(previous, current string, threshold float64) bool {
+	p, pErr := strconv.ParseFloat(previous, 64)
+	c, cErr := strconv.ParseFloat(current, 64)
+	if pErr != nil || cErr != nil {
+		return previous != current
+	}
+
+	if math.IsNaN(p) || math.IsNaN(c) {
+		return math.IsNaN(p) != math.IsNaN(c)
+	}
+
+	return math.Abs(c-p) > threshold
+}
+
+// seriesValues returns the values of the metrics by series.
+func seriesValues(metrics MetricsByCounter) map[string]string {
+	values := map[string]string{}
+	for counter, ms := range metrics {
+		for _, m := range ms {
+			values[seriesName(counter, m)] = m.Value
+		}
+	}
+
+	return values
+}
+
+// seriesName returns the series of a metric in the exposition format, with its labels sorted by name.
+func seriesName(counter Counter, m Metric) string {
+	labels := map[string]string{"gpu": m.GPU}
+	if m.GPUInstanceID != "" {
+		labels["GPU_I_ID"] = m.GPUInstanceID
+	}
+	maps.Copy(labels, m.Labels)
+	maps.Copy(labels, m.Attributes)
+
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", k, v))
+	}
+	slices.Sort(pairs)
+
+	return fmt.Sprintf("%s{%s}", counter.FieldName, strings.Join(pairs, ","))
+}
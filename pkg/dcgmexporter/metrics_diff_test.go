@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsServer_Diff(t *testing.T) {
+	temp := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+	power := Counter{FieldID: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge", Help: "Power draw (in W)."}
+	driver := Counter{FieldID: dcgm.DCGM_FI_DRIVER_VERSION, FieldName: "DCGM_FI_DRIVER_VERSION", PromType: "label", Help: "Driver version"}
+
+	snapshots := NewMetricsSnapshots()
+	snapshots.Record(MetricsByCounter{
+		temp: {
+			{Counter: temp, Value: "42", GPU: "0"},
+			{Counter: temp, Value: "50", GPU: "1"},
+		},
+		power: {
+			{Counter: power, Value: "100.000000", GPU: "0", Attributes: map[string]string{"pod": "a"}},
+		},
+		driver: {
+			{Counter: driver, Value: "535.104.05", GPU: "0"},
+		},
+	})
+	snapshots.Record(MetricsByCounter{
+		temp: {
+			{Counter: temp, Value: "42", GPU: "0"},
+			{Counter: temp, Value: "51", GPU: "1"},
+		},
+		power: {
+			{Counter: power, Value: "100.500000", GPU: "0", Attributes: map[string]string{"pod": "b"}},
+		},
+		driver: {
+			{Counter: driver, Value: "535.104.12", GPU: "0"},
+		},
+	})
+
+	server := &MetricsServer{snapshots: snapshots}
+	diff := func(query string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.Diff(recorder, httptest.NewRequest(http.MethodGet, "/debug/diff"+query, nil))
+		return recorder
+	}
+
+	recorder := diff("")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `~ DCGM_FI_DEV_GPU_TEMP{gpu="1"} 50 -> 51
+- DCGM_FI_DEV_POWER_USAGE{gpu="0",pod="a"} 100.000000
++ DCGM_FI_DEV_POWER_USAGE{gpu="0",pod="b"} 100.500000
+~ DCGM_FI_DRIVER_VERSION{gpu="0"} 535.104.05 -> 535.104.12
+`, recorder.Body.String())
+	assert.NotContains(t, recorder.Body.String(), `gpu="0"} 42`, "unchanged series are not part of the diff")
+
+	recorder = diff("?threshold=1")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "DCGM_FI_DEV_GPU_TEMP", "changes up to the threshold are ignored")
+	assert.Contains(t, recorder.Body.String(), "DCGM_FI_DRIVER_VERSION")
+
+	for _, query := range []string{"?threshold=foo", "?threshold=-1"} {
+		assert.Equal(t, http.StatusBadRequest, diff(query).Code, query)
+	}
+}
+
+func TestMetricsServer_DiffEndpoint(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		server, cleanup, err := NewMetricsServer(&Config{EnableDebugEndpoints: enabled}, make(chan string), NewRegistry(), nil,
+			NewMetricsSnapshots())
+		require.NoError(t, err)
+		defer cleanup()
+
+		recorder := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/diff", nil))
+		if enabled {
+			assert.Equal(t, http.StatusOK, recorder.Code)
+		} else {
+			assert.Equal(t, http.StatusNotFound, recorder.Code, "the diff is not exposed by default")
+		}
+	}
+}
+
+func TestMetricsSnapshotsDiffBeforeTwoCollections(t *testing.T) {
+	temp := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+
+	snapshots := NewMetricsSnapshots()
+	assert.Empty(t, snapshots.diff(0))
+
+	snapshots.Record(MetricsByCounter{temp: {{Counter: temp, Value: "42", GPU: "0"}}})
+	assert.Equal(t, []seriesDiff{{series: `DCGM_FI_DEV_GPU_TEMP{gpu="0"}`, current: "42"}}, snapshots.diff(0))
+}
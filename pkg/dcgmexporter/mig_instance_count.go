@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+)
+
+var migInstanceCountCounter = Counter{
+	FieldName: "DCGM_EXPORTER_MIG_INSTANCE_COUNT",
+	PromType:  "gauge",
+	Help:      "Number of GPU instances of the GPU.",
+}
+
+// appendMigInstanceCount adds DCGM_EXPORTER_MIG_INSTANCE_COUNT for every monitored GPU with MIG enabled, from the
+// GPU instances in the system info, so that no DCGM query is needed.
+func (c *DCGMCollector) appendMigInstanceCount(metrics MetricsByCounter, monitoringInfo []MonitoringInfo) {
+	uuid := "UUID"
+	if c.UseOldNamespace {
+		uuid = "uuid"
+	}
+
+	counted := map[uint]bool{}
+	for _, mi := range monitoringInfo {
+		gpu := mi.DeviceInfo.GPU
+		if counted[gpu] || gpu >= c.SysInfo.GPUCount || !c.SysInfo.GPUs[gpu].MigEnabled {
+			continue
+		}
+		counted[gpu] = true
+
+		m := Metric{
+			Counter: migInstanceCountCounter,
+			Value:   fmt.Sprint(len(c.SysInfo.GPUs[gpu].GPUInstances)),
+
+			UUID:         uuid,
+			GPU:          fmt.Sprintf("%d", gpu),
+			GPUUUID:      mi.DeviceInfo.UUID,
+			GPUDevice:    fmt.Sprintf("nvidia%d", gpu),
+			GPUModelName: getGPUModel(mi.DeviceInfo, c.ReplaceBlanksInModelName),
+			Hostname:     c.Hostname,
+
+			Labels:     map[string]string{},
+			Attributes: map[string]string{},
+		}
+
+		metrics[migInstanceCountCounter] = append(metrics[migInstanceCountCounter], m)
+	}
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendMigInstanceCount(t *testing.T) {
+	sysInfo := SystemInfo{
+		GPUCount: 2,
+		InfoType: dcgm.FE_GPU,
+		gOpt:     DeviceOptions{Flex: true},
+	}
+	sysInfo.GPUs[0] = GPUInfo{
+		DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-0"},
+		MigEnabled: true,
+		GPUInstances: []GPUInstanceInfo{
+			{EntityId: 10, ProfileName: "2g.20gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 1}},
+			{EntityId: 11, ProfileName: "2g.20gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 2}},
+			{EntityId: 12, ProfileName: "3g.40gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 3}},
+		},
+	}
+	sysInfo.GPUs[1] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 1, UUID: "GPU-1"}}
+
+	collector := &DCGMCollector{SysInfo: sysInfo, MetricOptions: MetricOptions{Hostname: "node"}}
+
+	metrics := MetricsByCounter{}
+	collector.appendMigInstanceCount(metrics, GetMonitoredEntities(sysInfo))
+
+	counts := metrics[migInstanceCountCounter]
+	require.Len(t, counts, 1, "the count is exported once per GPU with MIG enabled")
+	assert.Equal(t, "3", counts[0].Value)
+	assert.Equal(t, "0", counts[0].GPU)
+	assert.Equal(t, "GPU-0", counts[0].GPUUUID)
+	assert.Equal(t, "node", counts[0].Hostname)
+	assert.Empty(t, counts[0].GPUInstanceID)
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"slices"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/sirupsen/logrus"
+)
+
+// appendMigMode exports DCGM_FI_DEV_MIG_MODE once per GPU. When the GPU instances of a GPU are monitored instead of
+// the GPU, the mode is fetched for the GPU itself, since it describes the GPU rather than its instances.
+func (c *DCGMCollector) appendMigMode(metrics MetricsByCounter, monitoringInfo []MonitoringInfo) {
+	counter, err := FindCounterField(c.Counters, dcgm.DCGM_FI_DEV_MIG_MODE)
+	if err != nil {
+		return
+	}
+
+	metrics[counter] = slices.DeleteFunc(metrics[counter], func(m Metric) bool {
+		return m.GPUInstanceID != ""
+	})
+
+	monitored := map[uint]bool{}
+	for _, mi := range monitoringInfo {
+		if mi.InstanceInfo == nil {
+			monitored[mi.DeviceInfo.GPU] = true
+		}
+	}
+
+	var entities []dcgm.GroupEntityPair
+	devices := map[uint]dcgm.Device{}
+	for _, mi := range monitoringInfo {
+		if mi.InstanceInfo == nil || monitored[mi.DeviceInfo.GPU] {
+			continue
+		}
+
+		monitored[mi.DeviceInfo.GPU] = true
+		devices[mi.DeviceInfo.GPU] = mi.DeviceInfo
+		entities = append(entities, dcgm.GroupEntityPair{EntityGroupId: dcgm.FE_GPU, EntityId: mi.DeviceInfo.GPU})
+	}
+
+	if len(entities) == 0 {
+		return
+	}
+
+	values, err := dcgmEntitiesGetLatestValues(entities, []dcgm.Short{dcgm.DCGM_FI_DEV_MIG_MODE}, dcgm.DCGM_FV_FLAG_LIVE_DATA)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to get the MIG mode of the GPUs.")
+		return
+	}
+
+	for _, v := range values {
+		if v.Status != dcgm.DCGM_ST_OK {
+			continue
+		}
+
+		ToMetric(metrics,
+			[]dcgm.FieldValue_v1{toFieldValueV1(v)},
+			[]Counter{counter},
+			devices[v.EntityId],
+			nil,
+			c.MetricOptions)
+	}
+
+	if len(metrics[counter]) == 0 {
+		delete(metrics, counter)
+	}
+}
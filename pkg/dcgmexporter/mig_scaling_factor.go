@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+var migScalingFactorCounter = Counter{
+	FieldName: "DCGM_EXPORTER_MIG_SCALING_FACTOR",
+	PromType:  "gauge",
+	Help:      "Share of the GPU value of the attributed fields, which is attributed to the GPU instance.",
+}
+
+var migIdlePowerCounter = Counter{
+	FieldName: "DCGM_EXPORTER_MIG_SCALING_FACTOR_IDLE_POWER",
+	PromType:  "gauge",
+	Help:      "Power draw attributed to the GPU instance, while its graphics engine is idle (in W).",
+}
+
+var migActivePowerCounter = Counter{
+	FieldName: "DCGM_EXPORTER_MIG_SCALING_FACTOR_ACTIVE_POWER",
+	PromType:  "gauge",
+	Help:      "Power draw attributed to the GPU instance, while its graphics engine is active (in W).",
+}
+
+// appendMigScalingFactors adds DCGM_EXPORTER_MIG_SCALING_FACTOR for every monitored GPU instance, the slices of the
+// instance divided by the slices of its GPU, by which the GPU value of the attributed fields, e.g.
+// DCGM_FI_DEV_POWER_USAGE, is scaled. It is only added when an attributed field is collected.
+func (c *DCGMCollector) appendMigScalingFactors(metrics MetricsByCounter, monitoringInfo []MonitoringInfo) {
+	attributed := slices.ContainsFunc(c.Counters, func(counter Counter) bool {
+		return aggregationRules[aggregationKey{counter.FieldID, dcgm.FE_GPU_I}] == aggregationAttributed
+	})
+	if !attributed {
+		return
+	}
+
+	uuid := "UUID"
+	if c.UseOldNamespace {
+		uuid = "uuid"
+	}
+
+	for _, mi := range monitoringInfo {
+		if mi.InstanceInfo == nil || mi.InstanceInfo.GPUSlices == 0 {
+			continue
+		}
+
+		factor := float64(mi.InstanceInfo.Info.NvmlProfileSlices) / float64(mi.InstanceInfo.GPUSlices)
+
+		m := Metric{
+			Counter: migScalingFactorCounter,
+			Value:   fmt.Sprintf("%f", factor),
+
+			UUID:          uuid,
+			GPU:           fmt.Sprintf("%d", mi.DeviceInfo.GPU),
+			GPUUUID:       mi.DeviceInfo.UUID,
+			GPUDevice:     fmt.Sprintf("nvidia%d", mi.DeviceInfo.GPU),
+			GPUModelName:  getGPUModel(mi.DeviceInfo, c.ReplaceBlanksInModelName),
+			MigProfile:    mi.InstanceInfo.ProfileName,
+			GPUInstanceID: fmt.Sprintf("%d", mi.InstanceInfo.Info.NvmlInstanceId),
+			Hostname:      c.Hostname,
+
+			Labels:     map[string]string{},
+			Attributes: map[string]string{},
+		}
+
+		metrics[migScalingFactorCounter] = append(metrics[migScalingFactorCounter], m)
+	}
+}
+
+// AppendMigPowerSplit adds DCGM_EXPORTER_MIG_SCALING_FACTOR_ACTIVE_POWER and DCGM_EXPORTER_MIG_SCALING_FACTOR_IDLE_POWER
+// for every GPU instance, the power draw attributed to the instance split by DCGM_FI_PROF_GR_ENGINE_ACTIVE of the
+// instance, so that both add up to the attributed power draw. It is only added when the power draw is attributed to
+// MIG instances and both fields are collected.
+func AppendMigPowerSplit(metrics MetricsByCounter, counters []Counter) {
+	if aggregationRules[aggregationKey{dcgm.DCGM_FI_DEV_POWER_USAGE, dcgm.FE_GPU_I}] != aggregationAttributed {
+		return
+	}
+
+	powerCounter, powerErr := FindCounterField(counters, dcgm.DCGM_FI_DEV_POWER_USAGE)
+	activeCounter, activeErr := FindCounterField(counters, dcgm.DCGM_FI_PROF_GR_ENGINE_ACTIVE)
+	if powerErr != nil || activeErr != nil {
+		return
+	}
+
+	activeByInstance := map[string]float64{}
+	for _, m := range metrics[activeCounter] {
+		if m.GPUInstanceID == "" {
+			continue
+		}
+
+		active, err := strconv.ParseFloat(m.Value, 64)
+		if err != nil {
+			continue
+		}
+		activeByInstance[derivedMetricKey(m)] = min(max(active, 0), 1)
+	}
+
+	for _, m := range metrics[powerCounter] {
+		active, exists := activeByInstance[derivedMetricKey(m)]
+		if m.GPUInstanceID == "" || !exists {
+			continue
+		}
+
+		power, err := strconv.ParseFloat(m.Value, 64)
+		if err != nil {
+			continue
+		}
+
+		appendPower := func(counter Counter, value float64) {
+			derived := m
+			derived.Counter = counter
+			derived.Value = fmt.Sprintf("%f", value)
+			derived.Attributes = maps.Clone(m.Attributes)
+
+			metrics[counter] = append(metrics[counter], derived)
+		}
+		appendPower(migActivePowerCounter, power*active)
+		appendPower(migIdlePowerCounter, power*(1-active))
+	}
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendMigScalingFactors(t *testing.T) {
+	powerCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge", Help: "Power draw (in W)."}
+	tempCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+
+	sysInfo := SystemInfo{
+		GPUCount: 1,
+		InfoType: dcgm.FE_GPU,
+		gOpt:     DeviceOptions{Flex: true},
+	}
+	sysInfo.GPUs[0] = GPUInfo{
+		DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-0"},
+		MigEnabled: true,
+		GPUInstances: []GPUInstanceInfo{
+			{EntityId: 10, ProfileName: "2g.20gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 1, NvmlProfileSlices: 2}, GPUSlices: 7},
+			{EntityId: 11, ProfileName: "3g.40gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 2, NvmlProfileSlices: 3}, GPUSlices: 7},
+		},
+	}
+	monitoringInfo := GetMonitoredEntities(sysInfo)
+
+	t.Run("When an attributed field is collected", func(t *testing.T) {
+		SetAttributedFields([]dcgm.Short{dcgm.DCGM_FI_DEV_POWER_USAGE})
+		t.Cleanup(func() {
+			SetAttributedFields(nil)
+		})
+
+		collector := &DCGMCollector{Counters: []Counter{powerCounter}, SysInfo: sysInfo, MetricOptions: MetricOptions{Hostname: "node"}}
+
+		metrics := MetricsByCounter{}
+		collector.appendMigScalingFactors(metrics, monitoringInfo)
+
+		factors := metrics[migScalingFactorCounter]
+		require.Len(t, factors, 2, "the factor is exported per GPU instance")
+
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], math.Float64bits(280))
+		values := []dcgm.FieldValue_v1{{FieldId: uint(powerCounter.FieldID), FieldType: dcgm.DCGM_FT_DOUBLE, Value: value}}
+
+		for i, factor := range factors {
+			instanceInfo := &sysInfo.GPUs[0].GPUInstances[i]
+			assert.Equal(t, "0", factor.GPU)
+			assert.Equal(t, instanceInfo.ProfileName, factor.MigProfile)
+			assert.Equal(t, "node", factor.Hostname)
+
+			power := make(MetricsByCounter)
+			ToMetric(power, values, collector.Counters, sysInfo.GPUs[0].DeviceInfo, instanceInfo, MetricOptions{})
+			require.Len(t, power[powerCounter], 1)
+			assert.Equal(t, power[powerCounter][0].GPUInstanceID, factor.GPUInstanceID)
+			assert.InDelta(t, mustParseFloat(t, power[powerCounter][0].Value), 280*mustParseFloat(t, factor.Value), 1e-3,
+				"the factor scales the GPU value to the attributed value")
+		}
+	})
+
+	t.Run("When no attributed field is collected", func(t *testing.T) {
+		collector := &DCGMCollector{Counters: []Counter{tempCounter}, SysInfo: sysInfo}
+
+		metrics := MetricsByCounter{}
+		collector.appendMigScalingFactors(metrics, monitoringInfo)
+		assert.Empty(t, metrics)
+	})
+}
+
+func TestAppendMigPowerSplit(t *testing.T) {
+	powerCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge", Help: "Power draw (in W)."}
+	activeCounter := Counter{FieldID: dcgm.DCGM_FI_PROF_GR_ENGINE_ACTIVE, FieldName: "DCGM_FI_PROF_GR_ENGINE_ACTIVE", PromType: "gauge", Help: "Ratio of time the graphics engine is active."}
+	counters := []Counter{powerCounter, activeCounter}
+
+	d := dcgm.Device{GPU: 0, UUID: "GPU-0"}
+	instances := []*GPUInstanceInfo{
+		{ProfileName: "2g.20gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 1, NvmlProfileSlices: 2}, GPUSlices: 7},
+		{ProfileName: "3g.40gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 2, NvmlProfileSlices: 3}, GPUSlices: 7},
+	}
+	activity := []float64{0.25, 0}
+
+	fieldValue := func(fieldID dcgm.Short, v float64) dcgm.FieldValue_v1 {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], math.Float64bits(v))
+		return dcgm.FieldValue_v1{FieldId: uint(fieldID), FieldType: dcgm.DCGM_FT_DOUBLE, Value: value}
+	}
+
+	collect := func() MetricsByCounter {
+		metrics := make(MetricsByCounter)
+		ToMetric(metrics, []dcgm.FieldValue_v1{fieldValue(powerCounter.FieldID, 280)}, counters, d, nil, MetricOptions{})
+		for i, instanceInfo := range instances {
+			values := []dcgm.FieldValue_v1{
+				fieldValue(powerCounter.FieldID, 280),
+				fieldValue(activeCounter.FieldID, activity[i]),
+			}
+			ToMetric(metrics, values, counters, d, instanceInfo, MetricOptions{})
+		}
+		return metrics
+	}
+
+	t.Run("When the power draw is not attributed", func(t *testing.T) {
+		metrics := collect()
+		AppendMigPowerSplit(metrics, counters)
+
+		assert.Empty(t, metrics[migActivePowerCounter])
+		assert.Empty(t, metrics[migIdlePowerCounter])
+	})
+
+	SetAttributedFields([]dcgm.Short{dcgm.DCGM_FI_DEV_POWER_USAGE})
+	t.Cleanup(func() {
+		SetAttributedFields(nil)
+	})
+
+	t.Run("When the power draw is attributed", func(t *testing.T) {
+		metrics := collect()
+		AppendMigPowerSplit(metrics, counters)
+
+		require.Len(t, metrics[migActivePowerCounter], 2, "the split is exported per GPU instance, not per GPU")
+		require.Len(t, metrics[migIdlePowerCounter], 2)
+
+		for i, instanceInfo := range instances {
+			active := metrics[migActivePowerCounter][i]
+			idle := metrics[migIdlePowerCounter][i]
+			assert.Equal(t, fmt.Sprint(instanceInfo.Info.NvmlInstanceId), active.GPUInstanceID)
+			assert.Equal(t, active.GPUInstanceID, idle.GPUInstanceID)
+			assert.Equal(t, instanceInfo.ProfileName, active.MigProfile)
+
+			attributed := 280 * float64(instanceInfo.Info.NvmlProfileSlices) / float64(instanceInfo.GPUSlices)
+			assert.InDelta(t, attributed*activity[i], mustParseFloat(t, active.Value), 1e-3)
+			assert.InDelta(t, attributed*(1-activity[i]), mustParseFloat(t, idle.Value), 1e-3)
+		}
+
+		assert.Equal(t, "20.000000", metrics[migActivePowerCounter][0].Value, "a quarter of 2 of 7 slices of 280 W")
+		assert.Equal(t, "60.000000", metrics[migIdlePowerCounter][0].Value)
+	})
+
+	t.Run("When the activity of the instances is not collected", func(t *testing.T) {
+		metrics := collect()
+		AppendMigPowerSplit(metrics, []Counter{powerCounter})
+
+		assert.Empty(t, metrics[migActivePowerCounter])
+		assert.Empty(t, metrics[migIdlePowerCounter])
+	})
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+
+	"github.com/NVIDIA/dcgm-exporter/internal/pkg/nvmlprovider"
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/sirupsen/logrus"
+)
+
+var nvmlGetDeviceUtilizationByUUIDHook = nvmlprovider.GetDeviceUtilizationByUUID
+
+const bytesPerMiB = 1024 * 1024
+
+// nvmlFallbackFields maps the fields, which are reported from NVML when DCGM has no value for them,
+// to their value in the NVML utilization of the GPU.
+var nvmlFallbackFields = map[uint]func(u *nvmlprovider.DeviceUtilization) int64{
+	dcgm.DCGM_FI_DEV_GPU_UTIL:      func(u *nvmlprovider.DeviceUtilization) int64 { return int64(u.GPU) },
+	dcgm.DCGM_FI_DEV_MEM_COPY_UTIL: func(u *nvmlprovider.DeviceUtilization) int64 { return int64(u.Memory) },
+	dcgm.DCGM_FI_DEV_FB_TOTAL:      func(u *nvmlprovider.DeviceUtilization) int64 { return int64(u.MemoryTotal / bytesPerMiB) },
+	dcgm.DCGM_FI_DEV_FB_FREE:       func(u *nvmlprovider.DeviceUtilization) int64 { return int64(u.MemoryFree / bytesPerMiB) },
+	dcgm.DCGM_FI_DEV_FB_USED:       func(u *nvmlprovider.DeviceUtilization) int64 { return int64(u.MemoryUsed / bytesPerMiB) },
+}
+
+// nvmlFallbackValues replaces the values of the fallback fields, which DCGM has no value for, e.g. because the
+// field is not supported or not permitted, by the values reported by NVML for the GPU. NVML is only queried when
+// a value is missing; the values are returned unchanged if NVML fails as well.
+func nvmlFallbackValues(uuid string, values []dcgm.FieldValue_v1) []dcgm.FieldValue_v1 {
+	var utilization *nvmlprovider.DeviceUtilization
+
+	var filled []dcgm.FieldValue_v1
+	for i, val := range values {
+		fallback, exists := nvmlFallbackFields[val.FieldId]
+		if !exists || !ToTypedValue(val).Skip {
+			continue
+		}
+
+		if utilization == nil {
+			var err error
+			utilization, err = nvmlGetDeviceUtilizationByUUIDHook(uuid)
+			if err != nil {
+				logrus.WithError(err).WithField("uuid", uuid).Debug("Failed to get the utilization from NVML.")
+				return values
+			}
+		}
+
+		if filled == nil {
+			filled = append([]dcgm.FieldValue_v1(nil), values...)
+		}
+
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], uint64(fallback(utilization)))
+		filled[i].FieldType = dcgm.DCGM_FT_INT64
+		filled[i].Value = value
+	}
+
+	if filled == nil {
+		return values
+	}
+
+	return filled
+}
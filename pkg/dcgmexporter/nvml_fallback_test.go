@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/NVIDIA/dcgm-exporter/internal/pkg/nvmlprovider"
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetricsWithNVMLFallback(t *testing.T) {
+	utilCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_UTIL, FieldName: "DCGM_FI_DEV_GPU_UTIL", PromType: "gauge", Help: "GPU utilization (in %)."}
+	fbUsedCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_FB_USED, FieldName: "DCGM_FI_DEV_FB_USED", PromType: "gauge", Help: "Framebuffer memory used (in MiB)."}
+	tempCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+	counters := []Counter{utilCounter, fbUsedCounter, tempCounter}
+
+	int64Value := func(v int64) [4096]byte {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], uint64(v))
+		return value
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+	dcgmEntityGetLatestValues = func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		return []dcgm.FieldValue_v1{
+			{FieldId: dcgm.DCGM_FI_DEV_GPU_UTIL, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(dcgm.DCGM_FT_INT32_NOT_SUPPORTED)},
+			{FieldId: dcgm.DCGM_FI_DEV_FB_USED, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(dcgm.DCGM_FT_INT64_NOT_PERMISSIONED)},
+			{FieldId: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(42)},
+		}, nil
+	}
+
+	defer func(getUtilization func(string) (*nvmlprovider.DeviceUtilization, error)) {
+		nvmlGetDeviceUtilizationByUUIDHook = getUtilization
+	}(nvmlGetDeviceUtilizationByUUIDHook)
+
+	newCollector := func(fallback bool) *DCGMCollector {
+		sysInfo := SystemInfo{GPUCount: 1, InfoType: dcgm.FE_GPU, gOpt: DeviceOptions{Flex: true}}
+		sysInfo.GPUs[0] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-0"}}
+
+		return &DCGMCollector{
+			Counters:     counters,
+			DeviceFields: []dcgm.Short{utilCounter.FieldID, fbUsedCounter.FieldID, tempCounter.FieldID},
+			SysInfo:      sysInfo,
+			NVMLFallback: fallback,
+		}
+	}
+
+	t.Run("When DCGM returns skips", func(t *testing.T) {
+		var queried []string
+		nvmlGetDeviceUtilizationByUUIDHook = func(uuid string) (*nvmlprovider.DeviceUtilization, error) {
+			queried = append(queried, uuid)
+			return &nvmlprovider.DeviceUtilization{GPU: 87, MemoryUsed: 2048 * bytesPerMiB}, nil
+		}
+
+		metrics, err := newCollector(true).GetMetrics()
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"GPU-0"}, queried, "NVML is queried once per GPU")
+		require.Len(t, metrics[utilCounter], 1)
+		assert.Equal(t, "87", metrics[utilCounter][0].Value)
+		require.Len(t, metrics[fbUsedCounter], 1)
+		assert.Equal(t, "2048", metrics[fbUsedCounter][0].Value)
+		require.Len(t, metrics[tempCounter], 1)
+		assert.Equal(t, "42", metrics[tempCounter][0].Value, "values of DCGM are kept")
+	})
+
+	t.Run("When NVML fails as well", func(t *testing.T) {
+		nvmlGetDeviceUtilizationByUUIDHook = func(string) (*nvmlprovider.DeviceUtilization, error) {
+			return nil, errors.New("NVML not available")
+		}
+
+		metrics, err := newCollector(true).GetMetrics()
+		require.NoError(t, err)
+
+		assert.Empty(t, metrics[utilCounter])
+		assert.Empty(t, metrics[fbUsedCounter])
+		assert.Len(t, metrics[tempCounter], 1)
+	})
+
+	t.Run("When the fallback is disabled", func(t *testing.T) {
+		nvmlGetDeviceUtilizationByUUIDHook = func(string) (*nvmlprovider.DeviceUtilization, error) {
+			require.Fail(t, "NVML must not be queried")
+			return nil, nil
+		}
+
+		metrics, err := newCollector(false).GetMetrics()
+		require.NoError(t, err)
+
+		assert.Empty(t, metrics[utilCounter])
+	})
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"text/template"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/sirupsen/logrus"
+)
+
+const dcgmExporterOrphanLinks = "DCGM_EXPORTER_ORPHAN_LINKS"
+
+var orphanLinksFormat = `# HELP {{ .Name }} Number of collections of NvLinks, which were skipped because their parent switch is unknown.
+# TYPE {{ .Name }} counter
+{{ .Name }} {{ .Value }}
+`
+
+var getOrphanLinksTemplate = sync.OnceValue(func() *template.Template {
+	return template.Must(template.New("orphanLinks").Parse(orphanLinksFormat))
+})
+
+// orphanLinks counts the collections of NvLinks of all DCGM collectors, which were skipped for lack of a parent.
+var orphanLinks orphanLinkCount
+
+type orphanLinkCount struct {
+	count atomic.Uint64
+}
+
+func (c *orphanLinkCount) encode(out io.Writer) error {
+	return getOrphanLinksTemplate().Execute(out, struct {
+		Name  string
+		Value uint64
+	}{
+		Name:  dcgmExporterOrphanLinks,
+		Value: c.count.Load(),
+	})
+}
+
+// loggedOrphanLinks are the links, whose missing parent was already logged
+var loggedOrphanLinks sync.Map
+
+// isOrphanLink returns whether mi is a link, whose parent is not a known switch. The values of such a link cannot be
+// retrieved, so it is skipped instead of failing the collection; orphan links are counted and logged once.
+func isOrphanLink(mi MonitoringInfo, sysInfo SystemInfo) bool {
+	if mi.Entity.EntityGroupId != dcgm.FE_LINK {
+		return false
+	}
+
+	known := slices.ContainsFunc(sysInfo.Switches, func(sw SwitchInfo) bool {
+		return sw.EntityId == mi.ParentId
+	})
+	if known {
+		return false
+	}
+
+	orphanLinks.count.Add(1)
+
+	key := fmt.Sprintf("%d-%d", mi.Entity.EntityId, mi.ParentId)
+	if _, logged := loggedOrphanLinks.LoadOrStore(key, true); !logged {
+		logrus.WithFields(logrus.Fields{
+			"link":   mi.Entity.EntityId,
+			"parent": mi.ParentId,
+		}).Warn("Skipping the NvLink, whose parent switch is unknown.")
+	}
+
+	return true
+}
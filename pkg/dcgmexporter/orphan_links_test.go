@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetricsSkipsOrphanLinks(t *testing.T) {
+	flitErrors := Counter{FieldID: dcgm.DCGM_FI_DEV_NVSWITCH_LINK_FLIT_ERRORS, FieldName: "DCGM_FI_DEV_NVSWITCH_LINK_FLIT_ERRORS", PromType: "gauge", Help: "per-link flit errors"}
+
+	links := []dcgm.NvLinkStatus{
+		{ParentId: 0, ParentType: dcgm.FE_SWITCH, State: dcgm.LS_UP, Index: 0},
+		// The parent of link 1 is not a known switch
+		{ParentId: 7, ParentType: dcgm.FE_SWITCH, State: dcgm.LS_UP, Index: 1},
+	}
+
+	collector := &DCGMCollector{
+		Counters:     []Counter{flitErrors},
+		DeviceFields: []dcgm.Short{flitErrors.FieldID},
+		SysInfo: SystemInfo{
+			InfoType: dcgm.FE_LINK,
+			Switches: []SwitchInfo{{EntityId: 0, NvLinks: links}},
+			sOpt:     DeviceOptions{Flex: true},
+		},
+	}
+
+	getLatestValues, getLinkStatus := dcgmLinkGetLatestValues, dcgmGetNvLinkLinkStatus
+	t.Cleanup(func() {
+		dcgmLinkGetLatestValues, dcgmGetNvLinkLinkStatus = getLatestValues, getLinkStatus
+	})
+	var polled []uint
+	dcgmLinkGetLatestValues = func(link uint, parent uint, _ []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		if parent != 0 {
+			return nil, &dcgm.DcgmError{Code: dcgm.DCGM_ST_BADPARAM}
+		}
+		polled = append(polled, link)
+		return []dcgm.FieldValue_v1{{FieldId: uint(flitErrors.FieldID), FieldType: dcgm.DCGM_FT_INT64, Value: [4096]byte{0}}}, nil
+	}
+	dcgmGetNvLinkLinkStatus = func() ([]dcgm.NvLinkStatus, error) {
+		return links, nil
+	}
+
+	before := orphanLinks.count.Load()
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+	assert.Equal(t, []uint{0}, polled)
+	require.Len(t, metrics[flitErrors], 1)
+	assert.Equal(t, "0", metrics[flitErrors][0].GPU)
+	assert.Equal(t, before+1, orphanLinks.count.Load())
+
+	var out bytes.Buffer
+	require.NoError(t, orphanLinks.encode(&out))
+	assert.Contains(t, out.String(), "# TYPE DCGM_EXPORTER_ORPHAN_LINKS counter")
+}
@@ -21,6 +21,8 @@ import (
 	"encoding/csv"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
@@ -62,7 +64,7 @@ func GetCounterSet(c *Config) (*CounterSet, error) {
 	if err != nil || c.ConfigMapData == undefinedConfigMapData {
 		logrus.Infof("Falling back to metric file '%s'", c.CollectorsFile)
 
-		records, err = ReadCSVFile(c.CollectorsFile)
+		records, err = ReadCounterFile(c.CollectorsFile)
 		if err != nil {
 			logrus.Errorf("Could not read metrics file '%s'; err: %v", c.CollectorsFile, err)
 			return res, err
@@ -74,9 +76,54 @@ func GetCounterSet(c *Config) (*CounterSet, error) {
 		return res, err
 	}
 
+	res.EntityGroupCounters, err = getEntityGroupCounters(c)
+	if err != nil {
+		return nil, err
+	}
+
 	return res, err
 }
 
+// ParseCounterFiles parses entries of the form <entity group>=<counters file>, e.g. switch=/etc/switch.csv.
+func ParseCounterFiles(entries []string) (map[dcgm.Field_Entity_Group]string, error) {
+	return parseEntityGroupValues(entries, "counters file")
+}
+
+// getEntityGroupCounters loads the DCGM counters of the entity groups, which have a counters file of their own.
+func getEntityGroupCounters(c *Config) (map[dcgm.Field_Entity_Group][]Counter, error) {
+	if len(c.CounterFiles) == 0 {
+		return nil, nil
+	}
+
+	counters := map[dcgm.Field_Entity_Group][]Counter{}
+	for group, filename := range c.CounterFiles {
+		records, err := ReadCounterFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the metrics file '%s' of %s; err: %w", filename, group.String(), err)
+		}
+
+		cs, err := extractCounters(records, c)
+		if err != nil {
+			return nil, fmt.Errorf("could not load the metrics file '%s' of %s; err: %w", filename, group.String(), err)
+		}
+
+		counters[group] = cs.DCGMCounters
+	}
+
+	return counters, nil
+}
+
+// ReadCounterFile reads the records of the counters file, which is a YAML file when its extension is .yaml or .yml
+// and a CSV file otherwise.
+func ReadCounterFile(filename string) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return ReadYAMLFile(filename)
+	default:
+		return ReadCSVFile(filename)
+	}
+}
+
 func ReadCSVFile(filename string) ([][]string, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -87,6 +134,7 @@ func ReadCSVFile(filename string) ([][]string, error) {
 
 	r := csv.NewReader(file)
 	r.Comment = '#'
+	r.FieldsPerRecord = -1
 	records, err := r.ReadAll()
 
 	return records, err
@@ -105,12 +153,18 @@ func extractCounters(records [][]string, c *Config) (*CounterSet, error) {
 			record[j] = strings.Trim(r, " ")
 		}
 
-		if len(record) != 3 {
+		if len(record) != 3 && len(record) != 4 {
 			return nil, fmt.Errorf("malformed CSV record; err: failed to parse line %d (`%v`), "+
-				"expected 3 fields", i,
+				"expected 3 or 4 fields", i,
 				record)
 		}
 
+		// The optional fourth field is an expression, which transforms the value of the field
+		var expr string
+		if len(record) == 4 {
+			expr = record[3]
+		}
+
 		fieldID, ok := dcgm.DCGM_FI[record[0]]
 		oldFieldID, oldOk := dcgm.OLD_DCGM_FI[record[0]]
 		if !ok && !oldOk {
@@ -119,7 +173,11 @@ func extractCounters(records [][]string, c *Config) (*CounterSet, error) {
 			if err != nil {
 				return nil, fmt.Errorf("could not find DCGM field; err: %w", err)
 			} else if expField != DCGMFIUnknown {
-				res.ExporterCounters = append(res.ExporterCounters, Counter{dcgm.Short(expField), record[0], record[1], record[2]})
+				if expr != "" {
+					return nil, fmt.Errorf("exporter metric '%s' cannot have an expression", record[0])
+				}
+				res.ExporterCounters = append(res.ExporterCounters,
+					Counter{FieldID: dcgm.Short(expField), FieldName: record[0], PromType: record[1], Help: record[2]})
 				continue
 			}
 		}
@@ -128,6 +186,16 @@ func extractCounters(records [][]string, c *Config) (*CounterSet, error) {
 			useOld = true
 		}
 
+		if expr != "" {
+			if record[1] == "label" {
+				return nil, fmt.Errorf("label '%s' cannot have an expression", record[0])
+			}
+
+			if _, err := compileValueExpr(expr); err != nil {
+				return nil, fmt.Errorf("could not parse the expression of '%s'; err: %w", record[0], err)
+			}
+		}
+
 		if !useOld {
 			if !fieldIsSupported(uint(fieldID), c) {
 				logrus.Warnf("Skipping line %d ('%s'): metric not enabled", i, record[0])
@@ -138,7 +206,8 @@ func extractCounters(records [][]string, c *Config) (*CounterSet, error) {
 				return nil, fmt.Errorf("could not find Prometheus metric type '%s'", record[1])
 			}
 
-			res.DCGMCounters = append(res.DCGMCounters, Counter{fieldID, record[0], record[1], record[2]})
+			res.DCGMCounters = append(res.DCGMCounters,
+				Counter{FieldID: fieldID, FieldName: record[0], PromType: record[1], Help: record[2], Expr: expr})
 		} else {
 			if !fieldIsSupported(uint(oldFieldID), c) {
 				logrus.Warnf("Skipping line %d ('%s'): metric not enabled", i, record[0])
@@ -149,13 +218,55 @@ func extractCounters(records [][]string, c *Config) (*CounterSet, error) {
 				return nil, fmt.Errorf("could not find Prometheus metric type '%s'", record[1])
 			}
 
-			res.DCGMCounters = append(res.DCGMCounters, Counter{oldFieldID, record[0], record[1], record[2]})
+			res.DCGMCounters = append(res.DCGMCounters,
+				Counter{FieldID: oldFieldID, FieldName: record[0], PromType: record[1], Help: record[2], Expr: expr})
 		}
 	}
 
+	counters, err := resolveDuplicateFields(res.DCGMCounters, c.DuplicateFieldPolicy)
+	if err != nil {
+		return nil, err
+	}
+	res.DCGMCounters = applyTemperatureUnit(counters, c.TemperatureUnit)
+
 	return &res, nil
 }
 
+// resolveDuplicateFields applies the policy to the field IDs, which are listed under several names.
+// A name, which is listed twice, is only kept once. The empty policy is the first policy.
+func resolveDuplicateFields(counters []Counter, policy DuplicateFieldPolicy) ([]Counter, error) {
+	var resolved []Counter
+	for _, counter := range counters {
+		i := slices.IndexFunc(resolved, func(c Counter) bool { return c.FieldID == counter.FieldID })
+		if i < 0 {
+			resolved = append(resolved, counter)
+			continue
+		}
+
+		if slices.ContainsFunc(resolved, func(c Counter) bool { return c.FieldName == counter.FieldName }) {
+			logrus.Warnf("Skipping '%s': the field is listed twice", counter.FieldName)
+			continue
+		}
+
+		switch policy {
+		case DuplicateFieldPolicyError:
+			return nil, fmt.Errorf("field ID %d is listed as both '%s' and '%s'",
+				counter.FieldID, resolved[i].FieldName, counter.FieldName)
+		case DuplicateFieldPolicyLast:
+			logrus.Warnf("Skipping '%s': field ID %d is listed again as '%s'",
+				resolved[i].FieldName, counter.FieldID, counter.FieldName)
+			resolved = append(slices.Delete(resolved, i, i+1), counter)
+		case DuplicateFieldPolicyBoth:
+			resolved = append(resolved, counter)
+		default:
+			logrus.Warnf("Skipping '%s': field ID %d is already listed as '%s'",
+				counter.FieldName, counter.FieldID, resolved[i].FieldName)
+		}
+	}
+
+	return resolved, nil
+}
+
 func fieldIsSupported(fieldID uint, c *Config) bool {
 	if fieldID < dcpFieldsStart || fieldID >= cpuFieldsStart {
 		return true
@@ -194,6 +305,7 @@ func readConfigMap(kubeClient kubernetes.Interface, c *Config) ([][]string, erro
 
 	r := csv.NewReader(strings.NewReader(cm.Data["metrics"]))
 	r.Comment = '#'
+	r.FieldsPerRecord = -1
 	records, err := r.ReadAll()
 
 	if len(records) == 0 {
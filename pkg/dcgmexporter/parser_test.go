@@ -2,9 +2,11 @@ package dcgmexporter
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
@@ -157,3 +159,128 @@ func extractCountersHelper(t *testing.T, input string, valid bool) {
 		assert.Nil(t, cc, "Expected no counters.")
 	}
 }
+
+func TestExtractCountersWithDuplicateFields(t *testing.T) {
+	records := func() [][]string {
+		return [][]string{
+			{"DCGM_FI_DEV_SM_CLOCK", "gauge", "SM clock frequency (in MHz)."},
+			{"DCGM_FI_DEV_GPU_TEMP", "gauge", "GPU temperature (in C)."},
+			{"dcgm_sm_clock", "gauge", "SM clock frequency (in MHz)."},
+			{"DCGM_FI_DEV_GPU_TEMP", "gauge", "GPU temperature (in C)."},
+		}
+	}
+
+	names := func(counters []Counter) []string {
+		var names []string
+		for _, counter := range counters {
+			names = append(names, counter.FieldName)
+		}
+		return names
+	}
+
+	tests := []struct {
+		policy   DuplicateFieldPolicy
+		expected []string
+	}{
+		{policy: "", expected: []string{"DCGM_FI_DEV_SM_CLOCK", "DCGM_FI_DEV_GPU_TEMP"}},
+		{policy: DuplicateFieldPolicyFirst, expected: []string{"DCGM_FI_DEV_SM_CLOCK", "DCGM_FI_DEV_GPU_TEMP"}},
+		{policy: DuplicateFieldPolicyLast, expected: []string{"DCGM_FI_DEV_GPU_TEMP", "dcgm_sm_clock"}},
+		{policy: DuplicateFieldPolicyBoth, expected: []string{"DCGM_FI_DEV_SM_CLOCK", "DCGM_FI_DEV_GPU_TEMP", "dcgm_sm_clock"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			cc, err := extractCounters(records(), &Config{DuplicateFieldPolicy: tt.policy})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, names(cc.DCGMCounters))
+		})
+	}
+
+	t.Run(string(DuplicateFieldPolicyError), func(t *testing.T) {
+		cc, err := extractCounters(records(), &Config{DuplicateFieldPolicy: DuplicateFieldPolicyError})
+		assert.ErrorContains(t, err, "field ID 100 is listed as both 'DCGM_FI_DEV_SM_CLOCK' and 'dcgm_sm_clock'")
+		assert.Nil(t, cc)
+	})
+
+	t.Run("When a name is listed twice", func(t *testing.T) {
+		cc, err := extractCounters(records()[1:], &Config{DuplicateFieldPolicy: DuplicateFieldPolicyError})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"DCGM_FI_DEV_GPU_TEMP", "dcgm_sm_clock"}, names(cc.DCGMCounters))
+	})
+}
+
+func TestExtractCountersWithValueExpr(t *testing.T) {
+	cs, err := extractCounters([][]string{
+		{"DCGM_FI_DEV_FB_USED", "gauge", "Framebuffer memory used (in GiB).", "value / 1024"},
+		{"DCGM_FI_DEV_GPU_TEMP", "gauge", "GPU temperature (in C)."},
+	}, &Config{})
+	require.NoError(t, err)
+	require.Len(t, cs.DCGMCounters, 2)
+	assert.Equal(t, "value / 1024", cs.DCGMCounters[0].Expr)
+	assert.Empty(t, cs.DCGMCounters[1].Expr)
+
+	_, err = extractCounters([][]string{
+		{"DCGM_FI_DEV_FB_USED", "gauge", "Framebuffer memory used (in GiB).", "value /"},
+	}, &Config{})
+	assert.ErrorContains(t, err, "DCGM_FI_DEV_FB_USED")
+
+	_, err = extractCounters([][]string{
+		{"DCGM_FI_DRIVER_VERSION", "label", "Driver Version", "value * 2"},
+	}, &Config{})
+	assert.Error(t, err)
+}
+
+func TestGetCounterSetFromYAML(t *testing.T) {
+	dir := t.TempDir()
+
+	csvFile := filepath.Join(dir, "counters.csv")
+	require.NoError(t, os.WriteFile(csvFile, []byte(`# Temperature
+DCGM_FI_DEV_GPU_TEMP, gauge, GPU temperature (in C).
+DCGM_FI_DEV_POWER_USAGE, gauge, Power draw (in W)., value / 1000
+DCGM_FI_DRIVER_VERSION, label, Driver version.
+DCGM_EXP_XID_ERRORS_COUNT, gauge, Count of XID Errors within user-specified time window.
+`), 0o600))
+
+	yamlFile := filepath.Join(dir, "counters.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte(`# Temperature
+- name: DCGM_FI_DEV_GPU_TEMP
+  type: gauge
+  help: GPU temperature (in C).
+- name: DCGM_FI_DEV_POWER_USAGE
+  type: gauge
+  help: Power draw (in W).
+  expr: value / 1000
+- name: DCGM_FI_DRIVER_VERSION
+  type: label
+  help: Driver version.
+- name: DCGM_EXP_XID_ERRORS_COUNT
+  type: gauge
+  help: Count of XID Errors within user-specified time window.
+`), 0o600))
+
+	fromCSV, err := GetCounterSet(&Config{ConfigMapData: undefinedConfigMapData, CollectorsFile: csvFile})
+	require.NoError(t, err)
+	fromYAML, err := GetCounterSet(&Config{ConfigMapData: undefinedConfigMapData, CollectorsFile: yamlFile})
+	require.NoError(t, err)
+
+	require.Len(t, fromYAML.DCGMCounters, 3)
+	require.Len(t, fromYAML.ExporterCounters, 1)
+	assert.Equal(t, fromCSV.DCGMCounters, fromYAML.DCGMCounters)
+	assert.Equal(t, fromCSV.ExporterCounters, fromYAML.ExporterCounters)
+
+	t.Run("When a counter has an unknown key", func(t *testing.T) {
+		file := filepath.Join(dir, "unknown.yml")
+		require.NoError(t, os.WriteFile(file, []byte("- name: DCGM_FI_DEV_GPU_TEMP\n  type: gauge\n  unit: C\n"), 0o600))
+
+		_, err := ReadCounterFile(file)
+		assert.ErrorContains(t, err, "malformed YAML counters file")
+	})
+
+	t.Run("When a counter has no type", func(t *testing.T) {
+		file := filepath.Join(dir, "untyped.yml")
+		require.NoError(t, os.WriteFile(file, []byte("- name: DCGM_FI_DEV_GPU_TEMP\n"), 0o600))
+
+		_, err := ReadCounterFile(file)
+		assert.ErrorContains(t, err, "name and type are required")
+	})
+}
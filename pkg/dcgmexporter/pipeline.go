@@ -47,53 +47,65 @@ func NewMetricsPipeline(config *Config,
 		err             error
 	)
 
-	if item, exists := fieldEntityGroupTypeSystemInfo.Get(dcgm.FE_GPU); exists {
-		var cleanup func()
-		gpuCollector, cleanup, err = newDCGMCollector(counters, hostname, config, item)
-		if err != nil {
-			logrus.Warn("Cannot create DCGMCollector for dcgm.FE_GPU")
+	cleanupAll := func() {
+		for _, cleanup := range cleanups {
+			cleanup()
 		}
-		cleanups = append(cleanups, cleanup)
 	}
 
-	if item, exists := fieldEntityGroupTypeSystemInfo.Get(dcgm.FE_SWITCH); exists {
-		var cleanup func()
-		switchCollector, cleanup, err = newDCGMCollector(counters, hostname, config, item)
-		if err != nil {
-			logrus.Warn("Cannot create DCGMCollector for dcgm.FE_SWITCH")
+	// newCollector creates the collector of the entity group, if the group is monitored. A group without
+	// fields to watch has nothing to collect, so that its collector is skipped rather than failing the pipeline.
+	newCollector := func(entityGroup dcgm.Field_Entity_Group) (*DCGMCollector, error) {
+		item, exists := fieldEntityGroupTypeSystemInfo.Get(entityGroup)
+		if !exists {
+			return nil, nil
 		}
-		cleanups = append(cleanups, cleanup)
-	}
 
-	if item, exists := fieldEntityGroupTypeSystemInfo.Get(dcgm.FE_LINK); exists {
-		var cleanup func()
-		linkCollector, cleanup, err = newDCGMCollector(counters, hostname, config, item)
+		collector, cleanup, err := newDCGMCollector(item.countersOr(counters), hostname, config, item)
+		cleanups = append(cleanups, cleanup)
 		if err != nil {
-			logrus.Warn("Cannot create DCGMCollector for dcgm.FE_LINK")
+			if item.isEmpty() {
+				logrus.WithError(err).Warnf("Cannot create DCGMCollector for %s", entityGroup)
+				return nil, nil
+			}
+			return nil, fmt.Errorf("cannot create DCGMCollector for %s; err: %w", entityGroup, err)
 		}
-		cleanups = append(cleanups, cleanup)
+
+		return collector, nil
 	}
 
-	if item, exists := fieldEntityGroupTypeSystemInfo.Get(dcgm.FE_CPU); exists {
-		var cleanup func()
-		cpuCollector, cleanup, err = newDCGMCollector(counters, hostname, config, item)
-		if err != nil {
-			logrus.Warn("Cannot create DCGMCollector for dcgm.FE_CPU")
-		}
-		cleanups = append(cleanups, cleanup)
+	if gpuCollector, err = newCollector(dcgm.FE_GPU); err != nil {
+		cleanupAll()
+		return nil, func() {}, err
 	}
 
-	if item, exists := fieldEntityGroupTypeSystemInfo.Get(dcgm.FE_CPU_CORE); exists {
-		var cleanup func()
-		coreCollector, cleanup, err = newDCGMCollector(counters, hostname, config, item)
-		if err != nil {
-			logrus.Warn("Cannot create DCGMCollector for dcgm.FE_CPU_CORE")
-		}
-		cleanups = append(cleanups, cleanup)
+	if switchCollector, err = newCollector(dcgm.FE_SWITCH); err != nil {
+		cleanupAll()
+		return nil, func() {}, err
+	}
+
+	if linkCollector, err = newCollector(dcgm.FE_LINK); err != nil {
+		cleanupAll()
+		return nil, func() {}, err
+	}
+
+	if cpuCollector, err = newCollector(dcgm.FE_CPU); err != nil {
+		cleanupAll()
+		return nil, func() {}, err
+	}
+
+	if coreCollector, err = newCollector(dcgm.FE_CPU_CORE); err != nil {
+		cleanupAll()
+		return nil, func() {}, err
 	}
 
 	transformations := getTransformations(config)
 
+	staticGauges, err := formatStaticGauges(config, counters)
+	if err != nil {
+		logrus.Warnf("Failed to format exporter gauges; err: %v", err)
+	}
+
 	return &MetricsPipeline{
 			config: config,
 
@@ -110,11 +122,12 @@ func NewMetricsPipeline(config *Config,
 			transformations: transformations,
 			cpuCollector:    cpuCollector,
 			coreCollector:   coreCollector,
-		}, func() {
-			for _, cleanup := range cleanups {
-				cleanup()
-			}
-		}, nil
+			lastError:       NewLastCollectionError(),
+			snapshots:       NewMetricsSnapshots(),
+
+			staticGauges:       staticGauges,
+			entityGroupOutputs: newEntityGroupOutputs(config),
+		}, cleanupAll, nil
 }
 
 func getTransformations(c *Config) []Transform {
@@ -144,9 +157,23 @@ func NewMetricsPipelineWithGPUCollector(c *Config, collector *DCGMCollector) (*M
 
 		counters:     collector.Counters,
 		gpuCollector: collector,
+		lastError:    NewLastCollectionError(),
+		snapshots:    NewMetricsSnapshots(),
+
+		entityGroupOutputs: newEntityGroupOutputs(c),
 	}, func() {}, nil
 }
 
+// LastError returns the failure class of the last collections, shared with the metrics server.
+func (m *MetricsPipeline) LastError() *LastCollectionError {
+	return m.lastError
+}
+
+// Snapshots returns the metrics of the last two collections, shared with the metrics server.
+func (m *MetricsPipeline) Snapshots() *MetricsSnapshots {
+	return m.snapshots
+}
+
 func (m *MetricsPipeline) Run(out chan string, stop chan interface{}, wg *sync.WaitGroup) {
 	defer wg.Done()
 
@@ -163,62 +190,100 @@ func (m *MetricsPipeline) Run(out chan string, stop chan interface{}, wg *sync.W
 		case <-stop:
 			return
 		case <-t.C:
-			o, err := m.run()
+			outputs, err := m.collect()
+			m.lastError.Update(pipelineSource, err)
 			if err != nil {
 				logrus.Errorf("Failed to collect metrics; err: %v", err)
-				/* flush output rather than output stale data */
-				out <- ""
-				continue
 			}
 
-			if len(out) == cap(out) {
-				logrus.Errorf("Channel is full skipping.")
-			} else {
-				out <- o
+			/* on failure, outputs are empty; flush them rather than output stale data */
+			send(out, outputs[""], err)
+			for address, ch := range m.entityGroupOutputs {
+				send(ch, outputs[address], err)
 			}
 		}
 	}
 }
 
+// send passes the collected metrics to a metrics server, unless its channel is full.
+func send(ch chan string, metrics string, err error) {
+	if err == nil && len(ch) == cap(ch) {
+		logrus.Errorf("Channel is full skipping.")
+		return
+	}
+
+	ch <- metrics
+}
+
+// EntityGroupOutputs returns the channels of the metrics of entity groups, which are served on a separate address,
+// by address.
+func (m *MetricsPipeline) EntityGroupOutputs() map[string]chan string {
+	return m.entityGroupOutputs
+}
+
+// run returns the metrics, which are served on the main address.
 func (m *MetricsPipeline) run() (string, error) {
+	outputs, err := m.collect()
+	return outputs[""], err
+}
+
+// collect returns the formatted metrics by the address, which serves them; the main address is an empty string.
+func (m *MetricsPipeline) collect() (map[string]string, error) {
 	var metrics map[Counter][]Metric
 	var err error
-	var formatted string
+	var collected string
+
+	snapshot := MetricsByCounter{}
+	outputs := map[string]string{}
+	add := func(group dcgm.Field_Entity_Group, formatted string) {
+		outputs[entityGroupAddress(m.config, group)] += formatted
+		collected += formatted
+	}
+
+	limit := newSeriesLimit(m.config.MaxSeries)
 
 	if m.gpuCollector != nil {
 		/* Collect GPU Metrics */
 		metrics, err = m.gpuCollector.GetMetrics()
 		if err != nil {
-			return "", fmt.Errorf("failed to collect gpu metrics; err: %w", err)
+			return nil, fmt.Errorf("failed to collect gpu metrics; err: %w", err)
 		}
 
 		for _, transform := range m.transformations {
 			err := transform.Process(metrics, m.gpuCollector.SysInfo)
 			if err != nil {
-				return "", fmt.Errorf("failed to transform metrics for transform '%s'; err: %w", transform.Name(), err)
+				return nil, fmt.Errorf("failed to transform metrics for transform '%s'; err: %w", transform.Name(), err)
 			}
 		}
 
-		formatted, err = FormatMetrics(m.migMetricsFormat, metrics)
+		limit.apply(metrics)
+		mergeSnapshot(snapshot, metrics)
+
+		formatted, err := FormatMetrics(m.migMetricsFormat, metrics)
 		if err != nil {
-			return "", fmt.Errorf("failed to format metrics; err: %w", err)
+			return nil, fmt.Errorf("failed to format metrics; err: %w", err)
 		}
+
+		add(dcgm.FE_GPU, formatted)
 	}
 
 	if m.switchCollector != nil {
 		/* Collect Switch Metrics */
 		metrics, err = m.switchCollector.GetMetrics()
 		if err != nil {
-			return "", fmt.Errorf("failed to collect switch metrics; err: %w", err)
+			return nil, partialCollectionError(collected, fmt.Errorf("failed to collect switch metrics; err: %w", err))
 		}
 
+		limit.apply(metrics)
+		mergeSnapshot(snapshot, metrics)
+
 		if len(metrics) > 0 {
 			switchFormatted, err := FormatMetrics(m.switchMetricsFormat, metrics)
 			if err != nil {
 				logrus.Warnf("Failed to format switch metrics with error: %v", err)
 			}
 
-			formatted = formatted + switchFormatted
+			add(dcgm.FE_SWITCH, switchFormatted)
 		}
 	}
 
@@ -226,16 +291,19 @@ func (m *MetricsPipeline) run() (string, error) {
 		/* Collect Link Metrics */
 		metrics, err = m.linkCollector.GetMetrics()
 		if err != nil {
-			return "", fmt.Errorf("failed to collect link metrics; err: %w", err)
+			return nil, partialCollectionError(collected, fmt.Errorf("failed to collect link metrics; err: %w", err))
 		}
 
+		limit.apply(metrics)
+		mergeSnapshot(snapshot, metrics)
+
 		if len(metrics) > 0 {
 			switchFormatted, err := FormatMetrics(m.linkMetricsFormat, metrics)
 			if err != nil {
 				logrus.Warnf("failed to format link metrics; err: %v", err)
 			}
 
-			formatted = formatted + switchFormatted
+			add(dcgm.FE_LINK, switchFormatted)
 		}
 	}
 
@@ -243,16 +311,19 @@ func (m *MetricsPipeline) run() (string, error) {
 		/* Collect CPU Metrics */
 		metrics, err = m.cpuCollector.GetMetrics()
 		if err != nil {
-			return "", fmt.Errorf("failed to collect CPU metrics; err: %w", err)
+			return nil, partialCollectionError(collected, fmt.Errorf("failed to collect CPU metrics; err: %w", err))
 		}
 
+		limit.apply(metrics)
+		mergeSnapshot(snapshot, metrics)
+
 		if len(metrics) > 0 {
 			cpuFormatted, err := FormatMetrics(m.cpuMetricsFormat, metrics)
 			if err != nil {
 				logrus.Warnf("Failed to format cpu metrics with error: %v", err)
 			}
 
-			formatted = formatted + cpuFormatted
+			add(dcgm.FE_CPU, cpuFormatted)
 		}
 	}
 
@@ -260,20 +331,31 @@ func (m *MetricsPipeline) run() (string, error) {
 		/* Collect cpu core Metrics */
 		metrics, err = m.coreCollector.GetMetrics()
 		if err != nil {
-			return "", fmt.Errorf("failed to collect CPU core metrics; err: %w", err)
+			return nil, partialCollectionError(collected, fmt.Errorf("failed to collect CPU core metrics; err: %w", err))
 		}
 
+		limit.apply(metrics)
+		mergeSnapshot(snapshot, metrics)
+
 		if len(metrics) > 0 {
 			coreFormatted, err := FormatMetrics(m.cpuCoreMetricsFormat, metrics)
 			if err != nil {
 				logrus.Warnf("failed to format cpu core metrics; err: %v", err)
 			}
 
-			formatted = formatted + coreFormatted
+			add(dcgm.FE_CPU_CORE, coreFormatted)
 		}
 	}
 
-	return formatted, nil
+	seriesDropped, err := limit.format()
+	if err != nil {
+		return nil, fmt.Errorf("failed to format dropped series; err: %w", err)
+	}
+
+	outputs[""] += seriesDropped + m.staticGauges
+	m.snapshots.Record(snapshot)
+
+	return outputs, nil
 }
 
 /*
@@ -301,7 +383,7 @@ var migMetricsFormat = `
 	,{{ $k }}="{{ $v }}"
 {{- end -}}
 
-} {{ $metric.Value -}}
+} {{ $metric.Value }}{{ if $metric.Timestamp }} {{ $metric.Timestamp }}{{ end -}}
 {{- end }}
 {{ end }}`
 
@@ -315,7 +397,10 @@ var switchMetricsFormat = `
 {{- range $k, $v := $metric.Labels -}}
 	,{{ $k }}="{{ $v }}"
 {{- end -}}
-} {{ $metric.Value -}}
+{{- range $k, $v := $metric.Attributes -}}
+	,{{ $k }}="{{ $v }}"
+{{- end -}}
+} {{ $metric.Value }}{{ if $metric.Timestamp }} {{ $metric.Timestamp }}{{ end -}}
 {{- end }}
 {{ end }}`
 
@@ -329,7 +414,10 @@ var linkMetricsFormat = `
 {{- range $k, $v := $metric.Labels -}}
 	,{{ $k }}="{{ $v }}"
 {{- end -}}
-} {{ $metric.Value -}}
+{{- range $k, $v := $metric.Attributes -}}
+	,{{ $k }}="{{ $v }}"
+{{- end -}}
+} {{ $metric.Value }}{{ if $metric.Timestamp }} {{ $metric.Timestamp }}{{ end -}}
 {{- end }}
 {{ end }}`
 
@@ -343,7 +431,10 @@ var cpuMetricsFormat = `
 {{- range $k, $v := $metric.Labels -}}
 	,{{ $k }}="{{ $v }}"
 {{- end -}}
-} {{ $metric.Value -}}
+{{- range $k, $v := $metric.Attributes -}}
+	,{{ $k }}="{{ $v }}"
+{{- end -}}
+} {{ $metric.Value }}{{ if $metric.Timestamp }} {{ $metric.Timestamp }}{{ end -}}
 {{- end }}
 {{ end }}`
 
@@ -357,7 +448,10 @@ var cpuCoreMetricsFormat = `
 {{- range $k, $v := $metric.Labels -}}
 	,{{ $k }}="{{ $v }}"
 {{- end -}}
-} {{ $metric.Value -}}
+{{- range $k, $v := $metric.Attributes -}}
+	,{{ $k }}="{{ $v }}"
+{{- end -}}
+} {{ $metric.Value }}{{ if $metric.Timestamp }} {{ $metric.Timestamp }}{{ end -}}
 {{- end }}
 {{ end }}`
 
@@ -172,6 +172,39 @@ func TestCountPipelineCleanup(t *testing.T) {
 	}
 }
 
+func TestNewMetricsPipelineWhenCollectorFails(t *testing.T) {
+	fieldEntityGroupTypeSystemInfo := &FieldEntityGroupTypeSystemInfo{
+		items: map[dcgm.Field_Entity_Group]FieldEntityGroupTypeSystemInfoItem{
+			dcgm.FE_GPU: {
+				SystemInfo:   SystemInfo{InfoType: dcgm.FE_GPU},
+				DeviceFields: []dcgm.Short{dcgm.DCGM_FI_DEV_GPU_TEMP},
+			},
+			dcgm.FE_SWITCH: {
+				SystemInfo:   SystemInfo{InfoType: dcgm.FE_SWITCH},
+				DeviceFields: []dcgm.Short{dcgm.DCGM_FI_DEV_NVSWITCH_TEMPERATURE_CURRENT},
+			},
+		},
+	}
+
+	cleanups := 0
+	_, cleanup, err := NewMetricsPipeline(&Config{},
+		sampleCounters,
+		"",
+		func(_ []Counter, _ string, _ *Config, item FieldEntityGroupTypeSystemInfoItem) (*DCGMCollector, func(), error) {
+			if item.SystemInfo.InfoType == dcgm.FE_SWITCH {
+				return nil, func() {}, errors.New("failed to watch fields")
+			}
+			return &DCGMCollector{}, func() { cleanups++ }, nil
+		},
+		fieldEntityGroupTypeSystemInfo,
+	)
+	require.ErrorContains(t, err, "failed to watch fields")
+	assert.Equal(t, 1, cleanups, "the collectors created before the failure are cleaned up")
+
+	cleanup()
+	assert.Equal(t, 1, cleanups)
+}
+
 func TestNewMetricsPipelineWhenFieldEntityGroupTypeSystemInfoItemIsEmpty(t *testing.T) {
 	cleanup, err := dcgm.Init(dcgm.Embedded)
 	require.NoError(t, err)
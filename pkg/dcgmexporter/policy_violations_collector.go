@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/sirupsen/logrus"
+)
+
+const policyLabel = "policy"
+
+// policyNames maps the conditions of the DCGM policies to the policy label of DCGM_FI_DEV_POLICY_VIOLATIONS.
+var policyNames = map[string]string{
+	string(dcgm.DbePolicy):     "dbe",
+	string(dcgm.PCIePolicy):    "pcie",
+	string(dcgm.MaxRtPgPolicy): "max_retired_pages",
+	string(dcgm.ThermalPolicy): "thermal",
+	string(dcgm.PowerPolicy):   "power",
+	string(dcgm.NvlinkPolicy):  "nvlink",
+	string(dcgm.XidPolicy):     "xid",
+}
+
+// defaultPolicyViolations are the policies counted by default. They have no threshold, so that setting them does
+// not change when the policies of the GPUs are violated.
+var defaultPolicyViolations = []string{"dbe", "pcie", "nvlink", "xid"}
+
+// dcgmListenForPolicyViolations sets the DCGM policies of all GPUs and returns their violations, until ctx is done.
+// go-dcgm cannot register for the violations without setting the policies: it replaces the policies already set on
+// the hostengine, with fixed thresholds of 10 retired pages, 100 C and 250 W.
+var dcgmListenForPolicyViolations = func(ctx context.Context, policies []string) (<-chan dcgm.PolicyViolation, error) {
+	return dcgm.ListenForPolicyViolations(ctx, policyConditions(policies,
+		dcgm.DbePolicy,
+		dcgm.PCIePolicy,
+		dcgm.MaxRtPgPolicy,
+		dcgm.ThermalPolicy,
+		dcgm.PowerPolicy,
+		dcgm.NvlinkPolicy,
+		dcgm.XidPolicy)...)
+}
+
+// policyConditions returns the conditions, whose policy label is one of the policies. It is generic, because go-dcgm
+// does not export the type of the conditions.
+func policyConditions[C ~string](policies []string, conditions ...C) []C {
+	return slices.DeleteFunc(conditions, func(condition C) bool {
+		return !slices.Contains(policies, policyNames[string(condition)])
+	})
+}
+
+func isPolicyName(name string) bool {
+	for _, policy := range policyNames {
+		if policy == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsDCGMFIDevPolicyViolationsEnabled checks if the DCGM_FI_DEV_POLICY_VIOLATIONS counter exists
+func IsDCGMFIDevPolicyViolationsEnabled(counters []Counter) bool {
+	return slices.ContainsFunc(counters,
+		func(c Counter) bool {
+			return c.FieldName == dcgmFIDevPolicyViolations
+		})
+}
+
+// policyViolationsCollector counts the violations of the DCGM policies, by policy. DCGM notifies the
+// violations of the policies of all GPUs in the background; the collector only reports the counts.
+type policyViolationsCollector struct {
+	counter  Counter
+	hostname string
+	config   *Config
+	cancel   context.CancelFunc
+
+	mtx        sync.Mutex
+	violations map[string]uint64 // Violations by policy
+	done       chan struct{}     // Closed when DCGM stops notifying violations
+}
+
+func NewPolicyViolationsCollector(counters []Counter, hostname string, config *Config) (Collector, error) {
+	if !IsDCGMFIDevPolicyViolationsEnabled(counters) {
+		return nil, fmt.Errorf(dcgmFIDevPolicyViolations + " collector is disabled")
+	}
+
+	return newPolicyViolationsCollector(counters[slices.IndexFunc(counters, func(c Counter) bool {
+		return c.FieldName == dcgmFIDevPolicyViolations
+	})], hostname, config)
+}
+
+func newPolicyViolationsCollector(counter Counter, hostname string, config *Config) (*policyViolationsCollector, error) {
+	policies := config.PolicyViolations
+	if len(policies) == 0 {
+		policies = defaultPolicyViolations
+	}
+
+	for _, policy := range policies {
+		if !isPolicyName(policy) {
+			return nil, fmt.Errorf("unknown policy '%s'", policy)
+		}
+	}
+
+	logrus.Warnf("Setting the DCGM policies %v on all GPUs; they replace the policies already set on the hostengine",
+		policies)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	violations, err := dcgmListenForPolicyViolations(ctx, policies)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to register policies; err: %w", err)
+	}
+
+	collector := &policyViolationsCollector{
+		counter:    counter,
+		hostname:   hostname,
+		config:     config,
+		cancel:     cancel,
+		violations: map[string]uint64{},
+		done:       make(chan struct{}),
+	}
+	for _, policy := range policies {
+		collector.violations[policy] = 0
+	}
+
+	go collector.watch(violations)
+
+	return collector, nil
+}
+
+// watch counts the violations, until DCGM stops notifying them.
+func (c *policyViolationsCollector) watch(violations <-chan dcgm.PolicyViolation) {
+	defer close(c.done)
+
+	for violation := range violations {
+		policy, exists := policyNames[string(violation.Condition)]
+		if !exists {
+			logrus.Warnf("Unknown policy violation: %s", violation.Condition)
+			continue
+		}
+
+		c.mtx.Lock()
+		c.violations[policy]++
+		c.mtx.Unlock()
+	}
+}
+
+func (c *policyViolationsCollector) GetMetrics() (MetricsByCounter, error) {
+	uuid := "UUID"
+	if c.config.UseOldNamespace {
+		uuid = "uuid"
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	metrics := make(MetricsByCounter)
+	for policy, count := range c.violations {
+		metrics[c.counter] = append(metrics[c.counter], Metric{
+			Counter:  c.counter,
+			Value:    fmt.Sprint(count),
+			UUID:     uuid,
+			Hostname: c.hostname,
+
+			Labels:     map[string]string{policyLabel: policy},
+			Attributes: map[string]string{},
+		})
+	}
+
+	labelIdentity(metrics, identityLabels(c.config))
+
+	return metrics, nil
+}
+
+func (c *policyViolationsCollector) Cleanup() {
+	c.cancel()
+	<-c.done
+}
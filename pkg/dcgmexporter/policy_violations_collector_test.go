@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsDCGMFIDevPolicyViolationsEnabled(t *testing.T) {
+	assert.True(t, IsDCGMFIDevPolicyViolationsEnabled([]Counter{{FieldName: dcgmFIDevPolicyViolations}}))
+	assert.False(t, IsDCGMFIDevPolicyViolationsEnabled([]Counter{{FieldName: dcgmFIDevThrottleEvents}}))
+
+	counterType, err := IdentifyMetricType(dcgmFIDevPolicyViolations)
+	require.NoError(t, err)
+	assert.Equal(t, DCGMPolicyViolations, counterType)
+}
+
+func TestPolicyViolationsCollector(t *testing.T) {
+	counter := Counter{
+		FieldID:   dcgm.Short(DCGMPolicyViolations),
+		FieldName: dcgmFIDevPolicyViolations,
+		PromType:  "counter",
+		Help:      "Number of violations of the DCGM power, thermal and error policies, by policy.",
+	}
+
+	violations := make(chan dcgm.PolicyViolation)
+	listen := dcgmListenForPolicyViolations
+	t.Cleanup(func() {
+		dcgmListenForPolicyViolations = listen
+	})
+	var listened []string
+	dcgmListenForPolicyViolations = func(ctx context.Context, policies []string) (<-chan dcgm.PolicyViolation, error) {
+		listened = policies
+		go func() {
+			<-ctx.Done()
+			close(violations)
+		}()
+		return violations, nil
+	}
+
+	collector, err := newPolicyViolationsCollector(counter, "testhost",
+		&Config{PolicyViolations: []string{"power", "thermal", "xid"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"power", "thermal", "xid"}, listened)
+
+	counts := func() map[string]string {
+		metrics, err := collector.GetMetrics()
+		require.NoError(t, err)
+
+		counts := map[string]string{}
+		for _, m := range metrics[counter] {
+			assert.Equal(t, "testhost", m.Hostname)
+			counts[m.Labels[policyLabel]] = m.Value
+		}
+		return counts
+	}
+
+	assert.Len(t, counts(), 3, "only the listed policies are counted")
+	assert.Equal(t, "0", counts()["power"])
+
+	violations <- dcgm.PolicyViolation{Condition: dcgm.PowerPolicy, Timestamp: time.Now()}
+	violations <- dcgm.PolicyViolation{Condition: dcgm.PowerPolicy, Timestamp: time.Now()}
+	violations <- dcgm.PolicyViolation{Condition: dcgm.XidPolicy, Timestamp: time.Now()}
+
+	assert.Eventually(t, func() bool {
+		return counts()["xid"] == "1"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "2", counts()["power"])
+	assert.Equal(t, "0", counts()["thermal"])
+
+	collector.Cleanup()
+}
+
+func TestPolicyViolationsCollectorWhenRegistrationFails(t *testing.T) {
+	listen := dcgmListenForPolicyViolations
+	t.Cleanup(func() {
+		dcgmListenForPolicyViolations = listen
+	})
+	dcgmListenForPolicyViolations = func(context.Context, []string) (<-chan dcgm.PolicyViolation, error) {
+		return nil, errors.New("boom")
+	}
+
+	_, err := NewPolicyViolationsCollector([]Counter{{FieldName: dcgmFIDevPolicyViolations}}, "", &Config{})
+	assert.ErrorContains(t, err, "failed to register policies")
+}
+
+func TestPolicyViolationsCollectorPolicies(t *testing.T) {
+	listen := dcgmListenForPolicyViolations
+	t.Cleanup(func() {
+		dcgmListenForPolicyViolations = listen
+	})
+
+	var listened []string
+	dcgmListenForPolicyViolations = func(_ context.Context, policies []string) (<-chan dcgm.PolicyViolation, error) {
+		listened = policies
+		violations := make(chan dcgm.PolicyViolation)
+		close(violations)
+		return violations, nil
+	}
+
+	collector, err := NewPolicyViolationsCollector([]Counter{{FieldName: dcgmFIDevPolicyViolations}}, "", &Config{})
+	require.NoError(t, err)
+	collector.Cleanup()
+	assert.Equal(t, defaultPolicyViolations, listened, "the policies with thresholds are not set by default")
+
+	_, err = NewPolicyViolationsCollector([]Counter{{FieldName: dcgmFIDevPolicyViolations}}, "",
+		&Config{PolicyViolations: []string{"xid", "temperature"}})
+	assert.ErrorContains(t, err, "unknown policy 'temperature'")
+}
+
+func TestPolicyConditions(t *testing.T) {
+	conditions := policyConditions([]string{"power", "xid"}, dcgm.DbePolicy, dcgm.PowerPolicy, dcgm.XidPolicy)
+	require.Len(t, conditions, 2)
+	assert.Equal(t, dcgm.PowerPolicy, conditions[0])
+	assert.Equal(t, dcgm.XidPolicy, conditions[1])
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+var powerPeakCounter = Counter{
+	FieldName: "DCGM_FI_DEV_POWER_USAGE_PEAK",
+	PromType:  "gauge",
+	Help:      "Maximum power draw collected within the window (in W).",
+}
+
+// powerPeakTracker keeps the power draws of every GPU, which were collected within the window.
+type powerPeakTracker struct {
+	window time.Duration
+
+	mtx     sync.Mutex
+	history map[string][]windowSample
+}
+
+// newPowerPeakTracker returns a MetricProcessor, which adds DCGM_FI_DEV_POWER_USAGE_PEAK, the maximum of the
+// DCGM_FI_DEV_POWER_USAGE values collected within the window, for every GPU.
+func newPowerPeakTracker(window time.Duration) MetricProcessor {
+	tracker := &powerPeakTracker{
+		window:  window,
+		history: map[string][]windowSample{},
+	}
+
+	return tracker.process
+}
+
+func (p *powerPeakTracker) process(metrics MetricsByCounter) MetricsByCounter {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	now := timeNow()
+	history := map[string][]windowSample{}
+	var peaks []Metric
+
+	for counter, values := range metrics {
+		if counter.FieldID != dcgm.DCGM_FI_DEV_POWER_USAGE || counter.PromType == "label" {
+			continue
+		}
+
+		for _, m := range values {
+			// The power draw is only reported for whole GPUs; samples of sampled fields have their own timestamps
+			if m.GPUInstanceID != "" || m.Timestamp != 0 {
+				continue
+			}
+
+			value, err := strconv.ParseFloat(m.Value, 64)
+			if err != nil {
+				continue
+			}
+
+			key := seriesKey(m)
+			samples := append(evictSamples(p.history[key], now, p.window), windowSample{at: now, value: value})
+			history[key] = samples
+
+			peak := samples[0].value
+			for _, s := range samples[1:] {
+				peak = max(peak, s.value)
+			}
+
+			derived := m
+			derived.Counter = powerPeakCounter
+			derived.Value = fmt.Sprintf("%f", peak)
+			derived.Attributes = maps.Clone(m.Attributes)
+
+			peaks = append(peaks, derived)
+		}
+	}
+
+	// GPUs that were not collected this time are forgotten
+	p.history = history
+
+	if len(peaks) > 0 {
+		metrics[powerPeakCounter] = peaks
+	}
+
+	return metrics
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPowerPeakTracker(t *testing.T) {
+	powerCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge", Help: "Power draw (in W)."}
+
+	now := timeNow
+	defer func() {
+		timeNow = now
+	}()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	elapsed := time.Duration(0)
+	timeNow = func() time.Time {
+		return start.Add(elapsed)
+	}
+
+	collect := func(track MetricProcessor, power string) []Metric {
+		return track(MetricsByCounter{powerCounter: {
+			{Counter: powerCounter, Value: power, GPU: "0", Attributes: map[string]string{}},
+			{Counter: powerCounter, Value: "500", GPU: "0", GPUInstanceID: "1", Attributes: map[string]string{}},
+		}})[powerPeakCounter]
+	}
+
+	t.Run("When the power spikes", func(t *testing.T) {
+		elapsed = 0
+		track := newPowerPeakTracker(time.Minute)
+
+		peaks := collect(track, "100")
+		require.Len(t, peaks, 1, "MIG instances have no power draw of their own")
+		assert.Equal(t, "100.000000", peaks[0].Value)
+		assert.Equal(t, "0", peaks[0].GPU)
+
+		elapsed += 20 * time.Second
+		assert.Equal(t, "300.000000", collect(track, "300")[0].Value)
+
+		elapsed += 20 * time.Second
+		assert.Equal(t, "300.000000", collect(track, "120")[0].Value, "the peak holds after the spike")
+
+		elapsed += 20 * time.Second
+		assert.Equal(t, "300.000000", collect(track, "110")[0].Value)
+
+		elapsed += 20 * time.Second
+		assert.Equal(t, "120.000000", collect(track, "100")[0].Value, "the peak decays once the spike leaves the window")
+	})
+
+	t.Run("When a GPU is not collected", func(t *testing.T) {
+		elapsed = 0
+		track := newPowerPeakTracker(time.Minute)
+
+		collect(track, "300")
+		track(MetricsByCounter{})
+		assert.Equal(t, "100.000000", collect(track, "100")[0].Value, "the history of missing GPUs is forgotten")
+	})
+}
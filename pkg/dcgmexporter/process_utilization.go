@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/NVIDIA/dcgm-exporter/internal/pkg/nvmlprovider"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	pidAttribute         = "pid"
+	processNameAttribute = "process_name"
+)
+
+var nvmlGetProcessUtilizationByUUIDHook = nvmlprovider.GetProcessUtilizationByUUID
+
+// procRoot is the root of the proc filesystem, which the names of the processes are read from
+var procRoot = "/proc"
+
+var processSMUtilCounter = Counter{
+	FieldName: "DCGM_EXPORTER_PROCESS_SM_UTIL",
+	PromType:  "gauge",
+	Help:      "Utilization of the GPU by the process over its accounting interval (in %).",
+}
+
+var processMemUtilCounter = Counter{
+	FieldName: "DCGM_EXPORTER_PROCESS_MEM_UTIL",
+	PromType:  "gauge",
+	Help:      "Utilization of the device memory by the process over its accounting interval (in %).",
+}
+
+// processName returns the name of the process from the proc filesystem, or an empty name when the process is not
+// visible, e.g. it runs in another PID namespace.
+func processName(pid uint32) string {
+	comm, err := os.ReadFile(filepath.Join(procRoot, fmt.Sprint(pid), "comm"))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(comm))
+}
+
+// topProcesses returns the n processes with the highest GPU utilization, then memory utilization.
+func topProcesses(processes []nvmlprovider.ProcessUtilization, n int) []nvmlprovider.ProcessUtilization {
+	processes = slices.Clone(processes)
+	slices.SortFunc(processes, func(a, b nvmlprovider.ProcessUtilization) int {
+		if c := cmp.Compare(b.GPU, a.GPU); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Memory, a.Memory); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.PID, b.PID)
+	})
+
+	if len(processes) > n {
+		processes = processes[:n]
+	}
+
+	return processes
+}
+
+// appendProcessUtilization adds DCGM_EXPORTER_PROCESS_SM_UTIL and DCGM_EXPORTER_PROCESS_MEM_UTIL, labeled by pid
+// and process name, for the top ProcessUtilizationTopN processes of every monitored GPU, from the accounting stats
+// reported by NVML. GPUs, whose accounting is disabled or whose query failed, are skipped.
+func (c *DCGMCollector) appendProcessUtilization(metrics MetricsByCounter, monitoringInfo []MonitoringInfo) {
+	uuid := "UUID"
+	if c.UseOldNamespace {
+		uuid = "uuid"
+	}
+
+	queried := map[uint]bool{}
+	for _, mi := range monitoringInfo {
+		gpu := mi.DeviceInfo.GPU
+		if queried[gpu] {
+			continue
+		}
+		queried[gpu] = true
+
+		processes, err := nvmlGetProcessUtilizationByUUIDHook(mi.DeviceInfo.UUID)
+		if err != nil {
+			logrus.WithError(err).WithField("uuid", mi.DeviceInfo.UUID).Debug("Failed to get the process utilization.")
+			continue
+		}
+
+		for _, process := range topProcesses(processes, c.ProcessUtilizationTopN) {
+			for _, utilization := range []struct {
+				counter Counter
+				value   uint32
+			}{
+				{processSMUtilCounter, process.GPU},
+				{processMemUtilCounter, process.Memory},
+			} {
+				m := Metric{
+					Counter: utilization.counter,
+					Value:   fmt.Sprint(utilization.value),
+
+					UUID:         uuid,
+					GPU:          fmt.Sprintf("%d", gpu),
+					GPUUUID:      mi.DeviceInfo.UUID,
+					GPUDevice:    fmt.Sprintf("nvidia%d", gpu),
+					GPUModelName: getGPUModel(mi.DeviceInfo, c.ReplaceBlanksInModelName),
+					Hostname:     c.Hostname,
+
+					Labels: map[string]string{},
+					Attributes: map[string]string{
+						pidAttribute:         fmt.Sprint(process.PID),
+						processNameAttribute: processName(process.PID),
+					},
+				}
+
+				metrics[utilization.counter] = append(metrics[utilization.counter], m)
+			}
+		}
+	}
+}
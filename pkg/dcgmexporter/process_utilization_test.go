@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NVIDIA/dcgm-exporter/internal/pkg/nvmlprovider"
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetricsWithProcessUtilization(t *testing.T) {
+	tempCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+
+	sysInfo := SystemInfo{GPUCount: 1, InfoType: dcgm.FE_GPU, gOpt: DeviceOptions{Flex: true}}
+	sysInfo.GPUs[0] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-0"}}
+
+	collector := &DCGMCollector{
+		Counters:               []Counter{tempCounter},
+		DeviceFields:           []dcgm.Short{tempCounter.FieldID},
+		SysInfo:                sysInfo,
+		ProcessUtilizationTopN: 2,
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+	dcgmEntityGetLatestValues = func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], 42)
+		return []dcgm.FieldValue_v1{{FieldId: uint(tempCounter.FieldID), FieldType: dcgm.DCGM_FT_INT64, Value: value}}, nil
+	}
+
+	defer func(getProcessUtilization func(string) ([]nvmlprovider.ProcessUtilization, error)) {
+		nvmlGetProcessUtilizationByUUIDHook = getProcessUtilization
+	}(nvmlGetProcessUtilizationByUUIDHook)
+	nvmlGetProcessUtilizationByUUIDHook = func(string) ([]nvmlprovider.ProcessUtilization, error) {
+		return []nvmlprovider.ProcessUtilization{
+			{PID: 300, GPU: 5, Memory: 1},
+			{PID: 100, GPU: 80, Memory: 40},
+			{PID: 200, GPU: 30, Memory: 10},
+		}, nil
+	}
+
+	defer func(root string) {
+		procRoot = root
+	}(procRoot)
+	procRoot = t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(procRoot, "100"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(procRoot, "100", "comm"), []byte("python3\n"), 0o644))
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+
+	utilization := func(counter Counter) map[string]string {
+		values := map[string]string{}
+		for _, m := range metrics[counter] {
+			assert.Equal(t, "GPU-0", m.GPUUUID)
+			values[m.Attributes[pidAttribute]+"/"+m.Attributes[processNameAttribute]] = m.Value
+		}
+		return values
+	}
+
+	assert.Equal(t, map[string]string{"100/python3": "80", "200/": "30"}, utilization(processSMUtilCounter),
+		"the top 2 processes; the name of a process, which is not visible, is empty")
+	assert.Equal(t, map[string]string{"100/python3": "40", "200/": "10"}, utilization(processMemUtilCounter))
+
+	collector.ProcessUtilizationTopN = 0
+	metrics, err = collector.GetMetrics()
+	require.NoError(t, err)
+	assert.Empty(t, metrics[processSMUtilCounter])
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+const (
+	dcgmExporterProfilingMultiplexedEstimate = "DCGM_EXPORTER_PROFILING_MULTIPLEXED_ESTIMATE"
+	dcgmExporterFieldMultiplexedEstimate     = "DCGM_EXPORTER_FIELD_MULTIPLEXED_ESTIMATE"
+)
+
+var fieldMultiplexedCounter = Counter{
+	FieldName: dcgmExporterFieldMultiplexedEstimate,
+	PromType:  "gauge",
+	Help:      "1 when DCGM is expected to time-multiplex the profiling field, which samples it less often; estimated from the supported metric groups at startup.",
+}
+
+// ProfilingMultiplexed estimates whether DCGM time-multiplexes the profiling fields of the counters.
+// DCGM watches one metric group per major ID at a time, so the fields are expected to be multiplexed,
+// when no such selection of the supported metric groups contains all of them. DCGM does not report
+// whether it actually multiplexes the watched fields.
+func ProfilingMultiplexed(counters []Counter, groups []dcgm.MetricGroup) bool {
+	return len(MultiplexedFields(counters, groups)) > 0
+}
+
+// MultiplexedFields returns the profiling fields of the counters, which DCGM is expected to sample less often,
+// i.e. the fields outside of the selection of metric groups, one per major ID, which contains most of them.
+func MultiplexedFields(counters []Counter, groups []dcgm.MetricGroup) []uint {
+	fields := profilingFields(counters)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	var majors []uint
+	for _, group := range groups {
+		if !slices.Contains(majors, group.Major) {
+			majors = append(majors, group.Major)
+		}
+	}
+
+	return uncoveredByMetricGroups(fields, groups, majors)
+}
+
+// profilingFields returns the distinct profiling fields of the counters.
+func profilingFields(counters []Counter) []uint {
+	var fields []uint
+	for _, counter := range counters {
+		fieldID := uint(counter.FieldID)
+		if fieldID >= dcpFieldsStart && fieldID < cpuFieldsStart && !slices.Contains(fields, fieldID) {
+			fields = append(fields, fieldID)
+		}
+	}
+
+	return fields
+}
+
+// uncoveredByMetricGroups returns the fewest fields, which are not contained in the metric groups,
+// selecting at most one group for every of the majors.
+func uncoveredByMetricGroups(fields []uint, groups []dcgm.MetricGroup, majors []uint) []uint {
+	if len(fields) == 0 || len(majors) == 0 {
+		return fields
+	}
+
+	uncovered := fields
+	for _, group := range groups {
+		if group.Major != majors[0] {
+			continue
+		}
+
+		remaining := slices.DeleteFunc(slices.Clone(fields), func(fieldID uint) bool {
+			return slices.Contains(group.FieldIds, fieldID)
+		})
+		if remaining = uncoveredByMetricGroups(remaining, groups, majors[1:]); len(remaining) < len(uncovered) {
+			uncovered = remaining
+		}
+
+		if len(uncovered) == 0 {
+			break
+		}
+	}
+
+	return uncovered
+}
+
+// formatProfilingMultiplexed returns the DCGM_EXPORTER_PROFILING_MULTIPLEXED_ESTIMATE gauge, which is estimated
+// from the configured counters and the supported metric groups when the exporter starts, or nothing when profiling
+// metrics are not collected.
+func formatProfilingMultiplexed(config *Config, counters []Counter) (string, error) {
+	if !config.CollectDCP {
+		return "", nil
+	}
+
+	value := 0
+	if ProfilingMultiplexed(counters, config.MetricGroups) {
+		value = 1
+	}
+
+	return formatExporterGauge(dcgmExporterProfilingMultiplexedEstimate,
+		"1 when DCGM is expected to time-multiplex the configured profiling fields, which makes their values less accurate; estimated from the supported metric groups at startup.",
+		value)
+}
+
+// fieldMultiplexedMetrics returns DCGM_EXPORTER_FIELD_MULTIPLEXED_ESTIMATE for every profiling field of the counters.
+func fieldMultiplexedMetrics(counters []Counter, groups []dcgm.MetricGroup) MetricsByCounter {
+	multiplexed := MultiplexedFields(counters, groups)
+
+	metrics := make(MetricsByCounter)
+	for _, fieldID := range profilingFields(counters) {
+		value := "0"
+		if slices.Contains(multiplexed, fieldID) {
+			value = "1"
+		}
+
+		name := fmt.Sprint(fieldID)
+		if i := slices.IndexFunc(counters, func(c Counter) bool { return uint(c.FieldID) == fieldID }); i >= 0 {
+			name = counters[i].FieldName
+		}
+
+		metrics[fieldMultiplexedCounter] = append(metrics[fieldMultiplexedCounter], Metric{
+			Counter: fieldMultiplexedCounter,
+			Value:   value,
+			Labels: map[string]string{
+				fieldIDLabel:   fmt.Sprint(fieldID),
+				fieldNameLabel: name,
+			},
+			Attributes: map[string]string{},
+		})
+	}
+
+	return metrics
+}
+
+// formatFieldMultiplexed returns the DCGM_EXPORTER_FIELD_MULTIPLEXED_ESTIMATE gauges,
+// or nothing when profiling metrics are not collected.
+func formatFieldMultiplexed(config *Config, counters []Counter) (string, error) {
+	if !config.CollectDCP {
+		return "", nil
+	}
+
+	var res bytes.Buffer
+	err := getConstantMetricsTemplate().Execute(&res, fieldMultiplexedMetrics(counters, config.MetricGroups))
+	if err != nil {
+		return "", err
+	}
+
+	return res.String(), nil
+}
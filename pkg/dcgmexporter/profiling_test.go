@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var sampleMetricGroups = []dcgm.MetricGroup{
+	{Major: 0, Minor: 0, FieldIds: []uint{dcgm.DCGM_FI_PROF_GR_ENGINE_ACTIVE, dcgm.DCGM_FI_PROF_SM_ACTIVE}},
+	{Major: 0, Minor: 1, FieldIds: []uint{dcgm.DCGM_FI_PROF_PIPE_TENSOR_ACTIVE}},
+	{Major: 1, Minor: 0, FieldIds: []uint{dcgm.DCGM_FI_PROF_PCIE_TX_BYTES, dcgm.DCGM_FI_PROF_PCIE_RX_BYTES}},
+}
+
+func TestProfilingMultiplexed(t *testing.T) {
+	tests := []struct {
+		name     string
+		fieldIDs []dcgm.Short
+		want     bool
+	}{
+		{
+			name:     "no profiling fields",
+			fieldIDs: []dcgm.Short{dcgm.DCGM_FI_DEV_GPU_TEMP},
+		},
+		{
+			name:     "fields of one metric group",
+			fieldIDs: []dcgm.Short{dcgm.DCGM_FI_PROF_GR_ENGINE_ACTIVE, dcgm.DCGM_FI_PROF_SM_ACTIVE, dcgm.DCGM_FI_DEV_GPU_TEMP},
+		},
+		{
+			name:     "fields of metric groups with different major IDs",
+			fieldIDs: []dcgm.Short{dcgm.DCGM_FI_PROF_GR_ENGINE_ACTIVE, dcgm.DCGM_FI_PROF_PCIE_TX_BYTES},
+		},
+		{
+			name:     "fields of metric groups with the same major ID",
+			fieldIDs: []dcgm.Short{dcgm.DCGM_FI_PROF_GR_ENGINE_ACTIVE, dcgm.DCGM_FI_PROF_PIPE_TENSOR_ACTIVE},
+			want:     true,
+		},
+		{
+			name:     "field of no metric group",
+			fieldIDs: []dcgm.Short{dcgm.DCGM_FI_PROF_DRAM_ACTIVE},
+			want:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var counters []Counter
+			for _, fieldID := range tt.fieldIDs {
+				counters = append(counters, Counter{FieldID: fieldID, PromType: "gauge"})
+			}
+
+			assert.Equal(t, tt.want, ProfilingMultiplexed(counters, sampleMetricGroups))
+		})
+	}
+}
+
+func TestFormatProfilingMultiplexed(t *testing.T) {
+	counters := []Counter{
+		{FieldID: dcgm.DCGM_FI_PROF_GR_ENGINE_ACTIVE, FieldName: "DCGM_FI_PROF_GR_ENGINE_ACTIVE", PromType: "gauge", Help: "Ratio of time the graphics engine is active."},
+		{FieldID: dcgm.DCGM_FI_PROF_PIPE_TENSOR_ACTIVE, FieldName: "DCGM_FI_PROF_PIPE_TENSOR_ACTIVE", PromType: "gauge", Help: "Ratio of cycles the tensor (HMMA) pipe is active."},
+	}
+
+	out, err := formatProfilingMultiplexed(&Config{CollectDCP: true, MetricGroups: sampleMetricGroups}, counters)
+	require.NoError(t, err)
+	assert.Equal(t, `# HELP DCGM_EXPORTER_PROFILING_MULTIPLEXED_ESTIMATE 1 when DCGM is expected to time-multiplex the configured profiling fields, which makes their values less accurate; estimated from the supported metric groups at startup.
+# TYPE DCGM_EXPORTER_PROFILING_MULTIPLEXED_ESTIMATE gauge
+DCGM_EXPORTER_PROFILING_MULTIPLEXED_ESTIMATE 1
+`, out)
+
+	out, err = formatProfilingMultiplexed(&Config{CollectDCP: true, MetricGroups: sampleMetricGroups}, counters[:1])
+	require.NoError(t, err)
+	assert.Contains(t, out, "DCGM_EXPORTER_PROFILING_MULTIPLEXED_ESTIMATE 0\n")
+
+	out, err = formatProfilingMultiplexed(&Config{MetricGroups: sampleMetricGroups}, counters)
+	require.NoError(t, err)
+	assert.Empty(t, out)
+}
+
+func TestFormatFieldMultiplexed(t *testing.T) {
+	counters := []Counter{
+		{FieldID: dcgm.DCGM_FI_PROF_GR_ENGINE_ACTIVE, FieldName: "DCGM_FI_PROF_GR_ENGINE_ACTIVE", PromType: "gauge", Help: "Ratio of time the graphics engine is active."},
+		{FieldID: dcgm.DCGM_FI_PROF_PIPE_TENSOR_ACTIVE, FieldName: "DCGM_FI_PROF_PIPE_TENSOR_ACTIVE", PromType: "gauge", Help: "Ratio of cycles the tensor (HMMA) pipe is active."},
+		{FieldID: dcgm.DCGM_FI_PROF_PCIE_TX_BYTES, FieldName: "DCGM_FI_PROF_PCIE_TX_BYTES", PromType: "gauge", Help: "The rate of data transmitted over the PCIe bus."},
+		{FieldID: dcgm.DCGM_FI_PROF_DRAM_ACTIVE, FieldName: "DCGM_FI_PROF_DRAM_ACTIVE", PromType: "gauge", Help: "Ratio of cycles the device memory interface is active."},
+		{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."},
+	}
+
+	assert.ElementsMatch(t, []uint{dcgm.DCGM_FI_PROF_PIPE_TENSOR_ACTIVE, dcgm.DCGM_FI_PROF_DRAM_ACTIVE},
+		MultiplexedFields(counters, sampleMetricGroups))
+
+	out, err := formatFieldMultiplexed(&Config{CollectDCP: true, MetricGroups: sampleMetricGroups}, counters)
+	require.NoError(t, err)
+	assert.Equal(t, `# HELP DCGM_EXPORTER_FIELD_MULTIPLEXED_ESTIMATE 1 when DCGM is expected to time-multiplex the profiling field, which samples it less often; estimated from the supported metric groups at startup.
+# TYPE DCGM_EXPORTER_FIELD_MULTIPLEXED_ESTIMATE gauge
+DCGM_EXPORTER_FIELD_MULTIPLEXED_ESTIMATE{field_id="1001",field_name="DCGM_FI_PROF_GR_ENGINE_ACTIVE"} 0
+DCGM_EXPORTER_FIELD_MULTIPLEXED_ESTIMATE{field_id="1004",field_name="DCGM_FI_PROF_PIPE_TENSOR_ACTIVE"} 1
+DCGM_EXPORTER_FIELD_MULTIPLEXED_ESTIMATE{field_id="1009",field_name="DCGM_FI_PROF_PCIE_TX_BYTES"} 0
+DCGM_EXPORTER_FIELD_MULTIPLEXED_ESTIMATE{field_id="1005",field_name="DCGM_FI_PROF_DRAM_ACTIVE"} 1
+`, out)
+
+	out, err = formatFieldMultiplexed(&Config{CollectDCP: true, MetricGroups: sampleMetricGroups}, counters[:1])
+	require.NoError(t, err)
+	assert.Contains(t, out, `DCGM_EXPORTER_FIELD_MULTIPLEXED_ESTIMATE{field_id="1001",field_name="DCGM_FI_PROF_GR_ENGINE_ACTIVE"} 0`)
+
+	out, err = formatFieldMultiplexed(&Config{MetricGroups: sampleMetricGroups}, counters)
+	require.NoError(t, err)
+	assert.Empty(t, out)
+}
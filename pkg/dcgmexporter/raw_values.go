@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"maps"
+)
+
+const rawValueSuffix = "_raw"
+
+// rawCounter returns the counter of the untransformed values of counter.
+func rawCounter(counter Counter) Counter {
+	return Counter{
+		FieldID:   counter.FieldID,
+		FieldName: counter.FieldName + rawValueSuffix,
+		PromType:  counter.PromType,
+		Help:      "Value of " + counter.FieldName + " before the transforms of the exporter.",
+	}
+}
+
+// appendRawValues adds <field>_raw with the untransformed value for every metric, whose value was transformed,
+// e.g. by a value expression, so that the transforms can be debugged.
+func appendRawValues(metrics MetricsByCounter) {
+	raws := make(MetricsByCounter)
+	for counter, values := range metrics {
+		for _, m := range values {
+			if m.RawValue == "" {
+				continue
+			}
+
+			raw := rawCounter(counter)
+
+			m.Counter = raw
+			m.Value = m.RawValue
+			m.RawValue = ""
+			m.Labels = maps.Clone(m.Labels)
+			m.Attributes = maps.Clone(m.Attributes)
+
+			raws[raw] = append(raws[raw], m)
+		}
+	}
+
+	for counter, values := range raws {
+		metrics[counter] = values
+	}
+}
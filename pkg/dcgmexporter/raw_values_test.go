@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendRawValues(t *testing.T) {
+	int64Value := func(v int64) [4096]byte {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], uint64(v))
+		return value
+	}
+
+	values := []dcgm.FieldValue_v1{
+		{FieldId: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(40)},
+		{FieldId: dcgm.DCGM_FI_DEV_SM_CLOCK, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(1410)},
+	}
+
+	c := []Counter{
+		{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in F).", Expr: celsiusToFahrenheit},
+		{FieldID: dcgm.DCGM_FI_DEV_SM_CLOCK, FieldName: "DCGM_FI_DEV_SM_CLOCK", PromType: "gauge", Help: "SM clock frequency (in MHz)."},
+	}
+
+	metrics := make(MetricsByCounter)
+	ToMetric(metrics, values, c, dcgm.Device{UUID: "fake0"}, nil, MetricOptions{ShortestFloatFormat: true})
+
+	require.Len(t, metrics[c[0]], 1)
+	assert.Equal(t, "104", metrics[c[0]][0].Value)
+	assert.Equal(t, "40", metrics[c[0]][0].RawValue)
+	assert.Empty(t, metrics[c[1]][0].RawValue, "the value is not transformed")
+
+	appendRawValues(metrics)
+
+	raw := rawCounter(c[0])
+	assert.Equal(t, "DCGM_FI_DEV_GPU_TEMP_raw", raw.FieldName)
+	require.Len(t, metrics[raw], 1)
+	assert.Equal(t, "40", metrics[raw][0].Value, "the untransformed value")
+	assert.Equal(t, "fake0", metrics[raw][0].GPUUUID)
+	assert.Equal(t, "104", metrics[c[0]][0].Value, "the transformed value is kept")
+
+	assert.Len(t, metrics, 3, "no raw variant of untransformed fields")
+}
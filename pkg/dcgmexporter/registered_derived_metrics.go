@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+// DerivedMetricFunc computes a metric from the values of the fields of an entity. It returns false when the metric
+// cannot be computed for the entity, e.g. because a field it needs is not collected.
+type DerivedMetricFunc func(entity MonitoringInfo, vals []dcgm.FieldValue_v1) (Metric, bool)
+
+type registeredDerivedMetric struct {
+	counter Counter
+	fn      DerivedMetricFunc
+}
+
+var (
+	registeredDerivedMetricsMtx sync.RWMutex
+	registeredDerivedMetrics    []registeredDerivedMetric
+)
+
+// RegisterDerivedMetric registers the gauge name, computed by fn for every entity of every collection, so that
+// metrics can be derived from the collected fields without modifying the exporter. The exporter sets the counter
+// and the hostname of the metrics, and the GPU labels of the metrics of GPUs and GPU instances; fn sets the value
+// and may set attributes. Registering a name twice replaces its function.
+func RegisterDerivedMetric(name string, fn DerivedMetricFunc) {
+	registeredDerivedMetricsMtx.Lock()
+	defer registeredDerivedMetricsMtx.Unlock()
+
+	derived := registeredDerivedMetric{
+		counter: Counter{
+			FieldName: name,
+			PromType:  "gauge",
+			Help:      fmt.Sprintf("Derived metric %s.", name),
+		},
+		fn: fn,
+	}
+
+	i := slices.IndexFunc(registeredDerivedMetrics, func(r registeredDerivedMetric) bool {
+		return r.counter.FieldName == name
+	})
+	if i >= 0 {
+		registeredDerivedMetrics[i] = derived
+		return
+	}
+	registeredDerivedMetrics = append(registeredDerivedMetrics, derived)
+}
+
+// isRegisteredDerivedMetric returns whether name was registered with RegisterDerivedMetric.
+func isRegisteredDerivedMetric(name string) bool {
+	registeredDerivedMetricsMtx.RLock()
+	defer registeredDerivedMetricsMtx.RUnlock()
+
+	return slices.ContainsFunc(registeredDerivedMetrics, func(r registeredDerivedMetric) bool {
+		return r.counter.FieldName == name
+	})
+}
+
+// appendRegisteredDerivedMetrics appends the registered derived metrics of an entity.
+func appendRegisteredDerivedMetrics(metrics MetricsByCounter, vals []dcgm.FieldValue_v1, mi MonitoringInfo,
+	hostname string, replaceBlanksInModelName bool) {
+	registeredDerivedMetricsMtx.RLock()
+	defer registeredDerivedMetricsMtx.RUnlock()
+
+	for _, derived := range registeredDerivedMetrics {
+		m, ok := derived.fn(mi, vals)
+		if !ok {
+			continue
+		}
+
+		m.Counter = derived.counter
+		m.Hostname = hostname
+		m.Attributes = maps.Clone(m.Attributes)
+		if m.Attributes == nil {
+			m.Attributes = map[string]string{}
+		}
+
+		if mi.Entity.EntityGroupId == dcgm.FE_GPU || mi.Entity.EntityGroupId == dcgm.FE_GPU_I {
+			m.GPU = fmt.Sprintf("%d", mi.DeviceInfo.GPU)
+			m.GPUUUID = mi.DeviceInfo.UUID
+			m.GPUDevice = fmt.Sprintf("nvidia%d", mi.DeviceInfo.GPU)
+			m.GPUModelName = getGPUModel(mi.DeviceInfo, replaceBlanksInModelName)
+			if mi.InstanceInfo != nil {
+				m.MigProfile = mi.InstanceInfo.ProfileName
+				m.GPUInstanceID = fmt.Sprintf("%d", mi.InstanceInfo.Info.NvmlInstanceId)
+			}
+		}
+
+		metrics[derived.counter] = append(metrics[derived.counter], m)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetricsWithRegisteredDerivedMetric(t *testing.T) {
+	tempCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+
+	sysInfo := SystemInfo{GPUCount: 2, InfoType: dcgm.FE_GPU, gOpt: DeviceOptions{Flex: true}}
+	sysInfo.GPUs[0] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-0"}}
+	sysInfo.GPUs[1] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 1, UUID: "GPU-1"}}
+
+	collector := &DCGMCollector{
+		Counters:      []Counter{tempCounter},
+		DeviceFields:  []dcgm.Short{tempCounter.FieldID},
+		SysInfo:       sysInfo,
+		MetricOptions: MetricOptions{Hostname: "testhost"},
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+	dcgmEntityGetLatestValues = func(_ dcgm.Field_Entity_Group, gpu uint, _ []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], uint64(40+10*gpu))
+		return []dcgm.FieldValue_v1{{FieldId: uint(tempCounter.FieldID), FieldType: dcgm.DCGM_FT_INT64, Value: value}}, nil
+	}
+
+	defer func(registered []registeredDerivedMetric) {
+		registeredDerivedMetrics = registered
+	}(registeredDerivedMetrics)
+	registeredDerivedMetrics = nil
+
+	RegisterDerivedMetric("ORG_GPU_TEMP_F", func(_ MonitoringInfo, vals []dcgm.FieldValue_v1) (Metric, bool) {
+		for _, val := range vals {
+			if val.FieldId == uint(dcgm.DCGM_FI_DEV_GPU_TEMP) {
+				return Metric{Value: fmt.Sprint(val.Int64()*9/5 + 32)}, true
+			}
+		}
+		return Metric{}, false
+	})
+	RegisterDerivedMetric("ORG_UNCOMPUTABLE", func(MonitoringInfo, []dcgm.FieldValue_v1) (Metric, bool) {
+		return Metric{}, false
+	})
+	assert.True(t, isCounterName("ORG_GPU_TEMP_F"))
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+
+	var derived []Metric
+	for counter, values := range metrics {
+		assert.NotEqual(t, "ORG_UNCOMPUTABLE", counter.FieldName)
+		if counter.FieldName == "ORG_GPU_TEMP_F" {
+			assert.Equal(t, "gauge", counter.PromType)
+			derived = values
+		}
+	}
+
+	require.Len(t, derived, 2)
+	for i, m := range derived {
+		assert.Equal(t, fmt.Sprint(i), m.GPU)
+		assert.Equal(t, fmt.Sprintf("GPU-%d", i), m.GPUUUID)
+		assert.Equal(t, "testhost", m.Hostname)
+		assert.NotNil(t, m.Attributes)
+	}
+	assert.Equal(t, "104", derived[0].Value)
+	assert.Equal(t, "122", derived[1].Value)
+}
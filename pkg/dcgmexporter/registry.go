@@ -17,7 +17,12 @@
 package dcgmexporter
 
 import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
 	"sync"
+	"sync/atomic"
 
 	"golang.org/x/sync/errgroup"
 )
@@ -25,6 +30,16 @@ import (
 type Registry struct {
 	collectors []Collector
 	mtx        sync.RWMutex
+
+	inFlightMtx sync.Mutex
+	inFlight    *gathering // Gather in flight for GatherWithContext, if any
+}
+
+// gathering is the result of a gather, which is ready once done is closed.
+type gathering struct {
+	done    chan struct{}
+	metrics MetricsByCounter
+	err     error
 }
 
 func NewRegistry() *Registry {
@@ -39,19 +54,18 @@ func (r *Registry) Register(c Collector) {
 }
 
 // Gather gathers metrics from all registered collectors.
+// Every collector writes into its own MetricsByCounter, and those are merged once all collectors are done.
 func (r *Registry) Gather() (MetricsByCounter, error) {
 	r.mtx.Lock()
 	defer r.mtx.Unlock()
 
-	var wg sync.WaitGroup
-	wg.Add(len(r.collectors))
-
 	g := new(errgroup.Group)
 
-	var sm sync.Map
+	collectedMetrics := make([]MetricsByCounter, len(r.collectors))
+	var collected atomic.Bool
 
-	for _, c := range r.collectors {
-		c := c //creates new c, see https://golang.org/doc/faq#closures_and_goroutines
+	for i, c := range r.collectors {
+		i, c := i, c //creates new i and c, see https://golang.org/doc/faq#closures_and_goroutines
 		g.Go(func() error {
 			metrics, err := c.GetMetrics()
 
@@ -59,29 +73,107 @@ func (r *Registry) Gather() (MetricsByCounter, error) {
 				return err
 			}
 
-			for counter, metricVals := range metrics {
-				val, _ := sm.LoadOrStore(counter, []Metric{})
-				out := val.([]Metric)
-				out = append(out, metricVals...)
-				sm.Store(counter, out)
-			}
+			collected.Store(true)
+
+			collectedMetrics[i] = metrics
 
 			return nil
 		})
 	}
 
 	if err := g.Wait(); err != nil {
+		if collected.Load() {
+			return nil, errors.Join(ErrPartialCollection, err)
+		}
 		return nil, err
 	}
 
+	return mergeMetrics(collectedMetrics), nil
+}
+
+// mergeMetrics merges the metrics of the collectors in order. Counters that share a metric name are merged
+// under the first of them, so that a metric is never exposed twice with a different help or type.
+func mergeMetrics(collectedMetrics []MetricsByCounter) MetricsByCounter {
 	output := MetricsByCounter{}
+	counterByName := map[string]Counter{}
+
+	for _, metrics := range collectedMetrics {
+		counters := make([]Counter, 0, len(metrics))
+		for counter := range metrics {
+			counters = append(counters, counter)
+		}
+
+		slices.SortFunc(counters, compareCounters)
+
+		for _, counter := range counters {
+			merged, exists := counterByName[counter.FieldName]
+			if !exists {
+				merged = counter
+				counterByName[counter.FieldName] = counter
+			}
+
+			for _, metric := range metrics[counter] {
+				metric.Counter = merged
+				output[merged] = append(output[merged], metric)
+			}
+		}
+	}
+
+	return output
+}
+
+func compareCounters(a, b Counter) int {
+	if c := cmp.Compare(a.FieldName, b.FieldName); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.FieldID, b.FieldID); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.PromType, b.PromType); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Help, b.Help)
+}
+
+// GatherWithContext gathers metrics like Gather, but gives up as soon as ctx is done.
+// Collectors that are still running when ctx is done finish in the background. A gather that is still running,
+// because another scrape started it or its caller gave up, is joined rather than a new one started, so that
+// concurrent scrapes poll DCGM once and abandoned gathers do not pile up when the collectors are slower than the
+// scrape timeout.
+func (r *Registry) GatherWithContext(ctx context.Context) (MetricsByCounter, error) {
+	g := r.startGathering()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-g.done:
+		return g.metrics, g.err
+	}
+}
+
+// startGathering returns the gather in flight, or starts one when there is none.
+func (r *Registry) startGathering() *gathering {
+	r.inFlightMtx.Lock()
+	defer r.inFlightMtx.Unlock()
+
+	if r.inFlight != nil {
+		return r.inFlight
+	}
+
+	g := &gathering{done: make(chan struct{})}
+	r.inFlight = g
+
+	go func() {
+		g.metrics, g.err = r.Gather()
+
+		r.inFlightMtx.Lock()
+		r.inFlight = nil
+		r.inFlightMtx.Unlock()
 
-	sm.Range(func(key, value interface{}) bool {
-		output[key.(Counter)] = value.([]Metric)
-		return true // continue iteration
-	})
+		close(g.done)
+	}()
 
-	return output, nil
+	return g
 }
 
 // Cleanup resources of registered collectors
@@ -17,9 +17,16 @@
 package dcgmexporter
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -106,3 +113,134 @@ func TestRegistry_Gather(t *testing.T) {
 
 	}
 }
+
+func TestRegistry_GatherOverlappingCollectors(t *testing.T) {
+	gpuCounter := Counter{
+		FieldName: "DCGM_EXP_XID_ERRORS_COUNT",
+		PromType:  "gauge",
+		Help:      "Count of XID Errors within user-specified time window.",
+	}
+	cpuCounter := Counter{
+		FieldName: "DCGM_EXP_XID_ERRORS_COUNT",
+		PromType:  "counter",
+		Help:      "Count of XID Errors.",
+	}
+
+	gpuCollector := new(mockCollector)
+	gpuCollector.On("GetMetrics").Return(MetricsByCounter{
+		gpuCounter: {{Counter: gpuCounter, GPU: "0", UUID: "UUID", Value: "1", Attributes: map[string]string{}}},
+	}, nil)
+	cpuCollector := new(mockCollector)
+	cpuCollector.On("GetMetrics").Return(MetricsByCounter{
+		cpuCounter: {{Counter: cpuCounter, GPU: "1", UUID: "UUID", Value: "2", Attributes: map[string]string{}}},
+	}, nil)
+
+	reg := NewRegistry()
+	reg.Register(gpuCollector)
+	reg.Register(cpuCollector)
+
+	var metrics MetricsByCounter
+	require.NotPanics(t, func() {
+		var err error
+		metrics, err = reg.Gather()
+		require.NoError(t, err)
+	})
+
+	require.Len(t, metrics, 1, "counters sharing a name are merged")
+	require.Len(t, metrics[gpuCounter], 2, "the counter of the first collector wins")
+	for _, metric := range metrics[gpuCounter] {
+		assert.Equal(t, gpuCounter, metric.Counter)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, encodeExpMetrics(&buf, metrics))
+	assert.Equal(t, 1, strings.Count(buf.String(), "# HELP DCGM_EXP_XID_ERRORS_COUNT"))
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(&buf)
+	require.NoError(t, err)
+	require.Len(t, families["DCGM_EXP_XID_ERRORS_COUNT"].GetMetric(), 2)
+}
+
+func TestRegistry_GatherWithContextJoinsAbandonedGather(t *testing.T) {
+	counter := Counter{FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge"}
+
+	release := make(chan time.Time)
+	collector := new(mockCollector)
+	collector.On("GetMetrics").Return(MetricsByCounter{
+		counter: {{Counter: counter, Value: "42", Attributes: map[string]string{}}},
+	}, nil).WaitUntil(release)
+
+	reg := NewRegistry()
+	reg.Register(collector)
+
+	abandon := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := reg.GatherWithContext(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+
+	abandon()
+	abandoned := reg.startGathering()
+	abandon()
+
+	close(release)
+	<-abandoned.done
+	require.Len(t, abandoned.metrics[counter], 1)
+
+	metrics, err := reg.GatherWithContext(context.Background())
+	require.NoError(t, err)
+	require.Len(t, metrics[counter], 1)
+	assert.Equal(t, "42", metrics[counter][0].Value)
+
+	// The second scrape joined the abandoned gather, and the third started a new one
+	collector.AssertNumberOfCalls(t, "GetMetrics", 2)
+}
+
+// blockingCollector returns its metrics once release is closed, and signals every call of GetMetrics on entered.
+type blockingCollector struct {
+	metrics MetricsByCounter
+	entered chan struct{}
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (c *blockingCollector) GetMetrics() (MetricsByCounter, error) {
+	c.calls.Add(1)
+	c.entered <- struct{}{}
+	<-c.release
+	return c.metrics, nil
+}
+
+func (c *blockingCollector) Cleanup() {}
+
+func TestRegistry_GatherWithContextSharesGatherInFlight(t *testing.T) {
+	counter := Counter{FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge"}
+
+	collector := &blockingCollector{
+		metrics: MetricsByCounter{counter: {{Counter: counter, Value: "42", Attributes: map[string]string{}}}},
+		entered: make(chan struct{}, 2),
+		release: make(chan struct{}),
+	}
+
+	reg := NewRegistry()
+	reg.Register(collector)
+
+	first := reg.startGathering()
+	<-collector.entered
+
+	second := reg.startGathering()
+	assert.Same(t, first, second, "a scrape during a gather waits for it instead of polling DCGM again")
+
+	close(collector.release)
+	<-first.done
+	require.NoError(t, first.err)
+	require.Len(t, first.metrics[counter], 1)
+	assert.Equal(t, int32(1), collector.calls.Load())
+
+	metrics, err := reg.GatherWithContext(context.Background())
+	require.NoError(t, err)
+	require.Len(t, metrics[counter], 1)
+	assert.Equal(t, int32(2), collector.calls.Load(), "a scrape after the gather starts a new one")
+}
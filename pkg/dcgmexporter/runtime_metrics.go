@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"runtime"
+	"strings"
+)
+
+// formatRuntimeMetrics returns the standard gauges of the Go runtime, which describe the footprint of the exporter
+// process itself.
+func formatRuntimeMetrics() (string, error) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	gauges := []struct {
+		name  string
+		help  string
+		value any
+	}{
+		{"go_goroutines", "Number of goroutines that currently exist.", runtime.NumGoroutine()},
+		{"go_threads", "Number of OS threads created.", threadCount()},
+		{"go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", memStats.Alloc},
+		{"go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", memStats.HeapAlloc},
+		{"go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", memStats.HeapInuse},
+		{"go_memstats_heap_objects", "Number of allocated objects.", memStats.HeapObjects},
+		{"go_memstats_sys_bytes", "Number of bytes obtained from system.", memStats.Sys},
+	}
+
+	var res strings.Builder
+	for _, gauge := range gauges {
+		out, err := formatExporterGauge(gauge.name, gauge.help, gauge.value)
+		if err != nil {
+			return "", err
+		}
+		res.WriteString(out)
+	}
+
+	return res.String(), nil
+}
+
+func threadCount() int {
+	n, _ := runtime.ThreadCreateProfile(nil)
+	return n
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/prometheus/common/expfmt"
+	"github.com/sirupsen/logrus"
+)
+
+var dcgmCreateFakeEntities = dcgm.CreateFakeEntities
+
+// SelfTest loads the configured counters, collects them once from a fake GPU
+// and validates that the exposition is valid Prometheus text format.
+func SelfTest(config *Config, newDCGMCollector DCGMCollectorConstructor) error {
+	cs, err := GetCounterSet(config)
+	if err != nil {
+		return fmt.Errorf("failed to load counters; err: %w", err)
+	}
+
+	if len(cs.DCGMCounters) == 0 {
+		return fmt.Errorf("no counters to collect in '%s'", config.CollectorsFile)
+	}
+
+	_, err = dcgmCreateFakeEntities([]dcgm.MigHierarchyInfo{
+		{Entity: dcgm.GroupEntityPair{EntityGroupId: dcgm.FE_GPU}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create fake GPU; err: %w", err)
+	}
+
+	selfTestConfig := *config
+	selfTestConfig.UseFakeGPUs = true
+
+	fieldEntityGroupTypeSystemInfo := NewEntityGroupTypeSystemInfo(cs.DCGMCounters, &selfTestConfig)
+	err = fieldEntityGroupTypeSystemInfo.Load(dcgm.FE_GPU)
+	if err != nil {
+		return fmt.Errorf("failed to initialize system info; err: %w", err)
+	}
+
+	hostname, err := GetHostname(&selfTestConfig)
+	if err != nil {
+		return err
+	}
+
+	pipeline, cleanup, err := NewMetricsPipeline(&selfTestConfig,
+		cs.DCGMCounters,
+		hostname,
+		newDCGMCollector,
+		fieldEntityGroupTypeSystemInfo,
+	)
+	defer cleanup()
+	if err != nil {
+		return fmt.Errorf("failed to create metrics pipeline; err: %w", err)
+	}
+
+	out, err := pipeline.run()
+	if err != nil {
+		return err
+	}
+
+	if err := validateExposition(out); err != nil {
+		return err
+	}
+
+	logrus.Infof("Self-test passed; collected %d counters", len(cs.DCGMCounters))
+
+	return nil
+}
+
+// validateExposition reports an error, when out cannot be parsed as Prometheus text format.
+func validateExposition(out string) error {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(out))
+	if err != nil {
+		return fmt.Errorf("invalid metrics exposition; err: %w", err)
+	}
+
+	if len(families) == 0 {
+		return fmt.Errorf("no metrics were collected")
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfTest(t *testing.T) {
+	tests := []struct {
+		name      string
+		csv       string
+		wantError string
+	}{
+		{
+			name: "valid config",
+			csv:  "DCGM_FI_DEV_GPU_TEMP, gauge, GPU temperature (in C).\n",
+		},
+		{
+			name:      "malformed counter",
+			csv:       "DCGM_FI_DEV_GPU_TEMP, gauge\n",
+			wantError: "failed to load counters",
+		},
+		{
+			name:      "unknown Prometheus type",
+			csv:       "DCGM_FI_DEV_GPU_TEMP, gaugee, GPU temperature (in C).\n",
+			wantError: "could not find Prometheus metric type 'gaugee'",
+		},
+	}
+
+	mockSelfTestDCGM(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collectorsFile := filepath.Join(t.TempDir(), "counters.csv")
+			require.NoError(t, os.WriteFile(collectorsFile, []byte(tt.csv), 0o644))
+
+			config := &Config{
+				CollectorsFile:  collectorsFile,
+				ConfigMapData:   undefinedConfigMapData,
+				GPUDevices:      DeviceOptions{Flex: true, MajorRange: []int{-1}, MinorRange: []int{-1}},
+				NoHostname:      true,
+				CollectInterval: 1,
+			}
+
+			err := SelfTest(config, newSelfTestCollector)
+			if tt.wantError != "" {
+				require.ErrorContains(t, err, tt.wantError)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSelfTestFailsWhenGPUCannotBeCreated(t *testing.T) {
+	mockSelfTestDCGM(t)
+
+	dcgmCreateFakeEntities = func([]dcgm.MigHierarchyInfo) ([]uint, error) {
+		return nil, errors.New("injection is not supported")
+	}
+
+	collectorsFile := filepath.Join(t.TempDir(), "counters.csv")
+	require.NoError(t, os.WriteFile(collectorsFile, []byte("DCGM_FI_DEV_GPU_TEMP, gauge, GPU temperature (in C).\n"), 0o644))
+
+	err := SelfTest(&Config{CollectorsFile: collectorsFile, ConfigMapData: undefinedConfigMapData}, newSelfTestCollector)
+	assert.ErrorContains(t, err, "failed to create fake GPU")
+}
+
+func TestValidateExposition(t *testing.T) {
+	assert.NoError(t, validateExposition("# TYPE DCGM_FI_DEV_GPU_TEMP gauge\nDCGM_FI_DEV_GPU_TEMP{gpu=\"0\"} 42\n"))
+	assert.ErrorContains(t, validateExposition("DCGM_FI_DEV_GPU_TEMP{gpu=\"0\" 42\n"), "invalid metrics exposition")
+	assert.ErrorContains(t, validateExposition(""), "no metrics were collected")
+}
+
+// newSelfTestCollector creates a collector, which does not watch fields in DCGM.
+func newSelfTestCollector(c []Counter, hostname string, _ *Config, item FieldEntityGroupTypeSystemInfoItem) (*DCGMCollector, func(), error) {
+	return &DCGMCollector{
+		Counters:      c,
+		DeviceFields:  item.DeviceFields,
+		SysInfo:       item.SystemInfo,
+		MetricOptions: MetricOptions{Hostname: hostname},
+	}, func() {}, nil
+}
+
+func mockSelfTestDCGM(t *testing.T) {
+	createFakeEntities := dcgmCreateFakeEntities
+	getAllDeviceCount := dcgmGetAllDeviceCount
+	getDeviceInfo := dcgmGetDeviceInfo
+	getGpuInstanceHierarchy := dcgmGetGpuInstanceHierarchy
+	entityGetLatestValues := dcgmEntityGetLatestValues
+	fieldGetById := dcgmFieldGetById
+
+	t.Cleanup(func() {
+		dcgmCreateFakeEntities = createFakeEntities
+		dcgmGetAllDeviceCount = getAllDeviceCount
+		dcgmGetDeviceInfo = getDeviceInfo
+		dcgmGetGpuInstanceHierarchy = getGpuInstanceHierarchy
+		dcgmEntityGetLatestValues = entityGetLatestValues
+		dcgmFieldGetById = fieldGetById
+	})
+
+	dcgmFieldGetById = func(fieldId dcgm.Short) dcgm.FieldMeta {
+		return dcgm.FieldMeta{FieldId: fieldId, EntityLevel: dcgm.FE_GPU}
+	}
+
+	dcgmCreateFakeEntities = func(entities []dcgm.MigHierarchyInfo) ([]uint, error) {
+		return []uint{0}, nil
+	}
+
+	dcgmGetAllDeviceCount = func() (uint, error) {
+		return 1, nil
+	}
+
+	dcgmGetDeviceInfo = func(gpuId uint) (dcgm.Device, error) {
+		return dcgm.Device{}, errors.New("fake GPUs have no device info")
+	}
+
+	dcgmGetGpuInstanceHierarchy = func() (dcgm.MigHierarchy_v2, error) {
+		return dcgm.MigHierarchy_v2{}, nil
+	}
+
+	dcgmEntityGetLatestValues = func(_ dcgm.Field_Entity_Group, _ uint, fields []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		var values []dcgm.FieldValue_v1
+		for _, field := range fields {
+			value := dcgm.FieldValue_v1{FieldId: uint(field), FieldType: dcgm.DCGM_FT_INT64}
+			binary.LittleEndian.PutUint64(value.Value[:], 42)
+			values = append(values, value)
+		}
+
+		return values, nil
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import "github.com/sirupsen/logrus"
+
+const dcgmExporterSeriesDropped = "DCGM_EXPORTER_SERIES_DROPPED"
+
+// seriesLimit caps the number of series exported by one collection, shared by all collectors of the pipeline.
+type seriesLimit struct {
+	remaining int
+	dropped   int
+}
+
+// newSeriesLimit returns nil, when maxSeries does not limit the number of series.
+func newSeriesLimit(maxSeries int) *seriesLimit {
+	if maxSeries <= 0 {
+		return nil
+	}
+
+	return &seriesLimit{remaining: maxSeries}
+}
+
+// apply drops the counters with the most series from metrics, until the remaining series fit into the limit.
+func (l *seriesLimit) apply(metrics MetricsByCounter) {
+	if l == nil {
+		return
+	}
+
+	series := 0
+	for _, m := range metrics {
+		series += len(m)
+	}
+
+	for series > l.remaining {
+		var largest Counter
+		largestSeries := -1
+		for counter, m := range metrics {
+			if len(m) > largestSeries || (len(m) == largestSeries && counter.FieldName < largest.FieldName) {
+				largest = counter
+				largestSeries = len(m)
+			}
+		}
+
+		logrus.Warnf("Dropping %d series of %s; the maximum number of series is exceeded", largestSeries, largest.FieldName)
+
+		delete(metrics, largest)
+		series -= largestSeries
+		l.dropped += largestSeries
+	}
+
+	l.remaining -= series
+}
+
+// format returns the DCGM_EXPORTER_SERIES_DROPPED gauge.
+func (l *seriesLimit) format() (string, error) {
+	if l == nil {
+		return "", nil
+	}
+
+	return formatExporterGauge(dcgmExporterSeriesDropped,
+		"Number of series dropped by the last collection, because they exceeded the maximum number of series.",
+		l.dropped)
+}
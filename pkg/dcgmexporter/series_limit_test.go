@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesLimitApply(t *testing.T) {
+	temp := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "Temperature Help info"}
+	power := Counter{FieldID: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge", Help: "Power help info"}
+	xid := Counter{FieldID: dcgm.DCGM_FI_DEV_XID_ERRORS, FieldName: "DCGM_FI_DEV_XID_ERRORS", PromType: "gauge", Help: "XID help info"}
+
+	newMetrics := func() MetricsByCounter {
+		return MetricsByCounter{
+			temp:  make([]Metric, 2),
+			power: make([]Metric, 2),
+			xid:   make([]Metric, 5),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		maxSeries   int
+		wantDropped int
+		wantKept    []Counter
+	}{
+		{
+			name:      "within the limit",
+			maxSeries: 9,
+			wantKept:  []Counter{temp, power, xid},
+		},
+		{
+			name:        "drops the counter with the most series",
+			maxSeries:   8,
+			wantDropped: 5,
+			wantKept:    []Counter{temp, power},
+		},
+		{
+			name:        "drops counters with equal series in name order",
+			maxSeries:   3,
+			wantDropped: 7,
+			wantKept:    []Counter{power},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := newSeriesLimit(tt.maxSeries)
+			metrics := newMetrics()
+
+			limit.apply(metrics)
+
+			assert.Equal(t, tt.wantDropped, limit.dropped)
+			assert.ElementsMatch(t, tt.wantKept, keys(metrics))
+		})
+	}
+}
+
+func TestSeriesLimitIsSharedByCollectors(t *testing.T) {
+	temp := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "Temperature Help info"}
+	switchTemp := Counter{FieldID: dcgm.DCGM_FI_DEV_NVSWITCH_TEMPERATURE_CURRENT, FieldName: "DCGM_FI_DEV_NVSWITCH_TEMPERATURE_CURRENT", PromType: "gauge", Help: "switch temperature"}
+
+	limit := newSeriesLimit(3)
+
+	gpuMetrics := MetricsByCounter{temp: make([]Metric, 2)}
+	limit.apply(gpuMetrics)
+	assert.Len(t, gpuMetrics, 1)
+
+	switchMetrics := MetricsByCounter{switchTemp: make([]Metric, 2)}
+	limit.apply(switchMetrics)
+	assert.Empty(t, switchMetrics)
+	assert.Equal(t, 2, limit.dropped)
+}
+
+func TestSeriesLimitDisabled(t *testing.T) {
+	limit := newSeriesLimit(0)
+	assert.Nil(t, limit)
+
+	metrics := MetricsByCounter{
+		{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "Temperature Help info"}: make([]Metric, 10),
+	}
+	limit.apply(metrics)
+	assert.Len(t, metrics, 1)
+
+	out, err := limit.format()
+	require.NoError(t, err)
+	assert.Empty(t, out)
+}
+
+func TestPipelineReportsDroppedSeries(t *testing.T) {
+	fieldValue := [4096]byte{}
+	binary.LittleEndian.PutUint64(fieldValue[:], math.Float64bits(42))
+
+	util := Counter{FieldID: dcgm.DCGM_FI_DEV_CPU_UTIL_TOTAL, FieldName: "DCGM_FI_DEV_CPU_UTIL_TOTAL", PromType: "gauge", Help: "Total CPU utilization"}
+	power := Counter{FieldID: dcgm.DCGM_FI_DEV_CPU_POWER_UTIL_CURRENT, FieldName: "DCGM_FI_DEV_CPU_POWER_UTIL_CURRENT", PromType: "gauge", Help: "CPU socket power draw (in W)."}
+
+	collector := &DCGMCollector{
+		Counters:     []Counter{util, power},
+		DeviceFields: []dcgm.Short{util.FieldID, power.FieldID},
+		SysInfo: SystemInfo{
+			InfoType: dcgm.FE_CPU,
+			CPUs:     []CPUInfo{{EntityId: 0}, {EntityId: 1}},
+			cOpt:     DeviceOptions{Flex: true},
+		},
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+
+	dcgmEntityGetLatestValues = func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		return []dcgm.FieldValue_v1{
+			{FieldId: uint(util.FieldID), FieldType: dcgm.DCGM_FT_DOUBLE, Value: fieldValue},
+			{FieldId: uint(power.FieldID), FieldType: dcgm.DCGM_FT_DOUBLE, Value: fieldValue},
+		}, nil
+	}
+
+	p, cleanup, err := NewMetricsPipelineWithGPUCollector(&Config{MaxSeries: 3}, collector)
+	require.NoError(t, err)
+	defer cleanup()
+
+	out, err := p.run()
+	require.NoError(t, err)
+	assert.Contains(t, out, "DCGM_FI_DEV_CPU_UTIL_TOTAL{")
+	assert.NotContains(t, out, "DCGM_FI_DEV_CPU_POWER_UTIL_CURRENT{")
+	assert.Contains(t, out, "# TYPE DCGM_EXPORTER_SERIES_DROPPED gauge\nDCGM_EXPORTER_SERIES_DROPPED 2\n")
+}
+
+func keys(metrics MetricsByCounter) []Counter {
+	var counters []Counter
+	for counter := range metrics {
+		counters = append(counters, counter)
+	}
+
+	return counters
+}
@@ -17,8 +17,12 @@
 package dcgmexporter
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -28,7 +32,12 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func NewMetricsServer(c *Config, metrics chan string, registry *Registry) (*MetricsServer, func(), error) {
+func NewMetricsServer(c *Config,
+	metrics chan string,
+	registry *Registry,
+	lastError *LastCollectionError,
+	snapshots *MetricsSnapshots,
+) (*MetricsServer, func(), error) {
 	router := mux.NewRouter()
 	serverv1 := &MetricsServer{
 		server: &http.Server{
@@ -45,6 +54,9 @@ func NewMetricsServer(c *Config, metrics chan string, registry *Registry) (*Metr
 		metricsChan: metrics,
 		metrics:     "",
 		registry:    registry,
+		lastError:   lastError,
+		snapshots:   snapshots,
+		config:      c,
 	}
 
 	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -65,7 +77,24 @@ func NewMetricsServer(c *Config, metrics chan string, registry *Registry) (*Metr
 	})
 
 	router.HandleFunc("/health", serverv1.Health)
-	router.HandleFunc("/metrics", serverv1.Metrics)
+	router.HandleFunc("/metrics", withGzip(serverv1.Metrics))
+
+	if c.EnableDebugEndpoints {
+		router.HandleFunc("/debug/config", serverv1.Config)
+
+		if snapshots != nil {
+			router.HandleFunc("/debug/diff", serverv1.Diff)
+		}
+	}
+
+	if c.BackgroundCollection {
+		serverv1.gatherInterval = time.Millisecond * time.Duration(c.CollectInterval)
+	}
+
+	if c.EnableDiag {
+		serverv1.diag = &diagRunner{}
+		router.HandleFunc("/diag", serverv1.Diag).Methods(http.MethodPost)
+	}
 
 	return serverv1, func() {}, nil
 }
@@ -85,6 +114,14 @@ func (s *MetricsServer) Run(stop chan interface{}, wg *sync.WaitGroup) {
 		}
 	}()
 
+	if s.gatherInterval > 0 {
+		httpwg.Add(1)
+		go func() {
+			defer httpwg.Done()
+			s.gatherInBackground(stop)
+		}()
+	}
+
 	httpwg.Add(1)
 	go func() {
 		defer httpwg.Done()
@@ -109,25 +146,188 @@ func (s *MetricsServer) Run(stop chan interface{}, wg *sync.WaitGroup) {
 }
 
 func (s *MetricsServer) Metrics(w http.ResponseWriter, r *http.Request) {
+	filter, err := newCounterFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hostname := newHostnameOverride(r, s.config)
+
+	ctx, cancel := scrapeContext(r)
+	defer cancel()
+
+	metrics, err := s.gather(ctx)
+	if s.lastError != nil {
+		s.lastError.Update(registrySource, err)
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			logrus.WithError(err).Warn("Scrape abandoned before metrics were collected.")
+			http.Error(w, "scrape abandoned before metrics were collected", http.StatusServiceUnavailable)
+			return
+		}
+		logrus.WithError(err).Error("Failed to write response.")
+		http.Error(w, "failed to write response", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 	w.WriteHeader(http.StatusOK)
-	_, err := w.Write([]byte(s.getMetrics()))
+	exposition := hostname.overrideExposition(filter.filterExposition(s.getMetrics()))
+	_, err = w.Write([]byte(exposition))
 	if err != nil {
 		logrus.WithError(err).Error("Failed to write response.")
 		http.Error(w, "failed to write response", http.StatusInternalServerError)
 		return
 	}
-	metrics, err := s.registry.Gather()
+	var expExposition bytes.Buffer
+	err = encodeExpMetrics(&expExposition, hostname.overrideMetrics(filter.filterMetrics(metrics)))
 	if err != nil {
-		logrus.WithError(err).Error("Failed to write response.")
 		http.Error(w, "failed to write response", http.StatusInternalServerError)
 		return
 	}
-	err = encodeExpMetrics(w, metrics)
+	_, err = w.Write(expExposition.Bytes())
+	if err != nil {
+		logrus.WithError(err).Error("Failed to write response.")
+		return
+	}
+	// The exporter gauges are filtered like the field metrics, so that they can be requested by name as well
+	var gauges bytes.Buffer
+	err = s.encodeExporterGauges(&gauges, exposition+expExposition.String())
 	if err != nil {
 		http.Error(w, "failed to write response", http.StatusInternalServerError)
 		return
 	}
+	_, err = w.Write([]byte(filter.filterExposition(gauges.String())))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to write response.")
+		return
+	}
+}
+
+// encodeExporterGauges writes the gauges, which describe the exporter itself, for the served exposition.
+func (s *MetricsServer) encodeExporterGauges(w io.Writer, exposition string) error {
+	if s.lastError != nil {
+		err := s.lastError.encode(w)
+		if err != nil {
+			return err
+		}
+	}
+
+	encoders := []func(io.Writer) error{
+		watchedFields.encode,
+		collectionsTotal.encode,
+		clockSkewEvents.encode,
+		conversionErrors.encode,
+		orphanLinks.encode,
+	}
+	if s.diag != nil {
+		encoders = append(encoders, s.diag.encode)
+	}
+	for _, encode := range encoders {
+		err := encode(w)
+		if err != nil {
+			return err
+		}
+	}
+
+	formatters := []func() (string, error){formatUptime}
+	if s.gatherInterval != 0 {
+		formatters = append(formatters, func() (string, error) { return formatMetricsAge(s.gatheredAge()) })
+	}
+	if s.config != nil && s.config.EmitRuntimeMetrics {
+		formatters = append(formatters, formatRuntimeMetrics)
+	}
+	if s.config != nil && s.config.MetricsChecksum {
+		formatters = append(formatters, func() (string, error) { return formatMetricsChecksum(exposition) })
+	}
+	if s.config != nil && s.config.HostengineHealth {
+		formatters = append(formatters, formatHostengineHealth)
+	}
+	for _, format := range formatters {
+		gauge, err := format()
+		if err != nil {
+			return err
+		}
+
+		_, err = io.WriteString(w, gauge)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scrapeContext returns the request context, limited by the scrape timeout that Prometheus sends in the
+// X-Prometheus-Scrape-Timeout-Seconds header, so that collection is abandoned once the client stops waiting.
+func scrapeContext(r *http.Request) (context.Context, context.CancelFunc) {
+	timeout, err := strconv.ParseFloat(r.Header.Get(scrapeTimeoutHeader), 64)
+	if err != nil || timeout <= 0 {
+		return context.WithCancel(r.Context())
+	}
+
+	return context.WithTimeout(r.Context(), time.Duration(timeout*float64(time.Second)))
+}
+
+// Diag starts a DCGM diagnostic of the level in the level query parameter. The diagnostic runs in the background;
+// its result is exposed by the metrics endpoint once it is finished.
+func (s *MetricsServer) Diag(w http.ResponseWriter, r *http.Request) {
+	level, err := parseDiagLevel(r.URL.Query().Get(diagLevelQueryParam))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.diag.start(level)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Diff returns the series, which changed between the last two collections. Changes of numeric values up to the
+// threshold query parameter are ignored.
+func (s *MetricsServer) Diff(w http.ResponseWriter, r *http.Request) {
+	threshold := 0.0
+	if value := r.URL.Query().Get(diffThresholdQueryParam); value != "" {
+		var err error
+		threshold, err = strconv.ParseFloat(value, 64)
+		if err != nil || threshold < 0 {
+			http.Error(w, "invalid threshold: "+value, http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	err := s.snapshots.encodeDiff(w, threshold)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to write response.")
+	}
+}
+
+// Config returns the config the exporter runs with as JSON, with the credentials redacted.
+func (s *MetricsServer) Config(w http.ResponseWriter, r *http.Request) {
+	config, err := encodeConfig(s.config)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to encode the config.")
+		http.Error(w, "failed to encode the config", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(config)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to write response.")
+	}
 }
 
 func (s *MetricsServer) Health(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,314 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsServer_MetricsWithScrapeDeadline(t *testing.T) {
+	collector := new(mockCollector)
+	collector.On("GetMetrics").Return(MetricsByCounter{}, nil).After(200 * time.Millisecond)
+
+	reg := NewRegistry()
+	reg.Register(collector)
+
+	server := &MetricsServer{registry: reg, metrics: "DCGM_FI_DEV_GPU_TEMP{gpu=\"0\"} 42\n"}
+
+	t.Run("When the request context times out", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil).WithContext(ctx)
+		recorder := httptest.NewRecorder()
+		server.Metrics(recorder, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.NotContains(t, recorder.Body.String(), "DCGM_FI_DEV_GPU_TEMP")
+	})
+
+	t.Run("When the scrape timeout header is exceeded", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set(scrapeTimeoutHeader, "0.01")
+		recorder := httptest.NewRecorder()
+		server.Metrics(recorder, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	})
+
+	t.Run("When the collection completes in time", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set(scrapeTimeoutHeader, "10")
+		recorder := httptest.NewRecorder()
+		server.Metrics(recorder, req)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "DCGM_FI_DEV_GPU_TEMP")
+	})
+}
+
+func TestMetricsServer_MetricsWithCounterFilter(t *testing.T) {
+	xidCounter := Counter{FieldName: "DCGM_EXP_XID_ERRORS_COUNT", PromType: "gauge", Help: "Count of XID Errors within user-specified time window."}
+
+	collector := new(mockCollector)
+	collector.On("GetMetrics").Return(MetricsByCounter{
+		xidCounter: {{Counter: xidCounter, Value: "1", GPU: "0", UUID: "UUID", GPUUUID: "fake0"}},
+	}, nil)
+
+	reg := NewRegistry()
+	reg.Register(collector)
+
+	server := &MetricsServer{
+		registry:  reg,
+		lastError: NewLastCollectionError(),
+		metrics: `# HELP DCGM_FI_DEV_GPU_TEMP GPU temperature (in C).
+# TYPE DCGM_FI_DEV_GPU_TEMP gauge
+DCGM_FI_DEV_GPU_TEMP{gpu="0"} 42
+# HELP DCGM_FI_DEV_POWER_USAGE Power draw (in W).
+# TYPE DCGM_FI_DEV_POWER_USAGE gauge
+DCGM_FI_DEV_POWER_USAGE{gpu="0"} 100
+# HELP DCGM_FI_DEV_SM_CLOCK SM clock frequency (in MHz).
+# TYPE DCGM_FI_DEV_SM_CLOCK gauge
+DCGM_FI_DEV_SM_CLOCK{gpu="0"} 1410
+`,
+	}
+
+	t.Run("When counters are requested", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/metrics?counters=DCGM_FI_DEV_POWER_USAGE,DCGM_FI_DEV_GPU_TEMP", nil)
+		recorder := httptest.NewRecorder()
+		server.Metrics(recorder, req)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `# HELP DCGM_FI_DEV_GPU_TEMP GPU temperature (in C).
+# TYPE DCGM_FI_DEV_GPU_TEMP gauge
+DCGM_FI_DEV_GPU_TEMP{gpu="0"} 42
+# HELP DCGM_FI_DEV_POWER_USAGE Power draw (in W).
+# TYPE DCGM_FI_DEV_POWER_USAGE gauge
+DCGM_FI_DEV_POWER_USAGE{gpu="0"} 100
+`, recorder.Body.String())
+	})
+
+	t.Run("When an exporter counter is requested", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/metrics?counters=DCGM_EXP_XID_ERRORS_COUNT", nil)
+		recorder := httptest.NewRecorder()
+		server.Metrics(recorder, req)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "DCGM_EXP_XID_ERRORS_COUNT{")
+		assert.NotContains(t, recorder.Body.String(), "DCGM_FI_DEV_GPU_TEMP")
+	})
+
+	t.Run("When a derived counter is requested", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/metrics?counters=DCGM_EXPORTER_RETIRED_PAGES", nil)
+		recorder := httptest.NewRecorder()
+		server.Metrics(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("When exporter gauges are requested", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/metrics?counters=DCGM_EXPORTER_LAST_ERROR,DCGM_EXPORTER_UPTIME_SECONDS", nil)
+		recorder := httptest.NewRecorder()
+		server.Metrics(recorder, req)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "# TYPE DCGM_EXPORTER_LAST_ERROR gauge\n")
+		assert.Contains(t, recorder.Body.String(), "# TYPE DCGM_EXPORTER_UPTIME_SECONDS gauge\n")
+		assert.NotContains(t, recorder.Body.String(), dcgmExporterWatchedFields)
+		assert.NotContains(t, recorder.Body.String(), "DCGM_FI_DEV_GPU_TEMP")
+	})
+
+	t.Run("When no counters are requested", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		recorder := httptest.NewRecorder()
+		server.Metrics(recorder, req)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "DCGM_FI_DEV_SM_CLOCK{")
+		assert.Contains(t, recorder.Body.String(), "DCGM_EXP_XID_ERRORS_COUNT{")
+		assert.Contains(t, recorder.Body.String(), dcgmExporterLastError)
+	})
+
+	for _, query := range []string{"counters=DCGM_FI_DEV_GPU_TEMPERATURE", "counters=DCGM_FI_DEV_GPU_TEMP,foo", "counters="} {
+		t.Run("When the request is invalid: "+query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics?"+query, nil)
+			recorder := httptest.NewRecorder()
+			server.Metrics(recorder, req)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		})
+	}
+}
+
+func TestMetricsServer_MetricsWithUptime(t *testing.T) {
+	now, start := timeNow, processStart
+	defer func() {
+		timeNow, processStart = now, start
+	}()
+
+	processStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := processStart.Add(90 * time.Second)
+	timeNow = func() time.Time {
+		return clock
+	}
+
+	server := &MetricsServer{registry: NewRegistry()}
+
+	scrape := func() string {
+		recorder := httptest.NewRecorder()
+		server.Metrics(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		return recorder.Body.String()
+	}
+
+	assert.Contains(t, scrape(), "DCGM_EXPORTER_UPTIME_SECONDS 90.000\n")
+
+	clock = clock.Add(1500 * time.Millisecond)
+	assert.Contains(t, scrape(), "DCGM_EXPORTER_UPTIME_SECONDS 91.500\n")
+}
+
+func TestMetricsServer_MetricsWithGzip(t *testing.T) {
+	metrics := "# HELP DCGM_FI_DEV_GPU_TEMP GPU temperature (in C).\n# TYPE DCGM_FI_DEV_GPU_TEMP gauge\nDCGM_FI_DEV_GPU_TEMP{gpu=\"0\"} 42\n"
+	server := &MetricsServer{registry: NewRegistry(), metrics: metrics}
+	handler := withGzip(server.Metrics)
+
+	t.Run("When the client accepts gzip", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept-Encoding", "deflate, gzip;q=0.8")
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+		assert.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+		reader, err := gzip.NewReader(recorder.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), metrics)
+		assert.Contains(t, string(body), dcgmExporterUptimeSeconds)
+	})
+
+	for _, acceptEncoding := range []string{"", "deflate", "gzip;q=0"} {
+		t.Run("When the client does not accept gzip: "+acceptEncoding, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+			recorder := httptest.NewRecorder()
+			handler(recorder, req)
+
+			require.Equal(t, http.StatusOK, recorder.Code)
+			assert.Empty(t, recorder.Header().Get("Content-Encoding"))
+			assert.Contains(t, recorder.Body.String(), metrics)
+		})
+	}
+}
+
+func TestMetricsServer_MetricsWithHostnameHeader(t *testing.T) {
+	xidCounter := Counter{FieldName: "DCGM_EXP_XID_ERRORS_COUNT", PromType: "gauge", Help: "Count of XID Errors within user-specified time window."}
+
+	collector := new(mockCollector)
+	collector.On("GetMetrics").Return(MetricsByCounter{
+		xidCounter: {{Counter: xidCounter, Value: "1", GPU: "0", UUID: "UUID", GPUUUID: "fake0", Hostname: "node-a"}},
+	}, nil)
+
+	reg := NewRegistry()
+	reg.Register(collector)
+
+	newServer := func(config *Config) *MetricsServer {
+		return &MetricsServer{
+			registry: reg,
+			config:   config,
+			metrics: `# HELP DCGM_FI_DEV_GPU_TEMP GPU temperature (in C).
+# TYPE DCGM_FI_DEV_GPU_TEMP gauge
+DCGM_FI_DEV_GPU_TEMP{gpu="0",UUID="fake0",Hostname="node-a"} 42
+`,
+		}
+	}
+
+	scrape := func(server *MetricsServer, hostname string) string {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if hostname != "" {
+			req.Header.Set(hostnameHeader, hostname)
+		}
+		recorder := httptest.NewRecorder()
+		server.Metrics(recorder, req)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		return recorder.Body.String()
+	}
+
+	t.Run("When the header is allowed", func(t *testing.T) {
+		server := newServer(&Config{AllowHostnameHeader: true})
+
+		out := scrape(server, "node-b")
+		assert.Contains(t, out, `DCGM_FI_DEV_GPU_TEMP{gpu="0",UUID="fake0",Hostname="node-b"} 42`)
+		assert.Contains(t, out, `Hostname="node-b"} 1`)
+		assert.NotContains(t, out, "node-a")
+
+		out = scrape(server, `node-"c"`)
+		assert.Contains(t, out, `Hostname="node-\"c\""} 42`, "the header is escaped")
+		assert.NoError(t, validateExposition(out))
+
+		out = scrape(server, "")
+		assert.Contains(t, out, `Hostname="node-a"} 42`, "the hostname is kept without the header")
+	})
+
+	t.Run("When the header is not allowed", func(t *testing.T) {
+		for _, config := range []*Config{nil, {}} {
+			out := scrape(newServer(config), "node-b")
+			assert.Contains(t, out, `Hostname="node-a"} 42`)
+			assert.Contains(t, out, `Hostname="node-a"} 1`)
+			assert.NotContains(t, out, "node-b")
+		}
+	})
+}
+
+func TestMetricsServer_MetricsWithRuntimeMetrics(t *testing.T) {
+	collector := new(mockCollector)
+	collector.On("GetMetrics").Return(MetricsByCounter{}, nil)
+
+	reg := NewRegistry()
+	reg.Register(collector)
+
+	scrape := func(config *Config) string {
+		server := &MetricsServer{registry: reg, config: config}
+		recorder := httptest.NewRecorder()
+		server.Metrics(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		return recorder.Body.String()
+	}
+
+	out := scrape(&Config{EmitRuntimeMetrics: true})
+	assert.Contains(t, out, "# TYPE go_goroutines gauge\n")
+	assert.Regexp(t, `(?m)^go_goroutines [1-9][0-9]*$`, out)
+	assert.Regexp(t, `(?m)^go_memstats_heap_alloc_bytes [1-9][0-9]*$`, out)
+	assert.Contains(t, out, "# TYPE go_memstats_sys_bytes gauge\n")
+	assert.NoError(t, validateExposition(out))
+
+	assert.NotContains(t, scrape(&Config{}), "go_goroutines", "the runtime metrics are opt-in")
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+// skipValues maps a field to the values, which mean that the value of the field is unavailable although they are
+// not one of the blank values of DCGM, e.g. -1.
+var skipValues map[dcgm.Short][]string
+
+// ParseSkipValues parses the skip values of fields, given as <field name>=<value>, e.g. DCGM_FI_DEV_FAN_SPEED=-1.
+// A field may be given several times to skip several values.
+func ParseSkipValues(entries []string) (map[dcgm.Short][]string, error) {
+	values := map[dcgm.Short][]string{}
+	for _, entry := range entries {
+		name, value, found := strings.Cut(entry, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !found || value == "" {
+			return nil, fmt.Errorf("invalid skip value '%s'; expected <field name>=<value>", entry)
+		}
+
+		fieldID, exists := dcgm.DCGM_FI[name]
+		if !exists {
+			return nil, fmt.Errorf("could not find DCGM field '%s'", name)
+		}
+
+		values[fieldID] = append(values[fieldID], value)
+	}
+
+	return values, nil
+}
+
+// SetSkipValues sets the values of the fields, which are skipped like the blank values of DCGM. It must be called
+// before the metrics are collected.
+func SetSkipValues(values map[dcgm.Short][]string) {
+	skipValues = values
+}
+
+// isSkipValue returns whether v, the formatted value of val, is a skip value of its field. Numbers are compared by
+// their value, so that -1 matches -1.000000.
+func isSkipValue(val dcgm.FieldValue_v1, v string) bool {
+	configured, exists := skipValues[dcgm.Short(val.FieldId)]
+	if !exists {
+		return false
+	}
+
+	number, err := strconv.ParseFloat(v, 64)
+	return slices.ContainsFunc(configured, func(skip string) bool {
+		if skipNumber, skipErr := strconv.ParseFloat(skip, 64); err == nil && skipErr == nil {
+			return number == skipNumber
+		}
+		return skip == v
+	})
+}
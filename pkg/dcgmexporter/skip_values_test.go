@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSkipValues(t *testing.T) {
+	values, err := ParseSkipValues([]string{"DCGM_FI_DEV_FAN_SPEED=-1", "DCGM_FI_DEV_FAN_SPEED = 255", "DCGM_FI_DEV_GPU_TEMP=0"})
+	require.NoError(t, err)
+	assert.Equal(t, map[dcgm.Short][]string{
+		dcgm.DCGM_FI_DEV_FAN_SPEED: {"-1", "255"},
+		dcgm.DCGM_FI_DEV_GPU_TEMP:  {"0"},
+	}, values)
+
+	_, err = ParseSkipValues([]string{"DCGM_FI_DEV_FAN_SPEED"})
+	assert.Error(t, err)
+
+	_, err = ParseSkipValues([]string{"DCGM_FI_DEV_UNKNOWN=-1"})
+	assert.Error(t, err)
+}
+
+func TestToMetricSkipsConfiguredValues(t *testing.T) {
+	defer SetSkipValues(nil)
+	SetSkipValues(map[dcgm.Short][]string{
+		dcgm.DCGM_FI_DEV_FAN_SPEED:   {"-1"},
+		dcgm.DCGM_FI_DEV_POWER_USAGE: {"-1"},
+	})
+
+	int64Value := func(v int64) [4096]byte {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], uint64(v))
+		return value
+	}
+
+	float64Value := func(v float64) [4096]byte {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], math.Float64bits(v))
+		return value
+	}
+
+	c := []Counter{
+		{FieldID: dcgm.DCGM_FI_DEV_FAN_SPEED, FieldName: "DCGM_FI_DEV_FAN_SPEED", PromType: "gauge", Help: "Fan speed (in %)."},
+		{FieldID: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge", Help: "Power draw (in W)."},
+		{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."},
+	}
+	d := dcgm.Device{GPU: 0, UUID: "fake0"}
+
+	t.Run("When the values are skip values", func(t *testing.T) {
+		values := []dcgm.FieldValue_v1{
+			{FieldId: dcgm.DCGM_FI_DEV_FAN_SPEED, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(-1)},
+			{FieldId: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldType: dcgm.DCGM_FT_DOUBLE, Value: float64Value(-1)},
+			{FieldId: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(-1)},
+		}
+
+		metrics := make(MetricsByCounter)
+		ToMetric(metrics, values, c, d, nil, MetricOptions{})
+
+		assert.Empty(t, metrics[c[0]])
+		assert.Empty(t, metrics[c[1]], "doubles are compared by their value")
+		require.Len(t, metrics[c[2]], 1, "the skip values are configured per field")
+		assert.Equal(t, "-1", metrics[c[2]][0].Value)
+
+		assert.Equal(t, TypedValue{Skip: true, SkipReason: SkipConfigured}, formatFieldValue(values[0], false))
+	})
+
+	t.Run("When the values are not skip values", func(t *testing.T) {
+		values := []dcgm.FieldValue_v1{
+			{FieldId: dcgm.DCGM_FI_DEV_FAN_SPEED, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(40)},
+		}
+
+		metrics := make(MetricsByCounter)
+		ToMetric(metrics, values, c, d, nil, MetricOptions{})
+
+		require.Len(t, metrics[c[0]], 1)
+		assert.Equal(t, "40", metrics[c[0]][0].Value)
+	})
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const fieldAttribute = "field"
+
+var fieldStuckCounter = Counter{
+	FieldName: "DCGM_EXPORTER_FIELD_STUCK",
+	PromType:  "gauge",
+	Help:      "1 when the field returned the identical value for the configured number of consecutive collections.",
+}
+
+// defaultStuckFields are the fields checked for stuck values, unless fields are configured. Their values change
+// between collections on a healthy GPU, even when it is idle, unlike e.g. the utilization of an idle GPU.
+var defaultStuckFields = []string{"DCGM_FI_DEV_POWER_USAGE", "DCGM_FI_DEV_TOTAL_ENERGY_CONSUMPTION"}
+
+// fieldHistory is the last value of a series and the number of consecutive collections that returned it.
+type fieldHistory struct {
+	value   string
+	repeats int
+}
+
+// stuckFieldDetector tracks the values of every series, to detect DCGM returning frozen values.
+type stuckFieldDetector struct {
+	threshold int
+	fields    map[string]bool // Names of the checked fields
+
+	mtx     sync.Mutex
+	history map[string]fieldHistory
+}
+
+// newStuckFieldDetector returns a MetricProcessor, which adds DCGM_EXPORTER_FIELD_STUCK for every series of the
+// fields: 1 when the series returned the identical value for threshold consecutive collections, 0 otherwise.
+// The default fields are checked, when fields is empty.
+func newStuckFieldDetector(threshold int, fields []string) MetricProcessor {
+	detector := &stuckFieldDetector{
+		threshold: threshold,
+		fields:    map[string]bool{},
+		history:   map[string]fieldHistory{},
+	}
+
+	if len(fields) == 0 {
+		fields = defaultStuckFields
+	}
+
+	for _, field := range fields {
+		detector.fields[field] = true
+	}
+
+	return detector.process
+}
+
+func (d *stuckFieldDetector) process(metrics MetricsByCounter) MetricsByCounter {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	history := map[string]fieldHistory{}
+	var stuckMetrics []Metric
+
+	for counter, values := range metrics {
+		if counter.PromType == "label" || !d.fields[counter.FieldName] {
+			continue
+		}
+
+		for _, m := range values {
+			// Samples of sampled fields have their own timestamps, and do not repeat between collections
+			if m.Timestamp != 0 {
+				continue
+			}
+
+			key := seriesKey(m)
+			h := d.history[key]
+			if h.value == m.Value && h.repeats > 0 {
+				h.repeats++
+			} else {
+				h = fieldHistory{value: m.Value, repeats: 1}
+			}
+			history[key] = h
+
+			stuck := m
+			stuck.Counter = fieldStuckCounter
+			stuck.Value = "0"
+			if h.repeats >= d.threshold {
+				stuck.Value = "1"
+			}
+			stuck.Attributes = maps.Clone(m.Attributes)
+			if stuck.Attributes == nil {
+				stuck.Attributes = map[string]string{}
+			}
+			stuck.Attributes[fieldAttribute] = counter.FieldName
+
+			stuckMetrics = append(stuckMetrics, stuck)
+		}
+	}
+
+	// Series that were not collected this time are forgotten
+	d.history = history
+
+	if len(stuckMetrics) > 0 {
+		metrics[fieldStuckCounter] = stuckMetrics
+	}
+
+	return metrics
+}
+
+// seriesKey identifies a series of a field over collections.
+func seriesKey(m Metric) string {
+	attributes := make([]string, 0, len(m.Attributes))
+	for k, v := range m.Attributes {
+		attributes = append(attributes, k+"="+v)
+	}
+	slices.Sort(attributes)
+
+	return fmt.Sprintf("%s/%s/%s/%s/%s", m.Counter.FieldName, m.GPU, m.GPUDevice, m.GPUInstanceID,
+		strings.Join(attributes, ","))
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStuckFieldDetector(t *testing.T) {
+	utilCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_UTIL, FieldName: "DCGM_FI_DEV_GPU_UTIL", PromType: "gauge", Help: "GPU utilization (in %)."}
+	tempCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+	powerCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge", Help: "Power draw (in W)."}
+
+	collect := func(detect MetricProcessor, util, temp string) map[string]string {
+		metrics := MetricsByCounter{
+			utilCounter: {{Counter: utilCounter, Value: util, GPU: "0", Attributes: map[string]string{}}},
+			tempCounter: {{Counter: tempCounter, Value: temp, GPU: "0", Attributes: map[string]string{}}},
+		}
+
+		stuck := map[string]string{}
+		for _, m := range detect(metrics)[fieldStuckCounter] {
+			assert.Equal(t, "0", m.GPU)
+			stuck[m.Attributes[fieldAttribute]] = m.Value
+		}
+		return stuck
+	}
+
+	t.Run("When a field returns the identical value N times", func(t *testing.T) {
+		detect := newStuckFieldDetector(3, []string{"DCGM_FI_DEV_GPU_UTIL", "DCGM_FI_DEV_GPU_TEMP"})
+
+		notStuck := map[string]string{"DCGM_FI_DEV_GPU_UTIL": "0", "DCGM_FI_DEV_GPU_TEMP": "0"}
+		assert.Equal(t, notStuck, collect(detect, "42", "60"), "series, which are not stuck, report 0")
+		assert.Equal(t, notStuck, collect(detect, "42", "61"))
+
+		assert.Equal(t, map[string]string{"DCGM_FI_DEV_GPU_UTIL": "1", "DCGM_FI_DEV_GPU_TEMP": "0"},
+			collect(detect, "42", "62"), "the third identical reading trips the signal")
+		assert.Equal(t, "1", collect(detect, "42", "63")["DCGM_FI_DEV_GPU_UTIL"], "the field stays stuck")
+		assert.Equal(t, notStuck, collect(detect, "43", "64"), "a new value resets the signal")
+	})
+
+	t.Run("When only some fields are checked", func(t *testing.T) {
+		detect := newStuckFieldDetector(2, []string{"DCGM_FI_DEV_GPU_TEMP"})
+
+		collect(detect, "42", "60")
+		assert.Equal(t, map[string]string{"DCGM_FI_DEV_GPU_TEMP": "1"}, collect(detect, "42", "60"))
+	})
+
+	t.Run("When no fields are configured", func(t *testing.T) {
+		detect := newStuckFieldDetector(2, nil)
+
+		metrics := func() MetricsByCounter {
+			return MetricsByCounter{
+				utilCounter:  {{Counter: utilCounter, Value: "0", GPU: "0", Attributes: map[string]string{}}},
+				powerCounter: {{Counter: powerCounter, Value: "61.5", GPU: "0", Attributes: map[string]string{}}},
+			}
+		}
+
+		detect(metrics())
+		stuck := detect(metrics())[fieldStuckCounter]
+		require.Len(t, stuck, 1, "the utilization of an idle GPU is not checked by default")
+		assert.Equal(t, "DCGM_FI_DEV_POWER_USAGE", stuck[0].Attributes[fieldAttribute])
+		assert.Equal(t, "1", stuck[0].Value)
+	})
+
+	t.Run("When a series is not collected", func(t *testing.T) {
+		detect := newStuckFieldDetector(2, []string{"DCGM_FI_DEV_GPU_UTIL"})
+
+		collect(detect, "42", "60")
+		detect(MetricsByCounter{})
+		assert.Equal(t, map[string]string{"DCGM_FI_DEV_GPU_UTIL": "0"}, collect(detect, "42", "60"),
+			"the history of missing series is forgotten")
+	})
+
+	t.Run("When series of different GPUs repeat", func(t *testing.T) {
+		detect := newStuckFieldDetector(2, []string{"DCGM_FI_DEV_GPU_UTIL"})
+
+		metrics := func() MetricsByCounter {
+			return MetricsByCounter{utilCounter: {
+				{Counter: utilCounter, Value: "42", GPU: "0", Attributes: map[string]string{}},
+				{Counter: utilCounter, Value: "42", GPU: "1", Attributes: map[string]string{}},
+			}}
+		}
+
+		detect(metrics())
+		stuck := detect(metrics())[fieldStuckCounter]
+		require.Len(t, stuck, 2)
+		for _, m := range stuck {
+			assert.Equal(t, "1", m.Value)
+		}
+	})
+}
+
+func TestStuckFieldDetectorConcurrentCollections(t *testing.T) {
+	powerCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldName: "DCGM_FI_DEV_POWER_USAGE", PromType: "gauge", Help: "Power draw (in W)."}
+	detect := newStuckFieldDetector(2, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				metrics := detect(MetricsByCounter{
+					powerCounter: {{Counter: powerCounter, Value: "61.5", GPU: "0", Attributes: map[string]string{}}},
+				})
+				assert.Len(t, metrics[fieldStuckCounter], 1)
+			}
+		}()
+	}
+	wg.Wait()
+}
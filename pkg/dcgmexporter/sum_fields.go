@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const aggregationAttribute = "aggregation"
+
+// newFieldSummer returns a MetricProcessor, which adds a series with the sum of the values of all entities for
+// each of the fields, e.g. the node-wide total of a field that is collected per MIG instance. The sum has no
+// entity labels and the aggregation="sum" attribute, so that it is not added up with the series of the entities.
+func newFieldSummer(fields []string) MetricProcessor {
+	summed := map[string]bool{}
+	for _, field := range fields {
+		summed[field] = true
+	}
+
+	return func(metrics MetricsByCounter) MetricsByCounter {
+		for counter, values := range metrics {
+			if !summed[counter.FieldName] || counter.PromType == "label" {
+				continue
+			}
+
+			var sum float64
+			var first *Metric
+			for i, m := range values {
+				// Samples of sampled fields are several values of the same entity
+				if m.Timestamp != 0 {
+					continue
+				}
+
+				value, err := strconv.ParseFloat(m.Value, 64)
+				if err != nil {
+					continue
+				}
+
+				sum += value
+				if first == nil {
+					first = &values[i]
+				}
+			}
+
+			if first == nil {
+				continue
+			}
+
+			metrics[counter] = append(values, Metric{
+				Counter:    counter,
+				Value:      fmt.Sprintf("%f", sum),
+				UUID:       first.UUID,
+				Hostname:   first.Hostname,
+				Labels:     map[string]string{},
+				Attributes: map[string]string{aggregationAttribute: "sum"},
+			})
+		}
+
+		return metrics
+	}
+}
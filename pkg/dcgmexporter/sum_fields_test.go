@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetricsSumsFlaggedFields(t *testing.T) {
+	fbUsed := Counter{FieldID: dcgm.DCGM_FI_DEV_FB_USED, FieldName: "DCGM_FI_DEV_FB_USED", PromType: "gauge", Help: "Framebuffer memory used (in MiB)."}
+	fbFree := Counter{FieldID: dcgm.DCGM_FI_DEV_FB_FREE, FieldName: "DCGM_FI_DEV_FB_FREE", PromType: "gauge", Help: "Framebuffer memory free (in MiB)."}
+
+	sysInfo := SystemInfo{
+		GPUCount: 1,
+		InfoType: dcgm.FE_GPU,
+		gOpt:     DeviceOptions{Flex: true},
+	}
+	sysInfo.GPUs[0] = GPUInfo{
+		DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-0"},
+		MigEnabled: true,
+		GPUInstances: []GPUInstanceInfo{
+			{EntityId: 10, ProfileName: "1g.10gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 1}},
+			{EntityId: 11, ProfileName: "1g.10gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 2}},
+			{EntityId: 12, ProfileName: "2g.20gb", Info: dcgm.MigEntityInfo{NvmlInstanceId: 3}},
+		},
+	}
+
+	collector := &DCGMCollector{
+		Counters:      []Counter{fbUsed, fbFree},
+		DeviceFields:  []dcgm.Short{fbUsed.FieldID, fbFree.FieldID},
+		SysInfo:       sysInfo,
+		MetricOptions: MetricOptions{Hostname: "node"},
+		Processors:    []MetricProcessor{newFieldSummer([]string{fbUsed.FieldName})},
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+
+	int64Value := func(v int64) [4096]byte {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], uint64(v))
+		return value
+	}
+	dcgmEntityGetLatestValues = func(_ dcgm.Field_Entity_Group, id uint, _ []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		return []dcgm.FieldValue_v1{
+			{FieldId: uint(fbUsed.FieldID), FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(int64(id) * 100)},
+			{FieldId: uint(fbFree.FieldID), FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(1000)},
+		}, nil
+	}
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+
+	require.Len(t, metrics[fbUsed], 4)
+	var sums []Metric
+	for _, m := range metrics[fbUsed] {
+		if m.Attributes[aggregationAttribute] == "sum" {
+			sums = append(sums, m)
+			continue
+		}
+		assert.NotEmpty(t, m.GPUInstanceID)
+	}
+	require.Len(t, sums, 1)
+	assert.Equal(t, "3300.000000", sums[0].Value)
+	assert.Empty(t, sums[0].GPU)
+	assert.Empty(t, sums[0].GPUUUID)
+	assert.Empty(t, sums[0].GPUInstanceID)
+	assert.Empty(t, sums[0].MigProfile)
+	assert.Equal(t, "node", sums[0].Hostname)
+
+	assert.Len(t, metrics[fbFree], 3, "fields that are not flagged must not be summed")
+}
@@ -17,6 +17,7 @@
 package dcgmexporter
 
 import (
+	"cmp"
 	"fmt"
 	"math/rand"
 	"slices"
@@ -24,6 +25,8 @@ import (
 
 	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
 	"github.com/bits-and-blooms/bitset"
+
+	"github.com/NVIDIA/dcgm-exporter/internal/pkg/nvmlprovider"
 	"github.com/sirupsen/logrus"
 )
 
@@ -34,6 +37,9 @@ var (
 	dcgmAddEntityToGroup        = dcgm.AddEntityToGroup
 	dcgmCreateGroup             = dcgm.CreateGroup
 	dcgmGetCpuHierarchy         = dcgm.GetCpuHierarchy
+	dcgmDestroyGroup            = dcgm.DestroyGroup
+	dcgmEntitiesGetLatestValues = dcgm.EntitiesGetLatestValues
+	nvmlGetGPUSlicesByUUIDHook  = nvmlprovider.GetGPUSlicesByUUID
 )
 
 type ComputeInstanceInfo struct {
@@ -47,6 +53,7 @@ type GPUInstanceInfo struct {
 	ProfileName      string
 	EntityId         uint
 	ComputeInstances []ComputeInstanceInfo
+	GPUSlices        uint // Slices of the parent GPU
 }
 
 type GPUInfo struct {
@@ -96,13 +103,22 @@ func SetGPUInstanceProfileName(sysInfo *SystemInfo, entityId uint, profileName s
 	return false
 }
 
+// canonicalizeMigProfile returns the MIG profile name in the form reported by recent DCGM versions, e.g. "1g.10gb".
+// Other versions prefix the name with "MIG" and differ in spacing and casing.
+func canonicalizeMigProfile(profile string) string {
+	profile = strings.ToLower(strings.TrimSpace(profile))
+	profile = strings.TrimSpace(strings.TrimPrefix(profile, "mig"))
+
+	return strings.Join(strings.Fields(profile), "")
+}
+
 func SetMigProfileNames(sysInfo *SystemInfo, values []dcgm.FieldValue_v2) error {
 	var err error
 	var errFound bool
 	errStr := "cannot find match for entities:"
 
 	for _, v := range values {
-		if !SetGPUInstanceProfileName(sysInfo, v.EntityId, dcgm.Fv2_String(v)) {
+		if !SetGPUInstanceProfileName(sysInfo, v.EntityId, canonicalizeMigProfile(dcgm.Fv2_String(v))) {
 			errStr = fmt.Sprintf("%s group %d, id %d", errStr, v.EntityGroupId, v.EntityId)
 			errFound = true
 		}
@@ -124,13 +140,45 @@ func PopulateMigProfileNames(sysInfo *SystemInfo, entities []dcgm.GroupEntityPai
 	var fields []dcgm.Short
 	fields = append(fields, dcgm.DCGM_FI_DEV_NAME)
 	flags := dcgm.DCGM_FV_FLAG_LIVE_DATA
-	values, err := dcgm.EntitiesGetLatestValues(entities, fields, flags)
+	values, err := dcgmEntitiesGetLatestValues(entities, fields, flags)
 
 	if err != nil {
-		return err
+		// Fetch the profile of every instance on its own, so that a failed instance is left without a
+		// profile name, instead of failing all of them
+		values, err = getMigProfileNamesByEntity(entities, fields, flags, err)
+		if err != nil {
+			return err
+		}
 	}
 
-	return SetMigProfileNames(sysInfo, values)
+	return SetMigProfileNames(sysInfo, slices.DeleteFunc(values, func(v dcgm.FieldValue_v2) bool {
+		if v.Status != dcgm.DCGM_ST_OK {
+			logrus.Warnf("Failed to get the MIG profile of GPU instance %d; status: %d", v.EntityId, v.Status)
+			return true
+		}
+		return false
+	}))
+}
+
+// getMigProfileNamesByEntity fetches the fields of each entity on its own and returns the values of the entities,
+// which could be fetched, or batchErr when none could.
+func getMigProfileNamesByEntity(entities []dcgm.GroupEntityPair, fields []dcgm.Short, flags uint, batchErr error,
+) ([]dcgm.FieldValue_v2, error) {
+	var values []dcgm.FieldValue_v2
+	for _, entity := range entities {
+		entityValues, err := dcgmEntitiesGetLatestValues([]dcgm.GroupEntityPair{entity}, fields, flags)
+		if err != nil {
+			logrus.WithError(err).Warnf("Failed to get the MIG profile of GPU instance %d", entity.EntityId)
+			continue
+		}
+		values = append(values, entityValues...)
+	}
+
+	if len(values) == 0 {
+		return nil, batchErr
+	}
+
+	return values, nil
 }
 
 func GPUIdExists(sysInfo *SystemInfo, gpuId int) bool {
@@ -413,6 +461,8 @@ func InitializeGPUInfo(sysInfo SystemInfo, gOpt DeviceOptions, useFakeGPUs bool)
 			}
 		}
 
+		setGPUSlices(&sysInfo)
+
 		err = PopulateMigProfileNames(&sysInfo, entities)
 		if err != nil {
 			return sysInfo, err
@@ -427,6 +477,27 @@ func InitializeGPUInfo(sysInfo SystemInfo, gOpt DeviceOptions, useFakeGPUs bool)
 	return sysInfo, err
 }
 
+// setGPUSlices records in every GPU instance the number of slices of its GPU, whether or not the other slices
+// are taken by GPU instances. GPUs, whose slices are unknown, are left at 0 so that their values are not attributed.
+func setGPUSlices(sysInfo *SystemInfo) {
+	for i := uint(0); i < sysInfo.GPUCount; i++ {
+		if len(sysInfo.GPUs[i].GPUInstances) == 0 {
+			continue
+		}
+
+		total, err := nvmlGetGPUSlicesByUUIDHook(sysInfo.GPUs[i].DeviceInfo.UUID)
+		if err != nil {
+			logrus.WithError(err).WithField("gpu", i).Warn("Failed to get the slices of the GPU; " +
+				"the values of its MIG instances are not attributed.")
+			continue
+		}
+
+		for j := range sysInfo.GPUs[i].GPUInstances {
+			sysInfo.GPUs[i].GPUInstances[j].GPUSlices = total
+		}
+	}
+}
+
 func InitializeSystemInfo(
 	gOpt DeviceOptions, sOpt DeviceOptions, cOpt DeviceOptions, useFakeGPUs bool, entityType dcgm.Field_Entity_Group,
 ) (SystemInfo, error) {
@@ -488,7 +559,7 @@ func CreateCoreGroupsFromSystemInfo(sysInfo SystemInfo) ([]dcgm.GroupHandle, []f
 			}
 
 			cleanups = append(cleanups, func() {
-				err := dcgm.DestroyGroup(groupID)
+				err := dcgmDestroyGroup(groupID)
 				if err != nil && !strings.Contains(err.Error(), DCGM_ST_NOT_CONFIGURED) {
 					logrus.WithFields(logrus.Fields{
 						LoggerGroupIDKey: groupID,
@@ -535,7 +606,7 @@ func CreateLinkGroupsFromSystemInfo(sysInfo SystemInfo) ([]dcgm.GroupHandle, []f
 			}
 
 			cleanups = append(cleanups, func() {
-				err := dcgm.DestroyGroup(groupID)
+				err := dcgmDestroyGroup(groupID)
 				if err != nil && !strings.Contains(err.Error(), DCGM_ST_NOT_CONFIGURED) {
 					logrus.WithFields(logrus.Fields{
 						LoggerGroupIDKey: groupID,
@@ -560,7 +631,7 @@ func CreateGroupFromSystemInfo(sysInfo SystemInfo) (dcgm.GroupHandle, func(), er
 		err := dcgmAddEntityToGroup(groupID, mi.Entity.EntityGroupId, mi.Entity.EntityId)
 		if err != nil {
 			return groupID, func() {
-				err := dcgm.DestroyGroup(groupID)
+				err := dcgmDestroyGroup(groupID)
 				if err != nil && !strings.Contains(err.Error(), DCGM_ST_NOT_CONFIGURED) {
 					logrus.WithFields(logrus.Fields{
 						LoggerGroupIDKey: groupID,
@@ -572,7 +643,7 @@ func CreateGroupFromSystemInfo(sysInfo SystemInfo) (dcgm.GroupHandle, func(), er
 	}
 
 	return groupID, func() {
-		err := dcgm.DestroyGroup(groupID)
+		err := dcgmDestroyGroup(groupID)
 		if err != nil && !strings.Contains(err.Error(), DCGM_ST_NOT_CONFIGURED) {
 			logrus.WithFields(logrus.Fields{
 				LoggerGroupIDKey: groupID,
@@ -878,6 +949,20 @@ func GetMonitoredEntities(sysInfo SystemInfo) []MonitoringInfo {
 	return monitoring
 }
 
+// SortMonitoringInfo sorts the entities by entity group, parent and ID, so that they are collected in
+// the same order on every collection, regardless of the order in which the system info lists them.
+func SortMonitoringInfo(monitoring []MonitoringInfo) {
+	slices.SortStableFunc(monitoring, func(a, b MonitoringInfo) int {
+		if c := cmp.Compare(a.Entity.EntityGroupId, b.Entity.EntityGroupId); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.ParentId, b.ParentId); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Entity.EntityId, b.Entity.EntityId)
+	})
+}
+
 func GetGPUInstanceIdentifier(sysInfo SystemInfo, gpuuuid string, gpuInstanceID uint) string {
 	for i := uint(0); i < sysInfo.GPUCount; i++ {
 		if sysInfo.GPUs[i].DeviceInfo.UUID == gpuuuid {
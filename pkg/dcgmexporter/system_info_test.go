@@ -17,6 +17,7 @@
 package dcgmexporter
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -669,3 +670,202 @@ func TestSetMigProfileNames(t *testing.T) {
 		})
 	}
 }
+
+func TestCanonicalizeMigProfile(t *testing.T) {
+	tests := []struct {
+		profile  string
+		expected string
+	}{
+		{profile: "1g.10gb", expected: "1g.10gb"},
+		{profile: "MIG 1g.10gb", expected: "1g.10gb"},
+		{profile: "MIG1g.10gb", expected: "1g.10gb"},
+		{profile: "mig 1g.10gb", expected: "1g.10gb"},
+		{profile: "  MIG  1G.10GB ", expected: "1g.10gb"},
+		{profile: "MIG 1g.10 gb", expected: "1g.10gb"},
+		{profile: "MIG 1g.10gb+me", expected: "1g.10gb+me"},
+		{profile: "MIG 1c.3g.40gb", expected: "1c.3g.40gb"},
+		{profile: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			assert.Equal(t, tt.expected, canonicalizeMigProfile(tt.profile))
+		})
+	}
+}
+
+func TestSetMigProfileNamesCanonicalizesProfiles(t *testing.T) {
+	profileName := "MIG 1G.10GB"
+	sysInfo := SystemInfo{
+		GPUCount: 1,
+		GPUs: [dcgm.MAX_NUM_DEVICES]GPUInfo{
+			{
+				GPUInstances: []GPUInstanceInfo{
+					{EntityId: 1},
+				},
+			},
+		},
+	}
+
+	err := SetMigProfileNames(&sysInfo, []dcgm.FieldValue_v2{
+		{
+			EntityId:    1,
+			FieldType:   dcgm.DCGM_FT_STRING,
+			StringValue: &profileName,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "1g.10gb", sysInfo.GPUs[0].GPUInstances[0].ProfileName)
+}
+
+func TestSetGPUSlices(t *testing.T) {
+	defer func(getGPUSlices func(string) (uint, error)) {
+		nvmlGetGPUSlicesByUUIDHook = getGPUSlices
+	}(nvmlGetGPUSlicesByUUIDHook)
+	nvmlGetGPUSlicesByUUIDHook = func(uuid string) (uint, error) {
+		if uuid == "GPU-0" {
+			return 7, nil
+		}
+		return 0, errors.New("MIG is not supported")
+	}
+
+	sysInfo := SystemInfo{
+		GPUCount: 3,
+		GPUs: [dcgm.MAX_NUM_DEVICES]GPUInfo{
+			{
+				DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-0"},
+				GPUInstances: []GPUInstanceInfo{
+					{EntityId: 1, Info: dcgm.MigEntityInfo{NvmlProfileSlices: 3}},
+					{EntityId: 2, Info: dcgm.MigEntityInfo{NvmlProfileSlices: 2}},
+				},
+			},
+			{
+				DeviceInfo: dcgm.Device{GPU: 1, UUID: "GPU-1"},
+				GPUInstances: []GPUInstanceInfo{
+					{EntityId: 3, Info: dcgm.MigEntityInfo{NvmlProfileSlices: 1}},
+				},
+			},
+			{DeviceInfo: dcgm.Device{GPU: 2, UUID: "GPU-2"}},
+		},
+	}
+
+	setGPUSlices(&sysInfo)
+
+	for _, instance := range sysInfo.GPUs[0].GPUInstances {
+		assert.Equal(t, uint(7), instance.GPUSlices, "the slices of the GPU, not of its instances")
+	}
+	assert.Zero(t, sysInfo.GPUs[1].GPUInstances[0].GPUSlices, "the slices of the GPU are unknown")
+	assert.Empty(t, sysInfo.GPUs[2].GPUInstances)
+}
+
+func TestSortMonitoringInfo(t *testing.T) {
+	entity := func(group dcgm.Field_Entity_Group, id, parent uint) MonitoringInfo {
+		return MonitoringInfo{Entity: dcgm.GroupEntityPair{EntityGroupId: group, EntityId: id}, ParentId: parent}
+	}
+
+	monitoring := []MonitoringInfo{
+		entity(dcgm.FE_GPU_I, 0, 1),
+		entity(dcgm.FE_GPU, 1, 0),
+		entity(dcgm.FE_LINK, 0, 1),
+		entity(dcgm.FE_GPU_I, 1, 0),
+		entity(dcgm.FE_LINK, 1, 0),
+		entity(dcgm.FE_GPU, 0, 0),
+		entity(dcgm.FE_LINK, 0, 0),
+	}
+
+	SortMonitoringInfo(monitoring)
+
+	assert.Equal(t, []MonitoringInfo{
+		entity(dcgm.FE_GPU, 0, 0),
+		entity(dcgm.FE_GPU, 1, 0),
+		entity(dcgm.FE_GPU_I, 1, 0),
+		entity(dcgm.FE_GPU_I, 0, 1),
+		entity(dcgm.FE_LINK, 0, 0),
+		entity(dcgm.FE_LINK, 1, 0),
+		entity(dcgm.FE_LINK, 0, 1),
+	}, monitoring)
+}
+
+func TestPopulateMigProfileNamesWhenOneInstanceFails(t *testing.T) {
+	entitiesGetLatestValues := dcgmEntitiesGetLatestValues
+	defer func() {
+		dcgmEntitiesGetLatestValues = entitiesGetLatestValues
+	}()
+
+	newSysInfo := func() SystemInfo {
+		return SystemInfo{
+			GPUCount: 2,
+			GPUs: [dcgm.MAX_NUM_DEVICES]GPUInfo{
+				{GPUInstances: []GPUInstanceInfo{{EntityId: 1}, {EntityId: 2}}},
+				{GPUInstances: []GPUInstanceInfo{{EntityId: 3}, {EntityId: 4}}},
+			},
+		}
+	}
+	entities := []dcgm.GroupEntityPair{
+		{EntityGroupId: dcgm.FE_GPU_I, EntityId: 1},
+		{EntityGroupId: dcgm.FE_GPU_I, EntityId: 2},
+		{EntityGroupId: dcgm.FE_GPU_I, EntityId: 3},
+		{EntityGroupId: dcgm.FE_GPU_I, EntityId: 4},
+	}
+	profileName := "1g.10gb"
+	value := func(entityID uint, status int) dcgm.FieldValue_v2 {
+		return dcgm.FieldValue_v2{
+			EntityGroupId: dcgm.FE_GPU_I,
+			EntityId:      entityID,
+			FieldType:     dcgm.DCGM_FT_STRING,
+			Status:        status,
+			StringValue:   &profileName,
+		}
+	}
+	profileNames := func(sysInfo SystemInfo) []string {
+		var names []string
+		for _, gpu := range sysInfo.GPUs[:sysInfo.GPUCount] {
+			for _, instance := range gpu.GPUInstances {
+				names = append(names, instance.ProfileName)
+			}
+		}
+		return names
+	}
+
+	t.Run("When fetching one instance fails", func(t *testing.T) {
+		dcgmEntitiesGetLatestValues = func(entities []dcgm.GroupEntityPair, _ []dcgm.Short, _ uint) ([]dcgm.FieldValue_v2, error) {
+			var values []dcgm.FieldValue_v2
+			for _, entity := range entities {
+				if entity.EntityId == 2 {
+					return nil, fmt.Errorf("Error getting values for entity %d", entity.EntityId)
+				}
+				values = append(values, value(entity.EntityId, dcgm.DCGM_ST_OK))
+			}
+			return values, nil
+		}
+
+		sysInfo := newSysInfo()
+		require.NoError(t, PopulateMigProfileNames(&sysInfo, entities))
+		assert.Equal(t, []string{"1g.10gb", "", "1g.10gb", "1g.10gb"}, profileNames(sysInfo))
+	})
+
+	t.Run("When the value of one instance has an error status", func(t *testing.T) {
+		dcgmEntitiesGetLatestValues = func(entities []dcgm.GroupEntityPair, _ []dcgm.Short, _ uint) ([]dcgm.FieldValue_v2, error) {
+			return []dcgm.FieldValue_v2{
+				value(1, dcgm.DCGM_ST_OK),
+				value(2, dcgm.DCGM_ST_OK),
+				value(3, -1),
+				value(4, dcgm.DCGM_ST_OK),
+			}, nil
+		}
+
+		sysInfo := newSysInfo()
+		require.NoError(t, PopulateMigProfileNames(&sysInfo, entities))
+		assert.Equal(t, []string{"1g.10gb", "1g.10gb", "", "1g.10gb"}, profileNames(sysInfo))
+	})
+
+	t.Run("When fetching every instance fails", func(t *testing.T) {
+		dcgmEntitiesGetLatestValues = func([]dcgm.GroupEntityPair, []dcgm.Short, uint) ([]dcgm.FieldValue_v2, error) {
+			return nil, fmt.Errorf("Host engine connection invalid/disconnected")
+		}
+
+		sysInfo := newSysInfo()
+		assert.ErrorContains(t, PopulateMigProfileNames(&sysInfo, entities), "disconnected")
+		assert.Equal(t, []string{"", "", "", ""}, profileNames(sysInfo))
+	})
+}
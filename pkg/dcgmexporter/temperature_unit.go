@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+// temperatureFields are the fields of the GPUs, which DCGM reports in Celsius.
+var temperatureFields = []dcgm.Short{
+	dcgm.DCGM_FI_DEV_MEMORY_TEMP,
+	dcgm.DCGM_FI_DEV_GPU_TEMP,
+	dcgm.DCGM_FI_DEV_MEM_MAX_OP_TEMP,
+	dcgm.DCGM_FI_DEV_GPU_MAX_OP_TEMP,
+	dcgm.DCGM_FI_DEV_SLOWDOWN_TEMP,
+	dcgm.DCGM_FI_DEV_SHUTDOWN_TEMP,
+}
+
+// celsiusToFahrenheit converts value, the temperature in Celsius, to Fahrenheit.
+const celsiusToFahrenheit = "value * 9 / 5 + 32"
+
+// applyTemperatureUnit converts the temperature counters to the unit with their value expression, which ToMetric
+// evaluates. The expression of a counter, which has one already, is converted as well. Celsius is kept as is.
+func applyTemperatureUnit(counters []Counter, unit TemperatureUnit) []Counter {
+	if unit != TemperatureUnitFahrenheit {
+		return counters
+	}
+
+	for i, counter := range counters {
+		if !slices.Contains(temperatureFields, counter.FieldID) || counter.PromType == "label" {
+			continue
+		}
+
+		if counter.Expr == "" {
+			counter.Expr = celsiusToFahrenheit
+		} else {
+			counter.Expr = fmt.Sprintf("(%s) * 9 / 5 + 32", counter.Expr)
+		}
+		counter.Help = strings.ReplaceAll(counter.Help, "(in C)", "(in F)")
+
+		counters[i] = counter
+	}
+
+	return counters
+}
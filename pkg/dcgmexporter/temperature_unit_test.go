@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemperatureUnit(t *testing.T) {
+	records := func() [][]string {
+		return [][]string{
+			{"DCGM_FI_DEV_GPU_TEMP", "gauge", "GPU temperature (in C)."},
+			{"DCGM_FI_DEV_MEMORY_TEMP", "gauge", "Memory temperature (in C).", "value - 5"},
+			{"DCGM_FI_DEV_POWER_USAGE", "gauge", "Power draw (in W)."},
+		}
+	}
+
+	int64Value := func(v int64) [4096]byte {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], uint64(v))
+		return value
+	}
+	values := []dcgm.FieldValue_v1{
+		{FieldId: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(80)},
+		{FieldId: dcgm.DCGM_FI_DEV_MEMORY_TEMP, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(85)},
+		{FieldId: dcgm.DCGM_FI_DEV_POWER_USAGE, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(300)},
+	}
+
+	collect := func(counters []Counter) map[string]string {
+		metrics := make(MetricsByCounter)
+		ToMetric(metrics, values, counters, dcgm.Device{UUID: "fake0"}, nil, MetricOptions{ShortestFloatFormat: true})
+
+		collected := map[string]string{}
+		for counter, m := range metrics {
+			require.Len(t, m, 1)
+			collected[counter.FieldName] = m[0].Value
+		}
+		return collected
+	}
+
+	t.Run("Celsius by default", func(t *testing.T) {
+		cs, err := extractCounters(records(), &Config{})
+		require.NoError(t, err)
+		assert.Equal(t, "GPU temperature (in C).", cs.DCGMCounters[0].Help)
+
+		assert.Equal(t, map[string]string{
+			"DCGM_FI_DEV_GPU_TEMP":    "80",
+			"DCGM_FI_DEV_MEMORY_TEMP": "80",
+			"DCGM_FI_DEV_POWER_USAGE": "300",
+		}, collect(cs.DCGMCounters))
+	})
+
+	t.Run("Fahrenheit", func(t *testing.T) {
+		cs, err := extractCounters(records(), &Config{TemperatureUnit: TemperatureUnitFahrenheit})
+		require.NoError(t, err)
+		assert.Equal(t, "GPU temperature (in F).", cs.DCGMCounters[0].Help)
+		assert.Equal(t, "(value - 5) * 9 / 5 + 32", cs.DCGMCounters[1].Expr)
+		assert.Empty(t, cs.DCGMCounters[2].Expr)
+
+		assert.Equal(t, map[string]string{
+			"DCGM_FI_DEV_GPU_TEMP":    "176",
+			"DCGM_FI_DEV_MEMORY_TEMP": "176",
+			"DCGM_FI_DEV_POWER_USAGE": "300",
+		}, collect(cs.DCGMCounters))
+	})
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+var tensorThroughputCounter = Counter{
+	FieldName: "DCGM_EXPORTER_TENSOR_THROUGHPUT",
+	PromType:  "gauge",
+	Help:      "Tensor throughput (in TFLOPS), the ratio of cycles the tensor pipes are active times the peak tensor throughput of the GPU model.",
+}
+
+// ParseTensorCapabilities parses entries of the form <GPU model>=<peak tensor TFLOPS>,
+// e.g. NVIDIA A100-SXM4-80GB=312. The model is the modelName label of the GPU.
+func ParseTensorCapabilities(entries []string) (map[string]float64, error) {
+	capabilities := map[string]float64{}
+	for _, entry := range entries {
+		model, value, found := strings.Cut(entry, "=")
+		model = strings.TrimSpace(model)
+		if !found || model == "" {
+			return nil, fmt.Errorf("invalid tensor capability '%s'; expected <GPU model>=<peak TFLOPS>", entry)
+		}
+
+		capability, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || capability <= 0 {
+			return nil, fmt.Errorf("invalid peak TFLOPS '%s' of GPU model '%s'", value, model)
+		}
+
+		capabilities[model] = capability
+	}
+
+	return capabilities, nil
+}
+
+// AppendTensorThroughput adds DCGM_EXPORTER_TENSOR_THROUGHPUT computed from DCGM_FI_PROF_PIPE_TENSOR_ACTIVE and the
+// peak tensor throughput of the model of the GPU, for the GPUs whose model has a capability. The activity of
+// a MIG instance is relative to its own share of the GPU, so MIG instances are skipped.
+func AppendTensorThroughput(metrics MetricsByCounter, counters []Counter, capabilities map[string]float64) {
+	if len(capabilities) == 0 {
+		return
+	}
+
+	tensorCounter, err := FindCounterField(counters, dcgm.DCGM_FI_PROF_PIPE_TENSOR_ACTIVE)
+	if err != nil {
+		return
+	}
+
+	for _, m := range metrics[tensorCounter] {
+		capability, exists := capabilities[m.GPUModelName]
+		if !exists || m.GPUInstanceID != "" {
+			continue
+		}
+
+		tensorActive, err := strconv.ParseFloat(m.Value, 64)
+		if err != nil {
+			continue
+		}
+
+		derived := m
+		derived.Counter = tensorThroughputCounter
+		derived.Value = fmt.Sprintf("%f", tensorActive*capability)
+		derived.Attributes = maps.Clone(m.Attributes)
+
+		metrics[tensorThroughputCounter] = append(metrics[tensorThroughputCounter], derived)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTensorCapabilities(t *testing.T) {
+	capabilities, err := ParseTensorCapabilities([]string{"NVIDIA A100-SXM4-80GB=312", " NVIDIA H100 80GB HBM3 = 989.4 "})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"NVIDIA A100-SXM4-80GB": 312, "NVIDIA H100 80GB HBM3": 989.4}, capabilities)
+
+	for _, entry := range []string{"NVIDIA A100-SXM4-80GB", "=312", "NVIDIA A100-SXM4-80GB=fast", "NVIDIA A100-SXM4-80GB=0"} {
+		_, err := ParseTensorCapabilities([]string{entry})
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestGetMetricsTensorThroughput(t *testing.T) {
+	tensorCounter := Counter{FieldID: dcgm.DCGM_FI_PROF_PIPE_TENSOR_ACTIVE, FieldName: "DCGM_FI_PROF_PIPE_TENSOR_ACTIVE", PromType: "gauge", Help: "Ratio of cycles the tensor (HMMA) pipe is active."}
+
+	sysInfo := SystemInfo{GPUCount: 2, InfoType: dcgm.FE_GPU, gOpt: DeviceOptions{Flex: true}}
+	sysInfo.GPUs[0] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 0, UUID: "GPU-0",
+		Identifiers: dcgm.DeviceIdentifiers{Model: "NVIDIA A100-SXM4-80GB"}}}
+	sysInfo.GPUs[1] = GPUInfo{DeviceInfo: dcgm.Device{GPU: 1, UUID: "GPU-1",
+		Identifiers: dcgm.DeviceIdentifiers{Model: "NVIDIA T4"}}}
+
+	collector := &DCGMCollector{
+		Counters:           []Counter{tensorCounter},
+		DeviceFields:       []dcgm.Short{tensorCounter.FieldID},
+		SysInfo:            sysInfo,
+		TensorCapabilities: map[string]float64{"NVIDIA A100-SXM4-80GB": 312},
+	}
+
+	defer func(getLatestValues func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error)) {
+		dcgmEntityGetLatestValues = getLatestValues
+	}(dcgmEntityGetLatestValues)
+	dcgmEntityGetLatestValues = func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], math.Float64bits(0.25))
+		return []dcgm.FieldValue_v1{{FieldId: uint(tensorCounter.FieldID), FieldType: dcgm.DCGM_FT_DOUBLE, Value: value}}, nil
+	}
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+
+	require.Len(t, metrics[tensorThroughputCounter], 1, "GPUs without a capability are skipped")
+	m := metrics[tensorThroughputCounter][0]
+	assert.Equal(t, "0", m.GPU)
+	assert.Equal(t, "NVIDIA A100-SXM4-80GB", m.GPUModelName)
+	assert.Equal(t, "78.000000", m.Value)
+}
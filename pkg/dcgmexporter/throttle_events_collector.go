@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"slices"
+)
+
+const throttleReasonLabel = "reason"
+
+// IsDCGMFIDevThrottleEventsEnabled checks if the DCGM_FI_DEV_THROTTLE_EVENTS counter exists
+func IsDCGMFIDevThrottleEventsEnabled(counters []Counter) bool {
+	return slices.ContainsFunc(counters,
+		func(c Counter) bool {
+			return c.FieldName == dcgmFIDevThrottleEvents
+		})
+}
+
+// throttleEventsCollector counts the transitions of every GPU into a throttle state, by reason.
+// The throttle reasons of the previous scrape are kept per GPU to detect the transitions.
+type throttleEventsCollector struct {
+	throttleReasonsSampler
+
+	previous map[uint]clockEventBitmask            // Throttle reasons of the last scrape, by GPU
+	events   map[uint]map[clockEventBitmask]uint64 // Transitions into a throttle state, by GPU and reason
+}
+
+func NewThrottleEventsCollector(counters []Counter,
+	hostname string,
+	config *Config,
+	fieldEntityGroupTypeSystemInfo FieldEntityGroupTypeSystemInfoItem) (Collector, error) {
+	if !IsDCGMFIDevThrottleEventsEnabled(counters) {
+		return nil, fmt.Errorf(dcgmFIDevThrottleEvents + " collector is disabled")
+	}
+
+	collector := newThrottleEventsCollector(counters[slices.IndexFunc(counters, func(c Counter) bool {
+		return c.FieldName == dcgmFIDevThrottleEvents
+	})], hostname, config, fieldEntityGroupTypeSystemInfo.SystemInfo)
+
+	if err := collector.watch(); err != nil {
+		return nil, err
+	}
+
+	return collector, nil
+}
+
+func newThrottleEventsCollector(counter Counter,
+	hostname string,
+	config *Config,
+	sysInfo SystemInfo) *throttleEventsCollector {
+	return &throttleEventsCollector{
+		throttleReasonsSampler: newThrottleReasonsSampler(counter, hostname, config, sysInfo),
+		previous:               map[uint]clockEventBitmask{},
+		events:                 map[uint]map[clockEventBitmask]uint64{},
+	}
+}
+
+func (c *throttleEventsCollector) GetMetrics() (MetricsByCounter, error) {
+	return c.getMetrics(c.observe, func(mi MonitoringInfo) []Metric {
+		var metrics []Metric
+		for reason, count := range c.events[mi.DeviceInfo.GPU] {
+			metrics = append(metrics, c.metric(mi, fmt.Sprint(count), map[string]string{throttleReasonLabel: reason.String()}))
+		}
+		return metrics
+	})
+}
+
+// observe counts every throttle reason that is set in reasons, but was not set at the previous scrape
+// of the GPU. The first scrape of a GPU only records its state, since there is no transition to see.
+func (c *throttleEventsCollector) observe(gpu uint, reasons clockEventBitmask) {
+	previous, seen := c.previous[gpu]
+	c.previous[gpu] = reasons
+	if !seen {
+		return
+	}
+
+	for reason := range clockEventToString {
+		if reasons&reason == 0 || previous&reason != 0 {
+			continue
+		}
+
+		if _, exists := c.events[gpu]; !exists {
+			c.events[gpu] = map[clockEventBitmask]uint64{}
+		}
+		c.events[gpu][reason]++
+	}
+}
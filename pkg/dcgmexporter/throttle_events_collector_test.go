@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"sync"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func throttleReasonsValue(reasons clockEventBitmask) dcgm.FieldValue_v1 {
+	value := [4096]byte{}
+	binary.LittleEndian.PutUint64(value[:], uint64(reasons))
+
+	return dcgm.FieldValue_v1{
+		FieldId:   uint(dcgm.DCGM_FI_DEV_CLOCK_THROTTLE_REASONS),
+		FieldType: dcgm.DCGM_FT_INT64,
+		Value:     value,
+	}
+}
+
+func TestIsDCGMFIDevThrottleEventsEnabled(t *testing.T) {
+	assert.True(t, IsDCGMFIDevThrottleEventsEnabled([]Counter{{FieldName: dcgmFIDevThrottleEvents}}))
+	assert.False(t, IsDCGMFIDevThrottleEventsEnabled([]Counter{{FieldName: dcgmExpClockEventsCount}}))
+
+	counterType, err := IdentifyMetricType(dcgmFIDevThrottleEvents)
+	require.NoError(t, err)
+	assert.Equal(t, DCGMThrottleEvents, counterType)
+}
+
+func TestThrottleEventsCollector(t *testing.T) {
+	counter := Counter{
+		FieldID:   dcgm.Short(DCGMThrottleEvents),
+		FieldName: dcgmFIDevThrottleEvents,
+		PromType:  "counter",
+		Help:      "Number of times the GPU entered a throttle state, by reason.",
+	}
+
+	sysInfo := SystemInfo{
+		GPUCount: 1,
+		InfoType: dcgm.FE_GPU,
+		gOpt:     DeviceOptions{Flex: true},
+	}
+	sysInfo.GPUs[0].DeviceInfo = dcgm.Device{GPU: 0, UUID: "GPU-00000000-0000-0000-0000-000000000000"}
+
+	collector := newThrottleEventsCollector(counter, "testhost", &Config{}, sysInfo)
+
+	var reasons clockEventBitmask
+	getLatestValues := dcgmEntityGetLatestValues
+	t.Cleanup(func() {
+		dcgmEntityGetLatestValues = getLatestValues
+	})
+	dcgmEntityGetLatestValues = func(group dcgm.Field_Entity_Group, gpu uint, fields []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		assert.Equal(t, dcgm.FE_GPU, group)
+		assert.Equal(t, []dcgm.Short{dcgm.DCGM_FI_DEV_CLOCK_THROTTLE_REASONS}, fields)
+		return []dcgm.FieldValue_v1{throttleReasonsValue(reasons)}, nil
+	}
+
+	scrape := func(state clockEventBitmask) []Metric {
+		reasons = state
+		metrics, err := collector.GetMetrics()
+		require.NoError(t, err)
+		return metrics[counter]
+	}
+
+	assert.Empty(t, scrape(0), "unthrottled GPU has no events")
+
+	events := scrape(DCGM_CLOCKS_THROTTLE_REASON_HW_THERMAL)
+	require.Len(t, events, 1, "transition from unthrottled to thermal-throttled")
+	assert.Equal(t, "1", events[0].Value)
+	assert.Equal(t, map[string]string{throttleReasonLabel: "hw_thermal"}, events[0].Labels)
+	assert.Equal(t, "0", events[0].GPU)
+	assert.Equal(t, "testhost", events[0].Hostname)
+
+	events = scrape(DCGM_CLOCKS_THROTTLE_REASON_HW_THERMAL)
+	require.Len(t, events, 1)
+	assert.Equal(t, "1", events[0].Value, "staying thermal-throttled is not a new event")
+
+	scrape(0)
+	events = scrape(DCGM_CLOCKS_THROTTLE_REASON_HW_THERMAL | DCGM_CLOCKS_THROTTLE_REASON_SW_POWER_CAP)
+	require.Len(t, events, 2)
+
+	values := map[string]string{}
+	for _, event := range events {
+		values[event.Labels[throttleReasonLabel]] = event.Value
+	}
+	assert.Equal(t, map[string]string{"hw_thermal": "2", "power_cap": "1"}, values)
+}
+
+func TestThrottleEventsCollectorIgnoresInitialState(t *testing.T) {
+	collector := newThrottleEventsCollector(Counter{}, "", &Config{}, SystemInfo{})
+
+	collector.observe(0, DCGM_CLOCKS_THROTTLE_REASON_HW_THERMAL)
+	assert.Empty(t, collector.events, "a GPU throttled at the first scrape did not transition")
+
+	collector.observe(0, DCGM_CLOCKS_THROTTLE_REASON_HW_THERMAL)
+	assert.Empty(t, collector.events)
+}
+
+func TestThrottleEventsCollectorConcurrentScrapes(t *testing.T) {
+	sysInfo := SystemInfo{
+		GPUCount: 2,
+		InfoType: dcgm.FE_GPU,
+		gOpt:     DeviceOptions{Flex: true},
+	}
+	sysInfo.GPUs[0].DeviceInfo = dcgm.Device{GPU: 0}
+	sysInfo.GPUs[1].DeviceInfo = dcgm.Device{GPU: 1}
+
+	counter := Counter{FieldID: dcgm.Short(DCGMThrottleEvents), FieldName: dcgmFIDevThrottleEvents}
+	collector := newThrottleEventsCollector(counter, "", &Config{}, sysInfo)
+
+	var mtx sync.Mutex
+	polls := map[uint]int{}
+	getLatestValues := dcgmEntityGetLatestValues
+	t.Cleanup(func() {
+		dcgmEntityGetLatestValues = getLatestValues
+	})
+	dcgmEntityGetLatestValues = func(_ dcgm.Field_Entity_Group, gpu uint, _ []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		// Alternate between throttled and unthrottled, so that every other poll of a GPU is a transition
+		polls[gpu]++
+		return []dcgm.FieldValue_v1{throttleReasonsValue(clockEventBitmask(polls[gpu]%2) * DCGM_CLOCKS_THROTTLE_REASON_HW_THERMAL)}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				_, err := collector.GetMetrics()
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+	require.Len(t, metrics[counter], 2, "one series per GPU")
+	for _, m := range metrics[counter] {
+		assert.Equal(t, "100", m.Value, "every other of the 201 scrapes is a transition")
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+// throttleReasonsSampler samples the throttle reasons of every monitored GPU at every scrape. The throttle
+// counters are built on it, and only differ in what they keep of the samples.
+type throttleReasonsSampler struct {
+	counter         Counter
+	sysInfo         SystemInfo
+	hostname        string
+	config          *Config
+	cleanups        []func()
+	transformations []Transform
+
+	mtx sync.Mutex // Held for the whole of a scrape, since the counters keep their state across scrapes
+}
+
+func newThrottleReasonsSampler(counter Counter, hostname string, config *Config, sysInfo SystemInfo) throttleReasonsSampler {
+	return throttleReasonsSampler{
+		counter:         counter,
+		sysInfo:         sysInfo,
+		hostname:        hostname,
+		config:          config,
+		transformations: getTransformations(config),
+	}
+}
+
+// watch asks DCGM to sample the throttle reasons of the monitored GPUs at the collect interval.
+func (s *throttleReasonsSampler) watch() error {
+	cleanups, _, err := SetupDcgmFieldsWatch([]dcgm.Short{dcgm.DCGM_FI_DEV_CLOCK_THROTTLE_REASONS},
+		s.sysInfo,
+		int64(s.config.CollectInterval)*1000)
+	if err != nil {
+		return fmt.Errorf("failed to watch metrics; err: %w", err)
+	}
+
+	s.cleanups = cleanups
+
+	return nil
+}
+
+// getMetrics passes the throttle reasons of every monitored GPU to observe, then builds the metrics of every
+// monitored entity with metricsOf. GPU instances share the throttle reasons of their GPU.
+func (s *throttleReasonsSampler) getMetrics(observe func(gpu uint, reasons clockEventBitmask),
+	metricsOf func(mi MonitoringInfo) []Metric) (MetricsByCounter, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	monitoringInfo := GetMonitoredEntities(s.sysInfo)
+
+	scraped := map[uint]bool{}
+	for _, mi := range monitoringInfo {
+		gpu := mi.DeviceInfo.GPU
+		if scraped[gpu] {
+			continue
+		}
+		scraped[gpu] = true
+
+		values, err := dcgmEntityGetLatestValues(dcgm.FE_GPU, gpu, []dcgm.Short{dcgm.DCGM_FI_DEV_CLOCK_THROTTLE_REASONS})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, val := range values {
+			if val.FieldType != dcgm.DCGM_FT_INT64 || ToTypedValue(val).Skip {
+				continue
+			}
+
+			observe(gpu, clockEventBitmask(val.Int64()))
+		}
+	}
+
+	metrics := make(MetricsByCounter)
+	for _, mi := range monitoringInfo {
+		if entityMetrics := metricsOf(mi); len(entityMetrics) > 0 {
+			metrics[s.counter] = append(metrics[s.counter], entityMetrics...)
+		}
+	}
+
+	for _, transform := range s.transformations {
+		err := transform.Process(metrics, s.sysInfo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transform metrics for transform '%s'; err: %v", transform.Name(), err)
+		}
+	}
+
+	labelIdentity(metrics, identityLabels(s.config))
+
+	return metrics, nil
+}
+
+// metric returns the metric of the counter for the entity, with the value and labels given.
+func (s *throttleReasonsSampler) metric(mi MonitoringInfo, value string, labels map[string]string) Metric {
+	uuid := "UUID"
+	if s.config.UseOldNamespace {
+		uuid = "uuid"
+	}
+
+	m := Metric{
+		Counter:      s.counter,
+		Value:        value,
+		UUID:         uuid,
+		GPU:          fmt.Sprintf("%d", mi.DeviceInfo.GPU),
+		GPUUUID:      mi.DeviceInfo.UUID,
+		GPUDevice:    fmt.Sprintf("nvidia%d", mi.DeviceInfo.GPU),
+		GPUModelName: getGPUModel(mi.DeviceInfo, s.config.ReplaceBlanksInModelName),
+		Hostname:     s.hostname,
+
+		Labels:     labels,
+		Attributes: map[string]string{},
+	}
+	if mi.InstanceInfo != nil {
+		m.MigProfile = mi.InstanceInfo.ProfileName
+		m.GPUInstanceID = fmt.Sprintf("%d", mi.InstanceInfo.Info.NvmlInstanceId)
+	}
+
+	return m
+}
+
+func (s *throttleReasonsSampler) Cleanup() {
+	for _, cleanup := range s.cleanups {
+		cleanup()
+	}
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// IsDCGMFIDevThrottleSecondsEnabled checks if the DCGM_FI_DEV_THROTTLE_SECONDS_TOTAL counter exists
+func IsDCGMFIDevThrottleSecondsEnabled(counters []Counter) bool {
+	return slices.ContainsFunc(counters,
+		func(c Counter) bool {
+			return c.FieldName == dcgmFIDevThrottleSeconds
+		})
+}
+
+// throttleSecondsCollector accumulates the time every GPU spends in a throttle state. The throttle reasons
+// are sampled at every scrape and are assumed to hold until the next scrape of the GPU.
+type throttleSecondsCollector struct {
+	throttleReasonsSampler
+
+	previous   map[uint]clockEventBitmask // Throttle reasons of the last scrape, by GPU
+	previousAt map[uint]time.Time         // Time of the last scrape, by GPU
+	throttled  map[uint]float64           // Time spent in a throttle state (in seconds), by GPU
+}
+
+func NewThrottleSecondsCollector(counters []Counter,
+	hostname string,
+	config *Config,
+	fieldEntityGroupTypeSystemInfo FieldEntityGroupTypeSystemInfoItem) (Collector, error) {
+	if !IsDCGMFIDevThrottleSecondsEnabled(counters) {
+		return nil, fmt.Errorf(dcgmFIDevThrottleSeconds + " collector is disabled")
+	}
+
+	collector := newThrottleSecondsCollector(counters[slices.IndexFunc(counters, func(c Counter) bool {
+		return c.FieldName == dcgmFIDevThrottleSeconds
+	})], hostname, config, fieldEntityGroupTypeSystemInfo.SystemInfo)
+
+	if err := collector.watch(); err != nil {
+		return nil, err
+	}
+
+	return collector, nil
+}
+
+func newThrottleSecondsCollector(counter Counter,
+	hostname string,
+	config *Config,
+	sysInfo SystemInfo) *throttleSecondsCollector {
+	return &throttleSecondsCollector{
+		throttleReasonsSampler: newThrottleReasonsSampler(counter, hostname, config, sysInfo),
+		previous:               map[uint]clockEventBitmask{},
+		previousAt:             map[uint]time.Time{},
+		throttled:              map[uint]float64{},
+	}
+}
+
+func (c *throttleSecondsCollector) GetMetrics() (MetricsByCounter, error) {
+	now := timeNow()
+
+	return c.getMetrics(func(gpu uint, reasons clockEventBitmask) {
+		c.observe(gpu, reasons, now)
+	}, func(mi MonitoringInfo) []Metric {
+		seconds, exists := c.throttled[mi.DeviceInfo.GPU]
+		if !exists {
+			return nil
+		}
+		return []Metric{c.metric(mi, strconv.FormatFloat(seconds, 'f', 3, 64), map[string]string{})}
+	})
+}
+
+// observe adds the time since the previous scrape of the GPU, when the GPU was throttled at the previous scrape.
+// An idle GPU runs at reduced clocks by design, so it does not count as throttled.
+func (c *throttleSecondsCollector) observe(gpu uint, reasons clockEventBitmask, now time.Time) {
+	previous, seen := c.previous[gpu]
+	previousAt := c.previousAt[gpu]
+	c.previous[gpu] = reasons
+	c.previousAt[gpu] = now
+
+	if !seen {
+		c.throttled[gpu] = 0
+		return
+	}
+
+	if previous&^DCGM_CLOCKS_THROTTLE_REASON_GPU_IDLE != 0 && now.After(previousAt) {
+		c.throttled[gpu] += now.Sub(previousAt).Seconds()
+	}
+}
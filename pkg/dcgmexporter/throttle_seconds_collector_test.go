@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsDCGMFIDevThrottleSecondsEnabled(t *testing.T) {
+	assert.True(t, IsDCGMFIDevThrottleSecondsEnabled([]Counter{{FieldName: dcgmFIDevThrottleSeconds}}))
+	assert.False(t, IsDCGMFIDevThrottleSecondsEnabled([]Counter{{FieldName: dcgmFIDevThrottleEvents}}))
+
+	counterType, err := IdentifyMetricType(dcgmFIDevThrottleSeconds)
+	require.NoError(t, err)
+	assert.Equal(t, DCGMThrottleSeconds, counterType)
+}
+
+func TestThrottleSecondsCollector(t *testing.T) {
+	counter := Counter{
+		FieldID:   dcgm.Short(DCGMThrottleSeconds),
+		FieldName: dcgmFIDevThrottleSeconds,
+		PromType:  "counter",
+		Help:      "Time the GPU spent in a throttle state (in seconds).",
+	}
+
+	sysInfo := SystemInfo{
+		GPUCount: 1,
+		InfoType: dcgm.FE_GPU,
+		gOpt:     DeviceOptions{Flex: true},
+	}
+	sysInfo.GPUs[0].DeviceInfo = dcgm.Device{GPU: 0, UUID: "GPU-00000000-0000-0000-0000-000000000000"}
+
+	collector := newThrottleSecondsCollector(counter, "testhost", &Config{}, sysInfo)
+
+	now := timeNow
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time {
+		return clock
+	}
+
+	var reasons clockEventBitmask
+	getLatestValues := dcgmEntityGetLatestValues
+	t.Cleanup(func() {
+		dcgmEntityGetLatestValues = getLatestValues
+		timeNow = now
+	})
+	dcgmEntityGetLatestValues = func(group dcgm.Field_Entity_Group, gpu uint, fields []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		assert.Equal(t, dcgm.FE_GPU, group)
+		assert.Equal(t, []dcgm.Short{dcgm.DCGM_FI_DEV_CLOCK_THROTTLE_REASONS}, fields)
+		return []dcgm.FieldValue_v1{throttleReasonsValue(reasons)}, nil
+	}
+
+	scrape := func(after time.Duration, state clockEventBitmask) string {
+		clock = clock.Add(after)
+		reasons = state
+		metrics, err := collector.GetMetrics()
+		require.NoError(t, err)
+		require.Len(t, metrics[counter], 1)
+		assert.Equal(t, "0", metrics[counter][0].GPU)
+		assert.Equal(t, "testhost", metrics[counter][0].Hostname)
+		return metrics[counter][0].Value
+	}
+
+	assert.Equal(t, "0.000", scrape(0, DCGM_CLOCKS_THROTTLE_REASON_HW_THERMAL), "the first scrape only records the state")
+	assert.Equal(t, "10.000", scrape(10*time.Second, DCGM_CLOCKS_THROTTLE_REASON_SW_POWER_CAP))
+	assert.Equal(t, "25.500", scrape(15500*time.Millisecond, 0))
+	assert.Equal(t, "25.500", scrape(30*time.Second, DCGM_CLOCKS_THROTTLE_REASON_GPU_IDLE), "an unthrottled GPU accumulates no time")
+	assert.Equal(t, "25.500", scrape(30*time.Second, 0), "an idle GPU is not throttled")
+}
+
+func TestThrottleSecondsCollectorConcurrentScrapes(t *testing.T) {
+	sysInfo := SystemInfo{
+		GPUCount: 1,
+		InfoType: dcgm.FE_GPU,
+		gOpt:     DeviceOptions{Flex: true},
+	}
+	sysInfo.GPUs[0].DeviceInfo = dcgm.Device{GPU: 0}
+
+	counter := Counter{FieldID: dcgm.Short(DCGMThrottleSeconds), FieldName: dcgmFIDevThrottleSeconds}
+	collector := newThrottleSecondsCollector(counter, "", &Config{}, sysInfo)
+
+	getLatestValues := dcgmEntityGetLatestValues
+	t.Cleanup(func() {
+		dcgmEntityGetLatestValues = getLatestValues
+	})
+	dcgmEntityGetLatestValues = func(dcgm.Field_Entity_Group, uint, []dcgm.Short) ([]dcgm.FieldValue_v1, error) {
+		return []dcgm.FieldValue_v1{throttleReasonsValue(DCGM_CLOCKS_THROTTLE_REASON_HW_THERMAL)}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				_, err := collector.GetMetrics()
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	metrics, err := collector.GetMetrics()
+	require.NoError(t, err)
+	require.Len(t, metrics[counter], 1)
+}
@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"sync"
 	"text/template"
+	"time"
 
 	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
 	"github.com/prometheus/exporter-toolkit/web"
@@ -67,16 +68,52 @@ type MetricsPipeline struct {
 	linkCollector   *DCGMCollector
 	cpuCollector    *DCGMCollector
 	coreCollector   *DCGMCollector
+
+	lastError    *LastCollectionError
+	snapshots    *MetricsSnapshots
+	staticGauges string
+
+	entityGroupOutputs map[string]chan string // Metrics of the entity groups served on a separate address
 }
 
 type DCGMCollector struct {
-	Counters                 []Counter
-	DeviceFields             []dcgm.Short
-	Cleanups                 []func()
+	Counters               []Counter
+	DeviceFields           []dcgm.Short
+	Cleanups               []func()
+	SysInfo                SystemInfo
+	SampledFields          []dcgm.Short
+	StaleMetricsMaxAge     time.Duration
+	Processors             []MetricProcessor // Run in order after every collection
+	IdleCollectInterval    time.Duration
+	NVMLFallback           bool               // Report basic utilization and memory fields from NVML when DCGM has no value
+	CounterOK              bool               // Export whether every watched field returned a value
+	TensorCapabilities     map[string]float64 // Peak tensor TFLOPS by GPU model
+	ComputeCapabilityLabel bool               // Label the metrics of GPUs with their CUDA compute capability
+	RawValues              bool               // Export the untransformed value of the transformed fields as <field>_raw
+	CodecSessions          bool               // Export the number of active encoder sessions of the GPUs
+	FanStatus              bool               // Export whether the fans of the GPUs failed
+	ProcessUtilizationTopN int                // Export the utilization of the GPUs by their top N processes
+	MetricOptions                             // Convert the field values to metrics
+
+	sampledGroup      dcgm.GroupHandle
+	sampledFieldGroup dcgm.FieldHandle
+	samplesSince      time.Time
+	lastMetrics       MetricsByCounter
+	lastMetricsAt     time.Time
+	polls             map[dcgm.GroupEntityPair]entityPoll
+
+	computeCapabilities map[string]string // CUDA compute capability by GPU UUID
+}
+
+// MetricOptions are the options of a collector for converting field values to metrics.
+type MetricOptions struct {
 	UseOldNamespace          bool
-	SysInfo                  SystemInfo
 	Hostname                 string
 	ReplaceBlanksInModelName bool
+	FailedConversionsAsNaN   bool
+	ShortestFloatFormat      bool
+	MaxLabelValueLength      int
+	FloatZeroThreshold       float64
 }
 
 type Counter struct {
@@ -84,11 +121,15 @@ type Counter struct {
 	FieldName string
 	PromType  string
 	Help      string
+	Expr      string // Optional expression, which transforms the value of the field, see valueExpr
 }
 
 type Metric struct {
 	Counter Counter
 	Value   string
+	// RawValue is the value before the transforms of the exporter, e.g. the value expression; empty when the
+	// value is not transformed
+	RawValue string
 
 	GPU          string
 	GPUUUID      string
@@ -103,6 +144,9 @@ type Metric struct {
 
 	Labels     map[string]string
 	Attributes map[string]string
+
+	// Timestamp is the sample time in milliseconds; zero means the scrape time is used
+	Timestamp int64
 }
 
 func (m Metric) getIDOfType(idType KubernetesGPUIDType) (string, error) {
@@ -135,6 +179,15 @@ type MetricsServer struct {
 	metrics     string
 	metricsChan chan string
 	registry    *Registry
+	lastError   *LastCollectionError
+	snapshots   *MetricsSnapshots
+	diag        *diagRunner
+	config      *Config
+
+	gatherInterval time.Duration // If not zero, the registry is gathered in the background at this interval
+	gathered       MetricsByCounter
+	gatherErr      error
+	gatheredAt     time.Time
 }
 
 type PodMapper struct {
@@ -152,6 +205,7 @@ type MetricsByCounter map[Counter][]Metric
 
 // CounterSet return
 type CounterSet struct {
-	DCGMCounters     []Counter
-	ExporterCounters []Counter
+	DCGMCounters        []Counter
+	ExporterCounters    []Counter
+	EntityGroupCounters map[dcgm.Field_Entity_Group][]Counter // DCGM counters of the entity groups with a counters file of their own
 }
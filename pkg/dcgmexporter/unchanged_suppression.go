@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"sync"
+	"time"
+)
+
+// emittedValue is the last emitted value of a series and when it was emitted.
+type emittedValue struct {
+	value string
+	at    time.Time
+}
+
+// unchangedSuppressor tracks the emitted values of every series, to suppress the samples whose value did not change.
+type unchangedSuppressor struct {
+	window time.Duration
+
+	mtx     sync.Mutex
+	emitted map[string]emittedValue
+}
+
+// newUnchangedSuppressor returns a MetricProcessor, which exports every series with the time its value was first
+// emitted, to reduce the volume of remote write: Prometheus drops a sample that repeats the timestamp and value of
+// the last one, so that a series only adds a sample when its value changes. Every exposition keeps all series, so
+// that every scraper sees them. A series is emitted with the time of the collection again, once its value did not
+// change for the window, which must stay below the lookback delta of the queries (5 minutes by default), since
+// series with timestamps are not marked stale.
+func newUnchangedSuppressor(window time.Duration) MetricProcessor {
+	suppressor := &unchangedSuppressor{
+		window:  window,
+		emitted: map[string]emittedValue{},
+	}
+
+	return suppressor.process
+}
+
+func (s *unchangedSuppressor) process(metrics MetricsByCounter) MetricsByCounter {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := timeNow()
+	emitted := map[string]emittedValue{}
+
+	for counter, values := range metrics {
+		if counter.PromType == "label" {
+			continue
+		}
+
+		for i, m := range values {
+			// Samples of sampled fields have their own timestamps, and do not repeat between collections
+			if m.Timestamp != 0 {
+				continue
+			}
+
+			key := seriesKey(m)
+			last, exists := s.emitted[key]
+			if exists && last.value == m.Value && now.Sub(last.at) < s.window {
+				emitted[key] = last
+				values[i].Timestamp = last.at.UnixMilli()
+				continue
+			}
+
+			emitted[key] = emittedValue{value: m.Value, at: now}
+			values[i].Timestamp = now.UnixMilli()
+		}
+	}
+
+	// Series that were not collected this time are forgotten
+	s.emitted = emitted
+
+	return metrics
+}
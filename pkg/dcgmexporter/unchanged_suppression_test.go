@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnchangedSuppressor(t *testing.T) {
+	tempCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+
+	now := timeNow
+	defer func() {
+		timeNow = now
+	}()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	elapsed := time.Duration(0)
+	timeNow = func() time.Time {
+		return start.Add(elapsed)
+	}
+
+	// collect returns the time of the samples of the GPUs, in seconds since the start
+	collect := func(suppress MetricProcessor, gpu0, gpu1 string) map[string]int64 {
+		values := map[string]string{"0": gpu0, "1": gpu1}
+		metrics := suppress(MetricsByCounter{tempCounter: {
+			{Counter: tempCounter, Value: gpu0, GPU: "0"},
+			{Counter: tempCounter, Value: gpu1, GPU: "1"},
+		}})
+
+		emitted := map[string]int64{}
+		for _, m := range metrics[tempCounter] {
+			assert.Equal(t, values[m.GPU], m.Value, "every series is exported")
+			emitted[m.GPU] = (m.Timestamp - start.UnixMilli()) / 1000
+		}
+		return emitted
+	}
+
+	t.Run("When the values do not change", func(t *testing.T) {
+		elapsed = 0
+		suppress := newUnchangedSuppressor(time.Minute)
+
+		assert.Equal(t, map[string]int64{"0": 0, "1": 0}, collect(suppress, "40", "50"))
+
+		elapsed += 20 * time.Second
+		assert.Equal(t, map[string]int64{"0": 0, "1": 20}, collect(suppress, "40", "51"),
+			"unchanged values keep the time they were first exported")
+
+		elapsed += 20 * time.Second
+		assert.Equal(t, map[string]int64{"0": 0, "1": 20}, collect(suppress, "40", "51"))
+
+		elapsed += 20 * time.Second
+		assert.Equal(t, map[string]int64{"0": 60, "1": 20}, collect(suppress, "40", "51"),
+			"the window forces the unchanged value of GPU 0 to be exported at the time of the collection")
+
+		elapsed += 20 * time.Second
+		assert.Equal(t, map[string]int64{"0": 60, "1": 80}, collect(suppress, "40", "51"),
+			"the window of GPU 1 started when its value changed")
+	})
+
+	t.Run("When a series is not collected", func(t *testing.T) {
+		elapsed = 0
+		suppress := newUnchangedSuppressor(time.Minute)
+
+		collect(suppress, "40", "50")
+		suppress(MetricsByCounter{})
+
+		elapsed += 20 * time.Second
+		assert.Equal(t, map[string]int64{"0": 20, "1": 20}, collect(suppress, "40", "50"),
+			"the values of missing series are forgotten")
+	})
+}
@@ -0,0 +1,338 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"unicode"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/sirupsen/logrus"
+)
+
+// valueExprVariable is the name of the value of the field in a value expression.
+const valueExprVariable = "value"
+
+// valueExpr is a compiled value expression of a counter, e.g. `value / 1024` or
+// `DCGM_FI_DEV_FB_USED / (DCGM_FI_DEV_FB_USED + DCGM_FI_DEV_FB_FREE)`. An expression is arithmetic on numbers,
+// the value of the field and the values of the other fields of the same entity, with +, -, *, / and parentheses.
+// It has no other operations, so that it cannot do anything but compute a value.
+type valueExpr struct {
+	root   exprNode
+	fields []dcgm.Short // Other fields, whose values the expression uses
+}
+
+type exprNode interface {
+	eval(value float64, fields map[dcgm.Short]float64) (float64, error)
+}
+
+type exprNumber float64
+
+type exprValue struct{}
+
+type exprField struct {
+	id   dcgm.Short
+	name string
+}
+
+type exprUnary struct {
+	operand exprNode
+}
+
+type exprBinary struct {
+	op          byte
+	left, right exprNode
+}
+
+func (n exprNumber) eval(float64, map[dcgm.Short]float64) (float64, error) {
+	return float64(n), nil
+}
+
+func (exprValue) eval(value float64, _ map[dcgm.Short]float64) (float64, error) {
+	return value, nil
+}
+
+func (n exprField) eval(_ float64, fields map[dcgm.Short]float64) (float64, error) {
+	value, exists := fields[n.id]
+	if !exists {
+		return 0, fmt.Errorf("no value of field '%s'", n.name)
+	}
+
+	return value, nil
+}
+
+func (n exprUnary) eval(value float64, fields map[dcgm.Short]float64) (float64, error) {
+	operand, err := n.operand.eval(value, fields)
+	return -operand, err
+}
+
+func (n exprBinary) eval(value float64, fields map[dcgm.Short]float64) (float64, error) {
+	left, err := n.left.eval(value, fields)
+	if err != nil {
+		return 0, err
+	}
+
+	right, err := n.right.eval(value, fields)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	default:
+		return left / right, nil
+	}
+}
+
+// Eval returns the value of the expression for the value of the field and the values of the other fields.
+// It fails when a field has no value or the result is not finite, e.g. on a division by zero.
+func (e *valueExpr) Eval(value float64, fields map[dcgm.Short]float64) (float64, error) {
+	result, err := e.root.eval(value, fields)
+	if err != nil {
+		return 0, err
+	}
+
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+
+	return result, nil
+}
+
+var compiledValueExprs sync.Map
+
+// compileValueExpr compiles an expression once and returns the same compiled expression on later calls.
+func compileValueExpr(expr string) (*valueExpr, error) {
+	if compiled, exists := compiledValueExprs.Load(expr); exists {
+		return compiled.(*valueExpr), nil
+	}
+
+	compiled, err := parseValueExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	compiledValueExprs.Store(expr, compiled)
+
+	return compiled, nil
+}
+
+// parseValueExpr parses an expression of the grammar
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = "-" unary | primary
+//	primary = number | "value" | field name | "(" expr ")"
+func parseValueExpr(expr string) (*valueExpr, error) {
+	p := &exprParser{input: expr, fields: map[dcgm.Short]bool{}}
+	p.next()
+
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s'; err: %w", expr, err)
+	}
+
+	if p.token != "" {
+		return nil, fmt.Errorf("invalid expression '%s'; err: unexpected '%s'", expr, p.token)
+	}
+
+	compiled := &valueExpr{root: root}
+	for field := range p.fields {
+		compiled.fields = append(compiled.fields, field)
+	}
+	slices.Sort(compiled.fields)
+
+	return compiled, nil
+}
+
+type exprParser struct {
+	input  string
+	pos    int
+	token  string // Current token, empty at the end of the input
+	fields map[dcgm.Short]bool
+}
+
+// next advances to the next token, which is a number, a name or a single character.
+func (p *exprParser) next() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+
+	start := p.pos
+	isName := func(c byte) bool { return c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)) }
+	isNumber := func(c byte) bool { return c == '.' || unicode.IsDigit(rune(c)) }
+
+	switch {
+	case p.pos == len(p.input):
+	case isNumber(p.input[p.pos]):
+		for p.pos < len(p.input) && isNumber(p.input[p.pos]) {
+			p.pos++
+		}
+	case isName(p.input[p.pos]):
+		for p.pos < len(p.input) && isName(p.input[p.pos]) {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+
+	p.token = p.input[start:p.pos]
+}
+
+func (p *exprParser) parseExpr() (exprNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.token == "+" || p.token == "-" {
+		op := p.token[0]
+		p.next()
+
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = exprBinary{op: op, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *exprParser) parseTerm() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.token == "*" || p.token == "/" {
+		op := p.token[0]
+		p.next()
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = exprBinary{op: op, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.token == "-" {
+		p.next()
+
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return exprUnary{operand: operand}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	token := p.token
+
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case token == "(":
+		p.next()
+
+		node, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.token != ")" {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		p.next()
+
+		return node, nil
+	case token == valueExprVariable:
+		p.next()
+		return exprValue{}, nil
+	case unicode.IsDigit(rune(token[0])) || token[0] == '.':
+		number, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", token)
+		}
+		p.next()
+
+		return exprNumber(number), nil
+	}
+
+	fieldID, exists := dcgm.DCGM_FI[token]
+	if !exists {
+		return nil, fmt.Errorf("unknown name '%s'", token)
+	}
+	p.fields[fieldID] = true
+	p.next()
+
+	return exprField{id: fieldID, name: token}, nil
+}
+
+// evalValueExpr returns the value of the expression of the counter for the value v of its field and the other
+// values of the same entity, or FailedToConvert when the expression cannot be evaluated.
+func evalValueExpr(counter Counter, v string, values []dcgm.FieldValue_v1, shortestFloats bool) string {
+	expr, err := compileValueExpr(counter.Expr)
+	if err != nil {
+		logrus.WithError(err).Debugf("Failed to compile the expression of %s", counter.FieldName)
+		return FailedToConvert
+	}
+
+	value, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return FailedToConvert
+	}
+
+	fields := make(map[dcgm.Short]float64, len(expr.fields))
+	for _, val := range values {
+		if !slices.Contains(expr.fields, dcgm.Short(val.FieldId)) {
+			continue
+		}
+
+		if f, ok := ToFloat64(val); ok {
+			fields[dcgm.Short(val.FieldId)] = f
+		}
+	}
+
+	result, err := expr.Eval(value, fields)
+	if err != nil {
+		logrus.WithError(err).Debugf("Failed to evaluate the expression of %s", counter.FieldName)
+		return FailedToConvert
+	}
+
+	if shortestFloats {
+		return strconv.FormatFloat(result, 'g', -1, 64)
+	}
+
+	return fmt.Sprintf("%f", result)
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseValueExpr(t *testing.T) {
+	tests := []struct {
+		expr   string
+		value  float64
+		fields map[dcgm.Short]float64
+		want   float64
+	}{
+		{expr: "value", value: 3, want: 3},
+		{expr: "value / 1024", value: 2048, want: 2},
+		{expr: "value * 100 + 1.5", value: 0.25, want: 26.5},
+		{expr: "-value - -2", value: 1, want: 1},
+		{expr: "2 * (value + 1)", value: 1, want: 4},
+		{expr: "1 - 2 - 3", want: -4},
+		{expr: "8 / 4 / 2", want: 1},
+		{
+			expr:   "DCGM_FI_DEV_FB_USED / (DCGM_FI_DEV_FB_USED + DCGM_FI_DEV_FB_FREE)",
+			fields: map[dcgm.Short]float64{dcgm.DCGM_FI_DEV_FB_USED: 30, dcgm.DCGM_FI_DEV_FB_FREE: 90},
+			want:   0.25,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := parseValueExpr(tt.expr)
+			require.NoError(t, err)
+
+			got, err := expr.Eval(tt.value, tt.fields)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func TestParseValueExprErrors(t *testing.T) {
+	for _, expr := range []string{"", "value +", "(value", "value)", "value value", "1.2.3", "os.Exit(1)", "DCGM_FI_DEV_NOPE", "value % 2"} {
+		_, err := parseValueExpr(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestValueExprEvalErrors(t *testing.T) {
+	expr, err := parseValueExpr("value / DCGM_FI_DEV_FB_TOTAL")
+	require.NoError(t, err)
+	assert.Equal(t, []dcgm.Short{dcgm.DCGM_FI_DEV_FB_TOTAL}, expr.fields)
+
+	_, err = expr.Eval(1, nil)
+	assert.ErrorContains(t, err, "DCGM_FI_DEV_FB_TOTAL")
+
+	_, err = expr.Eval(1, map[dcgm.Short]float64{dcgm.DCGM_FI_DEV_FB_TOTAL: 0})
+	assert.Error(t, err, "division by zero")
+}
+
+func TestToMetricWithValueExpr(t *testing.T) {
+	int64Value := func(v int64) [4096]byte {
+		value := [4096]byte{}
+		binary.LittleEndian.PutUint64(value[:], uint64(v))
+		return value
+	}
+
+	values := []dcgm.FieldValue_v1{
+		{FieldId: dcgm.DCGM_FI_DEV_FB_USED, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(30720)},
+		{FieldId: dcgm.DCGM_FI_DEV_FB_FREE, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(0)},
+		{FieldId: dcgm.DCGM_FI_DEV_FB_TOTAL, FieldType: dcgm.DCGM_FT_INT64, Value: int64Value(81920)},
+	}
+
+	t.Run("Scale", func(t *testing.T) {
+		c := []Counter{
+			{FieldID: dcgm.DCGM_FI_DEV_FB_USED, FieldName: "DCGM_FI_DEV_FB_USED", PromType: "gauge", Help: "Framebuffer memory used (in GiB).", Expr: "value / 1024"},
+		}
+
+		metrics := make(MetricsByCounter)
+		ToMetric(metrics, values, c, dcgm.Device{UUID: "fake0"}, nil, MetricOptions{})
+
+		require.Len(t, metrics[c[0]], 1)
+		assert.Equal(t, "30.000000", metrics[c[0]][0].Value)
+	})
+
+	t.Run("Ratio of two fields", func(t *testing.T) {
+		c := []Counter{
+			{dcgm.DCGM_FI_DEV_FB_USED, "DCGM_FI_DEV_FB_USED", "gauge", "Ratio of framebuffer memory used.",
+				"DCGM_FI_DEV_FB_USED / DCGM_FI_DEV_FB_TOTAL"},
+		}
+
+		metrics := make(MetricsByCounter)
+		ToMetric(metrics, values, c, dcgm.Device{UUID: "fake0"}, nil, MetricOptions{ShortestFloatFormat: true})
+
+		require.Len(t, metrics[c[0]], 1)
+		assert.Equal(t, "0.375", metrics[c[0]][0].Value)
+	})
+
+	t.Run("When the expression cannot be evaluated", func(t *testing.T) {
+		c := []Counter{
+			{dcgm.DCGM_FI_DEV_FB_USED, "DCGM_FI_DEV_FB_USED", "gauge", "Framebuffer memory used per free memory.",
+				"value / DCGM_FI_DEV_FB_FREE"},
+		}
+
+		metrics := make(MetricsByCounter)
+		ToMetric(metrics, values, c, dcgm.Device{UUID: "fake0"}, nil, MetricOptions{})
+		assert.Empty(t, metrics[c[0]])
+
+		ToMetric(metrics, values, c, dcgm.Device{UUID: "fake0"}, nil, MetricOptions{FailedConversionsAsNaN: true})
+		require.Len(t, metrics[c[0]], 1)
+		assert.Equal(t, "NaN", metrics[c[0]][0].Value)
+		assert.Equal(t, "true", metrics[c[0]][0].Attributes[conversionFailedAttribute])
+	})
+}
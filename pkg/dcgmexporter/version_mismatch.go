@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/sirupsen/logrus"
+)
+
+const dcgmExporterVersionMismatch = "DCGM_EXPORTER_VERSION_MISMATCH"
+
+// versionMismatch is set when the installed DCGM library does not know all fields of the go-dcgm binding
+var versionMismatch atomic.Bool
+
+// latestBindingField returns the highest ID of the fields, which the go-dcgm binding defines. DCGM_FI_MAX_FIELDS and
+// DCGM_FI_UNKNOWN are bounds of the IDs rather than fields, so that DCGM does not know them.
+func latestBindingField() dcgm.Short {
+	var latest dcgm.Short
+	for name, fieldID := range dcgm.DCGM_FI {
+		if !strings.HasPrefix(name, "DCGM_FI_") || fieldID == dcgm.DCGM_FI_MAX_FIELDS || fieldID == dcgm.DCGM_FI_UNKNOWN {
+			continue
+		}
+		latest = max(latest, fieldID)
+	}
+
+	return latest
+}
+
+// CheckBindingVersion compares the fields of the go-dcgm binding the exporter is built against with the fields of
+// the installed DCGM library, which differ when their versions diverge, so that the IDs of fields may not match.
+// The library is older when it does not know the latest field of the binding. A newer library cannot be told apart
+// by probing the IDs after it, since the IDs of the fields have gaps. A mismatch is logged and exported as
+// DCGM_EXPORTER_VERSION_MISMATCH. It must be called after DCGM is initialized.
+func CheckBindingVersion() bool {
+	latest := latestBindingField()
+
+	_, knowsLatest := fieldMeta(latest)
+	if !knowsLatest {
+		logrus.WithField("field_id", latest).Warn(
+			"The installed DCGM library is older than the go-dcgm binding of the exporter; metrics may be wrong.")
+	}
+
+	versionMismatch.Store(!knowsLatest)
+
+	return !knowsLatest
+}
+
+// formatVersionMismatch returns the DCGM_EXPORTER_VERSION_MISMATCH gauge.
+func formatVersionMismatch() (string, error) {
+	value := 0
+	if versionMismatch.Load() {
+		value = 1
+	}
+
+	return formatExporterGauge(dcgmExporterVersionMismatch,
+		"1 when the installed DCGM library is older than the go-dcgm binding of the exporter.", value)
+}
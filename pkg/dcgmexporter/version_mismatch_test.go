@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestBindingField(t *testing.T) {
+	assert.Equal(t, dcgm.Short(dcgm.DCGM_FI_DEV_CPU_MODEL), latestBindingField(),
+		"DCGM_FI_MAX_FIELDS bounds the IDs, but is not a field")
+}
+
+func TestCheckBindingVersion(t *testing.T) {
+	defer func(getById func(dcgm.Short) dcgm.FieldMeta) {
+		dcgmFieldGetById = getById
+		versionMismatch.Store(false)
+	}(dcgmFieldGetById)
+
+	// The library knows the fields of the binding, except the ones it is too old for
+	libraryKnowsAllBut := func(unknown ...dcgm.Short) {
+		known := map[dcgm.Short]bool{}
+		for _, fieldID := range dcgm.DCGM_FI {
+			known[fieldID] = fieldID != dcgm.DCGM_FI_UNKNOWN && fieldID != dcgm.DCGM_FI_MAX_FIELDS &&
+				!slices.Contains(unknown, fieldID)
+		}
+
+		dcgmFieldGetById = func(fieldID dcgm.Short) dcgm.FieldMeta {
+			if !known[fieldID] {
+				return dcgm.FieldMeta{}
+			}
+			return dcgm.FieldMeta{FieldId: fieldID}
+		}
+	}
+
+	tests := []struct {
+		name     string
+		unknown  []dcgm.Short
+		mismatch bool
+		gauge    string
+	}{
+		{name: "Same version", gauge: dcgmExporterVersionMismatch + " 0\n"},
+		{
+			name:     "Older library",
+			unknown:  []dcgm.Short{dcgm.DCGM_FI_DEV_CPU_VENDOR, dcgm.DCGM_FI_DEV_CPU_MODEL},
+			mismatch: true,
+			gauge:    dcgmExporterVersionMismatch + " 1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			libraryKnowsAllBut(tt.unknown...)
+			assert.Equal(t, tt.mismatch, CheckBindingVersion())
+
+			out, err := formatStaticGauges(&Config{}, nil)
+			require.NoError(t, err)
+			assert.Contains(t, out, tt.gauge)
+			assert.NoError(t, validateExposition(out))
+		})
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"text/template"
+)
+
+const dcgmExporterWatchedFields = "DCGM_EXPORTER_WATCHED_FIELDS"
+
+var watchedFieldsFormat = `# HELP {{ .Name }} Number of fields watched in DCGM, summed over the watched entity groups (watch="fields"), and number of watched entity groups (watch="groups").
+# TYPE {{ .Name }} gauge
+{{ .Name }}{watch="fields"} {{ .Fields }}
+{{ .Name }}{watch="groups"} {{ .Groups }}
+`
+
+var getWatchedFieldsTemplate = sync.OnceValue(func() *template.Template {
+	return template.Must(template.New("watchedFields").Parse(watchedFieldsFormat))
+})
+
+// watchedFields counts the watches set up in DCGM, to diagnose watches that are never cleaned up.
+var watchedFields watchCount
+
+type watchCount struct {
+	fields atomic.Int64
+	groups atomic.Int64
+}
+
+// add counts the watch of fields on one entity group and returns the cleanup, which uncounts it.
+func (w *watchCount) add(fields int) func() {
+	w.fields.Add(int64(fields))
+	w.groups.Add(1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			w.fields.Add(-int64(fields))
+			w.groups.Add(-1)
+		})
+	}
+}
+
+func (w *watchCount) encode(out io.Writer) error {
+	return getWatchedFieldsTemplate().Execute(out, struct {
+		Name   string
+		Fields int64
+		Groups int64
+	}{
+		Name:   dcgmExporterWatchedFields,
+		Fields: w.fields.Load(),
+		Groups: w.groups.Load(),
+	})
+}
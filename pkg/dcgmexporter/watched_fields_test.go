@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchedFields(t *testing.T) {
+	createGroup, addEntityToGroup, destroyGroup := dcgmCreateGroup, dcgmAddEntityToGroup, dcgmDestroyGroup
+	fieldGroupCreate, fieldGroupDestroy, watchFieldsWithGroupEx := dcgmFieldGroupCreate, dcgmFieldGroupDestroy, dcgmWatchFieldsWithGroupEx
+	defer func() {
+		dcgmCreateGroup, dcgmAddEntityToGroup, dcgmDestroyGroup = createGroup, addEntityToGroup, destroyGroup
+		dcgmFieldGroupCreate, dcgmFieldGroupDestroy, dcgmWatchFieldsWithGroupEx = fieldGroupCreate, fieldGroupDestroy, watchFieldsWithGroupEx
+	}()
+
+	dcgmCreateGroup = func(string) (dcgm.GroupHandle, error) {
+		return dcgm.GroupHandle{}, nil
+	}
+	dcgmAddEntityToGroup = func(dcgm.GroupHandle, dcgm.Field_Entity_Group, uint) error {
+		return nil
+	}
+	dcgmDestroyGroup = func(dcgm.GroupHandle) error {
+		return nil
+	}
+	dcgmFieldGroupCreate = func(string, []dcgm.Short) (dcgm.FieldHandle, error) {
+		return dcgm.FieldHandle{}, nil
+	}
+	dcgmFieldGroupDestroy = func(dcgm.FieldHandle) error {
+		return nil
+	}
+	dcgmWatchFieldsWithGroupEx = func(dcgm.FieldHandle, dcgm.GroupHandle, int64, float64, int32) error {
+		return nil
+	}
+
+	sysInfo := SystemInfo{
+		GPUCount: 1,
+		InfoType: dcgm.FE_GPU,
+		gOpt:     DeviceOptions{Flex: true},
+	}
+
+	encode := func() string {
+		var out bytes.Buffer
+		require.NoError(t, watchedFields.encode(&out))
+		return out.String()
+	}
+
+	before := watchedFields.fields.Load()
+
+	cleanups, _, err := SetupDcgmFieldsWatch([]dcgm.Short{dcgm.DCGM_FI_DEV_GPU_TEMP}, sysInfo, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, before+1, watchedFields.fields.Load())
+
+	moreCleanups, _, err := SetupDcgmFieldsWatch([]dcgm.Short{dcgm.DCGM_FI_DEV_GPU_TEMP, dcgm.DCGM_FI_DEV_POWER_USAGE}, sysInfo, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, before+3, watchedFields.fields.Load(), "adding counters increments the gauge")
+	assert.Contains(t, encode(), `DCGM_EXPORTER_WATCHED_FIELDS{watch="groups"} 2`)
+
+	for _, cleanup := range append(cleanups, moreCleanups...) {
+		cleanup()
+		cleanup()
+	}
+
+	assert.Equal(t, before, watchedFields.fields.Load(), "cleanups decrement the gauge once")
+	assert.Contains(t, encode(), `DCGM_EXPORTER_WATCHED_FIELDS{watch="groups"} 0`)
+	assert.NoError(t, validateExposition(encode()))
+}
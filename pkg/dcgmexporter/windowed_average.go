@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+)
+
+var windowedAverageCounter = Counter{
+	FieldName: "DCGM_EXPORTER_WINDOWED_AVERAGE",
+	PromType:  "gauge",
+	Help:      "Average utilization of the field over the collections within the window (in %).",
+}
+
+// windowedFields are the utilization fields, which are averaged; their instantaneous values are spiky.
+var windowedFields = []dcgm.Short{
+	dcgm.DCGM_FI_DEV_GPU_UTIL,
+	dcgm.DCGM_FI_DEV_MEM_COPY_UTIL,
+	dcgm.DCGM_FI_DEV_ENC_UTIL,
+	dcgm.DCGM_FI_DEV_DEC_UTIL,
+}
+
+// windowSample is a value of a series and the time it was collected.
+type windowSample struct {
+	at    time.Time
+	value float64
+}
+
+// evictSamples drops the samples, which were collected a window or more before now.
+func evictSamples(samples []windowSample, now time.Time, window time.Duration) []windowSample {
+	return slices.DeleteFunc(samples, func(s windowSample) bool {
+		return now.Sub(s.at) >= window
+	})
+}
+
+// windowedAverager keeps the values of every series of the utilization fields, which were collected within the window.
+type windowedAverager struct {
+	window time.Duration
+
+	mtx     sync.Mutex
+	history map[string][]windowSample
+}
+
+// newWindowedAverager returns a MetricProcessor, which adds DCGM_EXPORTER_WINDOWED_AVERAGE, the average of the
+// values collected within the window, for every series of the utilization fields.
+func newWindowedAverager(window time.Duration) MetricProcessor {
+	averager := &windowedAverager{
+		window:  window,
+		history: map[string][]windowSample{},
+	}
+
+	return averager.process
+}
+
+func (a *windowedAverager) process(metrics MetricsByCounter) MetricsByCounter {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	now := timeNow()
+	history := map[string][]windowSample{}
+	var averages []Metric
+
+	for counter, values := range metrics {
+		if !slices.Contains(windowedFields, counter.FieldID) || counter.PromType == "label" {
+			continue
+		}
+
+		for _, m := range values {
+			// Samples of sampled fields have their own timestamps
+			if m.Timestamp != 0 {
+				continue
+			}
+
+			value, err := strconv.ParseFloat(m.Value, 64)
+			if err != nil {
+				continue
+			}
+
+			key := seriesKey(m)
+			samples := append(evictSamples(a.history[key], now, a.window), windowSample{at: now, value: value})
+			history[key] = samples
+
+			var sum float64
+			for _, s := range samples {
+				sum += s.value
+			}
+
+			average := m
+			average.Counter = windowedAverageCounter
+			average.Value = fmt.Sprintf("%f", sum/float64(len(samples)))
+			average.Labels = maps.Clone(m.Labels)
+			if average.Labels == nil {
+				average.Labels = map[string]string{}
+			}
+			average.Labels[windowSizeInMSLabel] = fmt.Sprint(a.window.Milliseconds())
+			average.Attributes = maps.Clone(m.Attributes)
+			if average.Attributes == nil {
+				average.Attributes = map[string]string{}
+			}
+			average.Attributes[fieldAttribute] = counter.FieldName
+
+			averages = append(averages, average)
+		}
+	}
+
+	// Series that were not collected this time are forgotten
+	a.history = history
+
+	if len(averages) > 0 {
+		metrics[windowedAverageCounter] = averages
+	}
+
+	return metrics
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-dcgm/pkg/dcgm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowedAverager(t *testing.T) {
+	utilCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_UTIL, FieldName: "DCGM_FI_DEV_GPU_UTIL", PromType: "gauge", Help: "GPU utilization (in %)."}
+	tempCounter := Counter{FieldID: dcgm.DCGM_FI_DEV_GPU_TEMP, FieldName: "DCGM_FI_DEV_GPU_TEMP", PromType: "gauge", Help: "GPU temperature (in C)."}
+
+	now := timeNow
+	defer func() {
+		timeNow = now
+	}()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	elapsed := time.Duration(0)
+	timeNow = func() time.Time {
+		return start.Add(elapsed)
+	}
+
+	collect := func(average MetricProcessor, util string) []Metric {
+		metrics := MetricsByCounter{
+			utilCounter: {{Counter: utilCounter, Value: util, GPU: "0", Attributes: map[string]string{}}},
+			tempCounter: {{Counter: tempCounter, Value: "60", GPU: "0", Attributes: map[string]string{}}},
+		}
+		metrics = average(metrics)
+		require.Len(t, metrics[utilCounter], 1, "the instantaneous value is kept")
+		return metrics[windowedAverageCounter]
+	}
+
+	t.Run("When the average converges over several scrapes", func(t *testing.T) {
+		elapsed = 0
+		average := newWindowedAverager(30 * time.Second)
+
+		averages := collect(average, "100")
+		require.Len(t, averages, 1, "only the utilization fields are averaged")
+		assert.Equal(t, "100.000000", averages[0].Value)
+		assert.Equal(t, "0", averages[0].GPU)
+		assert.Equal(t, map[string]string{fieldAttribute: "DCGM_FI_DEV_GPU_UTIL"}, averages[0].Attributes)
+		assert.Equal(t, map[string]string{windowSizeInMSLabel: "30000"}, averages[0].Labels)
+
+		elapsed += 10 * time.Second
+		assert.Equal(t, "50.000000", collect(average, "0")[0].Value)
+
+		elapsed += 10 * time.Second
+		assert.Equal(t, "50.000000", collect(average, "50")[0].Value)
+
+		// The value of 100 leaves the window
+		elapsed += 10 * time.Second
+		assert.Equal(t, "33.333333", collect(average, "50")[0].Value)
+
+		for i := 0; i < 3; i++ {
+			elapsed += 10 * time.Second
+			collect(average, "50")
+		}
+		assert.Equal(t, "50.000000", collect(average, "50")[0].Value, "the average converges to the steady value")
+	})
+
+	t.Run("When a series is not collected", func(t *testing.T) {
+		elapsed = 0
+		average := newWindowedAverager(30 * time.Second)
+
+		collect(average, "100")
+		average(MetricsByCounter{})
+		assert.Equal(t, "0.000000", collect(average, "0")[0].Value, "the history of missing series is forgotten")
+	})
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dcgmexporter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlCounter is a counter of the YAML counters file; it has the fields of a line of the CSV counters file.
+type yamlCounter struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+	Help string `yaml:"help"`
+	Expr string `yaml:"expr"`
+}
+
+// ReadYAMLFile reads the counters of a YAML counters file, a list of counters with the name, type, help and the
+// optional expr keys, into the records of the equivalent CSV file, so that both formats produce the same counters.
+func ReadYAMLFile(filename string) ([][]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var counters []yamlCounter
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	err = decoder.Decode(&counters)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("malformed YAML counters file; err: %w", err)
+	}
+
+	records := make([][]string, 0, len(counters))
+	for i, counter := range counters {
+		if counter.Name == "" || counter.Type == "" {
+			return nil, fmt.Errorf("malformed YAML counter %d; name and type are required", i)
+		}
+
+		record := []string{counter.Name, counter.Type, counter.Help}
+		if counter.Expr != "" {
+			record = append(record, counter.Expr)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}